@@ -3,6 +3,7 @@ package backend
 import (
 	"context"
 	"debug/pe"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -13,13 +14,18 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dweymouth/supersonic/backend/ipc"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaserver"
 	"github.com/dweymouth/supersonic/backend/player"
 	"github.com/dweymouth/supersonic/backend/player/mpv"
+	"github.com/dweymouth/supersonic/backend/rebroadcast"
 	"github.com/dweymouth/supersonic/backend/util"
+	"github.com/dweymouth/supersonic/backend/webhooks"
+	"github.com/dweymouth/supersonic/backend/webremote"
 	"github.com/dweymouth/supersonic/backend/windows"
 	"github.com/dweymouth/supersonic/sharedutil"
 	"github.com/google/uuid"
@@ -36,6 +42,11 @@ const (
 	savedShuffledQueueFile   = "saved_shuffled_queue.json"
 	themesDir                = "themes"
 	audioCacheSubdir         = "audio"
+	waveformCacheSubdir      = "waveforms"
+	ipcCertFile              = "ipc_cert.pem"
+	ipcKeyFile               = "ipc_key.pem"
+	ipcKnownHostsFile        = "ipc_known_hosts"
+	webhookQueueFile         = "webhook_queue.json"
 )
 
 var (
@@ -49,8 +60,11 @@ type App struct {
 	Config          *Config
 	ServerManager   *ServerManager
 	LyricsManager   *LyricsManager
+	BookmarkManager *BookmarkManager
+	PodcastManager  *PodcastManager
 	ImageManager    *ImageManager
 	AudioCache      *AudioCache
+	WaveformCache   *WaveformCache
 	AutoEQManager   *AutoEQManager
 	EQPresetManager *EQPresetManager
 	PlaybackManager *PlaybackManager
@@ -59,11 +73,16 @@ type App struct {
 	MPRISHandler    *MPRISHandler
 	WinSMTC         *windows.SMTC
 	ipcServer       ipc.IPCServer
+	mediaServer     *mediaserver.Server
+	rebroadcast     *rebroadcast.Server
+	webRemote       *webremote.Server
+	webhooks        *webhooks.Dispatcher
 
 	// UI callbacks to be set in main
 	OnReactivate  func()
 	OnExit        func()
 	OnReloadTheme func()
+	OnOpenURI     func(SupersonicURI) // called from a background goroutine
 
 	appName        string
 	displayAppName string
@@ -78,6 +97,9 @@ type App struct {
 
 	lastWrittenCfg Config
 
+	pendingURILock sync.Mutex
+	pendingURI     *SupersonicURI
+
 	logFile *os.File
 }
 
@@ -122,18 +144,44 @@ func StartupApp(appName, displayAppName, appVersion, appVersionTag, latestReleas
 	a.readConfig()
 
 	cli, _ := ipc.Connect()
+	if *FlagHost != "" {
+		token := *FlagToken
+		if token == "" {
+			token = os.Getenv(ipc.TokenEnvVar)
+		}
+		c, err := ipc.ConnectRemote(*FlagHost, token, filepath.Join(confDir, ipcKnownHostsFile))
+		if err != nil {
+			log.Fatalf("error connecting to %s: %s", *FlagHost, err.Error())
+		}
+		cli = c
+	}
+	deepLink := DeepLinkArg()
 	if HaveCommandLineOptions() {
 		if err := a.checkFlagsAndSendIPCMsg(cli); err != nil {
 			// we were supposed to control another instance and couldn't
 			log.Fatalf("error sending IPC message: %s", err.Error())
 		}
 		return nil, ErrAnotherInstance
+	} else if cli != nil && deepLink != "" {
+		// links are always opened in the running instance
+		if err := cli.OpenURI(deepLink); err != nil {
+			log.Fatalf("error opening %s: %s", deepLink, err.Error())
+		}
+		return nil, ErrAnotherInstance
 	} else if cli != nil && !a.Config.Application.AllowMultiInstance {
 		log.Println("Another instance is running. Reactivating it...")
 		cli.Show()
 		return nil, ErrAnotherInstance
 	}
 
+	if deepLink != "" {
+		if u, err := ParseSupersonicURI(deepLink); err == nil {
+			a.pendingURI = &u
+		} else {
+			log.Printf("ignoring invalid link %s", deepLink)
+		}
+	}
+
 	log.Printf("Starting %s...", appName)
 	log.Printf("Using config dir: %s", confDir)
 	log.Printf("Using cache dir: %s", cacheDir)
@@ -158,8 +206,19 @@ func StartupApp(appName, displayAppName, appVersion, appVersionTag, latestReleas
 			log.Printf("failed to create audio cache: %s", err.Error())
 		}
 		a.AudioCache = ac
+		a.Config.Playback.MaxWaveformCacheSizeMB = clamp(a.Config.Playback.MaxWaveformCacheSizeMB, 1, 500)
+		a.WaveformCache = NewWaveformCache(a.ServerManager, filepath.Join(cacheDir, waveformCacheSubdir),
+			int64(a.Config.Playback.MaxWaveformCacheSizeMB)*1_048_576)
 	}
-	a.PlaybackManager = NewPlaybackManager(a.bgrndCtx, a.ServerManager, a.AudioCache, a.LocalPlayer, &a.Config.Playback, &a.Config.Scrobbling, &a.Config.Transcoding, &a.Config.Application)
+	a.BookmarkManager = NewBookmarkManager(a.ServerManager)
+	a.PlaybackManager = NewPlaybackManager(a.bgrndCtx, a.ServerManager, a.AudioCache, a.WaveformCache, a.BookmarkManager, a.LocalPlayer, &a.Config.Playback, &a.Config.Scrobbling, &a.Config.Transcoding, &a.Config.Application)
+	a.PlaybackManager.migrateMPDPasswords()
+	a.PodcastManager = NewPodcastManager(a.ServerManager, confDir)
+	a.PlaybackManager.OnPlayedToEnd(func(item mediaprovider.MediaItem) {
+		if ep, ok := item.(*mediaprovider.PodcastEpisode); ok {
+			a.PodcastManager.SetPlayed(ep.ID, true)
+		}
+	})
 	a.Config.Application.MaxImageCacheSizeMB = clamp(a.Config.Application.MaxImageCacheSizeMB, 1, 500)
 	a.ImageManager.SetMaxOnDiskCacheSizeBytes(int64(a.Config.Application.MaxImageCacheSizeMB) * 1_048_576)
 	a.ServerManager.SetPrefetchAlbumCoverCallback(func(coverID string) {
@@ -218,27 +277,59 @@ func StartupApp(appName, displayAppName, appVersion, appVersionTag, latestReleas
 				if s := a.ServerManager.GetServer(); s != nil {
 					if tr := a.PlaybackManager.NowPlaying(); tr != nil && tr.Metadata().Type == mediaprovider.MediaItemTypeTrack {
 						if supportsRating, ok := s.(mediaprovider.SupportsRating); ok {
-							supportsRating.SetRating(mediaprovider.RatingFavoriteParameters{
+							err := supportsRating.SetRating(mediaprovider.RatingFavoriteParameters{
 								TrackIDs: []string{tr.Metadata().ID},
 							}, rating)
+							if err == nil {
+								a.OnTrackRatingsChanged([]string{tr.Metadata().ID}, rating)
+							} else {
+								log.Printf("error setting rating: %s", err.Error())
+							}
 						}
 					}
 				}
 			}
 
-			a.ipcServer = ipc.NewServer(
-				a.PlaybackManager,
-				ipcRatingHandler,
-				a.ServerManager,
-				a.callOnReactivate,
-				func() { _ = a.callOnExit() },
-				a.callOnReloadTheme)
+			a.ipcServer = ipc.NewServer(ipc.ServerOptions{
+				Playback:      a.PlaybackManager,
+				Queue:         ipcQueueHandler{a.PlaybackManager},
+				Status:        func() ipc.Status { return ipcStatus(a.PlaybackManager) },
+				SetRating:     ipcRatingHandler,
+				ServerManager: a.ServerManager,
+				Show:          a.callOnReactivate,
+				Quit:          func() { _ = a.callOnExit() },
+				ReloadTheme:   a.callOnReloadTheme,
+				OpenURI:       a.OpenURI,
+			})
+			a.publishIPCEvents(a.ipcServer)
 			go a.ipcServer.Serve(listener)
+			if a.Config.RemoteIPC.Enabled {
+				a.startRemoteIPC()
+			}
 		} else {
 			log.Printf("error starting IPC server: %s", err.Error())
 		}
 	}
 
+	if a.Config.MediaServer.Enabled {
+		a.startMediaServer(displayAppName, appVersion)
+	}
+	if a.Config.Rebroadcast.Enabled {
+		a.startRebroadcast(displayAppName)
+	}
+	if a.Config.WebRemote.Enabled {
+		a.startWebRemote()
+	}
+	if a.Config.Discord.Enabled && a.Config.Discord.ApplicationID != "" {
+		a.startDiscordPresence()
+	}
+	if a.Config.ScriptHooks.hasHooks() {
+		a.startScriptHooks()
+	}
+	if a.Config.Webhooks.Enabled && len(a.Config.Webhooks.Hooks) > 0 {
+		a.startWebhooks()
+	}
+
 	// OS media center integrations
 	if a.Config.Application.EnableOSMediaPlayerAPIs {
 		// Linux MPRIS
@@ -348,6 +439,39 @@ func (a *App) callOnReloadTheme() {
 	}
 }
 
+// OpenURI brings the window to the front and opens a supersonic:// URI
+// through OnOpenURI. Until a server is connected, the URI is kept
+// to be opened later by OpenPendingURI.
+func (a *App) OpenURI(uri string) error {
+	u, err := ParseSupersonicURI(uri)
+	if err != nil {
+		return err
+	}
+	a.callOnReactivate()
+	a.pendingURILock.Lock()
+	if a.OnOpenURI == nil || a.ServerManager.Server == nil {
+		a.pendingURI = &u
+		a.pendingURILock.Unlock()
+		return nil
+	}
+	a.pendingURILock.Unlock()
+	a.OnOpenURI(u)
+	return nil
+}
+
+// OpenPendingURI opens the URI the app was launched with, or that was
+// received before a server was connected, if any. It is to be called by
+// the UI once it has connected to a server.
+func (a *App) OpenPendingURI() {
+	a.pendingURILock.Lock()
+	u := a.pendingURI
+	a.pendingURI = nil
+	a.pendingURILock.Unlock()
+	if u != nil && a.OnOpenURI != nil {
+		a.OnOpenURI(*u)
+	}
+}
+
 func (a *App) callOnExit() error {
 	if a.OnExit == nil {
 		return errors.New("no quit handler registered")
@@ -591,6 +715,15 @@ func (a *App) Shutdown() {
 	if a.ipcServer != nil {
 		a.ipcServer.Shutdown(a.bgrndCtx)
 	}
+	if a.mediaServer != nil {
+		a.mediaServer.Shutdown(a.bgrndCtx)
+	}
+	if a.rebroadcast != nil {
+		a.rebroadcast.Shutdown(a.bgrndCtx)
+	}
+	if a.webRemote != nil {
+		a.webRemote.Shutdown(a.bgrndCtx)
+	}
 	if a.MPRISHandler != nil {
 		a.MPRISHandler.Shutdown()
 	}
@@ -599,6 +732,7 @@ func (a *App) Shutdown() {
 	}
 	a.PlaybackManager.DisableCallbacks()
 	a.PlaybackManager.Shutdown() // will trigger scrobble check
+	a.BookmarkManager.Shutdown()
 	if a.AudioCache != nil {
 		a.AudioCache.Shutdown()
 	}
@@ -606,6 +740,115 @@ func (a *App) Shutdown() {
 	a.LocalPlayer.Destroy()
 }
 
+func (a *App) startMediaServer(displayAppName, appVersion string) {
+	cfg := &a.Config.MediaServer
+	if cfg.DeviceUUID == "" {
+		cfg.DeviceUUID = mediaserver.NewDeviceUUID()
+	}
+	name := cfg.FriendlyName
+	if name == "" {
+		host, _ := os.Hostname()
+		name = fmt.Sprintf("%s (%s)", displayAppName, host)
+	}
+	a.mediaServer = mediaserver.NewServer(name, cfg.DeviceUUID, appVersion, cfg.Port, func() mediaprovider.MediaProvider {
+		return a.ServerManager.Server
+	})
+	if err := a.mediaServer.Start(); err != nil {
+		log.Printf("error starting UPnP media server: %s", err.Error())
+		a.mediaServer = nil
+		return
+	}
+	a.ServerManager.OnServerConnected(func(_ *ServerConfig) {
+		a.mediaServer.LibraryChanged()
+	})
+	a.ServerManager.OnLogout(func() {
+		a.mediaServer.LibraryChanged()
+	})
+}
+
+func (a *App) startRebroadcast(displayAppName string) {
+	cfg := a.Config.Rebroadcast
+	a.rebroadcast = rebroadcast.NewServer(displayAppName, cfg.Port, cfg.Format, cfg.BitRateKBPS)
+	if err := a.rebroadcast.Start(); err != nil {
+		log.Printf("error starting rebroadcast stream: %s", err.Error())
+		a.rebroadcast = nil
+		return
+	}
+
+	setTrack := func() {
+		item := a.PlaybackManager.NowPlaying()
+		if item == nil {
+			a.rebroadcast.SetTrack("", "", 0)
+			return
+		}
+		meta := item.Metadata()
+		title := meta.Name
+		if len(meta.Artists) > 0 {
+			title = strings.Join(meta.Artists, ", ") + " - " + title
+		}
+		stat := a.PlaybackManager.PlaybackStatus()
+		a.rebroadcast.SetTrack(a.PlaybackManager.NowPlayingMediaURL(), title, stat.TimePos)
+		a.rebroadcast.SetPaused(stat.State != player.Playing)
+	}
+	a.PlaybackManager.OnSongChange(func(mediaprovider.MediaItem, *mediaprovider.Track) { setTrack() })
+	a.PlaybackManager.OnSeek(setTrack)
+	a.PlaybackManager.OnPlaying(func() { a.rebroadcast.SetPaused(false) })
+	a.PlaybackManager.OnPaused(func() { a.rebroadcast.SetPaused(true) })
+	a.PlaybackManager.OnStopped(func() { a.rebroadcast.SetTrack("", "", 0) })
+	a.PlaybackManager.OnRadioMetadataChange(func(radioName, title, artist string) {
+		if artist != "" {
+			title = artist + " - " + title
+		}
+		a.rebroadcast.SetTitle(title)
+	})
+}
+
+// RebroadcastURL returns the URL of the rebroadcast stream, or "" if it is not running.
+func (a *App) RebroadcastURL() string {
+	if a.rebroadcast == nil {
+		return ""
+	}
+	return a.rebroadcast.StreamURL()
+}
+
+// webRemotePlayer adapts the PlaybackManager to the web remote.
+type webRemotePlayer struct {
+	*PlaybackManager
+}
+
+func (w webRemotePlayer) Status() (player.State, float64, float64) {
+	stat := w.PlaybackStatus()
+	return stat.State, stat.TimePos, stat.Duration
+}
+
+func (a *App) startWebRemote() {
+	cfg := &a.Config.WebRemote
+	if cfg.Token == "" {
+		cfg.Token = webremote.NewToken()
+	}
+	a.webRemote = webremote.NewServer(cfg.Port, cfg.Token, webRemotePlayer{a.PlaybackManager},
+		func() mediaprovider.MediaProvider { return a.ServerManager.Server },
+		a.ImageManager.GetCoverThumbnail)
+	if err := a.webRemote.Start(); err != nil {
+		log.Printf("error starting web remote: %s", err.Error())
+		a.webRemote = nil
+	}
+}
+
+func (a *App) startRemoteIPC() {
+	cfg := &a.Config.RemoteIPC
+	if cfg.Token == "" {
+		cfg.Token = ipc.NewToken()
+	}
+	listener, err := ipc.ListenTLS(fmt.Sprintf(":%d", cfg.Port),
+		filepath.Join(a.configDir, ipcCertFile), filepath.Join(a.configDir, ipcKeyFile))
+	if err != nil {
+		log.Printf("error starting remote IPC listener: %s", err.Error())
+		return
+	}
+	go a.ipcServer.ServeRemote(listener, cfg.Token)
+}
+
 func (a *App) SavePlayQueueIfEnabled() {
 	if !a.Config.Application.SavePlayQueue {
 		return
@@ -699,6 +942,23 @@ func (a *App) checkFlagsAndSendIPCMsg(cli *ipc.Client) error {
 		return errors.New("no IPC connection")
 	}
 	switch {
+	case *FlagStatus:
+		status, err := cli.Status()
+		if err != nil {
+			return err
+		}
+		if StatusFormatCLIArg == "" {
+			b, err := json.Marshal(status)
+			if err == nil {
+				fmt.Println(string(b))
+			}
+			return err
+		}
+		out, err := status.Format(StatusFormatCLIArg)
+		if err == nil {
+			fmt.Println(out)
+		}
+		return err
 	case *FlagPlay:
 		return cli.Play()
 	case *FlagPause:
@@ -717,6 +977,8 @@ func (a *App) checkFlagsAndSendIPCMsg(cli *ipc.Client) error {
 		return cli.Show()
 	case *FlagReloadTheme:
 		return cli.ReloadTheme()
+	case DeepLinkArg() != "":
+		return cli.OpenURI(DeepLinkArg())
 	case VolumeCLIArg >= 0:
 		return cli.SetVolume(VolumeCLIArg)
 	case VolumePctCLIArg != 0:
@@ -751,6 +1013,34 @@ func (a *App) checkFlagsAndSendIPCMsg(cli *ipc.Client) error {
 		return err
 	case RateCurrentCLIArg >= 0:
 		return cli.RateCurrentTrack(RateCurrentCLIArg)
+	case *FlagFollow:
+		return cli.Follow(func(event string) { fmt.Println(event) })
+	case *FlagQueue:
+		data, err := cli.Queue()
+		if err == nil {
+			fmt.Println(data)
+		}
+		return err
+	case EnqueueAlbumCLIArg != "":
+		return cli.EnqueueAlbum(EnqueueAlbumCLIArg, *FlagEnqueueNext)
+	case EnqueuePlaylistCLIArg != "":
+		return cli.EnqueuePlaylist(EnqueuePlaylistCLIArg, *FlagEnqueueNext)
+	case EnqueueTrackCLIArg != "":
+		return cli.EnqueueTrack(EnqueueTrackCLIArg, *FlagEnqueueNext)
+	case QueueRemoveCLIArg >= 0:
+		return cli.RemoveFromQueue(QueueRemoveCLIArg)
+	case QueueMoveFromCLIArg >= 0:
+		return cli.MoveInQueue(QueueMoveFromCLIArg, QueueMoveToCLIArg)
+	case *FlagClearQueue:
+		return cli.ClearQueue()
+	case QueueJumpCLIArg >= 0:
+		return cli.JumpToQueueIndex(QueueJumpCLIArg)
+	case *FlagToggleShuffle:
+		return cli.ToggleShuffle()
+	case LoopModeCLIArg == "next":
+		return cli.SetLoopMode("")
+	case LoopModeCLIArg != "":
+		return cli.SetLoopMode(LoopModeCLIArg)
 	default:
 		return nil
 	}
@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"log"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// BookmarkManager keeps a cache of the saved playback positions
+// on the current server, if it supports bookmarks, and saves
+// new positions to the server in the background.
+type BookmarkManager struct {
+	sm *ServerManager
+
+	mutex     sync.Mutex
+	positions map[string]float64 // track ID -> position (seconds)
+	pending   sync.WaitGroup
+
+	onBookmarksChange []func()
+}
+
+func NewBookmarkManager(sm *ServerManager) *BookmarkManager {
+	b := &BookmarkManager{sm: sm}
+	sm.OnServerConnected(func(*ServerConfig) {
+		go func() {
+			if _, err := b.GetBookmarks(); err != nil {
+				log.Printf("failed to load bookmarks: %v", err)
+			}
+		}()
+	})
+	sm.OnLogout(func() {
+		b.mutex.Lock()
+		b.positions = nil
+		b.mutex.Unlock()
+	})
+	return b
+}
+
+// Registers a callback that is invoked whenever a bookmark is saved or deleted.
+// The callback may be invoked from a background goroutine.
+func (b *BookmarkManager) OnBookmarksChange(cb func()) {
+	b.onBookmarksChange = append(b.onBookmarksChange, cb)
+}
+
+// IsSupported returns true if the current server supports bookmarks.
+func (b *BookmarkManager) IsSupported() bool {
+	_, ok := b.sm.Server.(mediaprovider.BookmarkProvider)
+	return ok
+}
+
+// GetBookmarks fetches all bookmarks from the server and refreshes the cache.
+func (b *BookmarkManager) GetBookmarks() ([]*mediaprovider.Bookmark, error) {
+	bp, ok := b.sm.Server.(mediaprovider.BookmarkProvider)
+	if !ok {
+		return nil, nil
+	}
+	bookmarks, err := bp.GetBookmarks()
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]float64, len(bookmarks))
+	for _, bm := range bookmarks {
+		positions[bm.Track.ID] = bm.Position
+	}
+	b.mutex.Lock()
+	b.positions = positions
+	b.mutex.Unlock()
+	return bookmarks, nil
+}
+
+// Position returns the bookmarked position of the given track, if any.
+func (b *BookmarkManager) Position(trackID string) (float64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	pos, ok := b.positions[trackID]
+	return pos, ok
+}
+
+// SaveAsync saves the bookmarked position of the given track.
+func (b *BookmarkManager) SaveAsync(trackID string, positionSecs float64) {
+	bp, ok := b.sm.Server.(mediaprovider.BookmarkProvider)
+	if !ok {
+		return
+	}
+	b.mutex.Lock()
+	if b.positions == nil {
+		b.positions = make(map[string]float64)
+	}
+	b.positions[trackID] = positionSecs
+	b.mutex.Unlock()
+
+	b.pending.Add(1)
+	go func() {
+		defer b.pending.Done()
+		if err := bp.CreateBookmark(trackID, positionSecs, ""); err != nil {
+			log.Printf("failed to save bookmark: %v", err)
+			return
+		}
+		b.invokeOnBookmarksChange()
+	}()
+}
+
+// DeleteAsync deletes the bookmark for the given track, if it exists.
+func (b *BookmarkManager) DeleteAsync(trackID string) {
+	bp, ok := b.sm.Server.(mediaprovider.BookmarkProvider)
+	if !ok {
+		return
+	}
+	b.mutex.Lock()
+	_, exists := b.positions[trackID]
+	delete(b.positions, trackID)
+	b.mutex.Unlock()
+	if !exists {
+		return
+	}
+
+	b.pending.Add(1)
+	go func() {
+		defer b.pending.Done()
+		if err := bp.DeleteBookmark(trackID); err != nil {
+			log.Printf("failed to delete bookmark: %v", err)
+			return
+		}
+		b.invokeOnBookmarksChange()
+	}()
+}
+
+// Shutdown waits for any in-progress bookmark saves to complete.
+func (b *BookmarkManager) Shutdown() {
+	b.pending.Wait()
+}
+
+func (b *BookmarkManager) invokeOnBookmarksChange() {
+	for _, cb := range b.onBookmarksChange {
+		cb()
+	}
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/util"
+)
+
+// returns the index of the chapter containing the given position (seconds),
+// or -1 if there are no chapters
+func chapterIndexAt(chapters []mediaprovider.Chapter, pos float64) int {
+	if len(chapters) == 0 {
+		return -1
+	}
+	idx := 0
+	for i, ch := range chapters {
+		if ch.Start > pos {
+			break
+		}
+		idx = i
+	}
+	return idx
+}
+
+// loads chapters from a CUE sheet next to the given audio file,
+// named either "album.cue" or "album.flac.cue" for "album.flac".
+// Returns nil if the file is not locally accessible or has no CUE sheet.
+func loadSidecarCueChapters(audioPath string) []mediaprovider.Chapter {
+	if audioPath == "" || !filepath.IsAbs(audioPath) {
+		return nil
+	}
+	candidates := []string{
+		strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".cue",
+		audioPath + ".cue",
+	}
+	for _, cuePath := range candidates {
+		f, err := os.Open(cuePath)
+		if err != nil {
+			continue
+		}
+		sheet, err := util.ParseCueSheet(f)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		if chapters := cueChaptersForFile(sheet, filepath.Base(audioPath)); len(chapters) > 0 {
+			return chapters
+		}
+	}
+	return nil
+}
+
+func cueChaptersForFile(sheet *util.CueSheet, fileName string) []mediaprovider.Chapter {
+	var file *util.CueFile
+	for i, f := range sheet.Files {
+		if strings.EqualFold(filepath.Base(f.Name), fileName) {
+			file = &sheet.Files[i]
+			break
+		}
+	}
+	if file == nil && len(sheet.Files) == 1 {
+		// the CUE sheet may reference the file by its name before
+		// it was transcoded (e.g. "album.wav" for "album.flac")
+		file = &sheet.Files[0]
+	}
+	if file == nil {
+		return nil
+	}
+
+	chapters := make([]mediaprovider.Chapter, len(file.Tracks))
+	for i, tr := range file.Tracks {
+		performer := tr.Performer
+		if performer == "" {
+			performer = sheet.Performer
+		}
+		chapters[i] = mediaprovider.Chapter{
+			Title:     tr.Title,
+			Performer: performer,
+			Start:     tr.Start,
+		}
+	}
+	return chapters
+}
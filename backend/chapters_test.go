@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+func TestChapterIndexAt(t *testing.T) {
+	chapters := []mediaprovider.Chapter{{Start: 0}, {Start: 60}, {Start: 120}}
+	for _, tt := range []struct {
+		pos  float64
+		want int
+	}{
+		{0, 0}, {59.9, 0}, {60, 1}, {119, 1}, {500, 2},
+	} {
+		if got := chapterIndexAt(chapters, tt.pos); got != tt.want {
+			t.Errorf("chapterIndexAt(%v) = %d, want %d", tt.pos, got, tt.want)
+		}
+	}
+	if got := chapterIndexAt(nil, 10); got != -1 {
+		t.Errorf("Expected -1 for no chapters, got %d", got)
+	}
+}
+
+func TestLoadSidecarCueChapters(t *testing.T) {
+	dir := t.TempDir()
+	audioPath := filepath.Join(dir, "album.flac")
+	cue := `PERFORMER "The Band"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 01 01:30:00
+`
+	if err := os.WriteFile(filepath.Join(dir, "album.cue"), []byte(cue), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chapters := loadSidecarCueChapters(audioPath)
+	if len(chapters) != 2 {
+		t.Fatalf("Expected 2 chapters, got %d", len(chapters))
+	}
+	if chapters[1].Title != "Two" || chapters[1].Performer != "The Band" || chapters[1].Start != 90 {
+		t.Errorf("Unexpected chapter %+v", chapters[1])
+	}
+
+	if chapters := loadSidecarCueChapters("album.flac"); chapters != nil {
+		t.Error("Expected no chapters for a relative (server-side) path")
+	}
+}
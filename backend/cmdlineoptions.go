@@ -1,11 +1,13 @@
 package backend
 
 import (
+	"errors"
 	"flag"
 	"os"
 	"strconv"
 	"strings"
 
+	"github.com/dweymouth/supersonic/backend/ipc"
 	"golang.org/x/term"
 )
 
@@ -23,6 +25,16 @@ var (
 	SearchPlaylistCLIArg string  = ""
 	SearchTrackCLIArg    string  = ""
 
+	EnqueueAlbumCLIArg    string = ""
+	EnqueuePlaylistCLIArg string = ""
+	EnqueueTrackCLIArg    string = ""
+	QueueRemoveCLIArg     int    = -1
+	QueueMoveFromCLIArg   int    = -1
+	QueueMoveToCLIArg     int    = -1
+	QueueJumpCLIArg       int    = -1
+	LoopModeCLIArg        string = ""
+	StatusFormatCLIArg    string = ""
+
 	FlagPlay              = flag.Bool("play", false, "unpause or begin playback")
 	FlagPause             = flag.Bool("pause", false, "pause playback")
 	FlagPlayPause         = flag.Bool("play-pause", false, "toggle play/pause state")
@@ -31,11 +43,21 @@ var (
 	FlagStop              = flag.Bool("stop", false, "stop playback")
 	FlagPauseAfterCurrent = flag.Bool("pause-after-current", false, "pause playback after current track")
 	FlagStartMinimized    = flag.Bool("start-minimized", false, "start app minimized")
+	FlagHeadless          = flag.Bool("headless", false, "run without a UI, as a player controlled by IPC, MPRIS, and remote clients")
+	FlagHost              = flag.String("host", "", "control the Supersonic instance at the given host:port, which has remote control enabled, instead of the local one")
+	FlagToken             = flag.String("token", "", "token for controlling a remote instance with -host (default from the "+ipc.TokenEnvVar+" environment variable)")
+	FlagPasswordFile      = flag.String("password-file", "", "read the server password from the given file instead of the keyring (to be used with -headless)")
 	FlagShow              = flag.Bool("show", false, "show minimized app")
 	FlagReloadTheme       = flag.Bool("reload-theme", false, "reload the current theme")
 	FlagShuffle           = flag.Bool("shuffle", false, "shuffle the tracklist (to be used with either -play-album-by-id or -play-playlist-by-id)")
 	FlagVersion           = flag.Bool("version", false, "print app version and exit")
 	FlagHelp              = flag.Bool("help", false, "print command line options and exit")
+	FlagFollow            = flag.Bool("follow", false, "print playback events from the running instance as JSON lines until it exits")
+	FlagStatus            = flag.Bool("status", false, "print the playback status as JSON, or formatted with -format")
+	FlagQueue             = flag.Bool("queue", false, "print the play queue as JSON")
+	FlagEnqueueNext       = flag.Bool("enqueue-next", false, "insert after the current track instead of at the end of the queue (to be used with -enqueue-album, -enqueue-playlist, or -enqueue-track)")
+	FlagClearQueue        = flag.Bool("clear-queue", false, "stop playback and clear the play queue")
+	FlagToggleShuffle     = flag.Bool("toggle-shuffle", false, "toggle shuffle mode")
 
 	FlagPlayAlbum    *bool
 	FlagPlayPlaylist *bool
@@ -104,6 +126,60 @@ func init() {
 		SearchTrackCLIArg = s
 		return nil
 	})
+	flag.Func("format", "Go text/template for -status output, e.g. '{{.Artist}} - {{.Title}} [{{.Position}}/{{.Duration}}]'. Fields: State, ID, Type, Title, Artist, Artists, Album, Position, Duration, PositionSeconds, DurationSeconds, Volume, LoopMode, Shuffle, QueueIndex, QueueLength", func(s string) error {
+		StatusFormatCLIArg = s
+		return nil
+	})
+	flag.Func("enqueue-album", "add the album with the given ID to the play queue", func(s string) error {
+		EnqueueAlbumCLIArg = s
+		return nil
+	})
+	flag.Func("enqueue-playlist", "add the playlist with the given ID to the play queue", func(s string) error {
+		EnqueuePlaylistCLIArg = s
+		return nil
+	})
+	flag.Func("enqueue-track", "add the track with the given ID to the play queue", func(s string) error {
+		EnqueueTrackCLIArg = s
+		return nil
+	})
+	flag.Func("remove-from-queue", "remove the item at the given index (starting from 0) from the play queue", func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err == nil {
+			QueueRemoveCLIArg = v
+		}
+		return err
+	})
+	flag.Func("move-in-queue", "move a play queue item, given as <from>:<to> indexes (starting from 0)", func(s string) error {
+		from, to, ok := strings.Cut(s, ":")
+		if !ok {
+			return errors.New("expected <from>:<to>")
+		}
+		f, err := strconv.Atoi(from)
+		if err != nil {
+			return err
+		}
+		t, err := strconv.Atoi(to)
+		if err != nil {
+			return err
+		}
+		QueueMoveFromCLIArg, QueueMoveToCLIArg = f, t
+		return nil
+	})
+	flag.Func("jump-to", "start playing the item at the given index (starting from 0) of the play queue", func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err == nil {
+			QueueJumpCLIArg = v
+		}
+		return err
+	})
+	flag.Func("loop-mode", "set the loop mode (none, all, one, or next)", func(s string) error {
+		switch s {
+		case "none", "all", "one", "next":
+			LoopModeCLIArg = s
+			return nil
+		}
+		return errors.New("expected none, all, one, or next")
+	})
 	flag.Func("rate-current", "rate the current track with the given rating (0-5)", func(s string) error {
 		v, err := strconv.Atoi(s)
 		if err == nil {
@@ -116,10 +192,19 @@ func init() {
 func HaveCommandLineOptions() bool {
 	visitedAny := false
 	flag.Visit(func(f *flag.Flag) {
-		// We skip `start-minimized` because it should't send an IPC message.
-		if f.Name != "start-minimized" {
+		// We skip flags for starting the app because they should't send an IPC message.
+		if f.Name != "start-minimized" && f.Name != "headless" && f.Name != "password-file" {
 			visitedAny = true
 		}
 	})
 	return visitedAny
 }
+
+// DeepLinkArg returns the supersonic:// URI given as a command line argument,
+// as when the app is launched to open a link, or "" if there is none.
+func DeepLinkArg() string {
+	if arg := flag.Arg(0); strings.HasPrefix(arg, URIScheme+"://") {
+		return arg
+	}
+	return ""
+}
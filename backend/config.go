@@ -30,6 +30,8 @@ type ServerConfig struct {
 	Nickname        string
 	Default         bool
 	SelectedLibrary string
+	// don't show what's playing from this server on Discord
+	HideDiscordPresence bool
 }
 
 type AppConfig struct {
@@ -117,8 +119,9 @@ type TracksPageConfig struct {
 }
 
 type NowPlayingPageConfig struct {
-	InitialView        string
-	UseBackgroundImage bool
+	InitialView             string
+	UseBackgroundImage      bool
+	BackgroundVisualization string // "None", "Spectrum", or "Spectrogram"
 }
 
 type PlaybackConfig struct {
@@ -128,6 +131,41 @@ type PlaybackConfig struct {
 	SkipOneStarWhenShuffling bool
 	SkipKeywordWhenShuffling string
 	UseWaveformSeekbar       bool
+	MaxWaveformCacheSizeMB   int
+
+	// Tracks at least this long have their playback position automatically
+	// bookmarked on servers that support it. 0 disables automatic bookmarks.
+	AutoBookmarkMinTrackMinutes int
+
+	// Groups of output devices that play in sync, shown in the cast menu
+	PlayerGroups []PlayerGroupConfig
+
+	// MPD servers to offer as remote players in the cast menu
+	MPDServers []MPDServerConfig
+}
+
+type PlayerGroupConfig struct {
+	Name    string
+	Members []PlayerGroupMemberConfig
+}
+
+type PlayerGroupMemberConfig struct {
+	// URL of the remote device, or "local" for this computer
+	DeviceURL string
+	// Delay of the device's audio output relative to the others;
+	// higher values start playback on this device earlier
+	LatencyOffsetMS int
+}
+
+type MPDServerConfig struct {
+	// ID the password is stored in the keyring under
+	ID   uuid.UUID
+	Name string
+	// host:port of the MPD server
+	Address     string
+	HasPassword bool
+	// Deprecated: passwords of older configs, moved to the keyring on startup
+	Password string `toml:",omitempty"`
 }
 
 type LocalPlaybackConfig struct {
@@ -170,9 +208,86 @@ type TranscodingConfig struct {
 	MaxBitRateKBPS   int
 }
 
+type MediaServerConfig struct {
+	Enabled bool
+	// name shown on DLNA devices; if empty, derived from the hostname
+	FriendlyName string
+	// 0 to use a random port
+	Port       int
+	DeviceUUID string
+}
+
+type RebroadcastConfig struct {
+	Enabled bool
+	Port    int
+	// "mp3" or "opus"
+	Format      string
+	BitRateKBPS int
+}
+
+type WebRemoteConfig struct {
+	Enabled bool
+	Port    int
+	// authenticates clients; generated when the web remote is enabled
+	Token string
+}
+
+type RemoteIPCConfig struct {
+	// accept IPC commands from other machines over TLS
+	Enabled bool
+	Port    int
+	// authenticates clients; generated when remote IPC is enabled
+	Token string
+}
+
+type DiscordConfig struct {
+	// show the playing track as Discord Rich Presence
+	Enabled bool
+	// ID of the Discord application the activity is shown as
+	ApplicationID string
+	// Go text/templates for the two lines of the activity.
+	// Fields: Title, Artist, Album, Paused
+	DetailsTemplate string
+	StateTemplate   string
+}
+
+// Shell commands run on playback events, with the track metadata in
+// SUPERSONIC_* environment variables and as JSON on standard input.
+type ScriptHooksConfig struct {
+	OnSongChange string
+	OnPlay       string
+	OnPause      string
+	OnStop       string
+	OnScrobble   string
+	// commands still running after this are killed
+	TimeoutSecs int
+	// events are skipped while this many commands are running
+	MaxConcurrent int
+}
+
+type WebhooksConfig struct {
+	Enabled bool
+	Hooks   []WebhookConfig
+}
+
+// HTTP endpoint that playback and library events are sent to
+type WebhookConfig struct {
+	URL string
+	// POST if empty
+	Method  string
+	Headers map[string]string
+	// Go text/template for the request body, executed with the JSON event,
+	// e.g. {{.event}} and {{json .data.track.title}}. The JSON event is sent if empty.
+	BodyTemplate string
+	// song_change, scrobble, favorite, rating or playlist; all events if empty
+	Events []string
+}
+
+// Config for the visualizations window (originally only the peak meter)
 type PeakMeterConfig struct {
-	WindowHeight int
-	WindowWidth  int
+	WindowHeight  int
+	WindowWidth   int
+	Visualization string
 }
 
 type Config struct {
@@ -195,6 +310,13 @@ type Config struct {
 	Transcoding      TranscodingConfig
 	Theme            ThemeConfig
 	PeakMeter        PeakMeterConfig
+	MediaServer      MediaServerConfig
+	Rebroadcast      RebroadcastConfig
+	WebRemote        WebRemoteConfig
+	RemoteIPC        RemoteIPCConfig
+	Discord          DiscordConfig
+	ScriptHooks      ScriptHooksConfig
+	Webhooks         WebhooksConfig
 }
 
 var SupportedStartupPages = []string{"Albums", "Favorites", "Playlists", "Artists"}
@@ -256,17 +378,21 @@ func DefaultConfig(appVersionTag string) *Config {
 			InitialView: "List",
 		},
 		NowPlayingConfig: NowPlayingPageConfig{
-			InitialView:        "Play Queue",
-			UseBackgroundImage: true,
+			InitialView:             "Play Queue",
+			UseBackgroundImage:      true,
+			BackgroundVisualization: "None",
 		},
 		TracksPage: TracksPageConfig{
 			TracklistColumns: []string{"Album", "Time", "Plays"},
 		},
 		Playback: PlaybackConfig{
-			Autoplay:           false,
-			Shuffle:            false,
-			RepeatMode:         "None",
-			UseWaveformSeekbar: false,
+			Autoplay:               false,
+			Shuffle:                false,
+			RepeatMode:             "None",
+			UseWaveformSeekbar:     false,
+			MaxWaveformCacheSizeMB: 20,
+
+			AutoBookmarkMinTrackMinutes: 20,
 		},
 		LocalPlayback: LocalPlaybackConfig{
 			// "auto" is the name to pass to MPV for autoselecting the output device
@@ -301,8 +427,28 @@ func DefaultConfig(appVersionTag string) *Config {
 			UseRoundedImageCorners: true,
 		},
 		PeakMeter: PeakMeterConfig{
-			WindowWidth:  375,
-			WindowHeight: 100,
+			WindowWidth:   375,
+			WindowHeight:  100,
+			Visualization: "Peak Meter",
+		},
+		Rebroadcast: RebroadcastConfig{
+			Port:        8000,
+			Format:      "mp3",
+			BitRateKBPS: 192,
+		},
+		WebRemote: WebRemoteConfig{
+			Port: 8085,
+		},
+		RemoteIPC: RemoteIPCConfig{
+			Port: 7655,
+		},
+		Discord: DiscordConfig{
+			DetailsTemplate: "{{.Title}}",
+			StateTemplate:   "{{.Artist}}",
+		},
+		ScriptHooks: ScriptHooksConfig{
+			TimeoutSecs:   10,
+			MaxConcurrent: 4,
 		},
 	}
 }
@@ -0,0 +1,172 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// interval between connection attempts while Discord is not running
+const defaultReconnectInterval = 15 * time.Second
+
+// ActivityTypeListening shows the activity as "Listening to <application name>".
+const ActivityTypeListening = 2
+
+// Activity is the Rich Presence shown on the user's Discord profile.
+type Activity struct {
+	Type       int         `json:"type"`
+	Details    string      `json:"details,omitempty"`
+	State      string      `json:"state,omitempty"`
+	Timestamps *Timestamps `json:"timestamps,omitempty"`
+}
+
+// Timestamps of the activity in Unix milliseconds, shown by
+// Discord as the elapsed time, or a progress bar if End is set.
+type Timestamps struct {
+	Start int64 `json:"start,omitempty"`
+	End   int64 `json:"end,omitempty"`
+}
+
+// Client keeps the Rich Presence activity of the Discord client in sync
+// with the latest activity set, connecting whenever Discord is running.
+type Client struct {
+	appID             string
+	dial              func() (net.Conn, error)
+	reconnectInterval time.Duration
+
+	lock     sync.Mutex
+	activity *Activity
+	update   chan struct{}
+	nonce    int
+}
+
+// NewClient returns a client publishing activity as the Discord
+// application with the given ID. It does nothing until Run is called.
+func NewClient(appID string) *Client {
+	return &Client{
+		appID:             appID,
+		dial:              dial,
+		reconnectInterval: defaultReconnectInterval,
+		update:            make(chan struct{}, 1),
+	}
+}
+
+// SetActivity sets the activity to show, or clears it if nil.
+func (c *Client) SetActivity(a *Activity) {
+	c.lock.Lock()
+	changed := !reflect.DeepEqual(a, c.activity)
+	c.activity = a
+	c.lock.Unlock()
+	if changed {
+		select {
+		case c.update <- struct{}{}:
+		default: // an update is already pending
+		}
+	}
+}
+
+// Run connects to Discord, reconnecting whenever it is restarted,
+// and publishes the activity until the context is canceled.
+func (c *Client) Run(ctx context.Context) {
+	for {
+		if err := c.runConn(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Discord connection lost: %s", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.reconnectInterval):
+		}
+	}
+}
+
+// runConn publishes activity over one connection to Discord,
+// returning nil if Discord isn't running.
+func (c *Client) runConn(ctx context.Context) error {
+	nc, err := c.dial()
+	if err != nil {
+		return nil // Discord isn't running
+	}
+	dc := &conn{Conn: nc}
+	defer dc.Close()
+	if err := dc.handshake(c.appID); err != nil {
+		return err
+	}
+	log.Println("Connected to Discord")
+
+	readErr := make(chan error, 1)
+	go func() { readErr <- c.readLoop(dc) }()
+
+	// publish the current activity, if any, since the last connection
+	// may have been lost before it was sent
+	select {
+	case <-c.update:
+	default:
+	}
+	c.lock.Lock()
+	pending := c.activity != nil
+	c.lock.Unlock()
+	for {
+		if pending {
+			if err := c.sendActivity(dc); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return err
+		case <-c.update:
+			pending = true
+		}
+	}
+}
+
+func (c *Client) sendActivity(dc *conn) error {
+	c.lock.Lock()
+	activity := c.activity
+	c.nonce++
+	nonce := strconv.Itoa(c.nonce)
+	c.lock.Unlock()
+
+	return dc.writeFrame(opFrame, message{
+		Cmd:   "SET_ACTIVITY",
+		Nonce: nonce,
+		Args: map[string]any{
+			"pid":      os.Getpid(),
+			"activity": activity,
+		},
+	})
+}
+
+// readLoop answers pings and logs command errors until the connection is closed.
+func (c *Client) readLoop(dc *conn) error {
+	for {
+		op, payload, err := dc.readFrame()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case opPing:
+			if err := dc.writeFrame(opPong, json.RawMessage(payload)); err != nil {
+				return err
+			}
+		case opClose:
+			return closeError(payload)
+		case opFrame:
+			var msg message
+			if json.Unmarshal(payload, &msg) == nil && msg.Evt == "ERROR" {
+				e := &errorData{}
+				json.Unmarshal(msg.Data, e)
+				log.Printf("Discord %s error: %s", msg.Cmd, e.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeDiscord is a stand-in for the Discord client's IPC socket.
+type fakeDiscord struct {
+	t *testing.T
+	l net.Listener
+}
+
+func (f *fakeDiscord) accept(appID string) *conn {
+	f.t.Helper()
+	nc, err := f.l.Accept()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	c := &conn{Conn: nc}
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	op, payload, err := c.readFrame()
+	if err != nil || op != opHandshake {
+		f.t.Fatalf("expected handshake, got op %d, err %v", op, err)
+	}
+	var hs struct {
+		ClientID string `json:"client_id"`
+	}
+	json.Unmarshal(payload, &hs)
+	if hs.ClientID != appID {
+		f.t.Errorf("expected client ID %s, got %s", appID, hs.ClientID)
+	}
+	if err := c.writeFrame(opFrame, message{Cmd: "DISPATCH", Evt: "READY"}); err != nil {
+		f.t.Fatal(err)
+	}
+	return c
+}
+
+func (f *fakeDiscord) readActivity(c *conn) *Activity {
+	f.t.Helper()
+	op, payload, err := c.readFrame()
+	if err != nil || op != opFrame {
+		f.t.Fatalf("expected frame, got op %d, err %v", op, err)
+	}
+	var msg struct {
+		Cmd  string `json:"cmd"`
+		Args struct {
+			PID      int       `json:"pid"`
+			Activity *Activity `json:"activity"`
+		} `json:"args"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		f.t.Fatal(err)
+	}
+	if msg.Cmd != "SET_ACTIVITY" || msg.Args.PID == 0 {
+		f.t.Errorf("unexpected command %s (pid %d)", msg.Cmd, msg.Args.PID)
+	}
+	return msg.Args.Activity
+}
+
+func TestClient(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "discord-ipc-0")
+	c := NewClient("1234")
+	c.dial = func() (net.Conn, error) { return net.Dial("unix", sock) }
+	c.reconnectInterval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	// Discord isn't running yet
+	time.Sleep(30 * time.Millisecond)
+	playing := &Activity{Type: ActivityTypeListening, Details: "Song", State: "Artist",
+		Timestamps: &Timestamps{Start: 1000, End: 181000}}
+	c.SetActivity(playing)
+
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f := &fakeDiscord{t: t, l: l}
+
+	dc := f.accept("1234")
+	if a := f.readActivity(dc); a == nil || a.Details != "Song" || a.Timestamps.End != 181000 {
+		t.Errorf("unexpected activity %+v", a)
+	}
+	if err := dc.writeFrame(opPing, map[string]any{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if op, _, err := dc.readFrame(); err != nil || op != opPong {
+		t.Errorf("expected pong, got op %d, err %v", op, err)
+	}
+
+	// the activity is published again after Discord restarts
+	dc.Close()
+	dc = f.accept("1234")
+	if a := f.readActivity(dc); a == nil || a.Details != "Song" {
+		t.Errorf("unexpected activity after reconnecting %+v", a)
+	}
+
+	c.SetActivity(nil)
+	if a := f.readActivity(dc); a != nil {
+		t.Errorf("expected activity to be cleared, got %+v", a)
+	}
+	dc.Close()
+}
@@ -0,0 +1,122 @@
+// Package discord publishes Rich Presence activity to the Discord
+// desktop client over its local IPC socket.
+package discord
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// frame opcodes of the Discord IPC protocol
+const (
+	opHandshake uint32 = 0
+	opFrame     uint32 = 1
+	opClose     uint32 = 2
+	opPing      uint32 = 3
+	opPong      uint32 = 4
+)
+
+// payloads larger than this are not sent by Discord
+const maxFrameSize = 64 * 1024
+
+// time to wait for Discord to respond to the handshake
+const handshakeTimeout = 10 * time.Second
+
+var errFrameTooLarge = errors.New("discord IPC frame too large")
+
+// conn is a connection to the Discord IPC socket, over which frames
+// of a little-endian opcode and length followed by a JSON payload are sent.
+type conn struct {
+	net.Conn
+	writeLock sync.Mutex
+}
+
+func (c *conn) writeFrame(op uint32, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 8, 8+len(b))
+	binary.LittleEndian.PutUint32(buf[0:4], op)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(b)))
+	buf = append(buf, b...)
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err = c.Write(buf)
+	return err
+}
+
+func (c *conn) readFrame() (op uint32, payload []byte, err error) {
+	var header [8]byte
+	if _, err := io.ReadFull(c, header[:]); err != nil {
+		return 0, nil, err
+	}
+	op = binary.LittleEndian.Uint32(header[0:4])
+	n := binary.LittleEndian.Uint32(header[4:8])
+	if n > maxFrameSize {
+		return 0, nil, errFrameTooLarge
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c, payload); err != nil {
+		return 0, nil, err
+	}
+	return op, payload, nil
+}
+
+// message is the payload of opFrame frames.
+type message struct {
+	Cmd   string          `json:"cmd,omitempty"`
+	Evt   string          `json:"evt,omitempty"`
+	Nonce string          `json:"nonce,omitempty"`
+	Args  any             `json:"args,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// errorData is the data of a message with the ERROR event,
+// and the payload of opClose frames.
+type errorData struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *errorData) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+func closeError(payload []byte) error {
+	e := &errorData{}
+	json.Unmarshal(payload, e)
+	return fmt.Errorf("closed by Discord: %w", e)
+}
+
+// handshake identifies the client as the Discord application
+// and waits for Discord to respond with the READY event.
+func (c *conn) handshake(appID string) error {
+	c.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer c.SetDeadline(time.Time{})
+	if err := c.writeFrame(opHandshake, map[string]any{"v": 1, "client_id": appID}); err != nil {
+		return err
+	}
+	op, payload, err := c.readFrame()
+	if err != nil {
+		return err
+	}
+	if op == opClose {
+		return closeError(payload)
+	}
+	var msg message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return err
+	}
+	if op != opFrame || msg.Evt != "READY" {
+		return fmt.Errorf("unexpected handshake response %q", msg.Evt)
+	}
+	return nil
+}
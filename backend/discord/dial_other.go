@@ -0,0 +1,34 @@
+//go:build !windows
+
+package discord
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// dial connects to the first of the discord-ipc-0 to discord-ipc-9
+// sockets of a running Discord client, in the runtime or temp directory.
+func dial() (net.Conn, error) {
+	dir := os.TempDir()
+	for _, env := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if d := os.Getenv(env); d != "" {
+			dir = d
+			break
+		}
+	}
+	// Flatpak and Snap installs of Discord put the socket in a subdirectory
+	var err error
+	for _, sub := range []string{"", "app/com.discordapp.Discord", "snap.discord"} {
+		for i := range 10 {
+			var c net.Conn
+			c, err = net.Dial("unix", filepath.Join(dir, sub, "discord-ipc-"+strconv.Itoa(i)))
+			if err == nil {
+				return c, nil
+			}
+		}
+	}
+	return nil, err
+}
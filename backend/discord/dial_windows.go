@@ -0,0 +1,26 @@
+//go:build windows
+
+package discord
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// dial connects to the first of the discord-ipc-0 to
+// discord-ipc-9 named pipes of a running Discord client.
+func dial() (net.Conn, error) {
+	timeout := time.Second
+	var err error
+	for i := range 10 {
+		var c net.Conn
+		c, err = winio.DialPipe(`\\.\pipe\discord-ipc-`+strconv.Itoa(i), &timeout)
+		if err == nil {
+			return c, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,170 @@
+package backend
+
+import (
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/discord"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+// Discord rejects activity text outside of this length range
+const (
+	discordMinTextLen = 2
+	discordMaxTextLen = 128
+)
+
+// DiscordTemplateData is the data the Discord activity templates are executed with.
+type DiscordTemplateData struct {
+	Title  string
+	Artist string
+	Album  string
+	Paused bool
+}
+
+// discordPresence publishes the playing track as Discord Rich Presence.
+type discordPresence struct {
+	client   *discord.Client
+	pm       *PlaybackManager
+	isHidden func() bool
+
+	details *template.Template
+	state   *template.Template
+
+	lock   sync.Mutex
+	item   mediaprovider.MediaItem
+	paused bool
+	start  time.Time // time the track would have started if played without pausing
+	total  float64
+}
+
+func (a *App) startDiscordPresence() {
+	cfg := a.Config.Discord
+	defaults := DefaultConfig("").Discord
+	d := &discordPresence{
+		client:  discord.NewClient(cfg.ApplicationID),
+		pm:      a.PlaybackManager,
+		details: parseDiscordTemplate(cfg.DetailsTemplate, defaults.DetailsTemplate),
+		state:   parseDiscordTemplate(cfg.StateTemplate, defaults.StateTemplate),
+		isHidden: func() bool {
+			for _, s := range a.Config.Servers {
+				if s.ID == a.ServerManager.ServerID {
+					return s.HideDiscordPresence
+				}
+			}
+			return false
+		},
+	}
+
+	pm := a.PlaybackManager
+	pm.OnSongChange(func(item mediaprovider.MediaItem, _ *mediaprovider.Track) {
+		d.lock.Lock()
+		d.item = item
+		d.paused = pm.PlaybackStatus().State == player.Paused
+		d.start = time.Now()
+		d.total = 0
+		if item != nil {
+			d.total = item.Metadata().Duration.Seconds()
+		}
+		d.lock.Unlock()
+		d.update()
+	})
+	pm.OnPlayTimeUpdate(func(cur, total float64, seeked bool) {
+		start := time.Now().Add(-time.Duration(cur * float64(time.Second)))
+		d.lock.Lock()
+		// Discord counts the time itself, so only update on seeks or drift
+		changed := seeked || total != d.total || math.Abs(start.Sub(d.start).Seconds()) > 2
+		if changed {
+			d.start = start
+			d.total = total
+		}
+		d.lock.Unlock()
+		if changed {
+			d.update()
+		}
+	})
+	pm.OnPaused(func() { d.setPaused(true) })
+	pm.OnPlaying(func() { d.setPaused(false) })
+	pm.OnStopped(func() {
+		d.lock.Lock()
+		d.item = nil
+		d.lock.Unlock()
+		d.update()
+	})
+	a.ServerManager.OnServerConnected(func(*ServerConfig) { d.update() })
+
+	go d.client.Run(a.bgrndCtx)
+}
+
+func parseDiscordTemplate(text, fallback string) *template.Template {
+	t, err := template.New("discord").Parse(text)
+	if err != nil {
+		log.Printf("invalid Discord template %q: %s", text, err.Error())
+		t = template.Must(template.New("discord").Parse(fallback))
+	}
+	return t
+}
+
+func (d *discordPresence) setPaused(paused bool) {
+	cur := d.pm.PlaybackStatus().TimePos
+	d.lock.Lock()
+	d.paused = paused
+	d.start = time.Now().Add(-time.Duration(cur * float64(time.Second)))
+	d.lock.Unlock()
+	d.update()
+}
+
+func (d *discordPresence) update() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.item == nil || d.isHidden() {
+		d.client.SetActivity(nil)
+		return
+	}
+
+	meta := d.item.Metadata()
+	data := DiscordTemplateData{
+		Title:  meta.Name,
+		Artist: strings.Join(meta.Artists, ", "),
+		Album:  meta.Album,
+		Paused: d.paused,
+	}
+	activity := &discord.Activity{
+		Type:    discord.ActivityTypeListening,
+		Details: executeDiscordTemplate(d.details, data),
+		State:   executeDiscordTemplate(d.state, data),
+	}
+	// no timestamps while paused, since Discord would keep counting
+	if !d.paused {
+		activity.Timestamps = &discord.Timestamps{Start: d.start.UnixMilli()}
+		if d.total > 0 {
+			activity.Timestamps.End = d.start.Add(time.Duration(d.total * float64(time.Second))).UnixMilli()
+		}
+	}
+	d.client.SetActivity(activity)
+}
+
+// executeDiscordTemplate returns the template output clamped to the
+// length Discord accepts, or "" to omit the line.
+func executeDiscordTemplate(t *template.Template, data DiscordTemplateData) string {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		log.Printf("error executing Discord template: %s", err.Error())
+		return ""
+	}
+	text := []rune(strings.TrimSpace(sb.String()))
+	switch {
+	case len(text) == 0:
+		return ""
+	case len(text) < discordMinTextLen:
+		text = append(text, ' ')
+	case len(text) > discordMaxTextLen:
+		text = append(text[:discordMaxTextLen-1], '…')
+	}
+	return string(text)
+}
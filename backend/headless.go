@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// interval between connection attempts while the server is unreachable in headless mode
+const headlessReconnectInterval = 15 * time.Second
+
+// RunHeadless runs the app as a player without a UI, to be controlled over
+// IPC, MPRIS, and remote clients. It logs in to the default server, with the
+// password read from passwordFile if given or from the keyring otherwise,
+// and blocks until the app is asked to quit or receives SIGINT or SIGTERM.
+// The caller should call Shutdown after it returns.
+func (a *App) RunHeadless(passwordFile string) error {
+	ctx, stop := signal.NotifyContext(a.bgrndCtx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	quit := make(chan struct{})
+	a.OnExit = sync.OnceFunc(func() { close(quit) })
+
+	if a.Config.Application.SavePlayQueue {
+		a.ServerManager.OnServerConnected(func(*ServerConfig) {
+			go func() {
+				if err := a.LoadSavedPlayQueue(); err != nil {
+					log.Printf("failed to load saved play queue: %s", err.Error())
+				}
+			}()
+		})
+	}
+
+	if err := a.headlessLogin(ctx, passwordFile); err != nil {
+		if ctx.Err() != nil {
+			return nil // interrupted while waiting for the server
+		}
+		return err
+	}
+	log.Println("Running headless")
+
+	select {
+	case <-ctx.Done():
+	case <-quit:
+	}
+	return nil
+}
+
+func (a *App) headlessLogin(ctx context.Context, passwordFile string) error {
+	serverCfg := a.ServerManager.GetDefaultServer()
+	if serverCfg == nil {
+		return ErrNoServers
+	}
+	var pass string
+	if passwordFile != "" {
+		b, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return fmt.Errorf("error reading password file: %v", err)
+		}
+		pass = strings.TrimRight(string(b), "\r\n")
+	} else {
+		p, err := a.ServerManager.GetServerPassword(serverCfg.ID)
+		if err != nil {
+			return fmt.Errorf("error reading keyring credentials: %v", err)
+		}
+		pass = p
+	}
+
+	timeout := time.Duration(a.Config.Application.RequestTimeoutSeconds) * time.Second
+	for {
+		tCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := a.ServerManager.TestConnectionAndAuth(tCtx, serverCfg.ServerConnection, pass)
+		cancel()
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrUnreachable) {
+			return err
+		}
+		log.Printf("server %s is unreachable, retrying in %v", serverCfg.Hostname, headlessReconnectInterval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(headlessReconnectInterval):
+		}
+	}
+	return a.ServerManager.ConnectToServer(serverCfg, pass)
+}
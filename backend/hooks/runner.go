@@ -0,0 +1,72 @@
+// Package hooks runs user-configured shell commands in response to app events.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// DefaultTimeout is used if the timeout given to NewRunner is not positive.
+const DefaultTimeout = 10 * time.Second
+
+// Runner runs commands in the background, each with a timeout,
+// dropping commands while the maximum number are already running.
+type Runner struct {
+	timeout time.Duration
+	sem     chan struct{}
+}
+
+// NewRunner returns a runner killing commands after timeout,
+// with at most maxConcurrent commands running at once.
+func NewRunner(timeout time.Duration, maxConcurrent int) *Runner {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Runner{
+		timeout: timeout,
+		sem:     make(chan struct{}, max(maxConcurrent, 1)),
+	}
+}
+
+// Run starts the command with the shell of the OS, with env added to the
+// environment of the app and stdin as its standard input. It returns
+// without waiting for the command to exit, and returns false if the
+// command was not started because too many commands are running.
+func (r *Runner) Run(ctx context.Context, command string, env []string, stdin []byte) bool {
+	select {
+	case r.sem <- struct{}{}:
+	default:
+		log.Printf("not running hook %q: too many hooks running", command)
+		return false
+	}
+	go func() {
+		defer func() { <-r.sem }()
+		if err := r.run(ctx, command, env, stdin); err != nil {
+			log.Printf("hook %q failed: %s", command, err.Error())
+		}
+	}()
+	return true
+}
+
+func (r *Runner) run(ctx context.Context, command string, env []string, stdin []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	// don't wait on output pipes held open by background children of the command
+	cmd.WaitDelay = time.Second
+	return cmd.Run()
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
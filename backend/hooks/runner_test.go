@@ -0,0 +1,60 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitIdle waits for all running commands to exit.
+func waitIdle(r *Runner) {
+	for range cap(r.sem) {
+		r.sem <- struct{}{}
+	}
+	for range cap(r.sem) {
+		<-r.sem
+	}
+}
+
+func TestRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test commands use sh")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	r := NewRunner(time.Second, 2)
+	if !r.Run(context.Background(), `printf '%s\n' "$HOOK_TITLE" > "$HOOK_OUT"; cat >> "$HOOK_OUT"`,
+		[]string{"HOOK_TITLE=Song", "HOOK_OUT=" + out}, []byte(`{"title":"Song"}`)) {
+		t.Fatal("command not run")
+	}
+	waitIdle(r)
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Song\n{\"title\":\"Song\"}"; string(b) != want {
+		t.Errorf("expected output %q, got %q", want, string(b))
+	}
+
+	// commands are killed after the timeout
+	r = NewRunner(100*time.Millisecond, 1)
+	start := time.Now()
+	r.Run(context.Background(), "sleep 10", nil, nil)
+	// and dropped while the maximum number are running
+	if r.Run(context.Background(), "true", nil, nil) {
+		t.Error("expected command to be dropped")
+	}
+	waitIdle(r)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command not killed after timeout (ran %v)", elapsed)
+	}
+}
+
+func TestNewRunnerDefaults(t *testing.T) {
+	r := NewRunner(0, 0)
+	if r.timeout != DefaultTimeout || cap(r.sem) != 1 {
+		t.Errorf("got timeout %v and max concurrency %d", r.timeout, cap(r.sem))
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 const (
 	PingPath              = "/ping"
+	StatusPath            = "/status"
 	PlayPath              = "/transport/play"
 	PlayAlbumPath         = "/transport/play-album"      // ?id=<album ID>&t=<firstTrack>&s=<shuffle>
 	PlayPlaylistPath      = "/transport/play-playlist"   // ?id=<playlist ID>&t=<firstTrack>&s=<shuffle>
@@ -28,9 +29,28 @@ const (
 	ShowPath              = "/window/show"
 	ReloadThemePath       = "/window/reload-theme"
 	QuitPath              = "/window/quit"
+	OpenURIPath           = "/window/open-uri"    // ?uri=<supersonic:// URI>
 	RateCurrentTrackPath  = "/current_track/rate" // ?r=<rating 0-5>
+	EventsPath            = "/events"             // Server-Sent Events stream of Event
+	QueuePath             = "/queue"
+	QueueAddAlbumPath     = "/queue/add-album"    // ?id=<album ID>&next=<bool>
+	QueueAddPlaylistPath  = "/queue/add-playlist" // ?id=<playlist ID>&next=<bool>
+	QueueAddTrackPath     = "/queue/add-track"    // ?id=<track ID>&next=<bool>
+	QueueRemovePath       = "/queue/remove"       // ?i=<index>
+	QueueMovePath         = "/queue/move"         // ?from=<index>&to=<index after the move>
+	QueueClearPath        = "/queue/clear"
+	QueueJumpPath         = "/queue/jump"    // ?i=<index>
+	ShufflePath           = "/queue/shuffle" // ?on=<bool>, or toggle if omitted
+	LoopModePath          = "/queue/loop"    // ?mode=<none|all|one>, or next mode if omitted
 )
 
+// QueueItem is an item of the play queue, as listed by QueuePath.
+type QueueItem struct {
+	Index      int  `json:"index"`
+	NowPlaying bool `json:"nowPlaying"`
+	TrackInfo
+}
+
 type Response struct {
 	Data  json.RawMessage `json:"data"`
 	Error string          `json:"error"`
@@ -64,6 +84,10 @@ func BuildPlayTrackPath(id string) string {
 	return fmt.Sprintf("%s?id=%s", PlayTrackPath, id)
 }
 
+func BuildOpenURIPath(uri string) string {
+	return fmt.Sprintf("%s?uri=%s", OpenURIPath, url.QueryEscape(uri))
+}
+
 func BuildSearchAlbumPath(search string) string {
 	s := url.QueryEscape(search)
 	return fmt.Sprintf("%s?s=%s", SearchAlbumPath, s)
@@ -79,6 +103,29 @@ func BuildSearchTrackPath(search string) string {
 	return fmt.Sprintf("%s?s=%s", SearchTrackPath, s)
 }
 
+func BuildQueueAddPath(path, id string, next bool) string {
+	return fmt.Sprintf("%s?id=%s&next=%t", path, url.QueryEscape(id), next)
+}
+
+func BuildQueueRemovePath(idx int) string {
+	return fmt.Sprintf("%s?i=%d", QueueRemovePath, idx)
+}
+
+func BuildQueueMovePath(from, to int) string {
+	return fmt.Sprintf("%s?from=%d&to=%d", QueueMovePath, from, to)
+}
+
+func BuildQueueJumpPath(idx int) string {
+	return fmt.Sprintf("%s?i=%d", QueueJumpPath, idx)
+}
+
+func BuildLoopModePath(mode string) string {
+	if mode == "" {
+		return LoopModePath
+	}
+	return fmt.Sprintf("%s?mode=%s", LoopModePath, url.QueryEscape(mode))
+}
+
 func BuildRateCurrentTrackPath(rating int) string {
 	return fmt.Sprintf("%s?r=%d", RateCurrentTrackPath, rating)
 }
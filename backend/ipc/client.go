@@ -11,12 +11,13 @@ import (
 var ErrPingFail = errors.New("ping failed")
 
 type Client struct {
-	httpC http.Client
+	baseURL string
+	httpC   http.Client
 }
 
 // Connect attempts to connect to the IPC socket as client.
 func Connect() (*Client, error) {
-	client := &Client{httpC: http.Client{
+	client := &Client{baseURL: "http://supersonic", httpC: http.Client{
 		Transport: &http.Transport{
 			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
 				return Dial()
@@ -36,6 +37,18 @@ func (c *Client) Ping() error {
 	return nil
 }
 
+func (c *Client) Status() (*Status, error) {
+	data, err := c.sendRequest(StatusPath)
+	if err != nil {
+		return nil, err
+	}
+	var s Status
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
 func (c *Client) Play() error {
 	_, err := c.sendRequest(PlayPath)
 	return err
@@ -123,11 +136,68 @@ func (c *Client) RateCurrentTrack(rating int) error {
 	return err
 }
 
+func (c *Client) Queue() (string, error) {
+	return c.sendRequest(QueuePath)
+}
+
+func (c *Client) EnqueueAlbum(id string, next bool) error {
+	_, err := c.sendRequest(BuildQueueAddPath(QueueAddAlbumPath, id, next))
+	return err
+}
+
+func (c *Client) EnqueuePlaylist(id string, next bool) error {
+	_, err := c.sendRequest(BuildQueueAddPath(QueueAddPlaylistPath, id, next))
+	return err
+}
+
+func (c *Client) EnqueueTrack(id string, next bool) error {
+	_, err := c.sendRequest(BuildQueueAddPath(QueueAddTrackPath, id, next))
+	return err
+}
+
+func (c *Client) RemoveFromQueue(idx int) error {
+	_, err := c.sendRequest(BuildQueueRemovePath(idx))
+	return err
+}
+
+func (c *Client) MoveInQueue(from, to int) error {
+	_, err := c.sendRequest(BuildQueueMovePath(from, to))
+	return err
+}
+
+func (c *Client) ClearQueue() error {
+	_, err := c.sendRequest(QueueClearPath)
+	return err
+}
+
+func (c *Client) JumpToQueueIndex(idx int) error {
+	_, err := c.sendRequest(BuildQueueJumpPath(idx))
+	return err
+}
+
+func (c *Client) ToggleShuffle() error {
+	_, err := c.sendRequest(ShufflePath)
+	return err
+}
+
+// SetLoopMode sets the loop mode to "none", "all", or "one",
+// or to the next loop mode if mode is empty.
+func (c *Client) SetLoopMode(mode string) error {
+	_, err := c.sendRequest(BuildLoopModePath(mode))
+	return err
+}
+
 func (c *Client) Show() error {
 	_, err := c.sendRequest(ShowPath)
 	return err
 }
 
+// OpenURI asks the running instance to open a supersonic:// URI.
+func (c *Client) OpenURI(uri string) error {
+	_, err := c.sendRequest(BuildOpenURIPath(uri))
+	return err
+}
+
 func (c *Client) ReloadTheme() error {
 	_, err := c.sendRequest(ReloadThemePath)
 	return err
@@ -139,7 +209,7 @@ func (c *Client) Quit() error {
 }
 
 func (c *Client) sendRequest(path string) (string, error) {
-	resp, err := c.httpC.Get("http://supersonic/" + path)
+	resp, err := c.httpC.Get(c.baseURL + path)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,194 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// Event types sent on the event stream
+const (
+	EventSong    = "song"    // data: TrackInfo, or null if nothing is playing
+	EventState   = "state"   // data: {"state": "playing"|"paused"|"stopped"}
+	EventSeek    = "seek"    // data: {"position": <seconds>}
+	EventVolume  = "volume"  // data: {"volume": <0-100>}
+	EventLoop    = "loop"    // data: {"mode": "none"|"all"|"one"}
+	EventShuffle = "shuffle" // data: {"shuffle": <bool>}
+	EventQueue   = "queue"   // data: {"length": <n>, "index": <now playing index>}
+)
+
+const (
+	// events buffered for each client before it starts missing events
+	eventBufferSize   = 64
+	keepAliveInterval = 30 * time.Second
+)
+
+// Event is a playback state change sent to clients following the event stream.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// NewEvent creates an event with the given data marshaled to JSON.
+func NewEvent(typ string, data any) Event {
+	b, err := json.Marshal(data)
+	if err != nil {
+		b = []byte("null")
+	}
+	return Event{Type: typ, Data: b}
+}
+
+// TrackInfo describes the now playing media item.
+type TrackInfo struct {
+	ID         string   `json:"id"`
+	Type       string   `json:"type"` // "track", "radio", or "episode"
+	Title      string   `json:"title"`
+	Artists    []string `json:"artists"`
+	Album      string   `json:"album"`
+	AlbumID    string   `json:"albumId"`
+	CoverArtID string   `json:"coverArtId"`
+	Duration   float64  `json:"duration"`
+}
+
+// NewTrackInfo returns the description of a media item, or nil if item is nil.
+func NewTrackInfo(item mediaprovider.MediaItem) *TrackInfo {
+	if item == nil {
+		return nil
+	}
+	meta := item.Metadata()
+	typ := "track"
+	switch meta.Type {
+	case mediaprovider.MediaItemTypeRadioStation:
+		typ = "radio"
+	case mediaprovider.MediaItemTypePodcastEpisode:
+		typ = "episode"
+	}
+	return &TrackInfo{
+		ID:         meta.ID,
+		Type:       typ,
+		Title:      meta.Name,
+		Artists:    meta.Artists,
+		Album:      meta.Album,
+		AlbumID:    meta.AlbumID,
+		CoverArtID: meta.CoverArtID,
+		Duration:   meta.Duration.Seconds(),
+	}
+}
+
+// eventBroker fans out events to event stream clients.
+type eventBroker struct {
+	lock     sync.Mutex
+	clients  map[chan Event]struct{}
+	closed   bool
+	snapshot func() []Event
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{clients: make(map[chan Event]struct{})}
+}
+
+func (b *eventBroker) publish(ev Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- ev:
+		default: // client isn't keeping up; drop the event
+		}
+	}
+}
+
+// subscribe returns a channel receiving events, starting with
+// the current state, which is closed when the broker is closed.
+func (b *eventBroker) subscribe() chan Event {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ch := make(chan Event, eventBufferSize)
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	if b.snapshot != nil {
+		for _, ev := range b.snapshot() {
+			ch <- ev
+		}
+	}
+	b.clients[ch] = struct{}{}
+	return ch
+}
+
+func (b *eventBroker) unsubscribe(ch chan Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.clients[ch]; ok {
+		delete(b.clients, ch)
+		close(ch)
+	}
+}
+
+// close disconnects all clients, so that the server can shut down.
+func (b *eventBroker) close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	for ch := range b.clients {
+		close(ch)
+	}
+	clear(b.clients)
+}
+
+// serveEvents streams events to the client as Server-Sent Events,
+// with each event's data being the JSON-encoded Event.
+func (s *serverImpl) serveEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	rc := http.NewResponseController(w)
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			b, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, b)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Follow streams events from the server, calling onEvent with the JSON
+// encoding of each, until the connection is closed.
+func (c *Client) Follow(onEvent func(string)) error {
+	resp, err := c.httpC.Get(c.baseURL + EventsPath)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("event stream: %s", resp.Status)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+			onEvent(data)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type nopPlaybackHandler struct{}
+
+func (nopPlaybackHandler) PlayPause()                           {}
+func (nopPlaybackHandler) Stop()                                {}
+func (nopPlaybackHandler) Pause()                               {}
+func (nopPlaybackHandler) Continue()                            {}
+func (nopPlaybackHandler) SeekBackOrPrevious()                  {}
+func (nopPlaybackHandler) SeekNext()                            {}
+func (nopPlaybackHandler) SetPauseAfterCurrent(bool)            {}
+func (nopPlaybackHandler) SeekSeconds(float64)                  {}
+func (nopPlaybackHandler) SeekBySeconds(float64)                {}
+func (nopPlaybackHandler) Volume() int                          { return 0 }
+func (nopPlaybackHandler) SetVolume(int)                        {}
+func (nopPlaybackHandler) PlayAlbum(string, int, bool) error    { return nil }
+func (nopPlaybackHandler) PlayPlaylist(string, int, bool) error { return nil }
+func (nopPlaybackHandler) PlayTrack(string) error               { return nil }
+
+func TestEventStream(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(ServerOptions{Playback: nopPlaybackHandler{}, Queue: &fakeQueueHandler{}})
+	s.SetEventSnapshot(func() []Event {
+		return []Event{NewEvent(EventState, map[string]string{"state": "paused"})}
+	})
+	go s.Serve(l)
+
+	c := &Client{baseURL: "http://supersonic", httpC: http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "tcp", l.Addr().String())
+		},
+	}}}
+	events := make(chan Event, 4)
+	done := make(chan error)
+	go func() {
+		done <- c.Follow(func(line string) {
+			var ev Event
+			if err := json.Unmarshal([]byte(line), &ev); err != nil {
+				t.Errorf("malformed event %q", line)
+			}
+			events <- ev
+		})
+	}()
+
+	next := func() Event {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for event")
+			return Event{}
+		}
+	}
+	if ev := next(); ev.Type != EventState || string(ev.Data) != `{"state":"paused"}` {
+		t.Errorf("expected snapshot state event, got %s %s", ev.Type, ev.Data)
+	}
+	// the client has subscribed once the snapshot arrived
+	s.PublishEvent(NewEvent(EventVolume, map[string]int{"volume": 40}))
+	if ev := next(); ev.Type != EventVolume || string(ev.Data) != `{"volume":40}` {
+		t.Errorf("unexpected event %s %s", ev.Type, ev.Data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	// as in Shutdown, without removing the real IPC socket file
+	impl := s.(*serverImpl)
+	impl.events.close()
+	if err := impl.server.Shutdown(ctx); err != nil {
+		t.Errorf("shutdown with a connected event stream: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("follow: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("event stream not closed on shutdown")
+	}
+}
@@ -0,0 +1,138 @@
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+var ErrQueueIndexOutOfRange = errors.New("play queue index out of range")
+
+// QueueHandler inspects and edits the play queue for the queue endpoints.
+type QueueHandler interface {
+	GetActivePlayQueue() []mediaprovider.MediaItem
+	NowPlayingIndex() int
+	// EnqueueAlbum, EnqueuePlaylist, and EnqueueTrack add the given item
+	// after the now playing track if next is true, or to the end of the queue.
+	EnqueueAlbum(id string, next bool) error
+	EnqueuePlaylist(id string, next bool) error
+	EnqueueTrack(id string, next bool) error
+	RemoveTracksFromQueue([]int)
+	UpdatePlayQueue([]mediaprovider.MediaItem)
+	StopAndClearPlayQueue()
+	PlayTrackAt(int)
+	IsShuffle() bool
+	SetShuffle(bool)
+	// SetLoopModeName sets the loop mode to one of "none", "all", or "one".
+	SetLoopModeName(string) error
+	SetNextLoopMode()
+}
+
+func (s *serverImpl) registerQueueHandlers(m *http.ServeMux) {
+	m.HandleFunc(QueuePath, func(w http.ResponseWriter, r *http.Request) {
+		queue := s.qHandler.GetActivePlayQueue()
+		nowPlaying := s.qHandler.NowPlayingIndex()
+		items := make([]QueueItem, 0, len(queue))
+		for i, item := range queue {
+			items = append(items, QueueItem{Index: i, NowPlaying: i == nowPlaying, TrackInfo: *NewTrackInfo(item)})
+		}
+		b, err := json.Marshal(items)
+		if err != nil {
+			s.writeErr(w, err)
+			return
+		}
+		s.writeData(w, b)
+	})
+	m.HandleFunc(QueueAddAlbumPath, s.makeEnqueueEndpointHandler(s.qHandler.EnqueueAlbum))
+	m.HandleFunc(QueueAddPlaylistPath, s.makeEnqueueEndpointHandler(s.qHandler.EnqueuePlaylist))
+	m.HandleFunc(QueueAddTrackPath, s.makeEnqueueEndpointHandler(s.qHandler.EnqueueTrack))
+	m.HandleFunc(QueueRemovePath, s.makeQueueIndexEndpointHandler("i", func(idx int) {
+		s.qHandler.RemoveTracksFromQueue([]int{idx})
+	}))
+	m.HandleFunc(QueueJumpPath, s.makeQueueIndexEndpointHandler("i", s.qHandler.PlayTrackAt))
+	m.HandleFunc(QueueMovePath, func(w http.ResponseWriter, r *http.Request) {
+		queue := s.qHandler.GetActivePlayQueue()
+		from, err := parseQueueIndex(r, "from", len(queue))
+		if err != nil {
+			s.writeErr(w, err)
+			return
+		}
+		to, err := parseQueueIndex(r, "to", len(queue))
+		if err != nil {
+			s.writeErr(w, err)
+			return
+		}
+		s.qHandler.UpdatePlayQueue(sharedutil.MoveItem(queue, from, to))
+		s.writeOK(w)
+	})
+	m.HandleFunc(QueueClearPath, s.makeSimpleEndpointHandler(s.qHandler.StopAndClearPlayQueue))
+	m.HandleFunc(ShufflePath, func(w http.ResponseWriter, r *http.Request) {
+		shuffle := !s.qHandler.IsShuffle()
+		if v := r.URL.Query().Get("on"); v != "" {
+			var err error
+			if shuffle, err = strconv.ParseBool(v); err != nil {
+				s.writeErr(w, err)
+				return
+			}
+		}
+		s.qHandler.SetShuffle(shuffle)
+		s.writeOK(w)
+	})
+	m.HandleFunc(LoopModePath, func(w http.ResponseWriter, r *http.Request) {
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			s.qHandler.SetNextLoopMode()
+		} else if err := s.qHandler.SetLoopModeName(mode); err != nil {
+			s.writeErr(w, err)
+			return
+		}
+		s.writeOK(w)
+	})
+}
+
+func (s *serverImpl) makeEnqueueEndpointHandler(f func(string, bool) error) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		next := false
+		if v := query.Get("next"); v != "" {
+			var err error
+			if next, err = strconv.ParseBool(v); err != nil {
+				s.writeErr(w, err)
+				return
+			}
+		}
+		if err := f(query.Get("id"), next); err != nil {
+			s.writeErr(w, err)
+			return
+		}
+		s.writeOK(w)
+	}
+}
+
+func (s *serverImpl) makeQueueIndexEndpointHandler(queryParam string, f func(int)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idx, err := parseQueueIndex(r, queryParam, len(s.qHandler.GetActivePlayQueue()))
+		if err != nil {
+			s.writeErr(w, err)
+			return
+		}
+		f(idx)
+		s.writeOK(w)
+	}
+}
+
+func parseQueueIndex(r *http.Request, queryParam string, queueLen int) (int, error) {
+	idx, err := strconv.Atoi(r.URL.Query().Get(queryParam))
+	if err != nil {
+		return 0, err
+	}
+	if idx < 0 || idx >= queueLen {
+		return 0, fmt.Errorf("%w: %d", ErrQueueIndexOutOfRange, idx)
+	}
+	return idx, nil
+}
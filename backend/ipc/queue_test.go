@@ -0,0 +1,113 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+type fakeQueueHandler struct {
+	queue    []mediaprovider.MediaItem
+	idx      int
+	shuffle  bool
+	loopMode string
+	enqueued []string
+}
+
+func (f *fakeQueueHandler) GetActivePlayQueue() []mediaprovider.MediaItem { return f.queue }
+func (f *fakeQueueHandler) NowPlayingIndex() int                          { return f.idx }
+func (f *fakeQueueHandler) EnqueueAlbum(id string, next bool) error {
+	f.enqueued = append(f.enqueued, fmt.Sprintf("album %s %t", id, next))
+	return nil
+}
+func (f *fakeQueueHandler) EnqueuePlaylist(string, bool) error { return nil }
+func (f *fakeQueueHandler) EnqueueTrack(string, bool) error    { return nil }
+func (f *fakeQueueHandler) RemoveTracksFromQueue(idxs []int) {
+	f.queue = slices.Delete(f.queue, idxs[0], idxs[0]+1)
+}
+func (f *fakeQueueHandler) UpdatePlayQueue(items []mediaprovider.MediaItem) { f.queue = items }
+func (f *fakeQueueHandler) StopAndClearPlayQueue()                          { f.queue = nil }
+func (f *fakeQueueHandler) PlayTrackAt(idx int)                             { f.idx = idx }
+func (f *fakeQueueHandler) IsShuffle() bool                                 { return f.shuffle }
+func (f *fakeQueueHandler) SetShuffle(shuffle bool)                         { f.shuffle = shuffle }
+func (f *fakeQueueHandler) SetNextLoopMode()                                { f.loopMode = "next" }
+func (f *fakeQueueHandler) SetLoopModeName(mode string) error {
+	f.loopMode = mode
+	return nil
+}
+
+func TestQueueEndpoints(t *testing.T) {
+	q := &fakeQueueHandler{idx: 1}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		q.queue = append(q.queue, &mediaprovider.Track{ID: id})
+	}
+	h := NewServer(ServerOptions{Playback: nopPlaybackHandler{}, Queue: q}).(*serverImpl).createHandler()
+	get := func(path string) Response {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "http://supersonic"+path, nil))
+		var r Response
+		json.NewDecoder(rec.Body).Decode(&r)
+		return r
+	}
+	ids := func() []string {
+		var items []QueueItem
+		if err := json.Unmarshal(get(QueuePath).Data, &items); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, it := range items {
+			if it.NowPlaying != (it.Index == q.idx) {
+				t.Errorf("item %d: unexpected now playing %t", it.Index, it.NowPlaying)
+			}
+			ids = append(ids, it.ID)
+		}
+		return ids
+	}
+
+	if got := ids(); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("unexpected queue %v", got)
+	}
+	get(BuildQueueMovePath(0, 2))
+	if got := ids(); !slices.Equal(got, []string{"b", "c", "a", "d"}) {
+		t.Errorf("after moving down: %v", got)
+	}
+	get(BuildQueueMovePath(3, 0))
+	if got := ids(); !slices.Equal(got, []string{"d", "b", "c", "a"}) {
+		t.Errorf("after moving up: %v", got)
+	}
+	get(BuildQueueRemovePath(1))
+	if got := ids(); !slices.Equal(got, []string{"d", "c", "a"}) {
+		t.Errorf("after removing: %v", got)
+	}
+	if r := get(BuildQueueRemovePath(3)); r.Error == "" {
+		t.Error("expected error removing out of range index")
+	}
+	get(BuildQueueJumpPath(2))
+	if q.idx != 2 {
+		t.Errorf("expected now playing index 2, got %d", q.idx)
+	}
+
+	get(BuildQueueAddPath(QueueAddAlbumPath, "al 1", true))
+	if !slices.Equal(q.enqueued, []string{"album al 1 true"}) {
+		t.Errorf("unexpected enqueued %v", q.enqueued)
+	}
+
+	get(ShufflePath)
+	get(ShufflePath)
+	get(ShufflePath + "?on=true")
+	if !q.shuffle {
+		t.Error("expected shuffle on")
+	}
+	get(BuildLoopModePath("one"))
+	if q.loopMode != "one" {
+		t.Errorf("unexpected loop mode %q", q.loopMode)
+	}
+	get(BuildLoopModePath(""))
+	if q.loopMode != "next" {
+		t.Errorf("unexpected loop mode %q", q.loopMode)
+	}
+}
@@ -0,0 +1,208 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenEnvVar is the environment variable the token for remote IPC
+// connections is read from if it is not given on the command line.
+const TokenEnvVar = "SUPERSONIC_IPC_TOKEN"
+
+var ErrUnauthorized = errors.New("invalid or missing IPC token")
+
+// NewToken returns a random token for authenticating remote IPC clients.
+func NewToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ListenTLS listens for remote IPC connections on the given TCP address,
+// using the self-signed certificate in certFile and keyFile,
+// which are generated if they don't exist yet.
+func ListenTLS(addr, certFile, keyFile string) (net.Listener, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		cert, err = generateCertificate(certFile, keyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a DER-encoded certificate.
+func CertificateFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+func generateCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Supersonic IPC"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(20, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+	log.Printf("generated IPC certificate with fingerprint %s", CertificateFingerprint(der))
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// requireToken rejects requests without the given token as a bearer token.
+func requireToken(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(t), []byte(token)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"` + ErrUnauthorized.Error() + `"}`))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// tokenTransport adds the token to each request as a bearer token.
+type tokenTransport struct {
+	base  http.RoundTripper
+	token string
+}
+
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(r)
+}
+
+// ConnectRemote connects to the remote IPC listener of Supersonic at
+// addr (host:port). Since the server's certificate is self-signed,
+// its fingerprint is trusted on first use and recorded in knownHostsFile,
+// and connections fail if the server presents a different certificate later.
+func ConnectRemote(addr, token, knownHostsFile string) (*Client, error) {
+	kh := &knownHosts{path: knownHostsFile}
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			// the certificate is verified by fingerprint below instead
+			InsecureSkipVerify: true,
+			VerifyConnection: func(cs tls.ConnectionState) error {
+				if len(cs.PeerCertificates) == 0 {
+					return errors.New("server presented no certificate")
+				}
+				return kh.verify(addr, CertificateFingerprint(cs.PeerCertificates[0].Raw))
+			},
+		},
+		DialContext: (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+	}
+	client := &Client{
+		baseURL: "https://" + addr,
+		httpC:   http.Client{Transport: &tokenTransport{base: transport, token: token}},
+	}
+	if _, err := client.sendRequest(PingPath); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// knownHosts is a file of "<host:port> <certificate fingerprint>" lines.
+type knownHosts struct {
+	lock sync.Mutex
+	path string
+}
+
+func (k *knownHosts) verify(addr, fingerprint string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if b, err := os.ReadFile(k.path); err == nil {
+		sc := bufio.NewScanner(strings.NewReader(string(b)))
+		for sc.Scan() {
+			host, fp, ok := strings.Cut(strings.TrimSpace(sc.Text()), " ")
+			if !ok || host != addr {
+				continue
+			}
+			if fp != fingerprint {
+				return fmt.Errorf("the certificate of %s has changed (fingerprint %s); if this is expected, remove its entry from %s",
+					addr, fingerprint, k.path)
+			}
+			return nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	f, err := os.OpenFile(k.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	log.Printf("trusting certificate of %s with fingerprint %s", addr, fingerprint)
+	_, err = fmt.Fprintf(f, "%s %s\n", addr, fingerprint)
+	return err
+}
+
+// ServeRemote serves the IPC API on a listener reachable from other
+// machines, such as from ListenTLS, requiring clients to send the token.
+func (s *serverImpl) ServeRemote(listener net.Listener, token string) error {
+	s.remoteLock.Lock()
+	s.remoteServer = &http.Server{Handler: requireToken(token, s.server.Handler)}
+	srv := s.remoteServer
+	s.remoteLock.Unlock()
+	return srv.Serve(listener)
+}
+
+func (s *serverImpl) shutdownRemote(ctx context.Context) error {
+	s.remoteLock.Lock()
+	defer s.remoteLock.Unlock()
+	if s.remoteServer == nil {
+		return nil
+	}
+	return s.remoteServer.Shutdown(ctx)
+}
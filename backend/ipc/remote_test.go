@@ -0,0 +1,72 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoteIPC(t *testing.T) {
+	dir := t.TempDir()
+	knownHosts := filepath.Join(dir, "known_hosts")
+	serve := func(certDir string) (addr string, stop func()) {
+		l, err := ListenTLS("127.0.0.1:0", filepath.Join(certDir, "cert.pem"), filepath.Join(certDir, "key.pem"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewServer(ServerOptions{
+			Playback: nopPlaybackHandler{},
+			Queue:    &fakeQueueHandler{},
+			Status:   func() Status { return Status{State: "paused"} },
+		}).(*serverImpl)
+		go s.ServeRemote(l, "secret")
+		return l.Addr().String(), func() {
+			s.shutdownRemote(context.Background())
+			l.Close()
+		}
+	}
+
+	addr, stop := serve(dir)
+	if _, err := ConnectRemote(addr, "wrong", knownHosts); err == nil || !strings.Contains(err.Error(), ErrUnauthorized.Error()) {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+	c, err := ConnectRemote(addr, "secret", knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := c.Status(); err != nil || status.State != "paused" {
+		t.Errorf("unexpected status %v, %v", status, err)
+	}
+	stop()
+
+	// same certificate on restart is still trusted
+	l, err := ListenTLS(addr, filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Skipf("could not listen on %s again: %v", addr, err)
+	}
+	s := NewServer(ServerOptions{Playback: nopPlaybackHandler{}, Queue: &fakeQueueHandler{}}).(*serverImpl)
+	go s.ServeRemote(l, "secret")
+	if _, err := ConnectRemote(addr, "secret", knownHosts); err != nil {
+		t.Errorf("reconnecting with same certificate: %v", err)
+	}
+	s.shutdownRemote(context.Background())
+	l.Close()
+
+	// a different certificate at the same address is rejected
+	l, err = ListenTLS(addr, filepath.Join(t.TempDir(), "cert.pem"), filepath.Join(t.TempDir(), "key.pem"))
+	if err != nil {
+		t.Skipf("could not listen on %s again: %v", addr, err)
+	}
+	defer l.Close()
+	go (&http.Server{Handler: http.NotFoundHandler()}).Serve(l)
+	_, err = ConnectRemote(addr, "secret", knownHosts)
+	if err == nil || !strings.Contains(err.Error(), "has changed") {
+		t.Errorf("expected changed certificate error, got %v", err)
+	}
+	if errors.Is(err, ErrUnauthorized) {
+		t.Error("token must not be sent to an untrusted server")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 )
@@ -34,6 +35,14 @@ type PlaybackHandler interface {
 type IPCServer interface {
 	Serve(net.Listener) error
 	Shutdown(context.Context) error
+	// ServeRemote serves the API on a listener reachable from other
+	// machines, requiring clients to authenticate with the token.
+	ServeRemote(listener net.Listener, token string) error
+	// PublishEvent sends an event to clients following the event stream.
+	PublishEvent(Event)
+	// SetEventSnapshot sets the function returning the events that describe
+	// the current state, sent to clients when they connect to the event stream.
+	SetEventSnapshot(func() []Event)
 }
 
 type ServerManager interface {
@@ -43,15 +52,47 @@ type ServerManager interface {
 type serverImpl struct {
 	server          *http.Server
 	pbHandler       PlaybackHandler
+	qHandler        QueueHandler
+	statusFn        func() Status
 	rateFn          func(int)
 	sm              ServerManager
 	showFn          func()
 	quitFn          func()
 	reloadThemeFn   func()
+	openURIFn       func(string) error
+	events          *eventBroker
+	remoteLock      sync.Mutex
+	remoteServer    *http.Server
 }
 
-func NewServer(pbHandler PlaybackHandler, rateFn func(int), sm ServerManager, showFn, quitFn, reloadThemeFn func()) IPCServer {
-	s := &serverImpl{pbHandler: pbHandler, rateFn: rateFn, sm: sm, showFn: showFn, quitFn: quitFn, reloadThemeFn: reloadThemeFn}
+// ServerOptions are the handlers the IPC server's endpoints are served by.
+type ServerOptions struct {
+	Playback      PlaybackHandler
+	Queue         QueueHandler
+	Status        func() Status
+	SetRating     func(rating int)
+	ServerManager ServerManager
+	// shows the main window
+	Show        func()
+	Quit        func()
+	ReloadTheme func()
+	// opens a supersonic:// URI
+	OpenURI func(uri string) error
+}
+
+func NewServer(opts ServerOptions) IPCServer {
+	s := &serverImpl{
+		pbHandler:     opts.Playback,
+		qHandler:      opts.Queue,
+		statusFn:      opts.Status,
+		rateFn:        opts.SetRating,
+		sm:            opts.ServerManager,
+		showFn:        opts.Show,
+		quitFn:        opts.Quit,
+		reloadThemeFn: opts.ReloadTheme,
+		openURIFn:     opts.OpenURI,
+		events:        newEventBroker(),
+	}
 	s.server = &http.Server{
 		Handler: s.createHandler(),
 	}
@@ -63,11 +104,24 @@ func (s *serverImpl) Serve(listener net.Listener) error {
 }
 
 func (s *serverImpl) Shutdown(ctx context.Context) error {
+	// event streams would otherwise keep the server from shutting down
+	s.events.close()
+	s.shutdownRemote(ctx)
 	err := s.server.Shutdown(ctx)
 	DestroyConn()
 	return err
 }
 
+func (s *serverImpl) PublishEvent(ev Event) {
+	s.events.publish(ev)
+}
+
+func (s *serverImpl) SetEventSnapshot(snapshot func() []Event) {
+	s.events.lock.Lock()
+	defer s.events.lock.Unlock()
+	s.events.snapshot = snapshot
+}
+
 func (s *serverImpl) createHandler() http.Handler {
 	m := http.NewServeMux()
 	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -75,10 +129,25 @@ func (s *serverImpl) createHandler() http.Handler {
 		w.Write([]byte("The given path is not valid"))
 	})
 	m.HandleFunc(PingPath, s.makeSimpleEndpointHandler(func() {}))
+	m.HandleFunc(StatusPath, func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.Marshal(s.statusFn())
+		if err != nil {
+			s.writeErr(w, err)
+			return
+		}
+		s.writeData(w, b)
+	})
 	m.HandleFunc(ShowPath, s.makeSimpleEndpointHandler(func() {
 		s.showFn()
 	}))
 	m.HandleFunc(ReloadThemePath, s.makeSimpleEndpointHandler(s.reloadThemeFn))
+	m.HandleFunc(OpenURIPath, func(w http.ResponseWriter, r *http.Request) {
+		if err := s.openURIFn(r.URL.Query().Get("uri")); err != nil {
+			s.writeErr(w, err)
+			return
+		}
+		s.writeOK(w)
+	})
 	m.HandleFunc(QuitPath, s.makeSimpleEndpointHandler(func() {
 		s.quitFn()
 	}))
@@ -175,6 +244,8 @@ func (s *serverImpl) createHandler() http.Handler {
 
 		return tracks, nil
 	}))
+	m.HandleFunc(EventsPath, s.serveEvents)
+	s.registerQueueHandlers(m)
 	m.HandleFunc(RateCurrentTrackPath, func(w http.ResponseWriter, r *http.Request) {
 		v := r.URL.Query().Get("r")
 		if rating, err := strconv.Atoi(v); err == nil {
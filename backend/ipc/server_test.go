@@ -0,0 +1,38 @@
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenURIEndpoint(t *testing.T) {
+	var opened string
+	openURI := func(uri string) error {
+		if uri == "bad" {
+			return errors.New("invalid URI")
+		}
+		opened = uri
+		return nil
+	}
+	h := NewServer(ServerOptions{Playback: nopPlaybackHandler{}, Queue: &fakeQueueHandler{}, OpenURI: openURI}).(*serverImpl).createHandler()
+	get := func(path string) Response {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "http://supersonic"+path, nil))
+		var r Response
+		json.NewDecoder(rec.Body).Decode(&r)
+		return r
+	}
+
+	uri := "supersonic://playlist/a%20b?play=1&shuffle=1"
+	if r := get(BuildOpenURIPath(uri)); r.Error != "" {
+		t.Fatalf("unexpected error %q", r.Error)
+	}
+	if opened != uri {
+		t.Errorf("expected %s to be opened, got %s", uri, opened)
+	}
+	if r := get(BuildOpenURIPath("bad")); r.Error == "" {
+		t.Error("expected an error for an invalid URI")
+	}
+}
@@ -0,0 +1,82 @@
+package ipc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+)
+
+// Status is the playback state returned by StatusPath.
+type Status struct {
+	State       string     `json:"state"` // "playing", "paused", or "stopped"
+	NowPlaying  *TrackInfo `json:"nowPlaying"`
+	Position    float64    `json:"position"`
+	Volume      int        `json:"volume"`
+	LoopMode    string     `json:"loopMode"` // "none", "all", or "one"
+	Shuffle     bool       `json:"shuffle"`
+	QueueIndex  int        `json:"queueIndex"`
+	QueueLength int        `json:"queueLength"`
+}
+
+// StatusFormatData is the data a status format template is executed with.
+type StatusFormatData struct {
+	State           string
+	ID              string
+	Type            string
+	Title           string
+	Artist          string // artist names joined by ", "
+	Artists         []string
+	Album           string
+	Position        string // formatted as [h:]mm:ss
+	Duration        string // formatted as [h:]mm:ss
+	PositionSeconds float64
+	DurationSeconds float64
+	Volume          int
+	LoopMode        string
+	Shuffle         bool
+	QueueIndex      int
+	QueueLength     int
+}
+
+// Format executes the text/template tmpl with the StatusFormatData for the status.
+func (s Status) Format(tmpl string) (string, error) {
+	t, err := template.New("status").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	data := StatusFormatData{
+		State:           s.State,
+		Position:        formatSeconds(s.Position),
+		Duration:        formatSeconds(0),
+		PositionSeconds: s.Position,
+		Volume:          s.Volume,
+		LoopMode:        s.LoopMode,
+		Shuffle:         s.Shuffle,
+		QueueIndex:      s.QueueIndex,
+		QueueLength:     s.QueueLength,
+	}
+	if np := s.NowPlaying; np != nil {
+		data.ID = np.ID
+		data.Type = np.Type
+		data.Title = np.Title
+		data.Artist = strings.Join(np.Artists, ", ")
+		data.Artists = np.Artists
+		data.Album = np.Album
+		data.Duration = formatSeconds(np.Duration)
+		data.DurationSeconds = np.Duration
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func formatSeconds(secs float64) string {
+	sec := int(math.Round(max(secs, 0)))
+	if sec >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+	}
+	return fmt.Sprintf("%d:%02d", sec/60, sec%60)
+}
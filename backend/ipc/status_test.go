@@ -0,0 +1,34 @@
+package ipc
+
+import "testing"
+
+func TestStatusFormat(t *testing.T) {
+	s := Status{
+		State:    "playing",
+		Position: 65.4,
+		NowPlaying: &TrackInfo{
+			Title:    "Song",
+			Artists:  []string{"A", "B"},
+			Duration: 3725,
+		},
+	}
+	out, err := s.Format("{{.Artist}} - {{.Title}} [{{.Position}}/{{.Duration}}] {{.State}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "A, B - Song [1:05/1:02:05] playing"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	out, err = Status{State: "stopped"}.Format("{{.Title}}|{{.Position}}/{{.Duration}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "|0:00/0:00"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	if _, err := s.Format("{{.NoSuchField}}"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
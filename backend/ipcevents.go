@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"github.com/dweymouth/supersonic/backend/ipc"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+// publishIPCEvents sends playback state changes to IPC event stream clients.
+func (a *App) publishIPCEvents(s ipc.IPCServer) {
+	pm := a.PlaybackManager
+	s.SetEventSnapshot(func() []ipc.Event {
+		return []ipc.Event{
+			songEvent(pm.NowPlaying()),
+			stateEvent(pm.PlaybackStatus().State),
+			ipc.NewEvent(ipc.EventVolume, map[string]int{"volume": pm.Volume()}),
+			loopEvent(pm.GetLoopMode()),
+			ipc.NewEvent(ipc.EventShuffle, map[string]bool{"shuffle": pm.IsShuffle()}),
+			queueEvent(pm),
+		}
+	})
+
+	pm.OnSongChange(func(item mediaprovider.MediaItem, _ *mediaprovider.Track) {
+		s.PublishEvent(songEvent(item))
+	})
+	pm.OnPlaying(func() { s.PublishEvent(stateEvent(player.Playing)) })
+	pm.OnPaused(func() { s.PublishEvent(stateEvent(player.Paused)) })
+	pm.OnStopped(func() { s.PublishEvent(stateEvent(player.Stopped)) })
+	pm.OnSeek(func() {
+		s.PublishEvent(ipc.NewEvent(ipc.EventSeek, map[string]float64{"position": pm.PlaybackStatus().TimePos}))
+	})
+	pm.OnVolumeChange(func(vol int) {
+		s.PublishEvent(ipc.NewEvent(ipc.EventVolume, map[string]int{"volume": vol}))
+	})
+	pm.OnLoopModeChange(func(mode LoopMode) { s.PublishEvent(loopEvent(mode)) })
+	pm.OnShuffleChange(func(shuffle bool) {
+		s.PublishEvent(ipc.NewEvent(ipc.EventShuffle, map[string]bool{"shuffle": shuffle}))
+	})
+	pm.OnQueueChange(func() { s.PublishEvent(queueEvent(pm)) })
+}
+
+// ipcStatus returns the current playback state for the IPC status endpoint.
+func ipcStatus(pm *PlaybackManager) ipc.Status {
+	status := pm.PlaybackStatus()
+	return ipc.Status{
+		State:       playbackStateName(status.State),
+		NowPlaying:  ipc.NewTrackInfo(pm.NowPlaying()),
+		Position:    status.TimePos,
+		Volume:      pm.Volume(),
+		LoopMode:    loopModeName(pm.GetLoopMode()),
+		Shuffle:     pm.IsShuffle(),
+		QueueIndex:  pm.NowPlayingIndex(),
+		QueueLength: len(pm.GetActivePlayQueue()),
+	}
+}
+
+func songEvent(item mediaprovider.MediaItem) ipc.Event {
+	return ipc.NewEvent(ipc.EventSong, ipc.NewTrackInfo(item))
+}
+
+func stateEvent(state player.State) ipc.Event {
+	return ipc.NewEvent(ipc.EventState, map[string]string{"state": playbackStateName(state)})
+}
+
+func loopEvent(mode LoopMode) ipc.Event {
+	return ipc.NewEvent(ipc.EventLoop, map[string]string{"mode": loopModeName(mode)})
+}
+
+func queueEvent(pm *PlaybackManager) ipc.Event {
+	return ipc.NewEvent(ipc.EventQueue, map[string]int{
+		"length": len(pm.GetActivePlayQueue()),
+		"index":  pm.NowPlayingIndex(),
+	})
+}
+
+func playbackStateName(state player.State) string {
+	switch state {
+	case player.Playing:
+		return "playing"
+	case player.Paused:
+		return "paused"
+	}
+	return "stopped"
+}
+
+func loopModeName(mode LoopMode) string {
+	switch mode {
+	case LoopAll:
+		return "all"
+	case LoopOne:
+		return "one"
+	}
+	return "none"
+}
@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// ipcQueueHandler adapts the PlaybackManager to the IPC queue endpoints.
+type ipcQueueHandler struct {
+	*PlaybackManager
+}
+
+func (q ipcQueueHandler) EnqueueAlbum(id string, next bool) error {
+	return q.LoadAlbum(id, enqueueMode(next), false)
+}
+
+func (q ipcQueueHandler) EnqueuePlaylist(id string, next bool) error {
+	return q.LoadPlaylist(id, enqueueMode(next), false)
+}
+
+func (q ipcQueueHandler) EnqueueTrack(id string, next bool) error {
+	tr, err := q.engine.sm.Server.GetTrack(id)
+	if err != nil {
+		return err
+	}
+	q.LoadTracks([]*mediaprovider.Track{tr}, enqueueMode(next), false)
+	return nil
+}
+
+func (q ipcQueueHandler) SetLoopModeName(name string) error {
+	switch name {
+	case "none":
+		q.SetLoopMode(LoopNone)
+	case "all":
+		q.SetLoopMode(LoopAll)
+	case "one":
+		q.SetLoopMode(LoopOne)
+	default:
+		return fmt.Errorf("unknown loop mode %q", name)
+	}
+	return nil
+}
+
+func enqueueMode(next bool) InsertQueueMode {
+	if next {
+		return InsertNext
+	}
+	return Append
+}
@@ -302,6 +302,13 @@ type CanSavePlayQueue interface {
 	GetPlayQueue() (*SavedPlayQueue, error)
 }
 
+type BookmarkProvider interface {
+	GetBookmarks() ([]*Bookmark, error)
+	// Creates or updates the bookmark for the given track
+	CreateBookmark(trackID string, positionSecs float64, comment string) error
+	DeleteBookmark(trackID string) error
+}
+
 type LyricsProvider interface {
 	GetLyrics(track *Track) (*Lyrics, error)
 }
@@ -311,18 +318,35 @@ type RadioProvider interface {
 	GetRadioStations() ([]*RadioStation, error)
 }
 
+type PodcastProvider interface {
+	// Returns all podcast channels, without their episodes
+	GetPodcastChannels() ([]*PodcastChannel, error)
+	// Returns the podcast channel with its episodes
+	GetPodcastChannel(id string) (*PodcastChannel, error)
+	// Subscribes to the podcast with the given RSS feed URL
+	CreatePodcastChannel(feedURL string) error
+	DeletePodcastChannel(id string) error
+	// Requests the server to download the given episode
+	DownloadPodcastEpisode(episodeID string) error
+	// Requests the server to check all channels for new episodes
+	RefreshPodcasts() error
+}
+
 type JukeboxProvider interface {
 	JukeboxStart() error
 	JukeboxStop() error
 	JukeboxSeek(idx, seconds int) error
 	JukeboxClear() error
-	JukeboxAdd(trackID string) error
+	JukeboxAdd(trackIDs ...string) error
 	JukeboxRemove(idx int) error
 	JukeboxGetStatus() (*JukeboxStatus, error)
 
+	// Returns the tracks in the jukebox queue along with the playback status
+	JukeboxGetQueue() ([]*Track, *JukeboxStatus, error)
+
 	// Performs a Clear followed by an Add to set the queue
-	// to contain a single track
-	JukeboxSet(trackID string) error
+	// to contain the given tracks
+	JukeboxSet(trackIDs ...string) error
 
 	// Sets the volume of the jukebox player (0-100)
 	JukeboxSetVolume(vol int) error
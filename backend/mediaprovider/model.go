@@ -198,6 +198,66 @@ type LyricLine struct {
 	Start float64 // seconds
 }
 
+// Chapter is a section of a track, such as an embedded chapter marker
+// in an audiobook or a track from a CUE sheet for a single-file album rip.
+type Chapter struct {
+	Title     string
+	Performer string
+	Start     float64 // seconds
+}
+
+// Bookmark is a saved playback position within a track.
+type Bookmark struct {
+	Track    *Track
+	Position float64 // seconds
+	Comment  string
+	Changed  time.Time
+}
+
+type PodcastChannel struct {
+	ID           string
+	Title        string
+	Description  string
+	URL          string
+	CoverArtID   string
+	Status       string
+	ErrorMessage string
+	Episodes     []*PodcastEpisode
+}
+
+// Server-side download status of a podcast episode
+const (
+	PodcastEpisodeStatusNew         = "new"
+	PodcastEpisodeStatusDownloading = "downloading"
+	PodcastEpisodeStatusCompleted   = "completed"
+	PodcastEpisodeStatusError       = "error"
+	PodcastEpisodeStatusDeleted     = "deleted"
+	PodcastEpisodeStatusSkipped     = "skipped"
+)
+
+type PodcastEpisode struct {
+	ID           string
+	StreamID     string // ID of the downloaded media file, empty until downloaded
+	ChannelID    string
+	ChannelTitle string
+	Title        string
+	Description  string
+	Status       string
+	PublishDate  time.Time
+	Duration     time.Duration
+	CoverArtID   string
+	ContentType  string
+	Size         int64
+	BitRate      int
+	Played       bool
+}
+
+// IsDownloaded returns true if the episode has been downloaded
+// by the server and can be played.
+func (e *PodcastEpisode) IsDownloaded() bool {
+	return e.Status == PodcastEpisodeStatusCompleted && e.StreamID != ""
+}
+
 type SavedPlayQueue struct {
 	Tracks   []*Track
 	TrackPos int
@@ -216,6 +276,7 @@ type MediaItemType int
 const (
 	MediaItemTypeTrack MediaItemType = iota
 	MediaItemTypeRadioStation
+	MediaItemTypePodcastEpisode
 )
 
 type MediaItemMetadata struct {
@@ -277,6 +338,30 @@ func (r *RadioStation) Copy() MediaItem {
 	return r // no need to copy since RadioStations are immutable
 }
 
+func (e *PodcastEpisode) Metadata() MediaItemMetadata {
+	if e == nil {
+		return MediaItemMetadata{}
+	}
+	return MediaItemMetadata{
+		Type:       MediaItemTypePodcastEpisode,
+		MIMEType:   e.ContentType,
+		ID:         e.ID,
+		Name:       e.Title,
+		Artists:    []string{e.ChannelTitle},
+		Album:      e.ChannelTitle,
+		CoverArtID: e.CoverArtID,
+		Duration:   e.Duration,
+	}
+}
+
+func (e *PodcastEpisode) Copy() MediaItem {
+	if e == nil {
+		return nil
+	}
+	new := *e
+	return &new
+}
+
 type ContentType int
 
 const (
@@ -0,0 +1,49 @@
+package subsonic
+
+import (
+	"strconv"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+var _ mediaprovider.BookmarkProvider = (*subsonicMediaProvider)(nil)
+
+func (s *subsonicMediaProvider) GetBookmarks() ([]*mediaprovider.Bookmark, error) {
+	resp, err := s.client.Get("getBookmarks", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Bookmarks == nil {
+		return nil, nil
+	}
+	bookmarks := make([]*mediaprovider.Bookmark, 0, len(resp.Bookmarks.Bookmark))
+	for _, b := range resp.Bookmarks.Bookmark {
+		if b.Entry == nil {
+			continue
+		}
+		bookmarks = append(bookmarks, &mediaprovider.Bookmark{
+			Track:    toTrack(b.Entry),
+			Position: float64(b.Position) / 1000,
+			Comment:  b.Comment,
+			Changed:  b.Changed,
+		})
+	}
+	return bookmarks, nil
+}
+
+func (s *subsonicMediaProvider) CreateBookmark(trackID string, positionSecs float64, comment string) error {
+	params := map[string]string{
+		"id":       trackID,
+		"position": strconv.FormatInt(int64(positionSecs*1000), 10),
+	}
+	if comment != "" {
+		params["comment"] = comment
+	}
+	_, err := s.client.Get("createBookmark", params)
+	return err
+}
+
+func (s *subsonicMediaProvider) DeleteBookmark(trackID string) error {
+	_, err := s.client.Get("deleteBookmark", map[string]string{"id": trackID})
+	return err
+}
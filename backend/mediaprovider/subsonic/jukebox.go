@@ -1,10 +1,16 @@
 package subsonic
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/supersonic-app/go-subsonic/subsonic"
 )
 
 var _ mediaprovider.JukeboxProvider = (*subsonicMediaProvider)(nil)
@@ -43,16 +49,15 @@ func (s *subsonicMediaProvider) JukeboxRemove(idx int) error {
 	return err
 }
 
-func (s *subsonicMediaProvider) JukeboxSet(trackID string) error {
-	_, err := s.client.JukeboxControl("set",
-		map[string]string{"id": trackID})
-	return err
+func (s *subsonicMediaProvider) JukeboxSet(trackIDs ...string) error {
+	return s.jukeboxControlIDs("set", trackIDs)
 }
 
-func (s *subsonicMediaProvider) JukeboxAdd(trackID string) error {
-	_, err := s.client.JukeboxControl("add",
-		map[string]string{"id": trackID})
-	return err
+func (s *subsonicMediaProvider) JukeboxAdd(trackIDs ...string) error {
+	if len(trackIDs) == 0 {
+		return nil
+	}
+	return s.jukeboxControlIDs("add", trackIDs)
 }
 
 func (s *subsonicMediaProvider) JukeboxGetStatus() (*mediaprovider.JukeboxStatus, error) {
@@ -67,3 +72,54 @@ func (s *subsonicMediaProvider) JukeboxGetStatus() (*mediaprovider.JukeboxStatus
 		PositionSeconds: float64(stat.Position),
 	}, nil
 }
+
+func (s *subsonicMediaProvider) JukeboxGetQueue() ([]*mediaprovider.Track, *mediaprovider.JukeboxStatus, error) {
+	pl, err := s.client.GetJukeboxPlaylist()
+	if err != nil {
+		return nil, nil, err
+	}
+	tracks := make([]*mediaprovider.Track, 0, len(pl.Entry))
+	for _, e := range pl.Entry {
+		tracks = append(tracks, toTrack(e))
+	}
+	return tracks, &mediaprovider.JukeboxStatus{
+		Volume:          int(pl.Gain * 100),
+		CurrentTrack:    pl.CurrentIndex,
+		Playing:         pl.Playing,
+		PositionSeconds: float64(pl.Position),
+	}, nil
+}
+
+// jukeboxControlIDs sends a jukeboxControl action with one id
+// parameter per track, which the client library's JukeboxControl
+// can't do since it takes its parameters as a map.
+func (s *subsonicMediaProvider) jukeboxControlIDs(action string, trackIDs []string) error {
+	params := url.Values{"action": {action}, "id": trackIDs}
+	resp, err := s.client.Request(http.MethodGet, "jukeboxControl", params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var parsed subsonic.Response
+	if s.client.UseJSON {
+		var wrapper struct {
+			Response *subsonic.Response `json:"subsonic-response"`
+		}
+		wrapper.Response = &parsed
+		err = json.Unmarshal(body, &wrapper)
+	} else {
+		err = xml.Unmarshal(body, &parsed)
+	}
+	if err != nil {
+		return err
+	}
+	if parsed.Error != nil {
+		return fmt.Errorf("Error #%d: %s", parsed.Error.Code, parsed.Error.Message)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package subsonic
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/supersonic-app/go-subsonic/subsonic"
+)
+
+var _ mediaprovider.PodcastProvider = (*subsonicMediaProvider)(nil)
+
+// The go-subsonic podcast models omit the channel and episode IDs,
+// so podcast responses are decoded into these types instead.
+type podcastsResponse struct {
+	Status   string          `xml:"status,attr" json:"status"`
+	Error    *subsonic.Error `xml:"error"       json:"error"`
+	Podcasts *struct {
+		Channel []*podcastChannel `xml:"channel" json:"channel"`
+	} `xml:"podcasts" json:"podcasts"`
+}
+
+type podcastChannel struct {
+	ID           string            `xml:"id,attr"           json:"id"`
+	URL          string            `xml:"url,attr"          json:"url"`
+	Title        string            `xml:"title,attr"        json:"title"`
+	Description  string            `xml:"description,attr"  json:"description"`
+	CoverArt     string            `xml:"coverArt,attr"     json:"coverArt"`
+	Status       string            `xml:"status,attr"       json:"status"`
+	ErrorMessage string            `xml:"errorMessage,attr" json:"errorMessage"`
+	Episode      []*podcastEpisode `xml:"episode"           json:"episode"`
+}
+
+type podcastEpisode struct {
+	ID          string `xml:"id,attr"          json:"id"`
+	StreamID    string `xml:"streamId,attr"    json:"streamId"`
+	ChannelID   string `xml:"channelId,attr"   json:"channelId"`
+	Title       string `xml:"title,attr"       json:"title"`
+	Album       string `xml:"album,attr"       json:"album"`
+	Description string `xml:"description,attr" json:"description"`
+	Status      string `xml:"status,attr"      json:"status"`
+	PublishDate string `xml:"publishDate,attr" json:"publishDate"`
+	Duration    int    `xml:"duration,attr"    json:"duration"`
+	CoverArt    string `xml:"coverArt,attr"    json:"coverArt"`
+	ContentType string `xml:"contentType,attr" json:"contentType"`
+	Size        int64  `xml:"size,attr"        json:"size"`
+	BitRate     int    `xml:"bitRate,attr"     json:"bitRate"`
+}
+
+func (s *subsonicMediaProvider) GetPodcastChannels() ([]*mediaprovider.PodcastChannel, error) {
+	resp, err := s.getPodcasts(url.Values{"includeEpisodes": {"false"}})
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]*mediaprovider.PodcastChannel, 0, len(resp))
+	for _, ch := range resp {
+		channels = append(channels, toPodcastChannel(ch))
+	}
+	return channels, nil
+}
+
+func (s *subsonicMediaProvider) GetPodcastChannel(id string) (*mediaprovider.PodcastChannel, error) {
+	resp, err := s.getPodcasts(url.Values{"id": {id}, "includeEpisodes": {"true"}})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, errors.New("podcast channel not found")
+	}
+	return toPodcastChannel(resp[0]), nil
+}
+
+func (s *subsonicMediaProvider) CreatePodcastChannel(feedURL string) error {
+	_, err := s.client.Get("createPodcastChannel", map[string]string{"url": feedURL})
+	return err
+}
+
+func (s *subsonicMediaProvider) DeletePodcastChannel(id string) error {
+	_, err := s.client.Get("deletePodcastChannel", map[string]string{"id": id})
+	return err
+}
+
+func (s *subsonicMediaProvider) DownloadPodcastEpisode(episodeID string) error {
+	_, err := s.client.Get("downloadPodcastEpisode", map[string]string{"id": episodeID})
+	return err
+}
+
+func (s *subsonicMediaProvider) RefreshPodcasts() error {
+	_, err := s.client.Get("refreshPodcasts", nil)
+	return err
+}
+
+func (s *subsonicMediaProvider) getPodcasts(params url.Values) ([]*podcastChannel, error) {
+	resp, err := s.client.Request(http.MethodGet, "getPodcasts", params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed podcastsResponse
+	if s.client.UseJSON {
+		var wrapper struct {
+			Response *podcastsResponse `json:"subsonic-response"`
+		}
+		wrapper.Response = &parsed
+		err = json.Unmarshal(body, &wrapper)
+	} else {
+		err = xml.Unmarshal(body, &parsed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Error != nil {
+		return nil, fmt.Errorf("Error #%d: %s", parsed.Error.Code, parsed.Error.Message)
+	}
+	if parsed.Podcasts == nil {
+		return nil, nil
+	}
+	return parsed.Podcasts.Channel, nil
+}
+
+func toPodcastChannel(ch *podcastChannel) *mediaprovider.PodcastChannel {
+	channel := &mediaprovider.PodcastChannel{
+		ID:           ch.ID,
+		Title:        ch.Title,
+		Description:  ch.Description,
+		URL:          ch.URL,
+		CoverArtID:   ch.CoverArt,
+		Status:       ch.Status,
+		ErrorMessage: ch.ErrorMessage,
+	}
+	for _, ep := range ch.Episode {
+		episode := toPodcastEpisode(ep)
+		if episode.ChannelTitle == "" {
+			episode.ChannelTitle = ch.Title
+		}
+		if episode.CoverArtID == "" {
+			episode.CoverArtID = ch.CoverArt
+		}
+		channel.Episodes = append(channel.Episodes, episode)
+	}
+	return channel
+}
+
+func toPodcastEpisode(ep *podcastEpisode) *mediaprovider.PodcastEpisode {
+	return &mediaprovider.PodcastEpisode{
+		ID:           ep.ID,
+		StreamID:     ep.StreamID,
+		ChannelID:    ep.ChannelID,
+		ChannelTitle: ep.Album,
+		Title:        ep.Title,
+		Description:  ep.Description,
+		Status:       ep.Status,
+		PublishDate:  parsePodcastDate(ep.PublishDate),
+		Duration:     time.Duration(ep.Duration) * time.Second,
+		CoverArtID:   ep.CoverArt,
+		ContentType:  ep.ContentType,
+		Size:         ep.Size,
+		BitRate:      ep.BitRate,
+	}
+}
+
+// parses an xsd:dateTime, which may omit the time zone
+func parsePodcastDate(s string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
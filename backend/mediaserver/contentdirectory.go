@@ -0,0 +1,479 @@
+package mediaserver
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// Object IDs of the content directory. Containers for server
+// entities are identified by a prefix followed by the entity ID.
+const (
+	rootID           = "0"
+	artistsID        = "artists"
+	albumsID         = "albums"
+	genresID         = "genres"
+	playlistsID      = "playlists"
+	favoritesID      = "favorites"
+	favArtistsID     = "favorites:artists"
+	favAlbumsID      = "favorites:albums"
+	favTracksID      = "favorites:tracks"
+	artistPrefix     = "artist:"
+	albumPrefix      = "album:"
+	genrePrefix      = "genre:"
+	playlistPrefix   = "playlist:"
+	trackPrefix      = "track:"
+	childrenCacheTTL = 5 * time.Minute
+	maxCachedLists   = 50
+)
+
+const (
+	classContainer = "object.container"
+	classArtist    = "object.container.person.musicArtist"
+	classAlbum     = "object.container.album.musicAlbum"
+	classGenre     = "object.container.genre.musicGenre"
+	classPlaylist  = "object.container.playlistContainer"
+	classTrack     = "object.item.audioItem.musicTrack"
+)
+
+const dlnaFeatures = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+// object is a container or item of the content directory.
+type object struct {
+	ID         string
+	ParentID   string
+	Title      string
+	Class      string
+	ChildCount int // -1 if unknown
+	Artist     string
+	Genre      string
+	CoverArtID string
+	Track      *mediaprovider.Track // set for items only
+}
+
+func (o *object) isContainer() bool {
+	return o.Track == nil
+}
+
+type cachedChildren struct {
+	children []object
+	expires  time.Time
+}
+
+// contentDirectory maps the library of the connected MediaProvider
+// to the object hierarchy of the ContentDirectory service.
+type contentDirectory struct {
+	provider func() mediaprovider.MediaProvider
+
+	mu       sync.Mutex
+	updateID uint32
+	cache    map[string]cachedChildren
+}
+
+var staticContainers = map[string]object{
+	rootID:       {ID: rootID, ParentID: "-1", Title: "Supersonic", Class: classContainer, ChildCount: 5},
+	artistsID:    {ID: artistsID, ParentID: rootID, Title: "Artists", Class: classContainer, ChildCount: -1},
+	albumsID:     {ID: albumsID, ParentID: rootID, Title: "Albums", Class: classContainer, ChildCount: -1},
+	genresID:     {ID: genresID, ParentID: rootID, Title: "Genres", Class: classContainer, ChildCount: -1},
+	playlistsID:  {ID: playlistsID, ParentID: rootID, Title: "Playlists", Class: classContainer, ChildCount: -1},
+	favoritesID:  {ID: favoritesID, ParentID: rootID, Title: "Favorites", Class: classContainer, ChildCount: 3},
+	favArtistsID: {ID: favArtistsID, ParentID: favoritesID, Title: "Artists", Class: classContainer, ChildCount: -1},
+	favAlbumsID:  {ID: favAlbumsID, ParentID: favoritesID, Title: "Albums", Class: classContainer, ChildCount: -1},
+	favTracksID:  {ID: favTracksID, ParentID: favoritesID, Title: "Tracks", Class: classContainer, ChildCount: -1},
+}
+
+var staticChildren = map[string][]string{
+	rootID:      {artistsID, albumsID, genresID, playlistsID, favoritesID},
+	favoritesID: {favArtistsID, favAlbumsID, favTracksID},
+}
+
+func newContentDirectory(provider func() mediaprovider.MediaProvider) *contentDirectory {
+	return &contentDirectory{
+		provider: provider,
+		updateID: 1,
+		cache:    make(map[string]cachedChildren),
+	}
+}
+
+// LibraryChanged invalidates cached listings and bumps the SystemUpdateID,
+// which tells control points to refresh their view of the library.
+func (c *contentDirectory) LibraryChanged() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updateID++
+	clear(c.cache)
+}
+
+func (c *contentDirectory) UpdateID() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updateID
+}
+
+func (c *contentDirectory) Metadata(id string) (*object, error) {
+	if o, ok := staticContainers[id]; ok {
+		return &o, nil
+	}
+	mp := c.provider()
+	if mp == nil {
+		return nil, errNoServer
+	}
+	entityID, kind := splitObjectID(id)
+	switch kind {
+	case artistPrefix:
+		a, err := mp.GetArtist(entityID)
+		if err != nil {
+			return nil, noSuchObject(id)
+		}
+		o := artistObject(&a.Artist, artistsID)
+		o.ChildCount = len(a.Albums)
+		return &o, nil
+	case albumPrefix:
+		a, err := mp.GetAlbum(entityID)
+		if err != nil {
+			return nil, noSuchObject(id)
+		}
+		o := albumObject(&a.Album, albumsID)
+		o.ChildCount = len(a.Tracks)
+		return &o, nil
+	case genrePrefix:
+		o := genreObject(entityID)
+		return &o, nil
+	case playlistPrefix:
+		p, err := mp.GetPlaylist(entityID)
+		if err != nil {
+			return nil, noSuchObject(id)
+		}
+		o := playlistObject(&p.Playlist)
+		o.ChildCount = len(p.Tracks)
+		return &o, nil
+	case trackPrefix:
+		t, err := mp.GetTrack(entityID)
+		if err != nil {
+			return nil, noSuchObject(id)
+		}
+		o := trackObject(t, albumPrefix+t.AlbumID)
+		return &o, nil
+	}
+	return nil, noSuchObject(id)
+}
+
+func (c *contentDirectory) Children(id string) ([]object, error) {
+	if ids, ok := staticChildren[id]; ok {
+		children := make([]object, 0, len(ids))
+		for _, childID := range ids {
+			children = append(children, staticContainers[childID])
+		}
+		return children, nil
+	}
+
+	c.mu.Lock()
+	cached, ok := c.cache[id]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.children, nil
+	}
+
+	children, err := c.fetchChildren(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// control points page through large containers with
+	// repeated Browse requests, so cache the listing
+	c.mu.Lock()
+	if len(c.cache) >= maxCachedLists {
+		clear(c.cache)
+	}
+	c.cache[id] = cachedChildren{children: children, expires: time.Now().Add(childrenCacheTTL)}
+	c.mu.Unlock()
+	return children, nil
+}
+
+func (c *contentDirectory) fetchChildren(id string) ([]object, error) {
+	mp := c.provider()
+	if mp == nil {
+		return nil, errNoServer
+	}
+
+	var children []object
+	switch id {
+	case artistsID:
+		iter := mp.IterateArtists(preferredSort(mp.ArtistSortOrders(), mediaprovider.ArtistSortNameAZ),
+			mediaprovider.NewArtistFilter(mediaprovider.ArtistFilterOptions{}))
+		for a := iter.Next(); a != nil; a = iter.Next() {
+			children = append(children, artistObject(a, id))
+		}
+		return children, nil
+	case albumsID:
+		return c.albumChildren(mp, id, mediaprovider.AlbumFilterOptions{}), nil
+	case genresID:
+		genres, err := mp.GetGenres()
+		if err != nil {
+			return nil, actionFailed(err)
+		}
+		for _, g := range genres {
+			children = append(children, genreObject(g.Name))
+		}
+		return children, nil
+	case playlistsID:
+		playlists, err := mp.GetPlaylists()
+		if err != nil {
+			return nil, actionFailed(err)
+		}
+		for _, p := range playlists {
+			children = append(children, playlistObject(p))
+		}
+		return children, nil
+	case favArtistsID, favAlbumsID, favTracksID:
+		favs, err := mp.GetFavorites()
+		if err != nil {
+			return nil, actionFailed(err)
+		}
+		switch id {
+		case favArtistsID:
+			for _, a := range favs.Artists {
+				children = append(children, artistObject(a, id))
+			}
+		case favAlbumsID:
+			for _, a := range favs.Albums {
+				children = append(children, albumObject(a, id))
+			}
+		case favTracksID:
+			for _, t := range favs.Tracks {
+				children = append(children, trackObject(t, id))
+			}
+		}
+		return children, nil
+	}
+
+	entityID, kind := splitObjectID(id)
+	switch kind {
+	case artistPrefix:
+		a, err := mp.GetArtist(entityID)
+		if err != nil {
+			return nil, noSuchObject(id)
+		}
+		for _, al := range a.Albums {
+			children = append(children, albumObject(al, id))
+		}
+	case albumPrefix:
+		a, err := mp.GetAlbum(entityID)
+		if err != nil {
+			return nil, noSuchObject(id)
+		}
+		for _, t := range a.Tracks {
+			children = append(children, trackObject(t, id))
+		}
+	case genrePrefix:
+		children = c.albumChildren(mp, id, mediaprovider.AlbumFilterOptions{Genres: []string{entityID}})
+	case playlistPrefix:
+		p, err := mp.GetPlaylist(entityID)
+		if err != nil {
+			return nil, noSuchObject(id)
+		}
+		for _, t := range p.Tracks {
+			children = append(children, trackObject(t, id))
+		}
+	default:
+		return nil, noSuchObject(id)
+	}
+	return children, nil
+}
+
+func (c *contentDirectory) albumChildren(mp mediaprovider.MediaProvider, parentID string, opts mediaprovider.AlbumFilterOptions) []object {
+	var children []object
+	iter := mp.IterateAlbums(preferredSort(mp.AlbumSortOrders(), mediaprovider.AlbumSortTitleAZ),
+		mediaprovider.NewAlbumFilter(opts))
+	for a := iter.Next(); a != nil; a = iter.Next() {
+		children = append(children, albumObject(a, parentID))
+	}
+	return children
+}
+
+func preferredSort(sorts []string, preferred string) string {
+	if slices.Contains(sorts, preferred) || len(sorts) == 0 {
+		return preferred
+	}
+	return sorts[0]
+}
+
+func splitObjectID(id string) (entityID, kind string) {
+	for _, prefix := range []string{artistPrefix, albumPrefix, genrePrefix, playlistPrefix, trackPrefix} {
+		if strings.HasPrefix(id, prefix) {
+			return id[len(prefix):], prefix
+		}
+	}
+	return id, ""
+}
+
+func artistObject(a *mediaprovider.Artist, parentID string) object {
+	return object{
+		ID:         artistPrefix + a.ID,
+		ParentID:   parentID,
+		Title:      a.Name,
+		Class:      classArtist,
+		ChildCount: a.AlbumCount,
+		CoverArtID: a.CoverArtID,
+	}
+}
+
+func albumObject(a *mediaprovider.Album, parentID string) object {
+	return object{
+		ID:         albumPrefix + a.ID,
+		ParentID:   parentID,
+		Title:      a.Name,
+		Class:      classAlbum,
+		ChildCount: a.TrackCount,
+		Artist:     strings.Join(a.ArtistNames, ", "),
+		Genre:      firstOrEmpty(a.Genres),
+		CoverArtID: a.CoverArtID,
+	}
+}
+
+func genreObject(name string) object {
+	return object{
+		ID:         genrePrefix + name,
+		ParentID:   genresID,
+		Title:      name,
+		Class:      classGenre,
+		ChildCount: -1,
+	}
+}
+
+func playlistObject(p *mediaprovider.Playlist) object {
+	return object{
+		ID:         playlistPrefix + p.ID,
+		ParentID:   playlistsID,
+		Title:      p.Name,
+		Class:      classPlaylist,
+		ChildCount: p.TrackCount,
+		CoverArtID: p.CoverArtID,
+	}
+}
+
+func trackObject(t *mediaprovider.Track, parentID string) object {
+	return object{
+		ID:         trackPrefix + t.ID,
+		ParentID:   parentID,
+		Title:      t.Title,
+		Class:      classTrack,
+		Artist:     strings.Join(t.ArtistNames, ", "),
+		Genre:      firstOrEmpty(t.Genres),
+		CoverArtID: t.CoverArtID,
+		Track:      t,
+	}
+}
+
+func firstOrEmpty(s []string) string {
+	if len(s) > 0 {
+		return s[0]
+	}
+	return ""
+}
+
+// didl renders the objects as a DIDL-Lite document.
+// baseURL is the address of this server as seen by the client.
+func didl(objects []object, baseURL string) string {
+	var sb strings.Builder
+	sb.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/" xmlns:dlna="urn:schemas-dlna-org:metadata-1-0/">`)
+	for _, o := range objects {
+		if o.isContainer() {
+			fmt.Fprintf(&sb, `<container id="%s" parentID="%s" restricted="1" searchable="0"`, xmlEscape(o.ID), xmlEscape(o.ParentID))
+			if o.ChildCount >= 0 {
+				fmt.Fprintf(&sb, ` childCount="%d"`, o.ChildCount)
+			}
+			sb.WriteString(">")
+		} else {
+			fmt.Fprintf(&sb, `<item id="%s" parentID="%s" restricted="1">`, xmlEscape(o.ID), xmlEscape(o.ParentID))
+		}
+		writeElement(&sb, "dc:title", o.Title)
+		writeElement(&sb, "upnp:class", o.Class)
+		if o.Artist != "" {
+			writeElement(&sb, "upnp:artist", o.Artist)
+			writeElement(&sb, "dc:creator", o.Artist)
+		}
+		writeElement(&sb, "upnp:genre", o.Genre)
+		if o.CoverArtID != "" {
+			fmt.Fprintf(&sb, `<upnp:albumArtURI dlna:profileID="JPEG_TN">%s</upnp:albumArtURI>`,
+				xmlEscape(baseURL+"/art/"+url.PathEscape(o.CoverArtID)))
+		}
+		if o.isContainer() {
+			sb.WriteString("</container>")
+			continue
+		}
+
+		t := o.Track
+		writeElement(&sb, "upnp:album", t.Album)
+		if t.TrackNumber > 0 {
+			fmt.Fprintf(&sb, "<upnp:originalTrackNumber>%d</upnp:originalTrackNumber>", t.TrackNumber)
+		}
+		if t.Year > 0 {
+			fmt.Fprintf(&sb, "<dc:date>%04d-01-01</dc:date>", t.Year)
+		}
+		fmt.Fprintf(&sb, `<res protocolInfo="%s" duration="%s"`, xmlEscape(protocolInfo(trackMIMEType(t))), formatDuration(t.Duration))
+		if t.Size > 0 {
+			fmt.Fprintf(&sb, ` size="%d"`, t.Size)
+		}
+		if t.BitRate > 0 {
+			// bitrate attribute is in bytes/sec
+			fmt.Fprintf(&sb, ` bitrate="%d"`, t.BitRate*1000/8)
+		}
+		if t.SampleRate > 0 {
+			fmt.Fprintf(&sb, ` sampleFrequency="%d"`, t.SampleRate)
+		}
+		if t.Channels > 0 {
+			fmt.Fprintf(&sb, ` nrAudioChannels="%d"`, t.Channels)
+		}
+		fmt.Fprintf(&sb, ">%s</res></item>", xmlEscape(streamURL(baseURL, t)))
+	}
+	sb.WriteString("</DIDL-Lite>")
+	return sb.String()
+}
+
+func writeElement(sb *strings.Builder, name, value string) {
+	if value != "" {
+		fmt.Fprintf(sb, "<%s>%s</%s>", name, xmlEscape(value), name)
+	}
+}
+
+func streamURL(baseURL string, t *mediaprovider.Track) string {
+	// some renderers sniff the format from the file extension,
+	// so end the URL with one; the file name is ignored by the server
+	ext := t.Extension
+	if ext == "" {
+		ext = "mp3"
+	}
+	return fmt.Sprintf("%s/stream/%s/track.%s", baseURL, url.PathEscape(t.ID), ext)
+}
+
+func trackMIMEType(t *mediaprovider.Track) string {
+	if t.ContentType != "" {
+		return t.ContentType
+	}
+	if t.Extension != "" {
+		if m := mime.TypeByExtension("." + t.Extension); m != "" {
+			return m
+		}
+	}
+	return "audio/mpeg"
+}
+
+func protocolInfo(mimeType string) string {
+	features := dlnaFeatures
+	if mimeType == "audio/mpeg" {
+		features = "DLNA.ORG_PN=MP3;" + features
+	}
+	return fmt.Sprintf("http-get:*:%s:%s", mimeType, features)
+}
+
+func formatDuration(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3_600_000, (ms/60_000)%60, (ms/1000)%60, ms%1000)
+}
@@ -0,0 +1,364 @@
+// Package mediaserver implements a UPnP AV MediaServer (ContentDirectory
+// and ConnectionManager services) that exposes the library of the
+// connected server to DLNA renderers and control points on the LAN.
+package mediaserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player/mediaproxy"
+	"github.com/dweymouth/supersonic/backend/util"
+	"github.com/google/uuid"
+	"github.com/koron/go-ssdp"
+)
+
+const (
+	ssdpMaxAge        = 1800
+	ssdpAliveInterval = 5 * time.Minute
+	coverArtSize      = 300
+	subscriptionSecs  = 1800
+	defaultBrowseSize = 5000
+)
+
+var errNoServer = &upnpError{Code: errActionFailed, Desc: "not connected to a server"}
+
+func noSuchObject(id string) error {
+	return &upnpError{Code: errNoSuchObject, Desc: "no such object: " + id}
+}
+
+func actionFailed(err error) error {
+	return &upnpError{Code: errActionFailed, Desc: err.Error()}
+}
+
+type Server struct {
+	friendlyName string
+	udn          string
+	appVersion   string
+	port         int
+
+	cd         *contentDirectory
+	provider   func() mediaprovider.MediaProvider
+	httpServer *http.Server
+
+	advertisersLock sync.Mutex
+	advertisers     []*ssdp.Advertiser
+	stopAlive       context.CancelFunc
+}
+
+// NewServer creates a media server that serves the library of the
+// MediaProvider returned by provider (nil when not connected).
+// deviceUUID should be persisted so that control points recognize
+// the server across restarts. If port is 0, a random port is used.
+func NewServer(friendlyName, deviceUUID, appVersion string, port int, provider func() mediaprovider.MediaProvider) *Server {
+	return &Server{
+		friendlyName: friendlyName,
+		udn:          "uuid:" + deviceUUID,
+		appVersion:   appVersion,
+		port:         port,
+		cd:           newContentDirectory(provider),
+		provider:     provider,
+	}
+}
+
+// NewDeviceUUID returns a new random UUID to identify the server.
+func NewDeviceUUID() string {
+	return uuid.NewString()
+}
+
+// Start starts the HTTP server and advertises the server on the LAN.
+func (s *Server) Start() error {
+	localIP, err := util.GetLocalIP()
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return err
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	s.httpServer = &http.Server{Handler: s.Handler()}
+	go s.httpServer.Serve(listener)
+
+	location := fmt.Sprintf("http://%s:%d%s", localIP, port, descriptionPath)
+	if err := s.advertise(location); err != nil {
+		s.httpServer.Close()
+		return err
+	}
+	log.Printf("UPnP media server listening on %s", location)
+	return nil
+}
+
+// Shutdown stops advertising the server and shuts down the HTTP server.
+func (s *Server) Shutdown(ctx context.Context) {
+	s.advertisersLock.Lock()
+	if s.stopAlive != nil {
+		s.stopAlive()
+	}
+	for _, a := range s.advertisers {
+		a.Bye()
+		a.Close()
+	}
+	s.advertisers = nil
+	s.advertisersLock.Unlock()
+
+	if s.httpServer != nil {
+		s.httpServer.Shutdown(ctx)
+	}
+}
+
+// LibraryChanged should be called when the connected server changes,
+// to notify control points that the content has changed.
+func (s *Server) LibraryChanged() {
+	s.cd.LibraryChanged()
+}
+
+// Handler returns the HTTP handler serving the device and service
+// descriptions, control endpoints, media streams and cover art.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(descriptionPath, func(w http.ResponseWriter, r *http.Request) {
+		writeXML(w, deviceDescription(s.friendlyName, s.udn, s.appVersion))
+	})
+	mux.HandleFunc(contentDirectorySCPDPath, func(w http.ResponseWriter, r *http.Request) {
+		writeXML(w, contentDirectorySCPD)
+	})
+	mux.HandleFunc(connManagerSCPDPath, func(w http.ResponseWriter, r *http.Request) {
+		writeXML(w, connectionManagerSCPD)
+	})
+	mux.HandleFunc(contentDirectoryCtlPath, s.handleControl(contentDirectoryType, s.contentDirectoryAction))
+	mux.HandleFunc(connManagerCtlPath, s.handleControl(connectionManagerType, s.connectionManagerAction))
+	mux.HandleFunc(contentDirectoryEvtPath, s.handleSubscription)
+	mux.HandleFunc(connManagerEvtPath, s.handleSubscription)
+	mux.HandleFunc("/stream/", s.handleStream)
+	mux.HandleFunc("/art/", s.handleCoverArt)
+	return mux
+}
+
+func (s *Server) advertise(location string) error {
+	server := fmt.Sprintf("%s/1.0 UPnP/1.0 Supersonic/%s", runtime.GOOS, s.appVersion)
+	targets := []struct{ st, usn string }{
+		{"upnp:rootdevice", s.udn + "::upnp:rootdevice"},
+		{s.udn, s.udn},
+		{deviceType, s.udn + "::" + deviceType},
+		{contentDirectoryType, s.udn + "::" + contentDirectoryType},
+		{connectionManagerType, s.udn + "::" + connectionManagerType},
+	}
+
+	s.advertisersLock.Lock()
+	defer s.advertisersLock.Unlock()
+	for _, t := range targets {
+		a, err := ssdp.Advertise(t.st, t.usn, location, server, ssdpMaxAge)
+		if err != nil {
+			for _, a := range s.advertisers {
+				a.Close()
+			}
+			s.advertisers = nil
+			return err
+		}
+		a.Alive()
+		s.advertisers = append(s.advertisers, a)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopAlive = cancel
+	go func() {
+		t := time.NewTicker(ssdpAliveInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				s.advertisersLock.Lock()
+				for _, a := range s.advertisers {
+					a.Alive()
+				}
+				s.advertisersLock.Unlock()
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *Server) handleControl(serviceType string, handler func(*http.Request, *soapAction) ([]soapArg, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		action, err := parseSOAPAction(r.Body)
+		if err != nil {
+			writeSOAPFault(w, &upnpError{Code: errInvalidAction, Desc: err.Error()})
+			return
+		}
+		args, err := handler(r, action)
+		if err != nil {
+			var upnpErr *upnpError
+			if !errors.As(err, &upnpErr) {
+				upnpErr = &upnpError{Code: errActionFailed, Desc: err.Error()}
+			}
+			writeSOAPFault(w, upnpErr)
+			return
+		}
+		writeSOAPResponse(w, serviceType, action.Name, args)
+	}
+}
+
+func (s *Server) contentDirectoryAction(r *http.Request, action *soapAction) ([]soapArg, error) {
+	switch action.Name {
+	case "Browse":
+		return s.browse(r, action.Args)
+	case "GetSearchCapabilities":
+		return []soapArg{{"SearchCaps", ""}}, nil
+	case "GetSortCapabilities":
+		return []soapArg{{"SortCaps", ""}}, nil
+	case "GetSystemUpdateID":
+		return []soapArg{{"Id", strconv.FormatUint(uint64(s.cd.UpdateID()), 10)}}, nil
+	}
+	return nil, &upnpError{Code: errInvalidAction, Desc: "invalid action " + action.Name}
+}
+
+func (s *Server) browse(r *http.Request, args map[string]string) ([]soapArg, error) {
+	objectID := args["ObjectID"]
+	start, _ := strconv.Atoi(args["StartingIndex"])
+	count, _ := strconv.Atoi(args["RequestedCount"])
+	if start < 0 || count < 0 {
+		return nil, &upnpError{Code: errInvalidArgs, Desc: "invalid index or count"}
+	}
+	if count == 0 {
+		count = defaultBrowseSize
+	}
+	// use the address the client reached us at in URLs,
+	// in case this machine has several network interfaces
+	baseURL := "http://" + r.Host
+
+	var result []object
+	var total int
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		o, err := s.cd.Metadata(objectID)
+		if err != nil {
+			return nil, err
+		}
+		result, total = []object{*o}, 1
+	case "BrowseDirectChildren":
+		children, err := s.cd.Children(objectID)
+		if err != nil {
+			return nil, err
+		}
+		total = len(children)
+		start = min(start, total)
+		result = children[start:min(start+count, total)]
+	default:
+		return nil, &upnpError{Code: errInvalidArgs, Desc: "invalid BrowseFlag"}
+	}
+
+	return []soapArg{
+		{"Result", didl(result, baseURL)},
+		{"NumberReturned", strconv.Itoa(len(result))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", strconv.FormatUint(uint64(s.cd.UpdateID()), 10)},
+	}, nil
+}
+
+func (s *Server) connectionManagerAction(r *http.Request, action *soapAction) ([]soapArg, error) {
+	switch action.Name {
+	case "GetProtocolInfo":
+		var source []string
+		for _, mimeType := range []string{"audio/mpeg", "audio/flac", "audio/ogg", "audio/mp4", "audio/aac", "audio/wav", "audio/x-ms-wma", "audio/opus"} {
+			source = append(source, protocolInfo(mimeType))
+		}
+		return []soapArg{{"Source", strings.Join(source, ",")}, {"Sink", ""}}, nil
+	case "GetCurrentConnectionIDs":
+		return []soapArg{{"ConnectionIDs", "0"}}, nil
+	case "GetCurrentConnectionInfo":
+		if action.Args["ConnectionID"] != "0" {
+			return nil, &upnpError{Code: 706, Desc: "invalid connection reference"}
+		}
+		return []soapArg{
+			{"RcsID", "-1"},
+			{"AVTransportID", "-1"},
+			{"ProtocolInfo", ""},
+			{"PeerConnectionManager", ""},
+			{"PeerConnectionID", "-1"},
+			{"Direction", "Output"},
+			{"Status", "OK"},
+		}, nil
+	}
+	return nil, &upnpError{Code: errInvalidAction, Desc: "invalid action " + action.Name}
+}
+
+// handleSubscription accepts GENA subscriptions so that control points
+// which require them work, but no events are sent; control points
+// re-check the SystemUpdateID when browsing.
+func (s *Server) handleSubscription(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "SUBSCRIBE":
+		sid := r.Header.Get("SID")
+		if sid == "" {
+			sid = "uuid:" + uuid.NewString()
+		}
+		w.Header().Set("SID", sid)
+		w.Header().Set("TIMEOUT", fmt.Sprintf("Second-%d", subscriptionSecs))
+		w.WriteHeader(http.StatusOK)
+	case "UNSUBSCRIBE":
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	// path is /stream/{trackID}/track.{ext}
+	escapedID, _, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/stream/"), "/")
+	trackID, err := url.PathUnescape(escapedID)
+	mp := s.provider()
+	if err != nil || trackID == "" || mp == nil {
+		http.NotFound(w, r)
+		return
+	}
+	streamURL, err := mp.GetStreamURL(trackID, nil, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	w.Header().Set("contentFeatures.dlna.org", dlnaFeatures)
+	mediaproxy.ServeURL(w, r, streamURL)
+}
+
+func (s *Server) handleCoverArt(w http.ResponseWriter, r *http.Request) {
+	coverID, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/art/"))
+	mp := s.provider()
+	if err != nil || coverID == "" || mp == nil {
+		http.NotFound(w, r)
+		return
+	}
+	img, err := mp.GetCoverArt(coverID, coverArtSize)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("contentFeatures.dlna.org", "DLNA.ORG_PN=JPEG_TN")
+	jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
+
+func writeXML(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Write([]byte(body))
+}
@@ -0,0 +1,206 @@
+package mediaserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// fakeProvider implements the parts of MediaProvider used by the media server.
+type fakeProvider struct {
+	mediaprovider.MediaProvider
+
+	streamServer string
+	albums       []*mediaprovider.Album
+	tracks       map[string][]*mediaprovider.Track
+}
+
+type sliceIterator[M any] struct{ items []*M }
+
+func (s *sliceIterator[M]) Next() *M {
+	if len(s.items) == 0 {
+		return nil
+	}
+	m := s.items[0]
+	s.items = s.items[1:]
+	return m
+}
+
+func (f *fakeProvider) AlbumSortOrders() []string {
+	return []string{mediaprovider.AlbumSortRecentlyAdded, mediaprovider.AlbumSortTitleAZ}
+}
+
+func (f *fakeProvider) IterateAlbums(sort string, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
+	var albums []*mediaprovider.Album
+	for _, a := range f.albums {
+		if filter.Matches(a) {
+			albums = append(albums, a)
+		}
+	}
+	return &sliceIterator[mediaprovider.Album]{items: albums}
+}
+
+func (f *fakeProvider) GetAlbum(id string) (*mediaprovider.AlbumWithTracks, error) {
+	for _, a := range f.albums {
+		if a.ID == id {
+			return &mediaprovider.AlbumWithTracks{Album: *a, Tracks: f.tracks[id]}, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeProvider) GetStreamURL(trackID string, _ *mediaprovider.TranscodeSettings, _ bool) (string, error) {
+	return f.streamServer + "/rest/stream?id=" + trackID, nil
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *fakeProvider) {
+	stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "audio for %s", r.URL.Query().Get("id"))
+	}))
+	t.Cleanup(stream.Close)
+
+	fp := &fakeProvider{streamServer: stream.URL, tracks: make(map[string][]*mediaprovider.Track)}
+	for i := 1; i <= 3; i++ {
+		fp.albums = append(fp.albums, &mediaprovider.Album{
+			ID:          fmt.Sprintf("al-%d", i),
+			Name:        fmt.Sprintf("Album %d", i),
+			ArtistNames: []string{"Artist & Co"},
+			Genres:      []string{"Jazz"},
+			TrackCount:  1,
+		})
+	}
+	fp.tracks["al-1"] = []*mediaprovider.Track{{
+		ID:          "tr/1",
+		Title:       "Track <1>",
+		Album:       "Album 1",
+		ArtistNames: []string{"Artist & Co"},
+		Duration:    3*time.Minute + 25*time.Second,
+		ContentType: "audio/flac",
+		Extension:   "flac",
+		TrackNumber: 1,
+	}}
+
+	s := NewServer("Test Library", "1234", "1.0", 0, func() mediaprovider.MediaProvider { return fp })
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return srv, fp
+}
+
+func soapBrowse(t *testing.T, baseURL, objectID, flag string, start, count int) (int, string) {
+	t.Helper()
+	body := fmt.Sprintf(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<s:Body><u:Browse xmlns:u="%s">
+<ObjectID>%s</ObjectID><BrowseFlag>%s</BrowseFlag><Filter>*</Filter>
+<StartingIndex>%d</StartingIndex><RequestedCount>%d</RequestedCount><SortCriteria></SortCriteria>
+</u:Browse></s:Body></s:Envelope>`, contentDirectoryType, xmlEscape(objectID), flag, start, count)
+	req, _ := http.NewRequest(http.MethodPost, baseURL+contentDirectoryCtlPath, strings.NewReader(body))
+	req.Header.Set("SOAPACTION", `"`+contentDirectoryType+`#Browse"`)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+// browseResult returns the unescaped DIDL-Lite result and the
+// NumberReturned and TotalMatches arguments of a Browse response.
+func browseResult(t *testing.T, resp string) (string, string, string) {
+	t.Helper()
+	action, err := parseSOAPAction(strings.NewReader(resp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return action.Args["Result"], action.Args["NumberReturned"], action.Args["TotalMatches"]
+}
+
+func TestBrowse(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	status, resp := soapBrowse(t, srv.URL, rootID, "BrowseDirectChildren", 0, 0)
+	if status != http.StatusOK {
+		t.Fatalf("root browse failed: %s", resp)
+	}
+	result, returned, total := browseResult(t, resp)
+	if returned != "5" || total != "5" || !strings.Contains(result, `<container id="albums" parentID="0"`) {
+		t.Errorf("unexpected root listing (%s/%s): %s", returned, total, result)
+	}
+
+	// paging through the albums container
+	_, resp = soapBrowse(t, srv.URL, albumsID, "BrowseDirectChildren", 1, 1)
+	result, returned, total = browseResult(t, resp)
+	if returned != "1" || total != "3" || !strings.Contains(result, `id="album:al-2"`) ||
+		!strings.Contains(result, "<upnp:artist>Artist &amp; Co</upnp:artist>") {
+		t.Errorf("unexpected album page (%s/%s): %s", returned, total, result)
+	}
+
+	_, resp = soapBrowse(t, srv.URL, genrePrefix+"Jazz", "BrowseDirectChildren", 0, 0)
+	if _, _, total = browseResult(t, resp); total != "3" {
+		t.Errorf("expected 3 albums in genre, got %s", total)
+	}
+
+	_, resp = soapBrowse(t, srv.URL, albumPrefix+"al-1", "BrowseDirectChildren", 0, 10)
+	result, _, _ = browseResult(t, resp)
+	for _, want := range []string{
+		`<item id="track:tr/1" parentID="album:al-1" restricted="1">`,
+		"<dc:title>Track &lt;1&gt;</dc:title>",
+		`protocolInfo="http-get:*:audio/flac:DLNA.ORG_OP=01;`,
+		`duration="0:03:25.000"`,
+		srv.URL + "/stream/tr%2F1/track.flac</res>",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("track listing missing %q: %s", want, result)
+		}
+	}
+
+	status, resp = soapBrowse(t, srv.URL, albumPrefix+"nonexistent", "BrowseMetadata", 0, 0)
+	if status != http.StatusInternalServerError || !strings.Contains(resp, "<errorCode>701</errorCode>") {
+		t.Errorf("expected no such object fault, got %d: %s", status, resp)
+	}
+}
+
+func TestStreamProxy(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/stream/tr%2F1/track.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if string(b) != "audio for tr/1" {
+		t.Errorf("unexpected stream response %q", b)
+	}
+	if resp.Header.Get("transferMode.dlna.org") != "Streaming" {
+		t.Error("missing DLNA transfer mode header")
+	}
+}
+
+func TestDeviceDescription(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + descriptionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{
+		"<friendlyName>Test Library</friendlyName>",
+		"<UDN>uuid:1234</UDN>",
+		"<controlURL>" + contentDirectoryCtlPath + "</controlURL>",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("device description missing %q", want)
+		}
+	}
+}
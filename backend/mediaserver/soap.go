@@ -0,0 +1,102 @@
+package mediaserver
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// UPnP error codes
+const (
+	errInvalidAction = 401
+	errInvalidArgs   = 402
+	errActionFailed  = 501
+	errNoSuchObject  = 701
+)
+
+type upnpError struct {
+	Code int
+	Desc string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("UPnP error %d: %s", e.Code, e.Desc)
+}
+
+// soapArg is an output argument of an action, written in order.
+type soapArg struct {
+	Name  string
+	Value string
+}
+
+// soapAction is a SOAP request to a service's control URL.
+type soapAction struct {
+	Name string
+	Args map[string]string
+}
+
+// parseSOAPAction decodes the action and its arguments from a SOAP envelope.
+func parseSOAPAction(r io.Reader) (*soapAction, error) {
+	dec := xml.NewDecoder(r)
+	var action *soapAction
+	var curArg string
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			// Envelope (1) > Body (2) > action (3) > arguments (4)
+			switch depth {
+			case 3:
+				action = &soapAction{Name: t.Name.Local, Args: make(map[string]string)}
+			case 4:
+				curArg = t.Name.Local
+				action.Args[curArg] = ""
+			}
+		case xml.CharData:
+			if depth == 4 {
+				action.Args[curArg] += string(t)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if action == nil {
+		return nil, fmt.Errorf("no action in SOAP request")
+	}
+	return action, nil
+}
+
+func writeSOAPResponse(w http.ResponseWriter, serviceType, action string, args []soapArg) {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	sb.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&sb, `<u:%sResponse xmlns:u="%s">`, action, serviceType)
+	for _, a := range args {
+		fmt.Fprintf(&sb, "<%s>%s</%s>", a.Name, xmlEscape(a.Value), a.Name)
+	}
+	fmt.Fprintf(&sb, `</u:%sResponse>`, action)
+	sb.WriteString(`</s:Body></s:Envelope>`)
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("EXT", "")
+	w.Write([]byte(sb.String()))
+}
+
+func writeSOAPFault(w http.ResponseWriter, err *upnpError) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`+
+		`<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+		`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError>`+
+		`</detail></s:Fault></s:Body></s:Envelope>`, err.Code, xmlEscape(err.Desc))
+}
@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/mpd"
+	"github.com/google/uuid"
+	"github.com/zalando/go-keyring"
+)
+
+const mpdURLPrefix = "mpd://"
+
+// MPDServers returns the configured MPD servers.
+func (p *PlaybackManager) MPDServers() []MPDServerConfig {
+	return p.cfg.MPDServers
+}
+
+// IsMPDServer returns true if the remote device is an MPD server.
+func (rp *RemotePlaybackDevice) IsMPDServer() bool {
+	return rp.Protocol == "MPD"
+}
+
+// AddMPDServer adds an MPD server to offer as a remote player,
+// storing its password, if any, in the keyring.
+// Like other config edits, it must be called on the UI thread.
+func (p *PlaybackManager) AddMPDServer(s MPDServerConfig, password string) error {
+	if s.Name == "" || s.Address == "" {
+		return errors.New("MPD server name and address are required")
+	}
+	if slices.ContainsFunc(p.cfg.MPDServers, func(c MPDServerConfig) bool { return c.Name == s.Name }) {
+		return fmt.Errorf("an MPD server named %s already exists", s.Name)
+	}
+	s.ID = uuid.New()
+	s.HasPassword = password != ""
+	s.Password = ""
+	if s.HasPassword {
+		if err := p.engine.sm.setPassword(s.ID, password); err != nil {
+			return fmt.Errorf("error saving MPD password to keyring: %w", err)
+		}
+	}
+	p.cfg.MPDServers = append(p.cfg.MPDServers, s)
+	return nil
+}
+
+// RemoveMPDServer removes the named MPD server, switching back to
+// local playback in the background if it is currently playing.
+// Like other config edits, it must be called on the UI thread.
+func (p *PlaybackManager) RemoveMPDServer(name string) {
+	idx := slices.IndexFunc(p.cfg.MPDServers, func(c MPDServerConfig) bool { return c.Name == name })
+	if idx < 0 {
+		return
+	}
+	s := p.cfg.MPDServers[idx]
+	p.cfg.MPDServers = slices.Delete(p.cfg.MPDServers, idx, idx+1)
+	if s.HasPassword && p.engine.sm.useKeyring {
+		keyring.Delete(p.engine.sm.appName, s.ID.String())
+	}
+	if rp := p.currentRemotePlayer; rp != nil && rp.URL == mpdURLPrefix+s.Address {
+		go p.setRemotePlayerAsync(nil)
+	}
+}
+
+// migrateMPDPasswords moves the passwords of MPD servers
+// added by older versions from the config to the keyring.
+func (p *PlaybackManager) migrateMPDPasswords() {
+	for i := range p.cfg.MPDServers {
+		s := &p.cfg.MPDServers[i]
+		if s.ID == uuid.Nil {
+			s.ID = uuid.New()
+		}
+		if s.Password == "" {
+			continue
+		}
+		if err := p.engine.sm.setPassword(s.ID, s.Password); err != nil {
+			log.Printf("error moving MPD password to keyring: %v", err)
+			continue
+		}
+		s.HasPassword = true
+		s.Password = ""
+	}
+}
+
+func (p *PlaybackManager) mpdServerDevice(s MPDServerConfig) RemotePlaybackDevice {
+	return RemotePlaybackDevice{
+		Name:     s.Name,
+		URL:      mpdURLPrefix + s.Address,
+		Protocol: "MPD",
+		new: func() (player.BasePlayer, error) {
+			password := s.Password // not yet moved to the keyring
+			if s.HasPassword {
+				var err error
+				if password, err = p.engine.sm.GetServerPassword(s.ID); err != nil {
+					return nil, fmt.Errorf("error reading MPD password from keyring: %w", err)
+				}
+			}
+			return mpd.NewMPDPlayer(s.Address, password)
+		},
+	}
+}
@@ -3,7 +3,9 @@ package backend
 import (
 	"encoding/base32"
 	"errors"
+	"net/url"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/backend/player"
@@ -43,6 +45,10 @@ type MPRISHandler struct {
 	pm           *PlaybackManager
 	s            *server.Server
 	evt          *events.EventHandler
+	props        map[string]map[string]mprisProperty // by interface and name
+	trackList    []dbus.ObjectPath                   // as of the last queue change
+
+	activePlaylist atomic.Pointer[activePlaylist]
 
 	// current radio metadata
 	radioStationName string
@@ -65,7 +71,7 @@ func NewMPRISHandler(playerName string, pm *PlaybackManager) *MPRISHandler {
 		if tr == nil {
 			m.curTrackPath = ""
 		} else {
-			m.curTrackPath = dbusTrackIDPrefix + encodeTrackId(tr.Metadata().ID)
+			m.curTrackPath = m.nowPlayingTrackPath(trackObjectPaths(m.pm.GetActivePlayQueue()))
 		}
 		if m.connErr == nil {
 			m.evt.Player.OnTitle()
@@ -97,6 +103,7 @@ func NewMPRISHandler(playerName string, pm *PlaybackManager) *MPRISHandler {
 	m.pm.OnStopped(emitPlayStatus)
 	m.pm.OnPlaying(emitPlayStatus)
 	m.pm.OnPaused(emitPlayStatus)
+	m.pm.OnQueueChange(m.onQueueChange)
 
 	return m
 }
@@ -106,7 +113,7 @@ func (m *MPRISHandler) Start() {
 	m.connErr = nil
 	go func() {
 		// exits early with err if unable to establish D-Bus connection
-		m.connErr = m.s.Listen()
+		m.connErr = m.listen()
 	}()
 }
 
@@ -147,15 +154,15 @@ func (m *MPRISHandler) Raise() error {
 }
 
 func (m *MPRISHandler) HasTrackList() (bool, error) {
-	return false, nil
+	return true, nil
 }
 
 func (m *MPRISHandler) SupportedUriSchemes() ([]string, error) {
-	return nil, nil
+	return []string{URIScheme, "http", "https"}, nil
 }
 
 func (m *MPRISHandler) SupportedMimeTypes() ([]string, error) {
-	return nil, nil
+	return []string{"audio/mpeg", "audio/aac", "audio/ogg", "audio/flac", "application/ogg"}, nil
 }
 
 // OrgMprisMediaPlayer2PlayerAdapter implementation
@@ -210,8 +217,35 @@ func (m *MPRISHandler) SetPosition(trackId string, position types.Microseconds)
 	return nil
 }
 
+// OpenUri plays the library item linked by a supersonic:// URI,
+// shuffled if the URI has the shuffle option, or an http(s) stream URL
+// as an internet radio station. As required by MPRIS, the item is
+// played whether or not the URI has the play option.
 func (m *MPRISHandler) OpenUri(uri string) error {
-	return errNotSupported
+	if u, err := url.Parse(uri); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		m.pm.PlayRadioStation(&mediaprovider.RadioStation{ID: uri, Name: u.Host, StreamURL: uri})
+		return nil
+	}
+	s, err := ParseSupersonicURI(uri)
+	if err != nil {
+		return err
+	}
+	if m.pm.engine.sm.Server == nil {
+		return errNotConnected
+	}
+	switch s.Kind {
+	case URIKindAlbum:
+		return m.pm.PlayAlbum(s.ID, 0, s.Shuffle)
+	case URIKindPlaylist:
+		return m.pm.PlayPlaylist(s.ID, 0, s.Shuffle)
+	case URIKindTrack:
+		return m.pm.PlayTrack(s.ID)
+	case URIKindArtist:
+		m.pm.PlayArtistDiscography(s.ID, s.Shuffle)
+	default:
+		return errors.New(s.Kind + " URIs can't be played")
+	}
+	return nil
 }
 
 func (m *MPRISHandler) PlaybackStatus() (types.PlaybackStatus, error) {
@@ -267,56 +301,49 @@ func (m *MPRISHandler) Metadata() (types.Metadata, error) {
 	}
 	status := m.pm.PlaybackStatus()
 
-	var meta mediaprovider.MediaItemMetadata
-	// metadata that can come only from tracks
-	var discNumber, trackNumber, userRating, playCount, year int
-	var genres []string
-
+	var mprisMeta types.Metadata
 	if np := m.pm.NowPlaying(); np != nil && status.State != player.Stopped {
-		meta = np.Metadata()
-		if track, ok := np.(*mediaprovider.Track); ok {
-			discNumber = track.DiscNumber
-			trackNumber = track.TrackNumber
-			userRating = track.Rating
-			playCount = track.PlayCount
-			year = track.Year
-			genres = track.Genres
-		}
-	}
-	var artURL string
-	if meta.ID != "" && m.ArtURLLookup != nil {
-		if u, err := m.ArtURLLookup(meta.CoverArtID); err == nil {
-			artURL = u
+		mprisMeta = m.itemMetadata(np, dbus.ObjectPath(trackObjPath), true /*lookupArt*/)
+		// if playing a radio station, override title/artist with current Icy metadata if present
+		if m.radioStationName == mprisMeta.Title && m.radioIcyTitle != "" {
+			mprisMeta.Title = m.radioIcyTitle
+			mprisMeta.Artist = []string{m.radioIcyArtist}
+			mprisMeta.Album = m.radioStationName
 		}
 	}
+	mprisMeta.TrackId = dbus.ObjectPath(trackObjPath)
+	mprisMeta.Length = secondsToMicroseconds(status.Duration)
+	return mprisMeta, nil
+}
 
-	title := meta.Name
-	artists := meta.Artists
-	album := meta.Album
-	// if playing a radio station, override title/artist with current Icy metadata if present
-	if m.radioStationName == title && m.radioIcyTitle != "" {
-		title = m.radioIcyTitle
-		artists = []string{m.radioIcyArtist}
-		album = m.radioStationName
-	}
-
+// itemMetadata returns the MPRIS metadata of a media item with the given track ID,
+// looking up the URL of its cover art if lookupArt is true.
+func (m *MPRISHandler) itemMetadata(item mediaprovider.MediaItem, trackID dbus.ObjectPath, lookupArt bool) types.Metadata {
+	meta := item.Metadata()
 	mprisMeta := types.Metadata{
-		TrackId:     dbus.ObjectPath(trackObjPath),
-		Length:      secondsToMicroseconds(status.Duration),
-		Title:       title,
-		Album:       album,
-		Artist:      artists,
-		DiscNumber:  discNumber,
-		TrackNumber: trackNumber,
-		UserRating:  float64(userRating) / 5,
-		UseCount:    playCount,
-		ArtUrl:      artURL,
-		Genre:       genres,
+		TrackId: trackID,
+		Length:  secondsToMicroseconds(meta.Duration.Seconds()),
+		Title:   meta.Name,
+		Album:   meta.Album,
+		Artist:  meta.Artists,
 	}
-	if year != 0 {
-		mprisMeta.ContentCreated = strconv.Itoa(year)
+	// metadata that can come only from tracks
+	if track, ok := item.(*mediaprovider.Track); ok {
+		mprisMeta.DiscNumber = track.DiscNumber
+		mprisMeta.TrackNumber = track.TrackNumber
+		mprisMeta.UserRating = float64(track.Rating) / 5
+		mprisMeta.UseCount = track.PlayCount
+		mprisMeta.Genre = track.Genres
+		if track.Year != 0 {
+			mprisMeta.ContentCreated = strconv.Itoa(track.Year)
+		}
 	}
-	return mprisMeta, nil
+	if lookupArt && meta.ID != "" && m.ArtURLLookup != nil {
+		if u, err := m.ArtURLLookup(meta.CoverArtID); err == nil {
+			mprisMeta.ArtUrl = u
+		}
+	}
+	return mprisMeta
 }
 
 func (m *MPRISHandler) Volume() (float64, error) {
@@ -0,0 +1,339 @@
+package backend
+
+import (
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/quarckster/go-mpris-server/pkg/types"
+)
+
+const (
+	mprisObjectPath     = "/org/mpris/MediaPlayer2"
+	mprisRootIface      = "org.mpris.MediaPlayer2"
+	mprisPlayerIface    = "org.mpris.MediaPlayer2.Player"
+	mprisTrackListIface = "org.mpris.MediaPlayer2.TrackList"
+	mprisPlaylistsIface = "org.mpris.MediaPlayer2.Playlists"
+	dbusPropertiesIface = "org.freedesktop.DBus.Properties"
+	dbusIntrospectIface = "org.freedesktop.DBus.Introspectable"
+)
+
+// mprisProperty is a D-Bus property of one of the exported MPRIS interfaces.
+type mprisProperty struct {
+	get func() (any, error)
+	set func(dbus.Variant) error // nil for read-only properties
+}
+
+func readOnly[T any](get func() (T, error)) mprisProperty {
+	return mprisProperty{get: func() (any, error) { return get() }}
+}
+
+// listen claims the MPRIS bus name and exports the MPRIS interfaces.
+// It is used instead of the go-mpris-server Listen, which only exports
+// the root and Player interfaces, and sets the server's Conn so that
+// the library's event handlers and Stop keep working.
+func (m *MPRISHandler) listen() error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	name := "org.mpris.MediaPlayer2." + m.playerName
+	reply, err := conn.RequestName(name, dbus.NameFlagReplaceExisting)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		return errors.New("unable to claim " + name)
+	}
+	m.s.Conn = conn
+	if err := m.export(conn); err != nil {
+		conn.ReleaseName(name)
+		return err
+	}
+	return nil
+}
+
+func (m *MPRISHandler) export(conn *dbus.Conn) error {
+	m.props = map[string]map[string]mprisProperty{
+		mprisRootIface: {
+			"CanQuit":             readOnly(m.CanQuit),
+			"CanRaise":            readOnly(m.CanRaise),
+			"HasTrackList":        readOnly(m.HasTrackList),
+			"Identity":            readOnly(m.Identity),
+			"SupportedUriSchemes": readOnly(m.SupportedUriSchemes),
+			"SupportedMimeTypes":  readOnly(m.SupportedMimeTypes),
+		},
+		mprisPlayerIface: {
+			"PlaybackStatus": readOnly(m.PlaybackStatus),
+			"LoopStatus": {
+				get: func() (any, error) { return m.LoopStatus() },
+				set: func(v dbus.Variant) error {
+					s, ok := v.Value().(string)
+					if !ok {
+						return errors.New("LoopStatus must be a string")
+					}
+					return m.SetLoopStatus(types.LoopStatus(s))
+				},
+			},
+			"Rate": {
+				get: func() (any, error) { return m.Rate() },
+				set: func(dbus.Variant) error { return errNotSupported },
+			},
+			"Metadata": readOnly(func() (map[string]dbus.Variant, error) {
+				meta, err := m.Metadata()
+				return meta.MakeMap(), err
+			}),
+			"Volume": {
+				get: func() (any, error) { return m.Volume() },
+				set: func(v dbus.Variant) error {
+					vol, ok := v.Value().(float64)
+					if !ok {
+						return errors.New("Volume must be a double")
+					}
+					return m.SetVolume(vol)
+				},
+			},
+			"Position":      readOnly(m.Position),
+			"MinimumRate":   readOnly(m.MinimumRate),
+			"MaximumRate":   readOnly(m.MaximumRate),
+			"CanGoNext":     readOnly(m.CanGoNext),
+			"CanGoPrevious": readOnly(m.CanGoPrevious),
+			"CanPlay":       readOnly(m.CanPlay),
+			"CanPause":      readOnly(m.CanPause),
+			"CanSeek":       readOnly(m.CanSeek),
+			"CanControl":    readOnly(m.CanControl),
+		},
+		mprisTrackListIface: {
+			"Tracks":        readOnly(m.Tracks),
+			"CanEditTracks": readOnly(m.CanEditTracks),
+		},
+		mprisPlaylistsIface: {
+			"PlaylistCount":  readOnly(m.PlaylistCount),
+			"Orderings":      readOnly(m.Orderings),
+			"ActivePlaylist": readOnly(m.ActivePlaylist),
+		},
+	}
+
+	exports := []struct {
+		iface   string
+		methods map[string]any
+	}{
+		{dbusIntrospectIface, map[string]any{
+			"Introspect": introspect.Introspectable(mprisIntrospectXML).Introspect,
+		}},
+		{dbusPropertiesIface, map[string]any{
+			"Get":    m.getProperty,
+			"GetAll": m.getAllProperties,
+			"Set":    m.setProperty,
+		}},
+		{mprisRootIface, map[string]any{
+			"Raise": func() *dbus.Error { return dbusError(m.Raise()) },
+			"Quit":  func() *dbus.Error { return dbusError(m.Quit()) },
+		}},
+		{mprisPlayerIface, map[string]any{
+			"Next":      func() *dbus.Error { return dbusError(m.Next()) },
+			"Previous":  func() *dbus.Error { return dbusError(m.Previous()) },
+			"Pause":     func() *dbus.Error { return dbusError(m.Pause()) },
+			"PlayPause": func() *dbus.Error { return dbusError(m.PlayPause()) },
+			"Stop":      func() *dbus.Error { return dbusError(m.Stop()) },
+			"Play":      func() *dbus.Error { return dbusError(m.Play()) },
+			"Seek": func(offset int64) *dbus.Error {
+				return dbusError(m.Seek(types.Microseconds(offset)))
+			},
+			"SetPosition": func(trackID dbus.ObjectPath, position int64) *dbus.Error {
+				return dbusError(m.SetPosition(string(trackID), types.Microseconds(position)))
+			},
+			"OpenUri": func(uri string) *dbus.Error { return dbusError(m.OpenUri(uri)) },
+		}},
+		{mprisTrackListIface, map[string]any{
+			"GetTracksMetadata": func(trackIDs []dbus.ObjectPath) ([]map[string]dbus.Variant, *dbus.Error) {
+				meta, err := m.GetTracksMetadata(trackIDs)
+				return meta, dbusError(err)
+			},
+			"AddTrack": func(uri string, afterTrack dbus.ObjectPath, setAsCurrent bool) *dbus.Error {
+				return dbusError(m.AddTrack(uri, afterTrack, setAsCurrent))
+			},
+			"RemoveTrack": func(trackID dbus.ObjectPath) *dbus.Error { return dbusError(m.RemoveTrack(trackID)) },
+			"GoTo":        func(trackID dbus.ObjectPath) *dbus.Error { return dbusError(m.GoTo(trackID)) },
+		}},
+		{mprisPlaylistsIface, map[string]any{
+			"ActivatePlaylist": func(playlistID dbus.ObjectPath) *dbus.Error {
+				return dbusError(m.ActivatePlaylist(playlistID))
+			},
+			"GetPlaylists": func(index, maxCount uint32, order string, reverseOrder bool) ([]mprisPlaylist, *dbus.Error) {
+				playlists, err := m.GetPlaylists(index, maxCount, order, reverseOrder)
+				return playlists, dbusError(err)
+			},
+		}},
+	}
+	for _, e := range exports {
+		if err := conn.ExportMethodTable(e.methods, mprisObjectPath, e.iface); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MPRISHandler) lookupProperty(iface, name string) (mprisProperty, *dbus.Error) {
+	props, ok := m.props[iface]
+	if !ok {
+		return mprisProperty{}, prop.ErrIfaceNotFound
+	}
+	p, ok := props[name]
+	if !ok {
+		return mprisProperty{}, prop.ErrPropNotFound
+	}
+	return p, nil
+}
+
+func (m *MPRISHandler) getProperty(iface, name string) (dbus.Variant, *dbus.Error) {
+	p, dErr := m.lookupProperty(iface, name)
+	if dErr != nil {
+		return dbus.Variant{}, dErr
+	}
+	v, err := p.get()
+	if err != nil {
+		return dbus.Variant{}, dbus.MakeFailedError(err)
+	}
+	return dbus.MakeVariant(v), nil
+}
+
+func (m *MPRISHandler) getAllProperties(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	props, ok := m.props[iface]
+	if !ok {
+		return nil, prop.ErrIfaceNotFound
+	}
+	all := make(map[string]dbus.Variant, len(props))
+	for name, p := range props {
+		v, err := p.get()
+		if err != nil {
+			return nil, dbus.MakeFailedError(err)
+		}
+		all[name] = dbus.MakeVariant(v)
+	}
+	return all, nil
+}
+
+func (m *MPRISHandler) setProperty(iface, name string, value dbus.Variant) *dbus.Error {
+	p, dErr := m.lookupProperty(iface, name)
+	if dErr != nil {
+		return dErr
+	}
+	if p.set == nil {
+		return prop.ErrReadOnly
+	}
+	if err := p.set(value); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// emitSignal emits a signal of one of the MPRIS interfaces, if connected.
+func (m *MPRISHandler) emitSignal(iface, name string, values ...any) {
+	if m.connErr == nil && m.s.Conn != nil {
+		m.s.Conn.Emit(mprisObjectPath, iface+"."+name, values...)
+	}
+}
+
+func dbusError(err error) *dbus.Error {
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+const mprisIntrospectXML = introspect.IntrospectDeclarationString + `<node name="/org/mpris/MediaPlayer2">
+  <interface name="org.mpris.MediaPlayer2">
+    <method name="Raise"/>
+    <method name="Quit"/>
+    <property name="CanQuit" type="b" access="read"/>
+    <property name="CanRaise" type="b" access="read"/>
+    <property name="HasTrackList" type="b" access="read"/>
+    <property name="Identity" type="s" access="read"/>
+    <property name="SupportedUriSchemes" type="as" access="read"/>
+    <property name="SupportedMimeTypes" type="as" access="read"/>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2.Player">
+    <method name="Next"/>
+    <method name="Previous"/>
+    <method name="Pause"/>
+    <method name="PlayPause"/>
+    <method name="Stop"/>
+    <method name="Play"/>
+    <method name="Seek">
+      <arg direction="in" type="x" name="Offset"/>
+    </method>
+    <method name="SetPosition">
+      <arg direction="in" type="o" name="TrackId"/>
+      <arg direction="in" type="x" name="Position"/>
+    </method>
+    <method name="OpenUri">
+      <arg direction="in" type="s" name="Uri"/>
+    </method>
+    <property name="PlaybackStatus" type="s" access="read"/>
+    <property name="LoopStatus" type="s" access="readwrite"/>
+    <property name="Rate" type="d" access="readwrite"/>
+    <property name="Metadata" type="a{sv}" access="read"/>
+    <property name="Volume" type="d" access="readwrite"/>
+    <property name="Position" type="x" access="read">
+      <annotation name="org.freedesktop.DBus.Property.EmitsChangedSignal" value="false"/>
+    </property>
+    <property name="MinimumRate" type="d" access="read"/>
+    <property name="MaximumRate" type="d" access="read"/>
+    <property name="CanGoNext" type="b" access="read"/>
+    <property name="CanGoPrevious" type="b" access="read"/>
+    <property name="CanPlay" type="b" access="read"/>
+    <property name="CanPause" type="b" access="read"/>
+    <property name="CanSeek" type="b" access="read"/>
+    <property name="CanControl" type="b" access="read">
+      <annotation name="org.freedesktop.DBus.Property.EmitsChangedSignal" value="false"/>
+    </property>
+    <signal name="Seeked">
+      <arg name="Position" type="x"/>
+    </signal>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2.TrackList">
+    <method name="GetTracksMetadata">
+      <arg direction="in" type="ao" name="TrackIds"/>
+      <arg direction="out" type="aa{sv}" name="Metadata"/>
+    </method>
+    <method name="AddTrack">
+      <arg direction="in" type="s" name="Uri"/>
+      <arg direction="in" type="o" name="AfterTrack"/>
+      <arg direction="in" type="b" name="SetAsCurrent"/>
+    </method>
+    <method name="RemoveTrack">
+      <arg direction="in" type="o" name="TrackId"/>
+    </method>
+    <method name="GoTo">
+      <arg direction="in" type="o" name="TrackId"/>
+    </method>
+    <property name="Tracks" type="ao" access="read">
+      <annotation name="org.freedesktop.DBus.Property.EmitsChangedSignal" value="invalidates"/>
+    </property>
+    <property name="CanEditTracks" type="b" access="read"/>
+    <signal name="TrackListReplaced">
+      <arg type="ao" name="Tracks"/>
+      <arg type="o" name="CurrentTrack"/>
+    </signal>
+    <signal name="TrackAdded">
+      <arg type="a{sv}" name="Metadata"/>
+      <arg type="o" name="AfterTrack"/>
+    </signal>
+    <signal name="TrackRemoved">
+      <arg type="o" name="TrackId"/>
+    </signal>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2.Playlists">
+    <method name="ActivatePlaylist">
+      <arg direction="in" type="o" name="PlaylistId"/>
+    </method>
+    <method name="GetPlaylists">
+      <arg direction="in" type="u" name="Index"/>
+      <arg direction="in" type="u" name="MaxCount"/>
+      <arg direction="in" type="s" name="Order"/>
+      <arg direction="in" type="b" name="ReverseOrder"/>
+      <arg direction="out" type="a(oss)" name="Playlists"/>
+    </method>
+    <property name="PlaylistCount" type="u" access="read"/>
+    <property name="Orderings" type="as" access="read"/>
+    <property name="ActivePlaylist" type="(b(oss))" access="read"/>
+  </interface>` + introspect.IntrospectDataString + prop.IntrospectDataString + `</node>`
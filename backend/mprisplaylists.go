@@ -0,0 +1,164 @@
+package backend
+
+import (
+	"encoding/base32"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+	"github.com/godbus/dbus/v5"
+)
+
+const dbusPlaylistIDPrefix = "/Supersonic/Playlist/"
+
+// playlist orderings supported by GetPlaylists
+const (
+	playlistOrderAlphabetical = "Alphabetical"
+	playlistOrderUserDefined  = "UserDefined" // the order returned by the server
+)
+
+var (
+	errNotConnected    = errors.New("not connected to a server")
+	errUnknownPlaylist = errors.New("unknown playlist ID")
+)
+
+// mprisPlaylist is the (oss) Playlist struct of the MPRIS Playlists interface.
+type mprisPlaylist struct {
+	ID   dbus.ObjectPath
+	Name string
+	Icon string
+}
+
+// mprisMaybePlaylist is the (b(oss)) type of the ActivePlaylist property.
+type mprisMaybePlaylist struct {
+	Valid    bool
+	Playlist mprisPlaylist
+}
+
+// activePlaylist is the playlist last activated through MPRIS,
+// and the track list it was loaded into the play queue as.
+type activePlaylist struct {
+	playlist mprisPlaylist
+	tracks   []dbus.ObjectPath
+}
+
+// OrgMprisMediaPlayer2Playlists implementation
+
+func (m *MPRISHandler) PlaylistCount() (uint32, error) {
+	playlists, err := m.getServerPlaylists()
+	return uint32(len(playlists)), err
+}
+
+func (m *MPRISHandler) Orderings() ([]string, error) {
+	return []string{playlistOrderAlphabetical, playlistOrderUserDefined}, nil
+}
+
+// ActivePlaylist returns the playlist last activated through MPRIS,
+// as long as the play queue has not been changed since.
+func (m *MPRISHandler) ActivePlaylist() (mprisMaybePlaylist, error) {
+	if ap := m.activePlaylist.Load(); ap != nil {
+		return mprisMaybePlaylist{Valid: true, Playlist: ap.playlist}, nil
+	}
+	return mprisMaybePlaylist{Playlist: mprisPlaylist{ID: "/"}}, nil
+}
+
+func (m *MPRISHandler) ActivatePlaylist(playlistID dbus.ObjectPath) error {
+	server := m.pm.engine.sm.Server
+	if server == nil {
+		return errNotConnected
+	}
+	id, ok := playlistIDFromObjectPath(playlistID)
+	if !ok {
+		return errUnknownPlaylist
+	}
+	playlist, err := server.GetPlaylist(id)
+	if err != nil {
+		return err
+	}
+	// stored before loading, since the queue change is handled asynchronously
+	ap := &activePlaylist{
+		playlist: toMPRISPlaylist(&playlist.Playlist),
+		tracks:   sortedTrackObjectPaths(sharedutil.CopyTrackSliceToMediaItemSlice(playlist.Tracks)),
+	}
+	m.activePlaylist.Store(ap)
+	if err := m.pm.PlayPlaylist(id, 0, false); err != nil {
+		m.activePlaylist.CompareAndSwap(ap, nil)
+		return err
+	}
+	m.emitActivePlaylistChanged()
+	return nil
+}
+
+func (m *MPRISHandler) GetPlaylists(index, maxCount uint32, order string, reverseOrder bool) ([]mprisPlaylist, error) {
+	playlists, err := m.getServerPlaylists()
+	if err != nil {
+		return nil, err
+	}
+	playlists = slices.Clone(playlists) // don't reorder the server's cached slice
+	if order == playlistOrderAlphabetical {
+		slices.SortStableFunc(playlists, func(a, b *mediaprovider.Playlist) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+	}
+	if reverseOrder {
+		slices.Reverse(playlists)
+	}
+	start := min(int(index), len(playlists))
+	end := min(start+int(maxCount), len(playlists))
+	result := make([]mprisPlaylist, 0, end-start)
+	for _, p := range playlists[start:end] {
+		result = append(result, toMPRISPlaylist(p))
+	}
+	return result, nil
+}
+
+// clearActivePlaylistIfChanged invalidates the ActivePlaylist once the
+// play queue no longer holds its tracks, in any order since it may be shuffled.
+func (m *MPRISHandler) clearActivePlaylistIfChanged(queue []mediaprovider.MediaItem) {
+	ap := m.activePlaylist.Load()
+	if ap == nil || slices.Equal(ap.tracks, sortedTrackObjectPaths(queue)) {
+		return
+	}
+	if m.activePlaylist.CompareAndSwap(ap, nil) {
+		m.emitActivePlaylistChanged()
+	}
+}
+
+func (m *MPRISHandler) emitActivePlaylistChanged() {
+	active, _ := m.ActivePlaylist()
+	m.emitSignal(dbusPropertiesIface, "PropertiesChanged", mprisPlaylistsIface,
+		map[string]dbus.Variant{"ActivePlaylist": dbus.MakeVariant(active)}, []string{})
+}
+
+func (m *MPRISHandler) getServerPlaylists() ([]*mediaprovider.Playlist, error) {
+	server := m.pm.engine.sm.Server
+	if server == nil {
+		return nil, errNotConnected
+	}
+	return server.GetPlaylists()
+}
+
+func toMPRISPlaylist(p *mediaprovider.Playlist) mprisPlaylist {
+	return mprisPlaylist{ID: playlistObjectPath(p.ID), Name: p.Name}
+}
+
+func playlistObjectPath(id string) dbus.ObjectPath {
+	return dbus.ObjectPath(dbusPlaylistIDPrefix + encodeTrackId(id))
+}
+
+func playlistIDFromObjectPath(path dbus.ObjectPath) (string, bool) {
+	encoded, ok := strings.CutPrefix(string(path), dbusPlaylistIDPrefix)
+	if !ok {
+		return "", false
+	}
+	id, err := base32.StdEncoding.WithPadding('0').DecodeString(encoded)
+	return string(id), err == nil
+}
+
+func sortedTrackObjectPaths(items []mediaprovider.MediaItem) []dbus.ObjectPath {
+	paths := trackObjectPaths(items)
+	slices.Sort(paths)
+	return paths
+}
@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/godbus/dbus/v5"
+)
+
+var errUnknownTrackID = errors.New("track ID is not in the track list")
+
+// OrgMprisMediaPlayer2TrackList implementation
+
+// Tracks returns the track IDs of the items in the active play queue.
+func (m *MPRISHandler) Tracks() ([]dbus.ObjectPath, error) {
+	return trackObjectPaths(m.pm.GetActivePlayQueue()), nil
+}
+
+func (m *MPRISHandler) CanEditTracks() (bool, error) {
+	return true, nil
+}
+
+func (m *MPRISHandler) GetTracksMetadata(trackIDs []dbus.ObjectPath) ([]map[string]dbus.Variant, error) {
+	queue := m.pm.GetActivePlayQueue()
+	metadata := make([]map[string]dbus.Variant, 0, len(trackIDs))
+	for _, id := range trackIDs {
+		if idx := indexOfTrackObjectPath(queue, id); idx >= 0 {
+			meta := m.itemMetadata(queue[idx], id, true /*lookupArt*/)
+			metadata = append(metadata, meta.MakeMap())
+		}
+	}
+	return metadata, nil
+}
+
+// AddTrack inserts the track, given as a supersonic://track URI or a
+// server track ID, after afterTrack, or at the start of the track list
+// for the NoTrack path.
+func (m *MPRISHandler) AddTrack(uri string, afterTrack dbus.ObjectPath, setAsCurrent bool) error {
+	server := m.pm.engine.sm.Server
+	if server == nil {
+		return errNotConnected
+	}
+	trackID := uri
+	if s, err := ParseSupersonicURI(uri); err == nil {
+		if s.Kind != URIKindTrack {
+			return errors.New("only track URIs can be added to the track list")
+		}
+		trackID = s.ID
+	}
+	queue := slices.Clone(m.pm.GetActivePlayQueue())
+	idx := 0
+	if afterTrack != noTrackObjectPath {
+		if idx = indexOfTrackObjectPath(queue, afterTrack); idx < 0 {
+			return errUnknownTrackID
+		}
+		idx++
+	}
+	tr, err := server.GetTrack(trackID)
+	if err != nil {
+		return err
+	}
+	m.pm.UpdatePlayQueue(slices.Insert(queue, idx, mediaprovider.MediaItem(tr)))
+	if setAsCurrent {
+		m.pm.PlayTrackAt(idx)
+	}
+	return nil
+}
+
+func (m *MPRISHandler) RemoveTrack(trackID dbus.ObjectPath) error {
+	idx := indexOfTrackObjectPath(m.pm.GetActivePlayQueue(), trackID)
+	if idx < 0 {
+		return errUnknownTrackID
+	}
+	m.pm.RemoveTracksFromQueue([]int{idx})
+	return nil
+}
+
+func (m *MPRISHandler) GoTo(trackID dbus.ObjectPath) error {
+	idx := indexOfTrackObjectPath(m.pm.GetActivePlayQueue(), trackID)
+	if idx < 0 {
+		return errUnknownTrackID
+	}
+	m.pm.PlayTrackAt(idx)
+	return nil
+}
+
+// onQueueChange emits the TrackList signal describing the change to the play queue.
+func (m *MPRISHandler) onQueueChange() {
+	queue := m.pm.GetActivePlayQueue()
+	tracks := trackObjectPaths(queue)
+	old := m.trackList
+	m.trackList = tracks
+	if m.curTrackPath != "" {
+		// the occurrence number of the current track may have changed
+		m.curTrackPath = m.nowPlayingTrackPath(tracks)
+	}
+	m.clearActivePlaylistIfChanged(queue)
+
+	switch change, idx := diffTrackList(old, tracks); change {
+	case trackListAdded:
+		after := dbus.ObjectPath(noTrackObjectPath)
+		if idx > 0 {
+			after = tracks[idx-1]
+		}
+		// art lookup could block the playback engine; clients
+		// can get the art URL with GetTracksMetadata instead
+		meta := m.itemMetadata(queue[idx], tracks[idx], false /*lookupArt*/)
+		m.emitSignal(mprisTrackListIface, "TrackAdded", meta.MakeMap(), after)
+	case trackListRemoved:
+		m.emitSignal(mprisTrackListIface, "TrackRemoved", old[idx])
+	case trackListReplaced:
+		current := dbus.ObjectPath(noTrackObjectPath)
+		if m.curTrackPath != "" {
+			current = dbus.ObjectPath(m.curTrackPath)
+		}
+		m.emitSignal(mprisTrackListIface, "TrackListReplaced", tracks, current)
+	}
+}
+
+// nowPlayingTrackPath returns the track ID of the now playing
+// entry of the track list, or "" if there is none.
+func (m *MPRISHandler) nowPlayingTrackPath(tracks []dbus.ObjectPath) string {
+	idx := m.pm.NowPlayingIndex()
+	if idx < 0 || idx >= len(tracks) {
+		return ""
+	}
+	return string(tracks[idx])
+}
+
+// trackObjectPath returns the track ID of the given occurrence (from 0)
+// of the media item with the given ID in the track list.
+func trackObjectPath(id string, occurrence int) dbus.ObjectPath {
+	path := dbusTrackIDPrefix + encodeTrackId(id)
+	if occurrence > 0 {
+		path += "/" + strconv.Itoa(occurrence)
+	}
+	return dbus.ObjectPath(path)
+}
+
+// trackObjectPaths returns the track IDs of the items, which are
+// unique even if the same media item is queued more than once.
+func trackObjectPaths(items []mediaprovider.MediaItem) []dbus.ObjectPath {
+	paths := make([]dbus.ObjectPath, len(items))
+	occurrences := make(map[string]int, len(items))
+	for i, item := range items {
+		id := item.Metadata().ID
+		paths[i] = trackObjectPath(id, occurrences[id])
+		occurrences[id]++
+	}
+	return paths
+}
+
+func indexOfTrackObjectPath(items []mediaprovider.MediaItem, path dbus.ObjectPath) int {
+	return slices.Index(trackObjectPaths(items), path)
+}
+
+type trackListChange int
+
+const (
+	trackListUnchanged trackListChange = iota
+	trackListAdded                     // one item inserted at idx
+	trackListRemoved                   // one item removed from idx
+	trackListReplaced
+)
+
+// diffTrackList classifies the change from the old to the new track list.
+func diffTrackList[T comparable](old, new []T) (change trackListChange, idx int) {
+	if slices.Equal(old, new) {
+		return trackListUnchanged, 0
+	}
+	longer, shorter := new, old
+	change = trackListAdded
+	if len(old) > len(new) {
+		longer, shorter = old, new
+		change = trackListRemoved
+	}
+	if len(longer) != len(shorter)+1 {
+		return trackListReplaced, 0
+	}
+	for idx < len(shorter) && shorter[idx] == longer[idx] {
+		idx++
+	}
+	if !slices.Equal(shorter[idx:], longer[idx+1:]) {
+		return trackListReplaced, 0
+	}
+	return change, idx
+}
@@ -0,0 +1,54 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/godbus/dbus/v5"
+)
+
+func TestDiffTrackList(t *testing.T) {
+	for _, tt := range []struct {
+		old, new   []string
+		wantChange trackListChange
+		wantIdx    int
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}, trackListUnchanged, 0},
+		{nil, nil, trackListUnchanged, 0},
+		{[]string{"a", "b"}, []string{"a", "x", "b"}, trackListAdded, 1},
+		{[]string{"a", "b"}, []string{"a", "b", "x"}, trackListAdded, 2},
+		{nil, []string{"x"}, trackListAdded, 0},
+		{[]string{"a", "b", "c"}, []string{"a", "c"}, trackListRemoved, 1},
+		{[]string{"a", "b", "c"}, []string{"b", "c"}, trackListRemoved, 0},
+		{[]string{"a", "a"}, []string{"a"}, trackListRemoved, 1},
+		{[]string{"a", "b", "c"}, []string{"c", "b", "a"}, trackListReplaced, 0},
+		{[]string{"a", "b"}, []string{"x", "b", "y"}, trackListReplaced, 0},
+		{[]string{"a"}, []string{"a", "b", "c"}, trackListReplaced, 0},
+	} {
+		change, idx := diffTrackList(tt.old, tt.new)
+		if change != tt.wantChange || idx != tt.wantIdx {
+			t.Errorf("diffTrackList(%v, %v) = %v, %d; want %v, %d", tt.old, tt.new, change, idx, tt.wantChange, tt.wantIdx)
+		}
+	}
+}
+
+func TestTrackObjectPaths(t *testing.T) {
+	a := &mediaprovider.Track{ID: "a"}
+	b := &mediaprovider.Track{ID: "b"}
+	paths := trackObjectPaths([]mediaprovider.MediaItem{a, b, a, a})
+	want := []dbus.ObjectPath{
+		trackObjectPath("a", 0), trackObjectPath("b", 0), trackObjectPath("a", 1), trackObjectPath("a", 2),
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("trackObjectPaths = %v, want %v", paths, want)
+	}
+	for i, p := range paths {
+		if !p.IsValid() {
+			t.Errorf("invalid object path %q", p)
+		}
+		if idx := indexOfTrackObjectPath([]mediaprovider.MediaItem{a, b, a, a}, p); idx != i {
+			t.Errorf("indexOfTrackObjectPath(%q) = %d, want %d", p, idx, i)
+		}
+	}
+}
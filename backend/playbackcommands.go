@@ -32,6 +32,8 @@ const (
 	cmdForceRestartPlayback
 
 	cmdLoadTrackPaused // arg: int (idx), arg2: float64 (startTime)
+
+	cmdPlayerQueueChange // arg: []mediaprovider.MediaItem, arg2: int (now playing idx)
 )
 
 type playbackCommand struct {
@@ -187,6 +189,13 @@ func (c *playbackCommandQueue) SetQueueState(tracks []*mediaprovider.Track, queu
 	c.cmdAvailable.Signal()
 }
 
+// PlayerQueueChanged applies a change of the play queue made on a
+// QueuePlayer by another client. Only the latest pending change is kept.
+func (c *playbackCommandQueue) PlayerQueueChanged(items []mediaprovider.MediaItem, nowPlaying int) {
+	c.filterCommandsAndAdd([]playbackCommandType{cmdPlayerQueueChange},
+		playbackCommand{Type: cmdPlayerQueueChange, Arg: items, Arg2: nowPlaying})
+}
+
 func (c *playbackCommandQueue) LoadTrackPaused(idx int, startTime float64) {
 	c.mutex.Lock()
 	c.queue = append(c.queue, playbackCommand{
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"slices"
 	"strings"
@@ -26,6 +27,16 @@ var (
 	ReplayGainAuto  = "Auto"
 )
 
+const (
+	// positions within this many seconds of the start or end
+	// of a track are not saved as bookmarks
+	bookmarkMinPositionSecs  = 30
+	bookmarkEndThresholdSecs = 30
+
+	// how often the position of a playing track is bookmarked
+	bookmarkSaveIntervalSecs = 60
+)
+
 type InsertQueueMode int
 
 const (
@@ -64,6 +75,7 @@ type playbackEngine struct {
 	cancelPollPos context.CancelFunc
 	sm            *ServerManager
 	audiocache    *AudioCache
+	bookmarks     *BookmarkManager
 	player        player.BasePlayer
 
 	playTimeStopwatch   util.Stopwatch
@@ -79,6 +91,13 @@ type playbackEngine struct {
 	loopMode      LoopMode
 	shuffle       bool
 
+	// chapters of the now playing track, if it has more than one
+	nowPlayingChapters []mediaprovider.Chapter
+
+	// bookmark state of the now playing track - reset on track change
+	bookmarkHandled     bool    // true iff the bookmark was already updated when leaving the track
+	lastBookmarkSavePos float64 // track position at the last periodic bookmark save
+
 	pauseAfterCurrent bool // flag to pause playback after current track ends
 
 	// flags for handleOnTrackChange / handleOnStopped callbacks - reset to false in the callbacks
@@ -122,14 +141,22 @@ type playbackEngine struct {
 	onStopped          []func()
 	onPlaying          []func()
 	onQueueChange      []func()
+	onChaptersChange   []func([]mediaprovider.Chapter)
+	onPlayedToEnd      []func(mediaprovider.MediaItem)
 
 	onRadioMetadataChange []func(radioName, title, artist string)
+
+	// invoked from the player's goroutine when a QueuePlayer's queue is
+	// changed externally; the PlaybackManager posts it to the command queue
+	// so that handlePlayerQueueChange runs on the engine goroutine
+	onPlayerQueueChange func(items []mediaprovider.MediaItem, nowPlaying int)
 }
 
 func NewPlaybackEngine(
 	ctx context.Context,
 	s *ServerManager,
 	c *AudioCache,
+	b *BookmarkManager,
 	p player.BasePlayer,
 	playbackCfg *PlaybackConfig,
 	scrobbleCfg *ScrobbleConfig,
@@ -141,6 +168,7 @@ func NewPlaybackEngine(
 		ctx:           ctx,
 		sm:            s,
 		audiocache:    c,
+		bookmarks:     b,
 		player:        p,
 		playbackCfg:   playbackCfg,
 		scrobbleCfg:   scrobbleCfg,
@@ -182,6 +210,20 @@ func (p *playbackEngine) registerPlayerCallbacks(pl player.BasePlayer) {
 		p.startPollTimePos()
 		p.invokeNoArgCallbacks(p.onPlaying)
 	})
+	if vp, ok := pl.(player.VolumeChangePlayer); ok {
+		vp.OnVolumeChange(func(vol int) {
+			for _, cb := range p.onVolumeChange {
+				cb(vol)
+			}
+		})
+	}
+	if qp, ok := pl.(player.QueuePlayer); ok {
+		qp.OnQueueChange(func(items []mediaprovider.MediaItem, nowPlaying int) {
+			if p.onPlayerQueueChange != nil {
+				p.onPlayerQueueChange(items, nowPlaying)
+			}
+		})
+	}
 }
 
 func (p *playbackEngine) unregisterPlayerCallbacks(pl player.BasePlayer) {
@@ -190,6 +232,12 @@ func (p *playbackEngine) unregisterPlayerCallbacks(pl player.BasePlayer) {
 	pl.OnStopped(nil)
 	pl.OnSeek(nil)
 	pl.OnTrackChange(nil)
+	if vp, ok := pl.(player.VolumeChangePlayer); ok {
+		vp.OnVolumeChange(nil)
+	}
+	if qp, ok := pl.(player.QueuePlayer); ok {
+		qp.OnQueueChange(nil)
+	}
 }
 
 func (p *playbackEngine) SetPlayer(pl player.BasePlayer) error {
@@ -226,6 +274,9 @@ func (p *playbackEngine) SetPlayer(pl player.BasePlayer) error {
 	}
 	p.player = pl
 	p.registerPlayerCallbacks(pl)
+	if p.getPlayQueueLength() > 0 {
+		p.syncPlayerQueue()
+	}
 
 	if needToUnpause {
 		p.playTrackAt(p.nowPlayingIdx, p.pendingPlayerChangeStatus.TimePos)
@@ -343,6 +394,19 @@ func (p *playbackEngine) playTrackAt(idx int, startTime float64) error {
 	if l := p.getPlayQueueLength(); idx < 0 || idx >= l {
 		return fmt.Errorf("track index (%d) out of range (0-%d)", idx, l)
 	}
+	// explicitly replaying the current track starts it from the
+	// beginning rather than resuming from the bookmark just saved
+	replaying := false
+	if p.NowPlaying() != nil {
+		p.updateBookmark(p.PlaybackStatus().TimePos)
+		p.bookmarkHandled = true
+		replaying = idx == p.nowPlayingIdx
+	}
+	if startTime == 0 && !replaying && p.bookmarks != nil {
+		if id := bookmarkID(p.getPlayQueueItemAt(idx)); id != "" {
+			startTime, _ = p.bookmarks.Position(id)
+		}
+	}
 	p.pendingLoadPaused = false
 	// scrobble current track if needed
 	p.checkScrobble()
@@ -433,7 +497,7 @@ func (p *playbackEngine) SetShuffle(shuffle bool) {
 
 	p.nowPlayingIdx = newNowPlayingIdx
 	p.handleNextTrackUpdated()
-	p.invokeNoArgCallbacks(p.onQueueChange)
+	p.handleQueueChanged()
 }
 
 func (p *playbackEngine) PlaybackStatus() PlaybackStatus {
@@ -474,10 +538,22 @@ func (p *playbackEngine) SeekNext() error {
 	if p.PlaybackStatus().State == player.Stopped {
 		return nil
 	}
+	if ch := p.CurrentChapterIndex(); ch >= 0 && ch < len(p.nowPlayingChapters)-1 {
+		return p.player.SeekSeconds(p.nowPlayingChapters[ch+1].Start)
+	}
 	return p.PlayTrackAt(p.nowPlayingIdx + 1)
 }
 
 func (p *playbackEngine) SeekBackOrPrevious() error {
+	if ch := p.CurrentChapterIndex(); ch >= 0 {
+		start := p.nowPlayingChapters[ch].Start
+		if p.PlaybackStatus().TimePos-start > 3 {
+			return p.player.SeekSeconds(start)
+		}
+		if ch > 0 {
+			return p.player.SeekSeconds(p.nowPlayingChapters[ch-1].Start)
+		}
+	}
 	if p.nowPlayingIdx == 0 || p.PlaybackStatus().TimePos > 3 {
 		return p.player.SeekSeconds(0)
 	}
@@ -502,6 +578,17 @@ func (p *playbackEngine) SeekFwdBackN(n int) error {
 	return p.PlayTrackAt(newIdx)
 }
 
+// Returns the chapters of the now playing track, if it has more than one.
+func (p *playbackEngine) NowPlayingChapters() []mediaprovider.Chapter {
+	return p.nowPlayingChapters
+}
+
+// Returns the index of the chapter containing the current playback position,
+// or -1 if the now playing track has no chapters.
+func (p *playbackEngine) CurrentChapterIndex() int {
+	return chapterIndexAt(p.nowPlayingChapters, p.PlaybackStatus().TimePos)
+}
+
 // Seek to given absolute position in the current track by seconds.
 func (p *playbackEngine) SeekSeconds(sec float64) error {
 	if p.isRadio {
@@ -515,6 +602,10 @@ func (p *playbackEngine) IsSeeking() bool {
 }
 
 func (p *playbackEngine) Stop() error {
+	if p.NowPlaying() != nil {
+		p.updateBookmark(p.PlaybackStatus().TimePos)
+		p.bookmarkHandled = true
+	}
 	if p.pendingLoadPaused {
 		p.pendingLoadPaused = false
 		p.pendingLoadStartTime = 0
@@ -565,7 +656,7 @@ func (p *playbackEngine) SetQueueState(tracks []*mediaprovider.Track, queueType
 		p.setPlayQueue(newTracks)
 		p.setShuffledPlayQueue(newTracks)
 	}
-	p.invokeNoArgCallbacks(p.onQueueChange)
+	p.handleQueueChanged()
 	return nil
 }
 
@@ -597,7 +688,7 @@ func (p *playbackEngine) LoadItemsAndPlayAtIdx(items []mediaprovider.MediaItem,
 	}
 
 	p.handleNextTrackUpdated()
-	p.invokeNoArgCallbacks(p.onQueueChange)
+	p.handleQueueChanged()
 
 	return nil
 }
@@ -645,7 +736,7 @@ func (p *playbackEngine) doLoaditems(items []mediaprovider.MediaItem, insertQueu
 		p.insertItemsIntoPlayQueueAt(items, insertIdx, queueType)
 	}
 
-	p.invokeNoArgCallbacks(p.onQueueChange)
+	p.handleQueueChanged()
 	return nil
 }
 
@@ -661,7 +752,7 @@ func (p *playbackEngine) LoadRadioStation(radio *mediaprovider.RadioStation, ins
 		insertIdx = p.nowPlayingIdx + 1
 	}
 	p.insertItemsIntoPlayQueueAt([]mediaprovider.MediaItem{radio}, insertIdx, Both)
-	p.invokeNoArgCallbacks(p.onQueueChange)
+	p.handleQueueChanged()
 }
 
 // Stop playback and clear the play queue.
@@ -669,7 +760,7 @@ func (p *playbackEngine) StopAndClearPlayQueue() {
 	changed := p.getPlayQueueLength() > 0
 	p.clearPlayQueue()
 	if changed {
-		p.invokeNoArgCallbacks(p.onQueueChange)
+		p.handleQueueChanged()
 	}
 }
 
@@ -721,7 +812,7 @@ func (p *playbackEngine) UpdatePlayQueue(items []mediaprovider.MediaItem) error
 	}
 	p.nowPlayingIdx = newNowPlayingIdx
 
-	p.invokeNoArgCallbacks(p.onQueueChange)
+	p.handleQueueChanged()
 	return nil
 }
 
@@ -776,7 +867,7 @@ func (p *playbackEngine) RemoveTracksFromQueue(idxs []int) {
 		}
 	}
 
-	p.invokeNoArgCallbacks(p.onQueueChange)
+	p.handleQueueChanged()
 }
 
 func (p *playbackEngine) RemoveTracksFromQueueByIdx(idxs []int, newQueue *[]mediaprovider.MediaItem, newNowPlaying *int, isPlayingTrackRemoved *bool, isNextPlayingTrackRemoved *bool) {
@@ -881,6 +972,11 @@ func (p *playbackEngine) handleOnTrackChange() {
 	if !p.alreadyScrobbled {
 		p.checkScrobble()
 	}
+	if !p.bookmarkHandled && !p.wasStopped {
+		p.handlePlayedToEnd()
+	}
+	p.bookmarkHandled = false
+	p.lastBookmarkSavePos = 0
 
 	if p.PlaybackStatus().State == player.Playing {
 		p.playTimeStopwatch.Start()
@@ -904,6 +1000,7 @@ func (p *playbackEngine) handleOnTrackChange() {
 
 	p.curTrackDuration = nowPlaying.Metadata().Duration.Seconds()
 	p.sendNowPlayingScrobble() // Must come before invokeOnChangeCallbacks b/c track may immediately be scrobbled
+	p.setNowPlayingChapters(p.loadChapters(nowPlaying))
 	p.invokeOnSongChangeCallbacks()
 	p.handleTimePosUpdate(false)
 	p.handleNextTrackUpdated()
@@ -921,7 +1018,12 @@ func (p *playbackEngine) handleOnStopped() {
 		p.checkScrobble()
 	}
 	p.stopPollTimePos()
+	if !p.bookmarkHandled {
+		p.handlePlayedToEnd()
+	}
+	p.bookmarkHandled = false
 	p.handleTimePosUpdate(false)
+	p.setNowPlayingChapters(nil)
 	p.invokeOnSongChangeCallbacks()
 	p.invokeNoArgCallbacks(p.onStopped)
 	p.alreadyScrobbled = false
@@ -930,6 +1032,44 @@ func (p *playbackEngine) handleOnStopped() {
 	p.pauseAfterCurrent = false
 }
 
+// to be invoked whenever the play queue is changed
+func (p *playbackEngine) handleQueueChanged() {
+	p.syncPlayerQueue()
+	p.invokeNoArgCallbacks(p.onQueueChange)
+}
+
+// syncPlayerQueue sends the play queue to players that hold the entire queue.
+func (p *playbackEngine) syncPlayerQueue() {
+	if qp, ok := p.player.(player.QueuePlayer); ok {
+		if err := qp.SetQueue(p.getActivePlayQueue(), p.nowPlayingIdx); err != nil {
+			log.Printf("failed to sync play queue to player: %v", err)
+		}
+	}
+}
+
+// handlePlayerQueueChange updates the play queue after it was changed
+// on a QueuePlayer by something other than us, e.g. another client.
+// Must be run from the command queue, like all other queue changes.
+func (p *playbackEngine) handlePlayerQueueChange(items []mediaprovider.MediaItem, nowPlaying int) {
+	var oldNowPlayingID string
+	if p.nowPlayingIdx >= 0 && p.nowPlayingIdx < p.getPlayQueueLength() {
+		oldNowPlayingID = p.getPlayQueueItemAt(p.nowPlayingIdx).Metadata().ID
+	}
+	p.setPlayQueue(items)
+	p.setShuffledPlayQueue(deepCopyMediaItemSlice(items))
+
+	switch {
+	case nowPlaying >= 0 && (nowPlaying != p.nowPlayingIdx || items[nowPlaying].Metadata().ID != oldNowPlayingID):
+		p.pendingTrackChangeNum = nowPlaying
+		p.handleOnTrackChange()
+	case nowPlaying < 0 && p.nowPlayingIdx >= 0:
+		p.Stop()
+	case nowPlaying >= 0:
+		p.handleNextTrackUpdated()
+	}
+	p.invokeNoArgCallbacks(p.onQueueChange)
+}
+
 // to be invoked as soon as the next item in the queue that should play changes
 func (p *playbackEngine) handleNextTrackUpdated() {
 	p.cacheNextTracks()
@@ -966,7 +1106,7 @@ func (p *playbackEngine) setTrack(idx int, next bool, startTime float64) error {
 	var url string
 	if idx >= 0 {
 		item = p.getPlayQueueItemAt(idx)
-		url = p.getMediaURLForIdx(idx)
+		url = p.getPlaybackURLForIdx(idx)
 	}
 	track, isTrack := item.(*mediaprovider.Track)
 	if p.audiocache != nil && isTrack {
@@ -982,7 +1122,8 @@ func (p *playbackEngine) setTrack(idx int, next bool, startTime float64) error {
 					url = filepath
 				}
 			}
-			if mpvP, ok := p.player.(*mpv.Player); ok && !isTrack {
+			_, isRadio := item.(*mediaprovider.RadioStation)
+			if mpvP, ok := p.player.(*mpv.Player); ok && isRadio {
 				mpvP.ObserveIcyRadioTitle(func(icytitle string) {
 					var title, artist string
 					if s := strings.Split(icytitle, " - "); len(s) == 2 {
@@ -1005,6 +1146,13 @@ func (p *playbackEngine) setTrack(idx int, next bool, startTime float64) error {
 			return urlP.SetNextFile(url, meta)
 		}
 		return urlP.PlayFile(url, meta, startTime)
+	} else if qP, ok := p.player.(player.QueuePlayer); ok {
+		// the player's queue may not have been updated yet
+		p.syncPlayerQueue()
+		if next {
+			return qP.SetNextTrackAt(idx)
+		}
+		return qP.PlayTrackAt(idx, startTime)
 	} else if trP, ok := p.player.(player.TrackPlayer); ok {
 		var track *mediaprovider.Track
 		if idx >= 0 {
@@ -1021,20 +1169,51 @@ func (p *playbackEngine) setTrack(idx int, next bool, startTime float64) error {
 	panic("Unsupported player type")
 }
 
+// nowPlayingMediaURL returns the URL or cached file path of the now playing item.
+func (p *playbackEngine) nowPlayingMediaURL() string {
+	if p.nowPlayingIdx < 0 || p.nowPlayingIdx >= p.getPlayQueueLength() {
+		return ""
+	}
+	if tr, ok := p.getPlayQueueItemAt(p.nowPlayingIdx).(*mediaprovider.Track); ok && p.audiocache != nil {
+		if filepath := p.audiocache.PathForCachedFile(tr.ID); filepath != "" {
+			return filepath
+		}
+	}
+	return p.getMediaURLForIdx(p.nowPlayingIdx)
+}
+
 func (p *playbackEngine) getMediaURLForIdx(idx int) string {
-	var url string
+	var ts *mediaprovider.TranscodeSettings
+	if p.transcodeCfg.RequestTranscode {
+		ts = &mediaprovider.TranscodeSettings{
+			Codec:       p.transcodeCfg.Codec,
+			BitRateKBPS: p.transcodeCfg.MaxBitRateKBPS,
+		}
+	}
+	return p.streamURLForItem(p.getPlayQueueItemAt(idx), ts, p.transcodeCfg.ForceRawFile)
+}
+
+// getPlaybackURLForIdx returns the URL to send to the current player,
+// which may request a transcode to a format it can play.
+func (p *playbackEngine) getPlaybackURLForIdx(idx int) string {
 	item := p.getPlayQueueItemAt(idx)
-	if tr, ok := item.(*mediaprovider.Track); ok {
-		var ts *mediaprovider.TranscodeSettings
-		if p.transcodeCfg.RequestTranscode {
-			ts = &mediaprovider.TranscodeSettings{
-				Codec:       p.transcodeCfg.Codec,
-				BitRateKBPS: p.transcodeCfg.MaxBitRateKBPS,
-			}
+	if tp, ok := p.player.(player.TranscodingPlayer); ok {
+		if ts := tp.TranscodeFor(item.Metadata().MIMEType); ts != nil {
+			return p.streamURLForItem(item, ts, false)
 		}
-		url, _ = p.sm.Server.GetStreamURL(tr.ID, ts, p.transcodeCfg.ForceRawFile)
-	} else {
-		url = item.(*mediaprovider.RadioStation).StreamURL
+	}
+	return p.getMediaURLForIdx(idx)
+}
+
+func (p *playbackEngine) streamURLForItem(item mediaprovider.MediaItem, ts *mediaprovider.TranscodeSettings, forceRaw bool) string {
+	var url string
+	switch item := item.(type) {
+	case *mediaprovider.Track:
+		url, _ = p.sm.Server.GetStreamURL(item.ID, ts, forceRaw)
+	case *mediaprovider.PodcastEpisode:
+		url, _ = p.sm.Server.GetStreamURL(item.StreamID, ts, forceRaw)
+	case *mediaprovider.RadioStation:
+		url = item.StreamURL
 	}
 	return url
 }
@@ -1101,6 +1280,93 @@ func deepCopyMediaItemSlice(tracks []mediaprovider.MediaItem) []mediaprovider.Me
 	return newTracks
 }
 
+// saves the bookmark of the now playing track at the given position,
+// or deletes it if the position is near the end of the track.
+// Only tracks long enough to be automatically bookmarked
+// or which already have a bookmark are considered.
+func (p *playbackEngine) updateBookmark(pos float64) {
+	if p.bookmarks == nil || p.nowPlayingIdx < 0 || p.nowPlayingIdx >= p.getPlayQueueLength() {
+		return
+	}
+	item := p.getPlayQueueItemAt(p.nowPlayingIdx)
+	id := bookmarkID(item)
+	if id == "" {
+		return
+	}
+	p.lastBookmarkSavePos = pos
+	dur := item.Metadata().Duration
+	minDur := time.Duration(p.playbackCfg.AutoBookmarkMinTrackMinutes) * time.Minute
+	_, hasBookmark := p.bookmarks.Position(id)
+	if !hasBookmark && (minDur <= 0 || dur < minDur) {
+		return
+	}
+	if pos >= dur.Seconds()-bookmarkEndThresholdSecs {
+		p.bookmarks.DeleteAsync(id)
+	} else if pos >= bookmarkMinPositionSecs {
+		p.bookmarks.SaveAsync(id, pos)
+	}
+}
+
+// returns the ID the bookmark of the given item is saved under,
+// or "" if the item can't be bookmarked
+func bookmarkID(item mediaprovider.MediaItem) string {
+	switch it := item.(type) {
+	case *mediaprovider.Track:
+		return it.ID
+	case *mediaprovider.PodcastEpisode:
+		// bookmarks refer to the episode's downloaded media file
+		return it.StreamID
+	}
+	return ""
+}
+
+// called when the now playing item has played to its end,
+// before p.nowPlayingIdx is updated
+func (p *playbackEngine) handlePlayedToEnd() {
+	if p.nowPlayingIdx < 0 || p.nowPlayingIdx >= p.getPlayQueueLength() {
+		return
+	}
+	p.updateBookmark(p.curTrackDuration)
+	if p.callbacksDisabled {
+		return
+	}
+	item := p.getPlayQueueItemAt(p.nowPlayingIdx)
+	for _, cb := range p.onPlayedToEnd {
+		cb(item)
+	}
+}
+
+// loads the chapters of the given item from the player,
+// falling back to a sidecar CUE sheet for locally accessible files
+func (p *playbackEngine) loadChapters(item mediaprovider.MediaItem) []mediaprovider.Chapter {
+	tr, ok := item.(*mediaprovider.Track)
+	if !ok {
+		return nil
+	}
+	if cp, ok := p.player.(player.ChapterPlayer); ok {
+		if chapters := cp.GetChapters(); len(chapters) > 1 {
+			return chapters
+		}
+	}
+	if chapters := loadSidecarCueChapters(tr.FilePath); len(chapters) > 1 {
+		return chapters
+	}
+	return nil
+}
+
+func (p *playbackEngine) setNowPlayingChapters(chapters []mediaprovider.Chapter) {
+	if len(chapters) == 0 && len(p.nowPlayingChapters) == 0 {
+		return
+	}
+	p.nowPlayingChapters = chapters
+	if p.callbacksDisabled {
+		return
+	}
+	for _, cb := range p.onChaptersChange {
+		cb(chapters)
+	}
+}
+
 func (p *playbackEngine) invokeOnSongChangeCallbacks() {
 	if p.callbacksDisabled {
 		return
@@ -1171,6 +1437,9 @@ func (p *playbackEngine) handleTimePosUpdate(seeked bool) {
 	if s.TimePos > p.latestTrackPosition {
 		p.latestTrackPosition = s.TimePos
 	}
+	if s.State == player.Playing && math.Abs(s.TimePos-p.lastBookmarkSavePos) >= bookmarkSaveIntervalSecs {
+		p.updateBookmark(s.TimePos)
+	}
 	duration := s.Duration
 	if p.isRadio {
 		// MPV reports buffered duration - we don't want to show this
@@ -14,7 +14,9 @@ import (
 	"github.com/charlievieth/strcase"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/cast"
 	"github.com/dweymouth/supersonic/backend/player/dlna"
+	"github.com/dweymouth/supersonic/backend/player/jukebox"
 	"github.com/dweymouth/supersonic/backend/player/mpv"
 	"github.com/dweymouth/supersonic/sharedutil"
 	"github.com/supersonic-app/go-upnpcast/device"
@@ -49,6 +51,9 @@ type PlaybackManager struct {
 	wasLoadTrackPaused bool
 }
 
+// number of upcoming tracks in the queue to precompute waveforms for
+const numWaveformPrecomputeTracks = 5
+
 type RemotePlaybackDevice struct {
 	Name     string
 	URL      string
@@ -56,18 +61,29 @@ type RemotePlaybackDevice struct {
 	new      func() (player.BasePlayer, error)
 }
 
+// device URL of the server's jukebox, which plays on the server's own audio output
+const jukeboxURL = "jukebox://"
+
+// IsJukebox returns true if the remote device is the server's jukebox.
+func (rp *RemotePlaybackDevice) IsJukebox() bool {
+	return rp.URL == jukeboxURL
+}
+
 func NewPlaybackManager(
 	ctx context.Context,
 	s *ServerManager,
 	c *AudioCache,
+	wc *WaveformCache,
+	b *BookmarkManager,
 	p player.BasePlayer,
 	playbackCfg *PlaybackConfig,
 	scrobbleCfg *ScrobbleConfig,
 	transcodeCfg *TranscodingConfig,
 	appCfg *AppConfig,
 ) *PlaybackManager {
-	e := NewPlaybackEngine(ctx, s, c, p, playbackCfg, scrobbleCfg, transcodeCfg)
+	e := NewPlaybackEngine(ctx, s, c, b, p, playbackCfg, scrobbleCfg, transcodeCfg)
 	q := NewCommandQueue()
+	e.onPlayerQueueChange = q.PlayerQueueChanged
 	pm := &PlaybackManager{
 		engine:      e,
 		cmdQueue:    q,
@@ -77,9 +93,15 @@ func NewPlaybackManager(
 		cache:       c,
 	}
 	if c != nil {
-		pm.wfmGen = NewWaveformImageGenerator(c)
+		pm.wfmGen = NewWaveformImageGenerator(c, wc)
 	}
 	pm.addOnTrackChangeHook()
+	s.OnLogout(func() {
+		// the jukebox belongs to the server we're logging out of
+		if rp := pm.currentRemotePlayer; rp != nil && rp.IsJukebox() {
+			pm.SetRemotePlayer(nil)
+		}
+	})
 	go pm.runCmdQueue(ctx)
 	return pm
 }
@@ -133,7 +155,10 @@ func (p *PlaybackManager) addOnTrackChangeHook() {
 		}
 	})
 
+	p.OnQueueChange(p.precomputeUpcomingWaveforms)
+
 	p.OnSongChange(func(item mediaprovider.MediaItem, _ *mediaprovider.Track) {
+		p.precomputeUpcomingWaveforms()
 		if p.wasLoadTrackPaused {
 			// if the song change was triggered by LoadTrackPaused when starting the app,
 			// we already called handleWaveformImageSongChange in the onBeforeSongChange hook above
@@ -226,6 +251,23 @@ func (p *PlaybackManager) handleWaveformImageSongChange(item mediaprovider.Media
 	}
 }
 
+// starts background computation of waveforms for the next few tracks in the queue
+// so that the waveform seekbar can appear immediately when they begin playing
+func (p *PlaybackManager) precomputeUpcomingWaveforms() {
+	if p.wfmGen == nil || !p.engine.playbackCfg.UseWaveformSeekbar || p.engine.sm.Server == nil {
+		return
+	}
+	var reqs []WaveformPrecomputeRequest
+	// the next-up track is already handled by the audio cache pipeline
+	start := max(p.engine.nowPlayingIdx, 0) + 2
+	for idx := start; idx < min(start+numWaveformPrecomputeTracks, p.engine.getPlayQueueLength()); idx++ {
+		if tr, ok := p.engine.getPlayQueueItemAt(idx).(*mediaprovider.Track); ok {
+			reqs = append(reqs, WaveformPrecomputeRequest{Track: tr, StreamURL: p.engine.getMediaURLForIdx(idx)})
+		}
+	}
+	p.wfmGen.PrecomputeWaveforms(reqs)
+}
+
 func (p *PlaybackManager) ScanRemotePlayers(ctx context.Context, fastScan bool) {
 	if fastScan {
 		p.scanRemotePlayers(ctx, 1 /*waitSec*/)
@@ -235,7 +277,19 @@ func (p *PlaybackManager) ScanRemotePlayers(ctx context.Context, fastScan bool)
 }
 
 func (p *PlaybackManager) scanRemotePlayers(ctx context.Context, waitSec int) {
+	var castDevices []cast.Device
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var err error
+		castDevices, err = cast.Discover(ctx, time.Duration(waitSec)*time.Second)
+		if err != nil {
+			log.Printf("error discovering Cast devices: %v", err)
+		}
+	}()
 	devices, _ := device.SearchMediaRenderers(ctx, waitSec, services.AVTransport, services.RenderingControl)
+	wg.Wait()
 
 	var discovered []RemotePlaybackDevice
 	for _, d := range devices {
@@ -249,16 +303,46 @@ func (p *PlaybackManager) scanRemotePlayers(ctx context.Context, waitSec int) {
 		}
 		discovered = append(discovered, p)
 	}
+	for _, d := range castDevices {
+		p := RemotePlaybackDevice{
+			Name:     d.Name,
+			URL:      "cast://" + d.Addr,
+			Protocol: "Chromecast",
+			new: func() (player.BasePlayer, error) {
+				return cast.NewCastPlayer(d)
+			},
+		}
+		discovered = append(discovered, p)
+	}
 
 	p.remotePlayersLock.Lock()
 	p.remotePlayers = discovered
 	p.remotePlayersLock.Unlock()
 }
 
+// RemotePlayers returns the server's jukebox, if supported, and the
+// discovered remote players, followed by the configured player groups.
 func (p *PlaybackManager) RemotePlayers() []RemotePlaybackDevice {
+	var players []RemotePlaybackDevice
+	if jp, ok := p.engine.sm.Server.(mediaprovider.JukeboxProvider); ok {
+		players = append(players, RemotePlaybackDevice{
+			Name:     "Server jukebox",
+			URL:      jukeboxURL,
+			Protocol: "Jukebox",
+			new: func() (player.BasePlayer, error) {
+				return jukebox.NewJukeboxPlayer(jp)
+			},
+		})
+	}
 	p.remotePlayersLock.Lock()
-	players := p.remotePlayers
+	players = append(players, p.remotePlayers...)
 	p.remotePlayersLock.Unlock()
+	for _, s := range p.cfg.MPDServers {
+		players = append(players, p.mpdServerDevice(s))
+	}
+	for _, g := range p.cfg.PlayerGroups {
+		players = append(players, p.playerGroupDevice(g))
+	}
 	return players
 }
 
@@ -323,6 +407,12 @@ func (p *PlaybackManager) NowPlaying() mediaprovider.MediaItem {
 	return p.engine.NowPlaying()
 }
 
+// NowPlayingMediaURL returns the stream URL, or local cached file
+// path, of the now playing media item, or "" if nothing is playing.
+func (p *PlaybackManager) NowPlayingMediaURL() string {
+	return p.engine.nowPlayingMediaURL()
+}
+
 func (p *PlaybackManager) NowPlayingIndex() int {
 	return p.engine.NowPlayingIndex()
 }
@@ -362,6 +452,18 @@ func (p *PlaybackManager) OnQueueChange(cb func()) {
 	p.engine.onQueueChange = append(p.engine.onQueueChange, cb)
 }
 
+// Registers a callback that is notified whenever the chapters
+// of the now playing track change (nil if it has no chapters).
+func (p *PlaybackManager) OnChaptersChange(cb func([]mediaprovider.Chapter)) {
+	p.engine.onChaptersChange = append(p.engine.onChaptersChange, cb)
+}
+
+// Registers a callback that is notified whenever a media item has played
+// to its end, rather than being skipped or stopped.
+func (p *PlaybackManager) OnPlayedToEnd(cb func(mediaprovider.MediaItem)) {
+	p.engine.onPlayedToEnd = append(p.engine.onPlayedToEnd, cb)
+}
+
 // Registers a callback that is notified whenever the player has been seeked.
 func (p *PlaybackManager) OnSeek(cb func()) {
 	p.engine.onSeek = append(p.engine.onSeek, cb)
@@ -712,6 +814,26 @@ func (p *PlaybackManager) SeekBackOrPrevious() {
 	p.cmdQueue.SeekBackOrPrevious()
 }
 
+// Returns the chapters of the now playing track, if it has more than one.
+func (p *PlaybackManager) NowPlayingChapters() []mediaprovider.Chapter {
+	return p.engine.NowPlayingChapters()
+}
+
+// Returns the index of the currently playing chapter,
+// or -1 if the now playing track has no chapters.
+func (p *PlaybackManager) CurrentChapterIndex() int {
+	return p.engine.CurrentChapterIndex()
+}
+
+// Seek to the beginning of the given chapter of the now playing track.
+func (p *PlaybackManager) SeekToChapter(idx int) {
+	chapters := p.engine.NowPlayingChapters()
+	if idx < 0 || idx >= len(chapters) {
+		return
+	}
+	p.cmdQueue.SeekSeconds(chapters[idx].Start)
+}
+
 // Seek to given absolute position in the current track by seconds.
 func (p *PlaybackManager) SeekSeconds(sec float64) {
 	p.cmdQueue.SeekSeconds(sec)
@@ -922,6 +1044,8 @@ func (p *PlaybackManager) runCmdQueue(ctx context.Context) {
 				)
 			case cmdLoadTrackPaused:
 				logIfErr("LoadTrackPaused", p.engine.loadTrackPaused(c.Arg.(int), c.Arg2.(float64)))
+			case cmdPlayerQueueChange:
+				p.engine.handlePlayerQueueChange(c.Arg.([]mediaprovider.MediaItem), c.Arg2.(int))
 			case cmdForceRestartPlayback:
 				if mpv, ok := p.engine.CurrentPlayer().(*mpv.Player); ok {
 					log.Println("Force-restarting MPV playback")
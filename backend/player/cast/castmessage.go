@@ -0,0 +1,173 @@
+package cast
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CASTV2 messages are protobuf-encoded CastMessages (see cast_channel.proto
+// in the Chromium source), prefixed by a 4-byte big-endian length.
+// The message is small and stable enough that it is encoded by hand
+// here rather than pulling in a protobuf runtime.
+
+const (
+	protocolVersionCastV2_1_0 = 0
+
+	payloadTypeString = 0
+	payloadTypeBinary = 1
+
+	// max message size allowed by the Cast protocol
+	maxMessageSize = 64 * 1024
+)
+
+// protobuf field numbers of the CastMessage
+const (
+	fieldProtocolVersion = 1
+	fieldSourceID        = 2
+	fieldDestinationID   = 3
+	fieldNamespace       = 4
+	fieldPayloadType     = 5
+	fieldPayloadUTF8     = 6
+	fieldPayloadBinary   = 7
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errMalformedMessage = errors.New("cast: malformed message")
+
+type castMessage struct {
+	ProtocolVersion int
+	SourceID        string
+	DestinationID   string
+	Namespace       string
+	PayloadType     int
+	PayloadUTF8     string
+	PayloadBinary   []byte
+}
+
+func (m *castMessage) marshal() []byte {
+	b := make([]byte, 0, 64+len(m.SourceID)+len(m.DestinationID)+len(m.Namespace)+len(m.PayloadUTF8)+len(m.PayloadBinary))
+	// protocol_version and payload_type are required fields,
+	// so they are always encoded, even if zero
+	b = appendVarintField(b, fieldProtocolVersion, uint64(m.ProtocolVersion))
+	b = appendBytesField(b, fieldSourceID, []byte(m.SourceID))
+	b = appendBytesField(b, fieldDestinationID, []byte(m.DestinationID))
+	b = appendBytesField(b, fieldNamespace, []byte(m.Namespace))
+	b = appendVarintField(b, fieldPayloadType, uint64(m.PayloadType))
+	if m.PayloadType == payloadTypeBinary {
+		b = appendBytesField(b, fieldPayloadBinary, m.PayloadBinary)
+	} else {
+		b = appendBytesField(b, fieldPayloadUTF8, []byte(m.PayloadUTF8))
+	}
+	return b
+}
+
+func (m *castMessage) unmarshal(b []byte) error {
+	*m = castMessage{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errMalformedMessage
+		}
+		b = b[n:]
+		field, wireType := int(key>>3), int(key&7)
+
+		switch wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return errMalformedMessage
+			}
+			b = b[n:]
+			switch field {
+			case fieldProtocolVersion:
+				m.ProtocolVersion = int(v)
+			case fieldPayloadType:
+				m.PayloadType = int(v)
+			}
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errMalformedMessage
+			}
+			v := b[n : n+int(l)]
+			b = b[n+int(l):]
+			switch field {
+			case fieldSourceID:
+				m.SourceID = string(v)
+			case fieldDestinationID:
+				m.DestinationID = string(v)
+			case fieldNamespace:
+				m.Namespace = string(v)
+			case fieldPayloadUTF8:
+				m.PayloadUTF8 = string(v)
+			case fieldPayloadBinary:
+				m.PayloadBinary = append([]byte(nil), v...)
+			}
+		case wireFixed64:
+			if len(b) < 8 {
+				return errMalformedMessage
+			}
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errMalformedMessage
+			}
+			b = b[4:]
+		default:
+			return errMalformedMessage
+		}
+	}
+	return nil
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|wireVarint))
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|wireBytes))
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// writeMessage writes the length-prefixed message to w.
+func writeMessage(w io.Writer, m *castMessage) error {
+	body := m.marshal()
+	if len(body) > maxMessageSize {
+		return fmt.Errorf("cast: message too large (%d bytes)", len(body))
+	}
+	buf := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(len(body)))
+	_, err := w.Write(append(buf, body...))
+	return err
+}
+
+// readMessage reads the next length-prefixed message from r.
+func readMessage(r io.Reader) (*castMessage, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	l := binary.BigEndian.Uint32(lenBuf[:])
+	if l > maxMessageSize {
+		return nil, fmt.Errorf("cast: message too large (%d bytes)", l)
+	}
+	body := make([]byte, l)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	m := &castMessage{}
+	if err := m.unmarshal(body); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,805 @@
+// Package cast implements a player that plays to Google Cast devices
+// (Chromecast, Google Home / Nest speakers, and Cast-enabled TVs and
+// speakers) through the Default Media Receiver application.
+package cast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/mediaproxy"
+)
+
+const (
+	stopped = 0
+	playing = 1
+	paused  = 2
+)
+
+const (
+	defaultMediaReceiverAppID = "CC1AD845"
+
+	// seconds before the end of the current track
+	// the receiver should start buffering the next one
+	queuePreloadTimeSecs = 10
+	statusPollInterval   = 1 * time.Second
+	requestTimeout       = 5 * time.Second
+	launchTimeout        = 15 * time.Second
+)
+
+type mediaStatus struct {
+	MediaSessionID int     `json:"mediaSessionId"`
+	PlayerState    string  `json:"playerState"`
+	IdleReason     string  `json:"idleReason"`
+	CurrentTime    float64 `json:"currentTime"`
+	CurrentItemID  int     `json:"currentItemId"`
+	Items          []struct {
+		ItemID int `json:"itemId"`
+	} `json:"items"`
+}
+
+type mediaStatusMessage struct {
+	messageHeader
+	Reason string        `json:"reason"`
+	Status []mediaStatus `json:"status"`
+}
+
+type receiverStatusMessage struct {
+	messageHeader
+	Reason string `json:"reason"`
+	Status struct {
+		Applications []struct {
+			AppID       string `json:"appId"`
+			SessionID   string `json:"sessionId"`
+			TransportID string `json:"transportId"`
+		} `json:"applications"`
+		Volume struct {
+			Level *float64 `json:"level"`
+		} `json:"volume"`
+	} `json:"status"`
+}
+
+type CastPlayer struct {
+	player.BasePlayerCallbackImpl
+
+	device Device
+	proxy  mediaproxy.Server
+	// held while reconnecting to the device
+	dialLock sync.Mutex
+
+	// callbacks triggered by messages from the device are invoked
+	// from this queue rather than the channel's read loop, since
+	// they may in turn issue requests to the device.
+	// The queue is unbounded so that the read loop never blocks.
+	pendingEvents []func()
+	wake          chan struct{}
+	done          chan struct{}
+
+	mu        sync.Mutex
+	ch        *channel
+	destroyed bool
+	// set while a LOAD request is in flight, so that stale
+	// status broadcasts for the previous media are ignored
+	loading bool
+	state   int // stopped, playing, paused
+	seeking bool
+	volume  int
+
+	transportID    string
+	sessionID      string
+	mediaSessionID int
+	curItemID      int
+	nextItemID     int
+	nextURL        string
+	// set if the device rejected QUEUE_INSERT, in which case
+	// the next track is loaded once the current one finishes
+	queueFailed bool
+
+	curTrackMeta  mediaprovider.MediaItemMetadata
+	nextTrackMeta mediaprovider.MediaItemMetadata
+
+	// playback position reported by the last status update,
+	// and the time it was received, to interpolate the position
+	lastPos     float64
+	lastPosTime time.Time
+}
+
+// NewCastPlayer connects to the Cast device.
+func NewCastPlayer(device Device) (*CastPlayer, error) {
+	p := &CastPlayer{
+		device: device,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		volume: 100,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	ch, err := p.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s", device.Name)
+	}
+	p.mu.Lock()
+	p.ch = ch
+	p.mu.Unlock()
+
+	go p.eventLoop()
+	go p.pollStatus()
+	return p, nil
+}
+
+func (p *CastPlayer) SetVolume(vol int) error {
+	if p.isDestroyed() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	ch, err := p.conn(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = ch.request(ctx, defaultReceiverID, namespaceReceiver, map[string]any{
+		"type":   "SET_VOLUME",
+		"volume": map[string]any{"level": float64(vol) / 100},
+	})
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.volume = vol
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *CastPlayer) GetVolume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.volume
+}
+
+func (p *CastPlayer) PlayFile(url string, meta mediaprovider.MediaItemMetadata, startTime float64) error {
+	if p.isDestroyed() {
+		return nil
+	}
+	p.proxy.EnsureStarted()
+
+	ctx, cancel := context.WithTimeout(context.Background(), launchTimeout)
+	defer cancel()
+	if err := p.ensureAppLaunched(ctx); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.loading = true
+	p.curTrackMeta = meta
+	p.nextTrackMeta = mediaprovider.MediaItemMetadata{}
+	p.curItemID, p.nextItemID = 0, 0
+	p.nextURL = ""
+	p.queueFailed = false
+	p.mu.Unlock()
+
+	resp, err := p.mediaRequest(ctx, map[string]any{
+		"type":        "LOAD",
+		"media":       p.mediaInfo(url, meta),
+		"autoplay":    true,
+		"currentTime": startTime,
+	})
+
+	p.mu.Lock()
+	p.loading = false
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	if len(resp.Status) > 0 {
+		p.mediaSessionID = resp.Status[0].MediaSessionID
+		p.curItemID = resp.Status[0].CurrentItemID
+	}
+	p.lastPos = startTime
+	p.lastPosTime = time.Now()
+	p.state = playing
+	p.mu.Unlock()
+
+	p.InvokeOnPlaying()
+	p.InvokeOnTrackChange()
+	if startTime > 0 {
+		p.InvokeOnSeek()
+	}
+	return nil
+}
+
+func (p *CastPlayer) SetNextFile(url string, meta mediaprovider.MediaItemMetadata) error {
+	if p.isDestroyed() {
+		return nil
+	}
+
+	p.mu.Lock()
+	p.nextTrackMeta = meta
+	p.nextURL = url
+	oldNextItemID := p.nextItemID
+	p.nextItemID = 0
+	sessionID := p.mediaSessionID
+	queueFailed := p.queueFailed
+	p.mu.Unlock()
+
+	if sessionID == 0 || queueFailed {
+		// nothing playing, or the next track
+		// will be loaded when the current one finishes
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if oldNextItemID != 0 {
+		if _, err := p.mediaRequest(ctx, map[string]any{
+			"type":           "QUEUE_REMOVE",
+			"mediaSessionId": sessionID,
+			"itemIds":        []int{oldNextItemID},
+		}); err != nil {
+			log.Printf("cast: failed to remove next track from queue: %v", err)
+		}
+	}
+	if url == "" {
+		return nil
+	}
+
+	p.proxy.EnsureStarted()
+	_, err := p.mediaRequest(ctx, map[string]any{
+		"type":           "QUEUE_INSERT",
+		"mediaSessionId": sessionID,
+		"items": []map[string]any{{
+			"media":       p.mediaInfo(url, meta),
+			"autoplay":    true,
+			"preloadTime": queuePreloadTimeSecs,
+		}},
+	})
+	if err != nil {
+		// not fatal - fall back to loading the next track
+		// after the current one has finished
+		log.Printf("cast: failed to queue next track: %v", err)
+		p.mu.Lock()
+		p.queueFailed = true
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+func (p *CastPlayer) Continue() error {
+	if p.isDestroyed() {
+		return nil
+	}
+	p.mu.Lock()
+	if p.state != paused || p.mediaSessionID == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	sessionID := p.mediaSessionID
+	// update state first so the status broadcast
+	// in response isn't seen as a state change
+	p.state = playing
+	p.lastPosTime = time.Now()
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if _, err := p.mediaRequest(ctx, map[string]any{"type": "PLAY", "mediaSessionId": sessionID}); err != nil {
+		p.mu.Lock()
+		p.state = paused
+		p.mu.Unlock()
+		return err
+	}
+	p.InvokeOnPlaying()
+	return nil
+}
+
+func (p *CastPlayer) Pause() error {
+	if p.isDestroyed() {
+		return nil
+	}
+	p.mu.Lock()
+	if p.state != playing || p.mediaSessionID == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	sessionID := p.mediaSessionID
+	p.lastPos = p.curPlayPos()
+	p.state = paused
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if _, err := p.mediaRequest(ctx, map[string]any{"type": "PAUSE", "mediaSessionId": sessionID}); err != nil {
+		p.mu.Lock()
+		p.state = playing
+		p.lastPosTime = time.Now()
+		p.mu.Unlock()
+		return err
+	}
+	p.InvokeOnPaused()
+	return nil
+}
+
+func (p *CastPlayer) Stop(force bool) error {
+	if p.isDestroyed() {
+		return nil
+	}
+	p.mu.Lock()
+	if p.state == stopped {
+		p.mu.Unlock()
+		return nil
+	}
+	sessionID := p.mediaSessionID
+	p.resetMediaSession()
+	p.mu.Unlock()
+
+	if sessionID != 0 {
+		timeout := requestTimeout
+		if force {
+			timeout = 2 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if _, err := p.mediaRequest(ctx, map[string]any{"type": "STOP", "mediaSessionId": sessionID}); err != nil {
+			log.Printf("cast: failed to stop playback: %v", err)
+		}
+	}
+	p.InvokeOnStopped()
+	return nil
+}
+
+func (p *CastPlayer) SeekSeconds(secs float64) error {
+	if p.isDestroyed() {
+		return nil
+	}
+	p.mu.Lock()
+	sessionID := p.mediaSessionID
+	if sessionID == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	p.seeking = true
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err := p.mediaRequest(ctx, map[string]any{
+		"type":           "SEEK",
+		"mediaSessionId": sessionID,
+		"currentTime":    secs,
+	})
+
+	p.mu.Lock()
+	p.seeking = false
+	if err == nil {
+		p.lastPos = secs
+		p.lastPosTime = time.Now()
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	p.InvokeOnSeek()
+	return nil
+}
+
+func (p *CastPlayer) IsSeeking() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seeking
+}
+
+func (p *CastPlayer) GetStatus() player.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := player.Stopped
+	if p.state == playing {
+		state = player.Playing
+	} else if p.state == paused {
+		state = player.Paused
+	}
+
+	var timePos float64
+	if p.state != stopped {
+		timePos = p.curPlayPos()
+	}
+	return player.Status{
+		State:    state,
+		TimePos:  timePos,
+		Duration: p.curTrackMeta.Duration.Seconds(),
+	}
+}
+
+func (p *CastPlayer) Destroy() {
+	p.mu.Lock()
+	if p.destroyed {
+		p.mu.Unlock()
+		return
+	}
+	p.destroyed = true
+	transportID := p.transportID
+	ch := p.ch
+	p.mu.Unlock()
+
+	close(p.done)
+	if transportID != "" {
+		ch.send(transportID, namespaceConnection, map[string]any{"type": "CLOSE"})
+	}
+	ch.Close()
+	p.proxy.Shutdown()
+}
+
+func (p *CastPlayer) isDestroyed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.destroyed
+}
+
+// must be called with lock held
+func (p *CastPlayer) curPlayPos() float64 {
+	pos := p.lastPos
+	if p.state == playing && !p.lastPosTime.IsZero() {
+		pos += time.Since(p.lastPosTime).Seconds()
+	}
+	if dur := p.curTrackMeta.Duration.Seconds(); dur > 0 && pos > dur {
+		pos = dur
+	}
+	return pos
+}
+
+// must be called with lock held
+func (p *CastPlayer) resetMediaSession() {
+	p.state = stopped
+	p.mediaSessionID = 0
+	p.curItemID, p.nextItemID = 0, 0
+	p.nextURL = ""
+	p.queueFailed = false
+	p.lastPos = 0
+	p.lastPosTime = time.Time{}
+}
+
+// dial opens a new connection to the device and fetches
+// its status to test connectivity and get the current volume.
+func (p *CastPlayer) dial(ctx context.Context) (*channel, error) {
+	ch, err := dialChannel(ctx, p.device.Addr, p.handleMessage, p.handleClose)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ch.request(ctx, defaultReceiverID, namespaceReceiver, map[string]any{"type": "GET_STATUS"}); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// conn returns the connection to the device,
+// reconnecting if the previous one was lost.
+func (p *CastPlayer) conn(ctx context.Context) (*channel, error) {
+	p.dialLock.Lock()
+	defer p.dialLock.Unlock()
+	p.mu.Lock()
+	ch := p.ch
+	p.mu.Unlock()
+	if !ch.isClosed() {
+		return ch, nil
+	}
+
+	ch, err := p.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cast: failed to reconnect to %s: %w", p.device.Name, err)
+	}
+	p.mu.Lock()
+	if p.destroyed {
+		p.mu.Unlock()
+		ch.Close()
+		return nil, errChannelClosed
+	}
+	p.ch = ch
+	p.mu.Unlock()
+	log.Printf("cast: reconnected to %s", p.device.Name)
+	return ch, nil
+}
+
+// ensureAppLaunched launches the Default Media Receiver on the device,
+// if not already running, and connects to it.
+func (p *CastPlayer) ensureAppLaunched(ctx context.Context) error {
+	ch, err := p.conn(ctx)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	launched := p.transportID != ""
+	p.mu.Unlock()
+	if launched {
+		return nil
+	}
+
+	b, err := ch.request(ctx, defaultReceiverID, namespaceReceiver, map[string]any{
+		"type":  "LAUNCH",
+		"appId": defaultMediaReceiverAppID,
+	})
+	if err != nil {
+		return err
+	}
+	var resp receiverStatusMessage
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return err
+	}
+	if resp.Type != "RECEIVER_STATUS" {
+		return fmt.Errorf("cast: failed to launch media receiver: %s %s", resp.Type, resp.Reason)
+	}
+	for _, app := range resp.Status.Applications {
+		if app.AppID == defaultMediaReceiverAppID {
+			if err := ch.connect(app.TransportID); err != nil {
+				return err
+			}
+			p.mu.Lock()
+			p.transportID = app.TransportID
+			p.sessionID = app.SessionID
+			p.mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("cast: media receiver not running on %s", p.device.Name)
+}
+
+func (p *CastPlayer) mediaRequest(ctx context.Context, payload map[string]any) (*mediaStatusMessage, error) {
+	p.mu.Lock()
+	transportID := p.transportID
+	ch := p.ch
+	p.mu.Unlock()
+	if transportID == "" {
+		return nil, fmt.Errorf("cast: media receiver not running on %s", p.device.Name)
+	}
+
+	b, err := ch.request(ctx, transportID, namespaceMedia, payload)
+	if err != nil {
+		return nil, err
+	}
+	var resp mediaStatusMessage
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Type != "MEDIA_STATUS" {
+		// LOAD_FAILED, LOAD_CANCELLED, INVALID_REQUEST, INVALID_PLAYER_STATE
+		return nil, fmt.Errorf("cast: %s failed: %s %s", payload["type"], resp.Type, resp.Reason)
+	}
+	return &resp, nil
+}
+
+func (p *CastPlayer) mediaInfo(url string, meta mediaprovider.MediaItemMetadata) map[string]any {
+	contentType := meta.MIMEType
+	if contentType == "" {
+		contentType = "audio/mpeg"
+	}
+	return map[string]any{
+		"contentId":   p.proxy.AddURL(url),
+		"contentType": contentType,
+		"streamType":  "BUFFERED",
+		"duration":    meta.Duration.Seconds(),
+		"metadata": map[string]any{
+			"metadataType": 3, // MusicTrackMediaMetadata
+			"title":        meta.Name,
+			"artist":       strings.Join(meta.Artists, ", "),
+			"albumName":    meta.Album,
+		},
+	}
+}
+
+// pollStatus periodically requests the media status, since
+// the device only broadcasts it when the player state changes,
+// and the position may drift from the interpolated one.
+func (p *CastPlayer) pollStatus() {
+	t := time.NewTicker(statusPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-t.C:
+			p.mu.Lock()
+			sessionID := p.mediaSessionID
+			p.mu.Unlock()
+			if sessionID == 0 {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			// the response is handled by handleMessage like any other status
+			p.mediaRequest(ctx, map[string]any{"type": "GET_STATUS", "mediaSessionId": sessionID})
+			cancel()
+		}
+	}
+}
+
+func (p *CastPlayer) eventLoop() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.wake:
+		}
+		p.mu.Lock()
+		events := p.pendingEvents
+		p.pendingEvents = nil
+		p.mu.Unlock()
+		for _, f := range events {
+			f()
+		}
+	}
+}
+
+// queueEvents adds the events to the queue without blocking.
+func (p *CastPlayer) queueEvents(events []func()) {
+	if len(events) == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.pendingEvents = append(p.pendingEvents, events...)
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *CastPlayer) handleMessage(namespace string, header messageHeader, payload []byte) {
+	var events []func()
+	switch {
+	case namespace == namespaceMedia && header.Type == "MEDIA_STATUS":
+		var msg mediaStatusMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return
+		}
+		p.mu.Lock()
+		if !p.loading && len(msg.Status) > 0 {
+			events = p.handleMediaStatus(&msg.Status[0])
+		}
+		p.mu.Unlock()
+	case namespace == namespaceReceiver && header.Type == "RECEIVER_STATUS":
+		var msg receiverStatusMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return
+		}
+		p.mu.Lock()
+		if l := msg.Status.Volume.Level; l != nil {
+			p.volume = int(*l*100 + 0.5)
+		}
+		if p.sessionID != "" {
+			running := false
+			for _, app := range msg.Status.Applications {
+				running = running || app.SessionID == p.sessionID
+			}
+			if !running {
+				// media receiver was closed, e.g. by another sender
+				events = p.handleAppClosed()
+			}
+		}
+		p.mu.Unlock()
+	case namespace == namespaceConnection && header.Type == "CLOSE":
+		p.mu.Lock()
+		events = p.handleAppClosed()
+		p.mu.Unlock()
+	}
+	p.queueEvents(events)
+}
+
+// must be called with lock held
+func (p *CastPlayer) handleMediaStatus(s *mediaStatus) []func() {
+	if p.mediaSessionID == 0 || s.MediaSessionID != p.mediaSessionID {
+		return nil // status of a stopped or old media session
+	}
+	var events []func()
+
+	if s.CurrentItemID != 0 && s.CurrentItemID != p.curItemID {
+		if p.curItemID != 0 {
+			// receiver advanced to the next queued item
+			p.curTrackMeta = p.nextTrackMeta
+			p.nextTrackMeta = mediaprovider.MediaItemMetadata{}
+			p.nextItemID = 0
+			p.nextURL = ""
+			events = append(events, p.InvokeOnTrackChange)
+		}
+		p.curItemID = s.CurrentItemID
+	}
+	if p.nextItemID == 0 && p.nextURL != "" && !p.queueFailed {
+		// queue item IDs are assigned in increasing order
+		for _, item := range s.Items {
+			if item.ItemID > p.curItemID {
+				p.nextItemID = item.ItemID
+				break
+			}
+		}
+	}
+
+	if !p.seeking {
+		p.lastPos = s.CurrentTime
+		p.lastPosTime = time.Now()
+	}
+
+	newState := p.state
+	switch s.PlayerState {
+	case "PLAYING", "BUFFERING":
+		newState = playing
+	case "PAUSED":
+		newState = paused
+	case "IDLE":
+		switch s.IdleReason {
+		case "FINISHED":
+			if p.queueFailed && p.nextURL != "" {
+				url, meta := p.nextURL, p.nextTrackMeta
+				events = append(events, func() { p.loadNext(url, meta) })
+				return events
+			}
+			newState = stopped
+		case "CANCELLED", "INTERRUPTED", "ERROR":
+			newState = stopped
+		default:
+			// transitional state while loading an item
+			return events
+		}
+	}
+
+	if newState != p.state {
+		switch newState {
+		case playing:
+			p.state = playing
+			events = append(events, p.InvokeOnPlaying)
+		case paused:
+			p.state = paused
+			events = append(events, p.InvokeOnPaused)
+		case stopped:
+			p.resetMediaSession()
+			events = append(events, p.InvokeOnStopped)
+		}
+	}
+	return events
+}
+
+// loadNext plays the next track after the current one finished
+// on devices that don't support queueing.
+func (p *CastPlayer) loadNext(url string, meta mediaprovider.MediaItemMetadata) {
+	if err := p.PlayFile(url, meta, 0); err != nil {
+		log.Printf("cast: failed to play next track: %v", err)
+		p.mu.Lock()
+		p.resetMediaSession()
+		p.mu.Unlock()
+		p.InvokeOnStopped()
+	}
+}
+
+// must be called with lock held
+func (p *CastPlayer) handleAppClosed() []func() {
+	if p.transportID == "" {
+		return nil
+	}
+	p.transportID = ""
+	p.sessionID = ""
+	wasStopped := p.state == stopped
+	p.resetMediaSession()
+	if wasStopped {
+		return nil
+	}
+	return []func(){p.InvokeOnStopped}
+}
+
+// handleClose is called when the connection to the device is lost.
+// The next request reconnects to it.
+func (p *CastPlayer) handleClose() {
+	p.mu.Lock()
+	if p.destroyed || p.ch == nil || !p.ch.isClosed() {
+		// destroyed, still connecting, or already reconnected
+		p.mu.Unlock()
+		return
+	}
+	events := p.handleAppClosed()
+	p.mu.Unlock()
+	p.queueEvents(events)
+}
@@ -0,0 +1,453 @@
+package cast
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/util"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestCastMessageRoundTrip(t *testing.T) {
+	msg := &castMessage{
+		SourceID:      "sender-0",
+		DestinationID: "receiver-0",
+		Namespace:     namespaceReceiver,
+		PayloadUTF8:   `{"type":"GET_STATUS","requestId":1}`,
+	}
+	var buf bytes.Buffer
+	if err := writeMessage(&buf, msg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("got %+v, want %+v", got, msg)
+	}
+
+	// unknown fields must be skipped
+	b := appendVarintField(msg.marshal(), 15, 42)
+	b = appendBytesField(b, 16, []byte("unknown"))
+	if err := got.unmarshal(b); err != nil || !reflect.DeepEqual(got, msg) {
+		t.Errorf("failed to skip unknown fields: %v", err)
+	}
+
+	if err := got.unmarshal(b[:len(b)-3]); err == nil {
+		t.Error("expected error for truncated message")
+	}
+}
+
+func TestParseDiscoveryResponse(t *testing.T) {
+	instance := dnsmessage.MustNewName("Chromecast-abc123._googlecast._tcp.local.")
+	host := dnsmessage.MustNewName("abc123.local.")
+	hdr := func(name dnsmessage.Name, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	b.StartAnswers()
+	b.PTRResource(hdr(dnsmessage.MustNewName(castServiceName), dnsmessage.TypePTR),
+		dnsmessage.PTRResource{PTR: instance})
+	b.StartAdditionals()
+	b.SRVResource(hdr(instance, dnsmessage.TypeSRV), dnsmessage.SRVResource{Target: host, Port: 8009})
+	b.TXTResource(hdr(instance, dnsmessage.TypeTXT),
+		dnsmessage.TXTResource{TXT: []string{"id=abc123", "md=Chromecast Audio", "fn=Living Room"}})
+	b.AResource(hdr(host, dnsmessage.TypeA), dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}})
+	pkt, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := newDiscoveryResult()
+	res.parseResponse(pkt, net.IPv4(192, 168, 1, 99))
+	devices := res.devices()
+	want := Device{Name: "Living Room", Model: "Chromecast Audio", ID: "abc123", Addr: "192.168.1.20:8009"}
+	if len(devices) != 1 || devices[0] != want {
+		t.Errorf("got %+v, want %+v", devices, want)
+	}
+}
+
+func TestCastPlayer(t *testing.T) {
+	musicServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("audio data"))
+	}))
+	defer musicServer.Close()
+
+	recv := newFakeReceiver(t)
+	defer recv.Close()
+
+	p, err := NewCastPlayer(Device{Name: "Fake", Addr: recv.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Destroy()
+
+	var mu sync.Mutex
+	var events []string
+	addEvent := func(e string) func() {
+		return func() {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		}
+	}
+	p.OnPlaying(addEvent("playing"))
+	p.OnPaused(addEvent("paused"))
+	p.OnStopped(addEvent("stopped"))
+	p.OnSeek(addEvent("seek"))
+	p.OnTrackChange(addEvent("trackchange"))
+	waitForEvents := func(want ...string) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for {
+			mu.Lock()
+			got := strings.Join(events, ",")
+			mu.Unlock()
+			if got == strings.Join(want, ",") {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("got events %q, want %q", got, strings.Join(want, ","))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if v := p.GetVolume(); v != 40 {
+		t.Errorf("initial volume: got %d, want 40", v)
+	}
+
+	meta := mediaprovider.MediaItemMetadata{Name: "Track 1", MIMEType: "audio/flac", Duration: 3 * time.Minute}
+	if err := p.PlayFile(musicServer.URL+"/stream?id=1", meta, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("playing", "trackchange")
+
+	contentID := recv.ContentID(0)
+	if contentID == "" || strings.HasPrefix(contentID, musicServer.URL) {
+		t.Fatalf("expected media to be loaded through the proxy, got %q", contentID)
+	}
+	if _, err := util.GetLocalIP(); err == nil {
+		resp, err := http.Get(contentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(b) != "audio data" {
+			t.Errorf("proxy returned %q", b)
+		}
+	}
+
+	if err := p.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SeekSeconds(42); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("playing", "trackchange", "paused", "seek")
+	if s := p.GetStatus(); s.State != player.Paused || s.TimePos != 42 || s.Duration != 180 {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	if err := p.Continue(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetVolume(75); err != nil {
+		t.Fatal(err)
+	}
+	if v := recv.Volume(); v != 0.75 {
+		t.Errorf("receiver volume: got %v, want 0.75", v)
+	}
+
+	next := mediaprovider.MediaItemMetadata{Name: "Track 2", Duration: 2 * time.Minute}
+	if err := p.SetNextFile(musicServer.URL+"/stream?id=2", next); err != nil {
+		t.Fatal(err)
+	}
+	if recv.ContentID(1) == "" {
+		t.Fatal("expected next track to be queued")
+	}
+
+	// receiver advances to the queued track by itself
+	recv.AdvanceQueue()
+	waitForEvents("playing", "trackchange", "paused", "seek", "playing", "trackchange")
+	if s := p.GetStatus(); s.State != player.Playing || s.Duration != 120 {
+		t.Errorf("unexpected status after track change %+v", s)
+	}
+
+	// and finishes the queue
+	recv.FinishQueue()
+	waitForEvents("playing", "trackchange", "paused", "seek", "playing", "trackchange", "stopped")
+	if s := p.GetStatus(); s.State != player.Stopped {
+		t.Errorf("expected stopped, got %+v", s)
+	}
+
+	// the connection drops, and is re-established by the next command
+	recv.DropConnection()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		p.mu.Lock()
+		closed := p.ch.isClosed()
+		p.mu.Unlock()
+		if closed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("connection drop not detected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := p.PlayFile(musicServer.URL+"/stream?id=3", meta, 0); err != nil {
+		t.Fatalf("failed to play after reconnecting: %v", err)
+	}
+	waitForEvents("playing", "trackchange", "paused", "seek", "playing", "trackchange", "stopped", "playing", "trackchange")
+}
+
+// fakeReceiver speaks enough of the CASTV2 protocol
+// to emulate the Default Media Receiver on a Cast device.
+type fakeReceiver struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu          sync.Mutex
+	conn        net.Conn
+	launched    bool
+	volume      float64
+	sessionID   int
+	items       []int
+	contentIDs  []string
+	currentItem int
+	playerState string
+	idleReason  string
+	currentTime float64
+}
+
+func newFakeReceiver(t *testing.T) *fakeReceiver {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{selfSignedCert(t)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeReceiver{t: t, listener: l, volume: 0.4}
+	go r.serve()
+	return r
+}
+
+func (r *fakeReceiver) Addr() string { return r.listener.Addr().String() }
+
+func (r *fakeReceiver) Close() { r.listener.Close() }
+
+func (r *fakeReceiver) Volume() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.volume
+}
+
+func (r *fakeReceiver) ContentID(i int) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i < len(r.contentIDs) {
+		return r.contentIDs[i]
+	}
+	return ""
+}
+
+func (r *fakeReceiver) AdvanceQueue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.currentItem = r.items[len(r.items)-1]
+	r.currentTime = 0
+	r.sendMediaStatus(0)
+}
+
+func (r *fakeReceiver) FinishQueue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.playerState, r.idleReason = "IDLE", "FINISHED"
+	r.sendMediaStatus(0)
+}
+
+// DropConnection closes the connection to the player,
+// as if the device were rebooted or left the network.
+func (r *fakeReceiver) DropConnection() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conn.Close()
+}
+
+func (r *fakeReceiver) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		r.conn = conn
+		r.mu.Unlock()
+		go r.handleConn(conn)
+	}
+}
+
+func (r *fakeReceiver) handleConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		var req struct {
+			Type        string                     `json:"type"`
+			RequestID   int                        `json:"requestId"`
+			AppID       string                     `json:"appId"`
+			CurrentTime float64                    `json:"currentTime"`
+			Volume      struct{ Level float64 }    `json:"volume"`
+			Media       struct{ ContentID string } `json:"media"`
+			Items       []struct {
+				Media struct{ ContentID string } `json:"media"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(msg.PayloadUTF8), &req); err != nil {
+			r.t.Errorf("invalid payload: %v", err)
+			return
+		}
+
+		r.mu.Lock()
+		switch msg.Namespace {
+		case namespaceHeartbeat:
+			if req.Type == "PING" {
+				r.send(msg.SourceID, namespaceHeartbeat, map[string]any{"type": "PONG"})
+			}
+		case namespaceReceiver:
+			switch req.Type {
+			case "LAUNCH":
+				if req.AppID == defaultMediaReceiverAppID {
+					r.launched = true
+				}
+			case "SET_VOLUME":
+				r.volume = req.Volume.Level
+			}
+			r.sendReceiverStatus(req.RequestID)
+		case namespaceMedia:
+			if msg.DestinationID != "transport-1" {
+				r.t.Errorf("media request sent to %q", msg.DestinationID)
+			}
+			switch req.Type {
+			case "LOAD":
+				r.sessionID++
+				r.items = []int{1}
+				r.currentItem = 1
+				r.contentIDs = []string{req.Media.ContentID}
+				r.playerState, r.idleReason = "PLAYING", ""
+				r.currentTime = req.CurrentTime
+			case "QUEUE_INSERT":
+				for _, item := range req.Items {
+					r.items = append(r.items, r.items[len(r.items)-1]+1)
+					r.contentIDs = append(r.contentIDs, item.Media.ContentID)
+				}
+			case "PLAY":
+				r.playerState = "PLAYING"
+			case "PAUSE":
+				r.playerState = "PAUSED"
+			case "SEEK":
+				r.currentTime = req.CurrentTime
+			case "STOP":
+				r.playerState, r.idleReason = "IDLE", "CANCELLED"
+			}
+			r.sendMediaStatus(req.RequestID)
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *fakeReceiver) sendReceiverStatus(requestID int) {
+	apps := []map[string]any{}
+	if r.launched {
+		apps = append(apps, map[string]any{
+			"appId":       defaultMediaReceiverAppID,
+			"sessionId":   "session-1",
+			"transportId": "transport-1",
+		})
+	}
+	r.send(defaultSenderID, namespaceReceiver, map[string]any{
+		"type":      "RECEIVER_STATUS",
+		"requestId": requestID,
+		"status": map[string]any{
+			"applications": apps,
+			"volume":       map[string]any{"level": r.volume},
+		},
+	})
+}
+
+func (r *fakeReceiver) sendMediaStatus(requestID int) {
+	items := []map[string]any{}
+	for _, id := range r.items {
+		items = append(items, map[string]any{"itemId": id})
+	}
+	status := map[string]any{
+		"mediaSessionId": r.sessionID,
+		"playerState":    r.playerState,
+		"currentTime":    r.currentTime,
+		"currentItemId":  r.currentItem,
+		"items":          items,
+	}
+	if r.idleReason != "" {
+		status["idleReason"] = r.idleReason
+	}
+	dest := defaultSenderID
+	if requestID == 0 {
+		dest = "*" // broadcast
+	}
+	r.send(dest, namespaceMedia, map[string]any{
+		"type":      "MEDIA_STATUS",
+		"requestId": requestID,
+		"status":    []any{status},
+	})
+}
+
+// must be called with lock held
+func (r *fakeReceiver) send(dest, namespace string, payload any) {
+	b, _ := json.Marshal(payload)
+	writeMessage(r.conn, &castMessage{
+		SourceID:      "transport-1",
+		DestinationID: dest,
+		Namespace:     namespace,
+		PayloadUTF8:   string(b),
+	})
+}
+
+func selfSignedCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake-cast-device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
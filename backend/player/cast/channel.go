@@ -0,0 +1,225 @@
+package cast
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	namespaceConnection = "urn:x-cast:com.google.cast.tp.connection"
+	namespaceHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	namespaceReceiver   = "urn:x-cast:com.google.cast.receiver"
+	namespaceMedia      = "urn:x-cast:com.google.cast.media"
+
+	defaultSenderID   = "sender-0"
+	defaultReceiverID = "receiver-0"
+
+	heartbeatInterval = 5 * time.Second
+	// the connection is considered dead if nothing,
+	// not even a PONG, is received for this long
+	readTimeout = 3 * heartbeatInterval
+)
+
+var errChannelClosed = errors.New("cast: connection closed")
+
+// messageHeader contains the fields common to all JSON payloads.
+type messageHeader struct {
+	Type      string `json:"type"`
+	RequestID int    `json:"requestId,omitempty"`
+}
+
+// channel is a CASTV2 connection to a Cast device.
+// Requests are matched to their responses by requestId;
+// all received messages are additionally passed to onMessage,
+// since the receiver also broadcasts status updates unprompted.
+type channel struct {
+	conn      net.Conn
+	writeLock sync.Mutex
+
+	nextRequestID atomic.Int64
+	pendingLock   sync.Mutex
+	pending       map[int]chan []byte
+
+	onMessage messageHandler
+	onClose   func()
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+type messageHandler func(namespace string, header messageHeader, payload []byte)
+
+func dialChannel(ctx context.Context, addr string, onMessage messageHandler, onClose func()) (*channel, error) {
+	dialer := &tls.Dialer{
+		// Cast devices present certificates signed by Google's
+		// device CA, which can't be verified as a hostname certificate
+		Config: &tls.Config{InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &channel{
+		conn:      conn,
+		pending:   make(map[int]chan []byte),
+		onMessage: onMessage,
+		onClose:   onClose,
+		closed:    make(chan struct{}),
+	}
+	if err := c.connect(defaultReceiverID); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.readLoop()
+	go c.heartbeatLoop()
+	return c, nil
+}
+
+// connect opens a virtual connection to the given destination,
+// which is either the platform receiver or a running application.
+func (c *channel) connect(destID string) error {
+	return c.send(destID, namespaceConnection, map[string]any{"type": "CONNECT"})
+}
+
+func (c *channel) send(destID, namespace string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	msg := &castMessage{
+		ProtocolVersion: protocolVersionCastV2_1_0,
+		SourceID:        defaultSenderID,
+		DestinationID:   destID,
+		Namespace:       namespace,
+		PayloadType:     payloadTypeString,
+		PayloadUTF8:     string(b),
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	select {
+	case <-c.closed:
+		return errChannelClosed
+	default:
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return writeMessage(c.conn, msg)
+}
+
+// request sends the payload with a new requestId and waits for the response.
+func (c *channel) request(ctx context.Context, destID, namespace string, payload map[string]any) ([]byte, error) {
+	id := int(c.nextRequestID.Add(1))
+	payload["requestId"] = id
+	respChan := make(chan []byte, 1)
+
+	c.pendingLock.Lock()
+	c.pending[id] = respChan
+	c.pendingLock.Unlock()
+	defer func() {
+		c.pendingLock.Lock()
+		delete(c.pending, id)
+		c.pendingLock.Unlock()
+	}()
+
+	if err := c.send(destID, namespace, payload); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-respChan:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.closed:
+		return nil, errChannelClosed
+	}
+}
+
+func (c *channel) readLoop() {
+	defer c.Close()
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(readTimeout))
+		msg, err := readMessage(c.conn)
+		if err != nil {
+			select {
+			case <-c.closed:
+			default:
+				log.Printf("cast: connection lost: %v", err)
+			}
+			return
+		}
+		if msg.PayloadType != payloadTypeString {
+			continue
+		}
+		payload := []byte(msg.PayloadUTF8)
+		var header messageHeader
+		if err := json.Unmarshal(payload, &header); err != nil {
+			continue
+		}
+
+		switch {
+		case msg.Namespace == namespaceHeartbeat && header.Type == "PING":
+			c.send(msg.SourceID, namespaceHeartbeat, map[string]any{"type": "PONG"})
+			continue
+		case msg.Namespace == namespaceConnection && header.Type == "CLOSE" && msg.SourceID == defaultReceiverID:
+			return
+		}
+
+		if header.RequestID != 0 {
+			c.pendingLock.Lock()
+			respChan, ok := c.pending[header.RequestID]
+			c.pendingLock.Unlock()
+			if ok {
+				select {
+				case respChan <- payload:
+				default: // already responded
+				}
+			}
+		}
+		if c.onMessage != nil {
+			c.onMessage(msg.Namespace, header, payload)
+		}
+	}
+}
+
+func (c *channel) heartbeatLoop() {
+	t := time.NewTicker(heartbeatInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-t.C:
+			if err := c.send(defaultReceiverID, namespaceHeartbeat, map[string]any{"type": "PING"}); err != nil {
+				c.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *channel) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *channel) Close() {
+	c.closeOnce.Do(func() {
+		c.writeLock.Lock()
+		close(c.closed)
+		c.writeLock.Unlock()
+		c.conn.Close()
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+}
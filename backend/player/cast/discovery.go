@@ -0,0 +1,196 @@
+package cast
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const castServiceName = "_googlecast._tcp.local."
+
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Device is a Cast device discovered on the local network.
+type Device struct {
+	// Friendly name of the device, as set by the user
+	Name string
+	// Model name, e.g. "Chromecast Audio"
+	Model string
+	// Unique ID of the device
+	ID string
+	// host:port address of the CASTV2 endpoint
+	Addr string
+}
+
+// Discover searches the local network for Cast devices with mDNS,
+// returning the devices that responded within the wait duration.
+func Discover(ctx context.Context, wait time.Duration) ([]Device, error) {
+	// Queries sent from a port other than 5353 are answered by unicast
+	// to the sending port (RFC 6762 section 6.7), so there is no need
+	// to join the multicast group or share the mDNS port with
+	// any responder running on this machine.
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	queryID := uint16(rand.N(1 << 16))
+	query, err := buildQuery(queryID)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	res := newDiscoveryResult()
+	buf := make([]byte, 9000)
+	// re-send the query periodically since UDP is unreliable
+	for nextQuery := time.Now(); ; {
+		if now := time.Now(); !now.Before(nextQuery) {
+			if _, err := conn.WriteToUDP(query, mdnsAddr); err != nil {
+				return nil, err
+			}
+			nextQuery = now.Add(time.Second)
+		}
+		readDeadline := nextQuery
+		if deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+		conn.SetReadDeadline(readDeadline)
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return nil, err
+			}
+			if ctx.Err() != nil || !time.Now().Before(deadline) {
+				break
+			}
+			continue
+		}
+		res.parseResponse(buf[:n], src.IP)
+	}
+	return res.devices(), nil
+}
+
+func buildQuery(id uint16) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{
+		Name: dnsmessage.MustNewName(castServiceName),
+		Type: dnsmessage.TypePTR,
+		// request a unicast response (the "QU" bit)
+		Class: dnsmessage.ClassINET | 1<<15,
+	}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+type srvRecord struct {
+	target string
+	port   uint16
+}
+
+// discoveryResult accumulates the records of all mDNS responses,
+// since a responder may split a service's records across packets.
+type discoveryResult struct {
+	instances map[string]net.IP // instance name -> address of responder
+	srv       map[string]srvRecord
+	txt       map[string][]string
+	addrs     map[string]net.IP // host name -> address
+}
+
+func newDiscoveryResult() *discoveryResult {
+	return &discoveryResult{
+		instances: make(map[string]net.IP),
+		srv:       make(map[string]srvRecord),
+		txt:       make(map[string][]string),
+		addrs:     make(map[string]net.IP),
+	}
+}
+
+func (d *discoveryResult) parseResponse(b []byte, src net.IP) {
+	var p dnsmessage.Parser
+	h, err := p.Start(b)
+	if err != nil || !h.Response {
+		return
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+	var resources []dnsmessage.Resource
+	if answers, err := p.AllAnswers(); err == nil {
+		resources = append(resources, answers...)
+	}
+	if err := p.SkipAllAuthorities(); err == nil {
+		if additionals, err := p.AllAdditionals(); err == nil {
+			resources = append(resources, additionals...)
+		}
+	}
+
+	for _, r := range resources {
+		name := strings.ToLower(r.Header.Name.String())
+		switch body := r.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if name == castServiceName {
+				d.instances[strings.ToLower(body.PTR.String())] = src
+			}
+		case *dnsmessage.SRVResource:
+			d.srv[name] = srvRecord{target: strings.ToLower(body.Target.String()), port: body.Port}
+		case *dnsmessage.TXTResource:
+			d.txt[name] = body.TXT
+		case *dnsmessage.AResource:
+			d.addrs[name] = net.IP(body.A[:])
+		}
+	}
+}
+
+func (d *discoveryResult) devices() []Device {
+	var devices []Device
+	for instance, src := range d.instances {
+		srv, ok := d.srv[instance]
+		if !ok {
+			continue
+		}
+		ip := d.addrs[srv.target]
+		if ip == nil {
+			ip = src
+		}
+		dev := Device{
+			Name: strings.TrimSuffix(instance, "."+castServiceName),
+			Addr: net.JoinHostPort(ip.String(), strconv.Itoa(int(srv.port))),
+		}
+		for _, kv := range d.txt[instance] {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "fn":
+				dev.Name = v
+			case "md":
+				dev.Model = v
+			case "id":
+				dev.ID = v
+			}
+		}
+		devices = append(devices, dev)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Name < devices[j].Name
+	})
+	return devices
+}
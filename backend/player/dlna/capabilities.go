@@ -0,0 +1,180 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player/mediaproxy"
+	"github.com/supersonic-app/go-upnpcast/services"
+)
+
+const dlnaFlags = "DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+// DLNA profile names for formats that have one
+var dlnaProfiles = map[string]string{
+	"audio/mpeg":          "MP3",
+	"audio/vnd.dlna.adts": "AAC_ADTS_320",
+}
+
+// transcodeFormat is a format that tracks can be transcoded to
+// for renderers that cannot play the original format.
+type transcodeFormat struct {
+	mimeType    string
+	codec       string // format to request from the server
+	bitRateKBPS int
+}
+
+// in order of preference
+var transcodeFormats = []transcodeFormat{
+	{mimeType: "audio/mpeg", codec: "mp3", bitRateKBPS: 320},
+	{mimeType: "audio/flac", codec: "flac"},
+	{mimeType: "audio/vnd.dlna.adts", codec: "aac", bitRateKBPS: 256},
+	{mimeType: "audio/wav", codec: "wav"},
+}
+
+// formatCache holds the MIME types each renderer reported it can play,
+// keyed by device description URL, so they are only queried once.
+var (
+	formatCache     = make(map[string][]string)
+	formatCacheLock sync.Mutex
+)
+
+// rendererFormats returns the (normalized) MIME types the renderer can play,
+// or nil if the renderer did not report them.
+func rendererFormats(client *http.Client, descURL, connectionManagerURL string) []string {
+	formatCacheLock.Lock()
+	defer formatCacheLock.Unlock()
+	if formats, ok := formatCache[descURL]; ok {
+		return formats
+	}
+	if connectionManagerURL == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := soapCall(ctx, client, connectionManagerURL, services.ConnectionManager, "GetProtocolInfo", nil)
+	if err != nil {
+		// don't cache, so it is retried the next time the renderer is selected
+		return nil
+	}
+	formats := parseSinkProtocolInfo(out["Sink"])
+	formatCache[descURL] = formats
+	return formats
+}
+
+// parseSinkProtocolInfo returns the MIME types of the HTTP entries of a
+// comma-separated protocolInfo list, e.g. "http-get:*:audio/mpeg:*,..."
+func parseSinkProtocolInfo(sink string) []string {
+	var formats []string
+	for _, entry := range strings.Split(sink, ",") {
+		// protocol:network:contentFormat:additionalInfo
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 4)
+		if len(parts) != 4 || (parts[0] != "http-get" && parts[0] != "*") {
+			continue
+		}
+		format := parts[2]
+		if format != "*" {
+			format = mediaproxy.NormalizeMIMEType(format)
+		}
+		if !slices.Contains(formats, format) {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// supportsFormat returns true if the given MIME type is in the renderer's
+// formats, or if the format support of either is unknown.
+func supportsFormat(formats []string, mimeType string) bool {
+	if len(formats) == 0 || mimeType == "" {
+		return true
+	}
+	mimeType = mediaproxy.NormalizeMIMEType(mimeType)
+	for _, f := range formats {
+		if f == "*" || f == mimeType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(f, "*"); ok && strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseTranscodeFormat returns the format to transcode media of the given
+// MIME type to, or nil if the renderer can play it (or no common format exists).
+func chooseTranscodeFormat(formats []string, mimeType string) *transcodeFormat {
+	if supportsFormat(formats, mimeType) {
+		return nil
+	}
+	for i, tf := range transcodeFormats {
+		if supportsFormat(formats, tf.mimeType) {
+			return &transcodeFormats[i]
+		}
+	}
+	return nil
+}
+
+// mediaItem is a media item to be sent to the renderer.
+type mediaItem struct {
+	URL        string
+	MIMEType   string
+	Transcoded bool
+	Meta       mediaprovider.MediaItemMetadata
+}
+
+func protocolInfo(mimeType string, transcoded bool) string {
+	var features strings.Builder
+	if pn, ok := dlnaProfiles[mimeType]; ok {
+		features.WriteString("DLNA.ORG_PN=" + pn + ";")
+	}
+	if transcoded {
+		// live transcodes support neither byte range nor time seek requests
+		features.WriteString("DLNA.ORG_OP=00;DLNA.ORG_CI=1;")
+	} else {
+		features.WriteString("DLNA.ORG_OP=01;DLNA.ORG_CI=0;")
+	}
+	features.WriteString(dlnaFlags)
+	return fmt.Sprintf("http-get:*:%s:%s", mimeType, features.String())
+}
+
+// didlMetadata returns the DIDL-Lite document describing the item
+// sent with SetAVTransportURI and SetNextAVTransportURI.
+func didlMetadata(item *mediaItem) string {
+	var sb strings.Builder
+	sb.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`)
+	sb.WriteString(`<item id="1" parentID="0" restricted="1">`)
+	writeElement(&sb, "dc:title", item.Meta.Name)
+	for _, artist := range item.Meta.Artists {
+		writeElement(&sb, "upnp:artist", artist)
+	}
+	writeElement(&sb, "upnp:album", item.Meta.Album)
+	sb.WriteString("<upnp:class>object.item.audioItem.musicTrack</upnp:class>")
+	fmt.Fprintf(&sb, `<res protocolInfo="%s"`, xmlEscape(protocolInfo(item.MIMEType, item.Transcoded)))
+	if d := item.Meta.Duration; d > 0 {
+		fmt.Fprintf(&sb, ` duration="%d:%02d:%02d"`, int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+	}
+	fmt.Fprintf(&sb, ">%s</res>", xmlEscape(item.URL))
+	sb.WriteString("</item></DIDL-Lite>")
+	return sb.String()
+}
+
+func writeElement(sb *strings.Builder, name, value string) {
+	if value != "" {
+		fmt.Fprintf(sb, "<%s>%s</%s>", name, xmlEscape(value), name)
+	}
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
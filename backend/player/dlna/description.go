@@ -0,0 +1,108 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/supersonic-app/go-upnpcast/services"
+)
+
+// rendererServices holds the absolute URLs of the renderer services
+// that are not exposed by the go-upnpcast clients.
+type rendererServices struct {
+	avTransportControl       string
+	avTransportEvents        string
+	renderingControlEvents   string
+	connectionManagerControl string
+}
+
+type deviceDescription struct {
+	DeviceType  string `xml:"deviceType"`
+	ServiceList struct {
+		Services []struct {
+			Type        string `xml:"serviceType"`
+			ControlURL  string `xml:"controlURL"`
+			EventSubURL string `xml:"eventSubURL"`
+		} `xml:"service"`
+	} `xml:"serviceList"`
+	DeviceList struct {
+		Devices []deviceDescription `xml:"device"`
+	} `xml:"deviceList"`
+}
+
+// fetchServices reads the renderer's device description
+// and returns the URLs of its services.
+func fetchServices(ctx context.Context, client *http.Client, descURL string) (rendererServices, error) {
+	var svcs rendererServices
+	base, err := url.Parse(descURL)
+	if err != nil {
+		return svcs, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, descURL, nil)
+	if err != nil {
+		return svcs, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return svcs, err
+	}
+	defer resp.Body.Close()
+
+	var root struct {
+		Device deviceDescription `xml:"device"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return svcs, err
+	}
+	mr := findMediaRenderer(&root.Device)
+	if mr == nil {
+		return svcs, errors.New("no MediaRenderer device found")
+	}
+	for _, s := range mr.ServiceList.Services {
+		control := resolveServiceURL(base, s.ControlURL)
+		events := resolveServiceURL(base, s.EventSubURL)
+		switch s.Type {
+		case services.AVTransport:
+			svcs.avTransportControl = control
+			svcs.avTransportEvents = events
+		case services.RenderingControl:
+			svcs.renderingControlEvents = events
+		case services.ConnectionManager:
+			svcs.connectionManagerControl = control
+		}
+	}
+	return svcs, nil
+}
+
+// resolveServiceURL resolves a service URL from the device description
+// against the host of the description URL, the same way go-upnpcast does.
+func resolveServiceURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if !u.IsAbs() && !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	return base.ResolveReference(u).String()
+}
+
+func findMediaRenderer(d *deviceDescription) *deviceDescription {
+	// MediaRenderer can be either at root or nested
+	if strings.HasPrefix(d.DeviceType, "urn:schemas-upnp-org:device:MediaRenderer:") {
+		return d
+	}
+	for i := range d.DeviceList.Devices {
+		if mr := findMediaRenderer(&d.DeviceList.Devices[i]); mr != nil {
+			return mr
+		}
+	}
+	return nil
+}
@@ -2,15 +2,11 @@ package dlna
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -18,9 +14,11 @@ import (
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/mediaproxy"
 	"github.com/dweymouth/supersonic/backend/util"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/supersonic-app/go-upnpcast/device"
+	"github.com/supersonic-app/go-upnpcast/services"
 	"github.com/supersonic-app/go-upnpcast/services/avtransport"
 	"github.com/supersonic-app/go-upnpcast/services/renderingcontrol"
 )
@@ -31,26 +29,43 @@ const (
 	paused  = 2
 )
 
-type proxyMapEntry struct {
-	key string
-	url string
-}
+const (
+	// renderers commonly report STOPPED while switching media;
+	// such events are ignored for this long after loading a track
+	loadGracePeriod = 3 * time.Second
+	// a STOPPED event this close to the end of the track is
+	// treated as the track having finished
+	trackEndTolerance = 10 * time.Second
+)
 
 type DLNAPlayer struct {
 	player.BasePlayerCallbackImpl
 
-	destroyed     bool
+	destroyed     atomic.Bool
 	cancelRequest context.CancelFunc
 
+	client        *http.Client
+	services      rendererServices
 	avTransport   *avtransport.Client
 	renderControl *renderingcontrol.Client
 
-	state   int // stopped, playing, paused
-	seeking bool
+	// MIME types the renderer can play, or nil if unknown
+	formats []string
+
+	// guards state, lastStartTime and stopwatch, which are changed both
+	// by player commands and renderer events. Acquire after metaLock.
+	stateLock sync.Mutex
+	state     int // stopped, playing, paused
+	seeking   bool
 
 	metaLock      sync.Mutex
 	curTrackMeta  mediaprovider.MediaItemMetadata
 	nextTrackMeta mediaprovider.MediaItemMetadata
+	// proxied URLs of the current and next track, used to
+	// recognize track changes reported in renderer events
+	curTrackURL  string
+	nextTrackURL string
+	lastLoad     time.Time
 
 	// if true, report playback time 00:00
 	// pending time sync with player after beginning playback
@@ -60,28 +75,28 @@ type DLNAPlayer struct {
 	// how long the track has been playing since last time sync
 	stopwatch util.Stopwatch
 
-	proxyServer *http.Server
-	proxyActive atomic.Bool
-	localIP     string
-	proxyPort   int
+	proxy mediaproxy.Server
 
 	pendingSeek     bool
 	pendingSeekSecs float64
 
-	// keep in order of most recently accessed at the end
-	// that way the item in proxyURLs[0] can be kicked out
-	// when adding a new URL to the proxy, since
-	// only two will need to be active at any given time
-	proxyURLs    [3]proxyMapEntry
-	proxyURLLock sync.Mutex
-
 	// If SetNextAVTransport fails (e.g. because the device
 	// does not support the API/gapless), this flag is set
 	// true, and the next firing of the track change timer
 	// should clear it to false and use SetAVTransport
 	// to begin playing the item in nextTrackMeta.
 	failedToSetNext    bool
-	unsetNextMediaItem *avtransport.MediaItem
+	unsetNextMediaItem *mediaItem
+
+	// If the renderer accepts GENA event subscriptions, state changes
+	// are driven by its events. Otherwise, track changes are inferred
+	// by the track change timer and periodic position polling.
+	events     *eventListener
+	subscribed atomic.Bool
+
+	volume         atomic.Int32
+	volumeKnown    atomic.Bool
+	onVolumeChange func(int)
 
 	timerActive atomic.Bool
 	timer       *time.Timer
@@ -113,27 +128,125 @@ func NewDLNAPlayer(device *device.MediaRenderer) (*DLNAPlayer, error) {
 		return nil, fmt.Errorf("failed to connect to %s", device.FriendlyName)
 	}
 
-	return &DLNAPlayer{
+	svcs, err := fetchServices(ctx, cli, device.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device description of %s: %w", device.FriendlyName, err)
+	}
+
+	d := &DLNAPlayer{
+		client:        cli,
+		services:      svcs,
 		avTransport:   avt,
 		renderControl: rc,
+		formats:       rendererFormats(cli, device.URL, svcs.connectionManagerControl),
 		resetChan:     make(chan time.Duration),
-	}, nil
+	}
+	if err := d.subscribeEvents(ctx); err != nil {
+		log.Printf("DLNA event subscription failed for %s, falling back to polling: %v", device.FriendlyName, err)
+	}
+	return d, nil
+}
+
+func (d *DLNAPlayer) subscribeEvents(ctx context.Context) error {
+	avtURL, rcURL := d.services.avTransportEvents, d.services.renderingControlEvents
+	if avtURL == "" {
+		return errors.New("renderer has no AVTransport event URL")
+	}
+	l, err := newEventListener(d.client, avtURL, d.handleEvent, d.handleSubscriptionChange)
+	if err != nil {
+		return err
+	}
+	if err := l.Subscribe(ctx, eventAVTransport, avtURL); err != nil {
+		l.Close()
+		return err
+	}
+	if rcURL != "" {
+		if err := l.Subscribe(ctx, eventRenderingControl, rcURL); err != nil {
+			// volume will be queried from the renderer instead
+			log.Printf("DLNA RenderingControl event subscription failed: %v", err)
+		}
+	}
+	d.events = l
+	d.subscribed.Store(true)
+	return nil
+}
+
+// handleSubscriptionChange falls back to inferring track changes
+// with the track change timer while AVTransport events are lost.
+func (d *DLNAPlayer) handleSubscriptionChange(service string, active bool) {
+	if service != eventAVTransport || d.destroyed.Load() {
+		return
+	}
+	if active {
+		// stop the timer before events take over again
+		d.setTrackChangeTimer(0)
+		d.subscribed.Store(true)
+		return
+	}
+	d.subscribed.Store(false)
+	if d.getState() == playing {
+		d.metaLock.Lock()
+		nextTrackChange := d.curTrackMeta.Duration - d.curPlayPos()
+		d.metaLock.Unlock()
+		d.setTrackChangeTimer(nextTrackChange)
+	}
+}
+
+// TranscodeFor returns the transcode to request from the server for media
+// of the given MIME type, or nil if the renderer can play it as is.
+func (d *DLNAPlayer) TranscodeFor(mimeType string) *mediaprovider.TranscodeSettings {
+	tf := chooseTranscodeFormat(d.formats, mimeType)
+	if tf == nil {
+		return nil
+	}
+	return &mediaprovider.TranscodeSettings{Codec: tf.codec, BitRateKBPS: tf.bitRateKBPS}
+}
+
+// newMediaItem adds the URL to the proxy and returns the item to send
+// to the renderer, transcoded if the renderer can't play its format.
+func (d *DLNAPlayer) newMediaItem(url string, meta mediaprovider.MediaItemMetadata) *mediaItem {
+	item := &mediaItem{MIMEType: meta.MIMEType, Meta: meta}
+	if item.MIMEType == "" {
+		// e.g. radio streams
+		item.MIMEType = "audio/mpeg"
+	}
+	if tf := chooseTranscodeFormat(d.formats, meta.MIMEType); tf != nil {
+		item.MIMEType = tf.mimeType
+		item.Transcoded = true
+		item.URL = d.proxy.AddTranscodedURL(url, tf.mimeType)
+	} else {
+		item.URL = d.proxy.AddURL(url)
+	}
+	return item
+}
+
+// OnVolumeChange registers a callback which is invoked when
+// the volume is changed on the renderer.
+func (d *DLNAPlayer) OnVolumeChange(cb func(int)) {
+	d.onVolumeChange = cb
 }
 
 func (d *DLNAPlayer) SetVolume(vol int) error {
-	if d.destroyed {
+	if d.destroyed.Load() {
 		return nil
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	d.cancelRequest = cancel
 	defer cancel()
-	return d.renderControl.SetVolume(ctx, vol)
+	if err := d.renderControl.SetVolume(ctx, vol); err != nil {
+		return err
+	}
+	d.volume.Store(int32(vol))
+	return nil
 }
 
 func (d *DLNAPlayer) GetVolume() int {
-	if d.destroyed {
+	if d.destroyed.Load() {
 		return 0
 	}
+	if d.volumeKnown.Load() {
+		return int(d.volume.Load())
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	d.cancelRequest = cancel
 	defer cancel()
@@ -142,50 +255,47 @@ func (d *DLNAPlayer) GetVolume() int {
 }
 
 func (d *DLNAPlayer) PlayFile(urlstr string, meta mediaprovider.MediaItemMetadata, startTime float64) error {
-	if d.destroyed {
+	if d.destroyed.Load() {
 		return nil
 	}
 
-	d.ensureSetupProxy()
+	d.proxy.EnsureStarted()
 
+	media := d.newMediaItem(urlstr, meta)
 	d.metaLock.Lock()
 	d.curTrackMeta = meta
+	d.curTrackURL = media.URL
+	d.nextTrackURL = ""
 	d.metaLock.Unlock()
-	key := d.addURLToProxy(urlstr)
-
-	media := avtransport.MediaItem{
-		URL:         d.urlForItem(key),
-		Title:       meta.Name,
-		ContentType: meta.MIMEType,
-		Seekable:    true,
-	}
 
-	if err := d.playAVTransportMedia(&media); err != nil {
+	if err := d.playAVTransportMedia(media); err != nil {
 		return err
 	}
 	d.pendingPlayStart = true
 	if startTime > 0 {
 		// TODO: do something better than this!!
 		time.Sleep(2 * time.Second)
-		if !d.destroyed {
+		if !d.destroyed.Load() {
 			d.sendSeekCmd(startTime)
 		}
 		d.pendingPlayStart = false
 	} else {
 		go func() {
 			time.Sleep(2 * time.Second)
-			if !d.destroyed {
+			if !d.destroyed.Load() {
 				d.syncPlaybackTime()
 			}
 			d.pendingPlayStart = false
 		}()
 	}
-	d.state = playing
 	remainingDur := meta.Duration - time.Duration(startTime)*time.Second
 	d.setTrackChangeTimer(remainingDur)
+	d.stateLock.Lock()
+	d.state = playing
 	d.stopwatch.Reset()
 	d.stopwatch.Start()
 	d.lastStartTime = int(startTime)
+	d.stateLock.Unlock()
 	d.InvokeOnPlaying()
 	d.InvokeOnTrackChange()
 	if startTime > 0 {
@@ -195,12 +305,19 @@ func (d *DLNAPlayer) PlayFile(urlstr string, meta mediaprovider.MediaItemMetadat
 	return nil
 }
 
-func (d *DLNAPlayer) playAVTransportMedia(media *avtransport.MediaItem) error {
+func (d *DLNAPlayer) playAVTransportMedia(media *mediaItem) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	d.cancelRequest = cancel
 	defer cancel()
 
-	err := d.avTransport.SetAVTransportMedia(ctx, media)
+	d.metaLock.Lock()
+	d.lastLoad = time.Now()
+	d.metaLock.Unlock()
+	_, err := soapCall(ctx, d.client, d.services.avTransportControl, services.AVTransport, "SetAVTransportURI", []soapArg{
+		{Name: "InstanceID", Value: "0"},
+		{Name: "CurrentURI", Value: media.URL},
+		{Name: "CurrentURIMetaData", Value: didlMetadata(media)},
+	})
 	if err != nil {
 		return err
 	}
@@ -211,33 +328,36 @@ func (d *DLNAPlayer) playAVTransportMedia(media *avtransport.MediaItem) error {
 }
 
 func (d *DLNAPlayer) SetNextFile(url string, meta mediaprovider.MediaItemMetadata) error {
-	if d.destroyed {
+	if d.destroyed.Load() {
 		return nil
 	}
 
-	var media *avtransport.MediaItem
+	media := &mediaItem{}
 	d.metaLock.Lock()
 	d.nextTrackMeta = meta
 	d.metaLock.Unlock()
 	if url != "" {
-		d.ensureSetupProxy()
-
-		key := d.addURLToProxy(url)
-		media = &avtransport.MediaItem{
-			URL:         d.urlForItem(key),
-			ContentType: meta.MIMEType,
-			Title:       meta.Name,
-			Seekable:    true,
-		}
-	} else {
-		// empty media item to signify erasing next track in device queue
-		media = &avtransport.MediaItem{}
+		d.proxy.EnsureStarted()
+		media = d.newMediaItem(url, meta)
 	}
 
+	d.metaLock.Lock()
+	d.nextTrackURL = media.URL
+	d.metaLock.Unlock()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	d.cancelRequest = cancel
 	defer cancel()
-	err := d.avTransport.SetNextAVTransportMedia(ctx, media)
+	var metadata string
+	if media.URL != "" {
+		metadata = didlMetadata(media)
+	}
+	// an empty URI erases the next track in the device queue
+	_, err := soapCall(ctx, d.client, d.services.avTransportControl, services.AVTransport, "SetNextAVTransportURI", []soapArg{
+		{Name: "InstanceID", Value: "0"},
+		{Name: "NextURI", Value: media.URL},
+		{Name: "NextURIMetaData", Value: metadata},
+	})
 	if err != nil {
 		d.metaLock.Lock()
 		d.failedToSetNext = true
@@ -248,7 +368,7 @@ func (d *DLNAPlayer) SetNextFile(url string, meta mediaprovider.MediaItemMetadat
 }
 
 func (d *DLNAPlayer) Continue() error {
-	if d.destroyed || d.state == playing {
+	if d.destroyed.Load() || d.getState() == playing {
 		return nil
 	}
 
@@ -270,15 +390,17 @@ func (d *DLNAPlayer) Continue() error {
 	d.metaLock.Lock()
 	nextTrackChange := d.curTrackMeta.Duration - d.curPlayPos()
 	d.metaLock.Unlock()
-	d.state = playing
 	d.setTrackChangeTimer(nextTrackChange)
+	d.stateLock.Lock()
+	d.state = playing
 	d.stopwatch.Start()
+	d.stateLock.Unlock()
 	d.InvokeOnPlaying()
 	return nil
 }
 
 func (d *DLNAPlayer) Pause() error {
-	if d.destroyed || d.state != playing {
+	if d.destroyed.Load() || d.getState() != playing {
 		return nil
 	}
 
@@ -289,21 +411,23 @@ func (d *DLNAPlayer) Pause() error {
 		return err
 	}
 	d.setTrackChangeTimer(0)
+	d.stateLock.Lock()
 	d.stopwatch.Stop()
 	d.state = paused
+	d.stateLock.Unlock()
 	d.InvokeOnPaused()
 	return nil
 }
 
 func (d *DLNAPlayer) Stop(force bool) error {
-	if d.destroyed {
+	if d.destroyed.Load() {
 		return nil
 	}
 	if force && d.cancelRequest != nil {
 		d.cancelRequest()
 	}
 
-	switch d.state {
+	switch d.getState() {
 	case stopped:
 		return nil
 	case playing:
@@ -323,9 +447,11 @@ func (d *DLNAPlayer) Stop(force bool) error {
 		fallthrough
 	case paused:
 		d.setTrackChangeTimer(0)
+		d.stateLock.Lock()
 		d.stopwatch.Reset()
 		d.lastStartTime = 0
 		d.state = stopped
+		d.stateLock.Unlock()
 		d.InvokeOnStopped()
 		return nil
 	default:
@@ -334,11 +460,11 @@ func (d *DLNAPlayer) Stop(force bool) error {
 }
 
 func (d *DLNAPlayer) SeekSeconds(secs float64) error {
-	if d.destroyed {
+	if d.destroyed.Load() {
 		return nil
 	}
 
-	if d.state == paused {
+	if d.getState() == paused {
 		d.pendingSeek = true
 		d.pendingSeekSecs = secs
 	} else {
@@ -347,22 +473,27 @@ func (d *DLNAPlayer) SeekSeconds(secs float64) error {
 		}
 	}
 
+	d.stateLock.Lock()
 	d.lastStartTime = int(secs)
 	d.stopwatch.Reset()
+	isPlaying := d.state == playing
+	if isPlaying {
+		d.stopwatch.Start()
+	}
+	d.stateLock.Unlock()
 
-	if d.state == playing {
+	if isPlaying {
 		d.metaLock.Lock()
 		nextTrackChange := d.curTrackMeta.Duration - time.Duration(secs)*time.Second
 		d.metaLock.Unlock()
 		d.setTrackChangeTimer(nextTrackChange)
-		d.stopwatch.Start()
 	}
 
 	d.InvokeOnSeek()
 
 	go func() {
 		time.Sleep(4 * time.Second)
-		if !d.destroyed {
+		if !d.destroyed.Load() {
 			d.syncPlaybackTime()
 		}
 	}()
@@ -386,6 +517,12 @@ func (d *DLNAPlayer) IsSeeking() bool {
 }
 
 func (d *DLNAPlayer) GetStatus() player.Status {
+	d.metaLock.Lock()
+	duration := d.curTrackMeta.Duration.Seconds()
+	d.metaLock.Unlock()
+
+	d.stateLock.Lock()
+	defer d.stateLock.Unlock()
 	state := player.Stopped
 	if d.state == playing {
 		state = player.Playing
@@ -395,73 +532,69 @@ func (d *DLNAPlayer) GetStatus() player.Status {
 
 	var timePos float64
 	if !d.pendingPlayStart {
-		timePos = d.curPlayPos().Seconds()
+		timePos = d.curPlayPosLocked().Seconds()
 	}
 	return player.Status{
 		State:    state,
 		TimePos:  timePos,
-		Duration: d.curTrackMeta.Duration.Seconds(),
+		Duration: duration,
 	}
 }
 
+func (d *DLNAPlayer) getState() int {
+	d.stateLock.Lock()
+	defer d.stateLock.Unlock()
+	return d.state
+}
+
 func (d *DLNAPlayer) curPlayPos() time.Duration {
+	d.stateLock.Lock()
+	defer d.stateLock.Unlock()
+	return d.curPlayPosLocked()
+}
+
+// curPlayPosLocked must be called with stateLock held.
+func (d *DLNAPlayer) curPlayPosLocked() time.Duration {
 	return time.Duration(d.lastStartTime)*time.Second + d.stopwatch.Elapsed()
 }
 
 func (d *DLNAPlayer) Destroy() {
-	d.destroyed = true
+	d.destroyed.Store(true)
 	d.setTrackChangeTimer(0)
 	if d.cancelRequest != nil {
 		d.cancelRequest()
 	}
-
-	if d.proxyServer != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		go d.proxyServer.Shutdown(ctx)
-		d.proxyServer = nil
+	if d.events != nil {
+		d.events.Close()
 	}
+
+	d.proxy.Shutdown()
 }
 
 func (d *DLNAPlayer) syncPlaybackTime() {
 	start := time.Now()
 	if pos, err := d.avTransport.GetPositionInfo(context.Background()); err == nil {
+		d.metaLock.Lock()
+		duration := d.curTrackMeta.Duration
+		d.metaLock.Unlock()
+		d.stateLock.Lock()
 		d.lastStartTime = int(pos.RelTime.Seconds() + (time.Since(start) / 2).Seconds())
 		d.stopwatch.Reset()
 		if d.state == playing {
 			d.stopwatch.Start()
 		}
-		d.setTrackChangeTimer(d.curTrackMeta.Duration - time.Duration(d.lastStartTime)*time.Second)
+		remaining := duration - time.Duration(d.lastStartTime)*time.Second
+		d.stateLock.Unlock()
+		d.setTrackChangeTimer(remaining)
 		d.InvokeOnSeek()
 	}
 }
 
-func (d *DLNAPlayer) ensureSetupProxy() error {
-	if d.proxyActive.Swap(true) {
-		return nil // already active
-	}
-
-	var err error
-	d.localIP, err = util.GetLocalIP()
-	if err != nil {
-		return err
-	}
-
-	listener, err := net.Listen("tcp", ":0")
-	if err != nil {
-		return err
-	}
-	d.proxyPort = listener.Addr().(*net.TCPAddr).Port
-
-	d.proxyServer = &http.Server{
-		Handler: http.HandlerFunc(d.handleRequest),
-	}
-
-	go d.proxyServer.Serve(listener)
-	return nil
-}
-
 func (d *DLNAPlayer) setTrackChangeTimer(dur time.Duration) {
+	if d.subscribed.Load() {
+		// track changes are reported by renderer events
+		return
+	}
 	if d.timerActive.Swap(true) {
 		// was active
 		d.resetChan <- dur
@@ -514,12 +647,16 @@ func (d *DLNAPlayer) handleOnTrackChange() {
 	}
 	d.curTrackMeta = d.nextTrackMeta
 	d.nextTrackMeta = mediaprovider.MediaItemMetadata{}
+	d.curTrackURL = d.nextTrackURL
+	d.nextTrackURL = ""
 	nextTrackChange := d.curTrackMeta.Duration
 	d.metaLock.Unlock()
 
 	if stopping {
+		d.stateLock.Lock()
 		d.lastStartTime = 0
 		d.stopwatch.Reset()
+		d.stateLock.Unlock()
 		d.InvokeOnStopped()
 	} else {
 		d.metaLock.Lock()
@@ -533,121 +670,105 @@ func (d *DLNAPlayer) handleOnTrackChange() {
 			d.metaLock.Unlock()
 		}
 
+		d.stateLock.Lock()
 		d.lastStartTime = 0
 		d.stopwatch.Reset()
 		d.stopwatch.Start()
+		d.stateLock.Unlock()
 		d.setTrackChangeTimer(nextTrackChange)
 		d.InvokeOnTrackChange()
 
 		go func() {
 			time.Sleep(5 * time.Second)
-			if !d.destroyed {
+			if !d.destroyed.Load() {
 				d.syncPlaybackTime()
 			}
 		}()
 	}
 }
 
-func (d *DLNAPlayer) urlForItem(key string) string {
-	return fmt.Sprintf("http://%s:%d/%s", d.localIP, d.proxyPort, key)
-}
-
-func (d *DLNAPlayer) handleRequest(w http.ResponseWriter, r *http.Request) {
-	key := strings.TrimPrefix(r.URL.Path, "/")
-	url, _ := d.lookupProxyURL(key)
-
-	if url == "" {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("404"))
-		return
-	}
-
-	// if the url is a filepath for a local cached file, serve it
-	if info, err := os.Stat(url); err == nil && info.Size() > 0 {
-		http.ServeFile(w, r, url)
+func (d *DLNAPlayer) handleEvent(service string, vars map[string]string) {
+	if d.destroyed.Load() {
 		return
 	}
-
-	// Otherwise, proxy request to the music server
-	proxyReq, err := http.NewRequest(r.Method, url, r.Body)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	switch service {
+	case eventAVTransport:
+		d.handleTransportEvent(vars)
+	case eventRenderingControl:
+		if vol, err := strconv.Atoi(vars["Volume"]); err == nil {
+			old := d.volume.Swap(int32(vol))
+			wasKnown := d.volumeKnown.Swap(true)
+			if wasKnown && old != int32(vol) && d.onVolumeChange != nil {
+				d.onVolumeChange(vol)
+			}
+		}
 	}
+}
 
-	// Copy headers from the original request to the new request
-	proxyReq.Header = r.Header
-
-	// Create an HTTP client and send the request
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
-		return
+func (d *DLNAPlayer) handleTransportEvent(vars map[string]string) {
+	uri, ok := vars["CurrentTrackURI"]
+	if !ok {
+		uri, ok = vars["AVTransportURI"]
 	}
-	defer resp.Body.Close()
-
-	// Copy headers from the response to the writer
-	for name, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(name, value)
+	if ok && uri != "" {
+		d.metaLock.Lock()
+		advanced := uri != d.curTrackURL && uri == d.nextTrackURL
+		d.metaLock.Unlock()
+		if advanced {
+			// renderer moved on to the track set with SetNextAVTransportURI
+			d.handleOnTrackChange()
 		}
 	}
 
-	// Set the status code
-	w.WriteHeader(resp.StatusCode)
-
-	// Copy the response body to the writer
-	_, err = io.Copy(w, resp.Body)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error copying response body:", err)
+	switch vars["TransportState"] {
+	case "PLAYING":
+		d.stateLock.Lock()
+		changed := d.state != playing
+		if changed {
+			d.state = playing
+			d.stopwatch.Start()
+		}
+		d.stateLock.Unlock()
+		if changed {
+			d.InvokeOnPlaying()
+		}
+	case "PAUSED_PLAYBACK":
+		d.stateLock.Lock()
+		changed := d.state == playing
+		if changed {
+			d.state = paused
+			d.stopwatch.Stop()
+		}
+		d.stateLock.Unlock()
+		if changed {
+			d.InvokeOnPaused()
+		}
+	case "STOPPED", "NO_MEDIA_PRESENT":
+		d.handleStoppedEvent()
 	}
 }
 
-func (d *DLNAPlayer) addURLToProxy(url string) string {
-	hash := md5.Sum([]byte(url))
-	key := base64.StdEncoding.EncodeToString(hash[:])
-	d.proxyURLLock.Lock()
-	defer d.proxyURLLock.Unlock()
-	d._updateProxyURL(key, url)
-	return key
-}
-
-// lookupProxyURL finds a URL by key and updates its position to most recently used
-func (d *DLNAPlayer) lookupProxyURL(key string) (string, bool) {
-	d.proxyURLLock.Lock()
-	defer d.proxyURLLock.Unlock()
+func (d *DLNAPlayer) handleStoppedEvent() {
+	d.metaLock.Lock()
+	loading := time.Since(d.lastLoad) < loadGracePeriod
+	atTrackEnd := d.curTrackMeta.Duration-d.curPlayPos() < trackEndTolerance
+	// the renderer could not queue the next track, so it must be started now
+	playNext := d.failedToSetNext && d.nextTrackMeta.ID != "" && atTrackEnd
+	d.metaLock.Unlock()
 
-	for i := range len(d.proxyURLs) {
-		if d.proxyURLs[i].key == key {
-			url := d.proxyURLs[i].url
-			// Move accessed entry to the most recent position
-			d._updateProxyURL(key, url)
-			return url, true
-		}
+	if d.getState() == stopped || loading {
+		return
 	}
-
-	return "", false
-}
-
-func (d *DLNAPlayer) _updateProxyURL(key, url string) {
-	// Check if the key already exists, and if so, move it to the most recently used position
-	for i := range len(d.proxyURLs) {
-		if d.proxyURLs[i].key == key {
-			if i < len(d.proxyURLs)-1 {
-				// Shift elements to the left from found position to the end
-				copy(d.proxyURLs[i:], d.proxyURLs[i+1:])
-			}
-			// Place updated entry at the last position
-			d.proxyURLs[len(d.proxyURLs)-1] = proxyMapEntry{key: key, url: url}
-			return
-		}
+	if playNext {
+		d.handleOnTrackChange()
+		return
 	}
-
-	// Shift all elements left to make room for the new entry at the end
-	copy(d.proxyURLs[:], d.proxyURLs[1:])
-	// Insert new element at the most recent position
-	d.proxyURLs[len(d.proxyURLs)-1] = proxyMapEntry{key: key, url: url}
+	d.stateLock.Lock()
+	d.stopwatch.Reset()
+	d.lastStartTime = 0
+	d.state = stopped
+	d.stateLock.Unlock()
+	d.InvokeOnStopped()
 }
 
 // httpClientHandler wraps an http.Client to implement services.RequestHandler
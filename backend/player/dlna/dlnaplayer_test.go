@@ -0,0 +1,328 @@
+package dlna
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/supersonic-app/go-upnpcast/services/avtransport"
+	"github.com/supersonic-app/go-upnpcast/services/renderingcontrol"
+)
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+<device><deviceType>urn:schemas-upnp-org:device:Basic:1</deviceType>
+<deviceList><device>
+<deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+<friendlyName>Fake Renderer</friendlyName>
+<serviceList>
+<service><serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType>
+<controlURL>/ctl/avt</controlURL><eventSubURL>evt/avt</eventSubURL></service>
+<service><serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType>
+<controlURL>/ctl/rc</controlURL><eventSubURL>/evt/rc</eventSubURL></service>
+<service><serviceType>urn:schemas-upnp-org:service:ConnectionManager:1</serviceType>
+<controlURL>/ctl/cm</controlURL><eventSubURL>/evt/cm</eventSubURL></service>
+</serviceList>
+</device></deviceList>
+</device>
+</root>`
+
+// fakeRenderer serves a device description, accepts any SOAP action,
+// and records GENA subscriptions so the test can send events.
+type fakeRenderer struct {
+	lock         sync.Mutex
+	callbacks    map[string]string
+	unsubscribed []string
+	actions      map[string]map[string]string
+
+	// if set, sent as the initial AVTransport event
+	// before the SUBSCRIBE response, as some renderers do
+	initialEvent       string
+	initialEventStatus int
+}
+
+func (f *fakeRenderer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/desc.xml":
+		w.Write([]byte(testDescription))
+	case strings.HasPrefix(r.URL.Path, "/ctl/"):
+		action := r.Header.Get("SOAPACTION")
+		action = strings.Trim(action[strings.Index(action, "#")+1:], `"`)
+		args, _ := parseElementValues(r.Body, 4)
+		f.lock.Lock()
+		f.actions[action] = args
+		f.lock.Unlock()
+
+		w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+		var out string
+		if action == "GetProtocolInfo" {
+			out = "<Source></Source><Sink>http-get:*:audio/mpeg:DLNA.ORG_PN=MP3;DLNA.ORG_OP=01,http-get:*:audio/x-wav:*,rtsp-rtp-udp:*:audio/flac:*</Sink>"
+		}
+		fmt.Fprintf(w, `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:%sResponse>%s</u:%sResponse></s:Body></s:Envelope>`, action, out, action)
+	case strings.HasPrefix(r.URL.Path, "/evt/"):
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		service := strings.TrimPrefix(r.URL.Path, "/evt/")
+		switch r.Method {
+		case "SUBSCRIBE":
+			cb := strings.Trim(r.Header.Get("CALLBACK"), "<>")
+			if cb == "" || r.Header.Get("NT") != "upnp:event" {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			f.callbacks[service] = cb
+			if service == eventAVTransport && f.initialEvent != "" {
+				f.lock.Unlock()
+				status := f.notifyWithSID(nil, service, "uuid:sub-"+service, f.initialEvent)
+				f.lock.Lock()
+				f.initialEventStatus = status
+			}
+			w.Header()["SID"] = []string{"uuid:sub-" + service}
+			w.Header()["TIMEOUT"] = []string{"Second-1800"}
+		case "UNSUBSCRIBE":
+			f.unsubscribed = append(f.unsubscribed, r.Header.Get("SID"))
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeRenderer) notify(t *testing.T, service, lastChange string) {
+	t.Helper()
+	if status := f.notifyWithSID(t, service, "uuid:sub-"+service, lastChange); status != http.StatusOK {
+		t.Fatalf("NOTIFY returned %d", status)
+	}
+}
+
+// notifyWithSID returns the status of the NOTIFY request, or 0 if it failed.
+func (f *fakeRenderer) notifyWithSID(t *testing.T, service, sid, lastChange string) int {
+	f.lock.Lock()
+	cb := f.callbacks[service]
+	f.lock.Unlock()
+
+	body := fmt.Sprintf(`<?xml version="1.0"?><e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>%s</LastChange></e:property></e:propertyset>`,
+		html.EscapeString(`<Event xmlns="urn:schemas-upnp-org:metadata-1-0/AVT/"><InstanceID val="0">`+lastChange+`</InstanceID></Event>`))
+	req, _ := http.NewRequest("NOTIFY", cb, strings.NewReader(body))
+	req.Header["NT"] = []string{"upnp:event"}
+	req.Header["NTS"] = []string{"upnp:propchange"}
+	req.Header["SID"] = []string{sid}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func newTestPlayer(t *testing.T) (*DLNAPlayer, *fakeRenderer) {
+	t.Helper()
+	return newTestPlayerWithRenderer(t, &fakeRenderer{})
+}
+
+func newTestPlayerWithRenderer(t *testing.T, renderer *fakeRenderer) (*DLNAPlayer, *fakeRenderer) {
+	t.Helper()
+	renderer.callbacks = make(map[string]string)
+	renderer.actions = make(map[string]map[string]string)
+	srv := httptest.NewServer(renderer)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	svcs, err := fetchServices(ctx, srv.Client(), srv.URL+"/desc.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &DLNAPlayer{
+		client:        srv.Client(),
+		services:      svcs,
+		avTransport:   avtransport.NewClient(svcs.avTransportControl, ""),
+		renderControl: renderingcontrol.NewClient(srv.URL + "/ctl/rc"),
+		formats:       rendererFormats(srv.Client(), srv.URL+"/desc.xml", svcs.connectionManagerControl),
+		resetChan:     make(chan time.Duration),
+	}
+	if err := d.subscribeEvents(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return d, renderer
+}
+
+func waitFor(t *testing.T, ch <-chan int, what string) int {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		return 0
+	}
+}
+
+func TestDLNAPlayerEvents(t *testing.T) {
+	d, renderer := newTestPlayer(t)
+
+	trackChanged := make(chan int, 4)
+	paused := make(chan int, 4)
+	stopped := make(chan int, 4)
+	volume := make(chan int, 4)
+	d.OnTrackChange(func() { trackChanged <- 1 })
+	d.OnPaused(func() { paused <- 1 })
+	d.OnStopped(func() { stopped <- 1 })
+	d.OnVolumeChange(func(v int) { volume <- v })
+
+	meta := mediaprovider.MediaItemMetadata{ID: "1", Duration: 3 * time.Minute}
+	if err := d.PlayFile("http://music.example/1.flac", meta, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, trackChanged, "initial track change")
+	if d.timerActive.Load() {
+		t.Error("track change timer should not run when subscribed to events")
+	}
+	if err := d.SetNextFile("http://music.example/2.flac", mediaprovider.MediaItemMetadata{ID: "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// renderer advances to the queued next track
+	d.metaLock.Lock()
+	nextURL := d.nextTrackURL
+	d.metaLock.Unlock()
+	renderer.notify(t, eventAVTransport, `<TransportState val="PLAYING"/><CurrentTrackURI val="`+html.EscapeString(nextURL)+`"/>`)
+	waitFor(t, trackChanged, "gapless track change")
+	d.metaLock.Lock()
+	if d.curTrackMeta.ID != "2" {
+		t.Errorf("expected current track 2, got %q", d.curTrackMeta.ID)
+	}
+	d.metaLock.Unlock()
+
+	// events of unknown subscriptions are rejected
+	if status := renderer.notifyWithSID(t, eventAVTransport, "uuid:old", `<TransportState val="STOPPED"/>`); status != http.StatusPreconditionFailed {
+		t.Errorf("expected NOTIFY with unknown SID to fail, got %d", status)
+	}
+
+	renderer.notify(t, eventAVTransport, `<TransportState val="PAUSED_PLAYBACK"/>`)
+	waitFor(t, paused, "pause")
+	if s := d.GetStatus().State; s != player.Paused {
+		t.Errorf("expected paused state, got %v", s)
+	}
+
+	renderer.notify(t, eventRenderingControl, `<Volume channel="Master" val="30"/><Volume channel="LF" val="80"/>`)
+	renderer.notify(t, eventRenderingControl, `<Volume channel="Master" val="45"/>`)
+	if v := waitFor(t, volume, "volume change"); v != 45 {
+		t.Errorf("expected volume 45, got %d", v)
+	}
+	if v := d.GetVolume(); v != 45 {
+		t.Errorf("expected cached volume 45, got %d", v)
+	}
+
+	// outside of the load grace period, STOPPED is a renderer-side stop
+	d.metaLock.Lock()
+	d.lastLoad = time.Now().Add(-time.Minute)
+	d.metaLock.Unlock()
+	renderer.notify(t, eventAVTransport, `<TransportState val="STOPPED"/>`)
+	waitFor(t, stopped, "stop")
+	if s := d.GetStatus().State; s != player.Stopped {
+		t.Errorf("expected stopped state, got %v", s)
+	}
+
+	d.Destroy()
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+	if len(renderer.unsubscribed) != 2 {
+		t.Errorf("expected 2 unsubscribes, got %v", renderer.unsubscribed)
+	}
+}
+
+func TestDLNAPlayerInitialEvent(t *testing.T) {
+	d, renderer := newTestPlayerWithRenderer(t, &fakeRenderer{initialEvent: `<TransportState val="STOPPED"/>`})
+	defer d.Destroy()
+	renderer.lock.Lock()
+	defer renderer.lock.Unlock()
+	if renderer.initialEventStatus != http.StatusOK {
+		t.Errorf("expected initial event sent before the SUBSCRIBE response to be accepted, got %d", renderer.initialEventStatus)
+	}
+}
+
+func TestDLNAPlayerSubscriptionLost(t *testing.T) {
+	d, _ := newTestPlayer(t)
+	defer d.Destroy()
+	meta := mediaprovider.MediaItemMetadata{ID: "1", Duration: 3 * time.Minute}
+	if err := d.PlayFile("http://music.example/1.flac", meta, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	d.handleSubscriptionChange(eventAVTransport, false)
+	if d.subscribed.Load() || !d.timerActive.Load() {
+		t.Error("expected track change timer to take over from lost subscription")
+	}
+	d.handleSubscriptionChange(eventAVTransport, true)
+	// the timer goroutine stops asynchronously
+	for deadline := time.Now().Add(time.Second); d.timerActive.Load() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !d.subscribed.Load() || d.timerActive.Load() {
+		t.Error("expected events to take over from track change timer after resubscribing")
+	}
+}
+
+func TestDLNAPlayerTranscode(t *testing.T) {
+	d, renderer := newTestPlayer(t)
+	defer d.Destroy()
+
+	if got := strings.Join(d.formats, ","); got != "audio/mpeg,audio/wav" {
+		t.Errorf("unexpected renderer formats %q", got)
+	}
+	if ts := d.TranscodeFor("audio/mpeg"); ts != nil {
+		t.Errorf("unexpected transcode for supported format: %v", ts)
+	}
+	if ts := d.TranscodeFor("audio/flac"); ts == nil || ts.Codec != "mp3" {
+		t.Errorf("expected mp3 transcode for flac, got %v", ts)
+	}
+
+	// music server that honors the transcode request
+	music := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte("mp3 data"))
+	}))
+	defer music.Close()
+
+	meta := mediaprovider.MediaItemMetadata{ID: "1", Name: "Song", MIMEType: "audio/flac", Duration: time.Minute}
+	if err := d.PlayFile(music.URL+"/stream?format=mp3", meta, 0); err != nil {
+		t.Fatal(err)
+	}
+	renderer.lock.Lock()
+	args := renderer.actions["SetAVTransportURI"]
+	renderer.lock.Unlock()
+	if !strings.Contains(args["CurrentURIMetaData"], `protocolInfo="http-get:*:audio/mpeg:DLNA.ORG_PN=MP3;DLNA.ORG_OP=00;DLNA.ORG_CI=1;`) {
+		t.Errorf("unexpected DIDL-Lite metadata: %s", args["CurrentURIMetaData"])
+	}
+
+	resp, err := http.Get(args["CurrentURI"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if string(b) != "mp3 data" || resp.Header.Get("Content-Type") != "audio/mpeg" {
+		t.Errorf("unexpected proxied transcode %q (%s)", b, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestParseLastChange(t *testing.T) {
+	vars, err := parseLastChange(`<Event xmlns="urn:schemas-upnp-org:metadata-1-0/RCS/">` +
+		`<InstanceID val="1"><Volume channel="Master" val="10"/></InstanceID>` +
+		`<InstanceID val="0"><Volume channel="Master" val="20"/><Volume channel="RF" val="5"/><Mute channel="Master" val="0"/></InstanceID>` +
+		`</Event>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["Volume"] != "20" || vars["Mute"] != "0" || len(vars) != 2 {
+		t.Errorf("unexpected LastChange vars: %v", vars)
+	}
+}
@@ -0,0 +1,411 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths on the local callback server that the renderer
+// sends NOTIFY requests to, one per subscribed service.
+const (
+	eventAVTransport       = "avt"
+	eventRenderingControl  = "rc"
+	subscriptionTimeout    = 1800 * time.Second
+	minSubscriptionRenewal = 10 * time.Second
+)
+
+// genaEvent is the set of state variables reported in one LastChange event.
+type genaEvent struct {
+	service string
+	vars    map[string]string
+}
+
+type subscription struct {
+	service  string
+	eventURL string
+	sid      string
+	timeout  time.Duration
+}
+
+// eventListener subscribes to UPnP GENA events from a renderer and
+// receives them on a local HTTP server. Events are delivered in order
+// on a separate goroutine so that the renderer's NOTIFY request is
+// answered before any (possibly blocking) handling takes place.
+type eventListener struct {
+	client  *http.Client
+	server  *http.Server
+	baseURL string
+	onEvent func(service string, vars map[string]string)
+	// called when a subscription is lost, or re-established
+	// after being lost, since no events are received meanwhile
+	onSubscriptionChange func(service string, active bool)
+
+	lock    sync.Mutex
+	subs    []*subscription
+	pending []genaEvent // received events not yet handled
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+func newEventListener(client *http.Client, rendererURL string, onEvent func(string, map[string]string), onSubscriptionChange func(string, bool)) (*eventListener, error) {
+	localIP, err := localIPFor(rendererURL)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(localIP, "0"))
+	if err != nil {
+		return nil, err
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	l := &eventListener{
+		client:  client,
+		baseURL: "http://" + net.JoinHostPort(localIP, strconv.Itoa(port)),
+		onEvent: onEvent,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+
+		onSubscriptionChange: onSubscriptionChange,
+	}
+	l.server = &http.Server{
+		Handler:     http.HandlerFunc(l.handleNotify),
+		ReadTimeout: 10 * time.Second,
+	}
+	go l.server.Serve(listener)
+	go l.eventLoop()
+	return l, nil
+}
+
+// localIPFor returns the local address used to reach the host of the
+// given URL, which is the address the renderer can send events to.
+func localIPFor(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+	}
+	// no packets are sent for a UDP "connection"
+	conn, err := net.Dial("udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// Subscribe subscribes to events from the service at eventURL
+// and keeps the subscription renewed until Close is called.
+func (l *eventListener) Subscribe(ctx context.Context, service, eventURL string) error {
+	sub := &subscription{service: service, eventURL: eventURL}
+	// added before subscribing so that the initial event sent
+	// by the renderer right after subscribing is accepted
+	l.lock.Lock()
+	l.subs = append(l.subs, sub)
+	l.lock.Unlock()
+	if err := l.subscribe(ctx, sub); err != nil {
+		l.lock.Lock()
+		l.subs = slices.DeleteFunc(l.subs, func(s *subscription) bool { return s == sub })
+		l.lock.Unlock()
+		return err
+	}
+	go l.renewLoop(sub)
+	return nil
+}
+
+func (l *eventListener) subscribe(ctx context.Context, sub *subscription) error {
+	req, err := http.NewRequestWithContext(ctx, "SUBSCRIBE", sub.eventURL, nil)
+	if err != nil {
+		return err
+	}
+	// the SID of the new subscription isn't known until the response
+	// is received, which may be after the renderer's initial event
+	l.lock.Lock()
+	sub.sid = ""
+	l.lock.Unlock()
+	// set directly to preserve the upper-case header names
+	// that some renderers require
+	req.Header["CALLBACK"] = []string{"<" + l.baseURL + "/" + sub.service + ">"}
+	req.Header["NT"] = []string{"upnp:event"}
+	req.Header["TIMEOUT"] = []string{formatTimeout(subscriptionTimeout)}
+	return l.doSubscribe(req, sub)
+}
+
+func (l *eventListener) renew(ctx context.Context, sub *subscription) error {
+	req, err := http.NewRequestWithContext(ctx, "SUBSCRIBE", sub.eventURL, nil)
+	if err != nil {
+		return err
+	}
+	l.lock.Lock()
+	req.Header["SID"] = []string{sub.sid}
+	l.lock.Unlock()
+	req.Header["TIMEOUT"] = []string{formatTimeout(subscriptionTimeout)}
+	return l.doSubscribe(req, sub)
+}
+
+func (l *eventListener) doSubscribe(req *http.Request, sub *subscription) error {
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("subscribe to %s: %s", sub.eventURL, resp.Status)
+	}
+	sid := resp.Header.Get("SID")
+	if sid == "" {
+		return fmt.Errorf("subscribe to %s: no SID in response", sub.eventURL)
+	}
+	l.lock.Lock()
+	sub.sid = sid
+	sub.timeout = parseTimeout(resp.Header.Get("TIMEOUT"))
+	l.lock.Unlock()
+	return nil
+}
+
+func (l *eventListener) renewLoop(sub *subscription) {
+	active := true
+	for {
+		l.lock.Lock()
+		wait := max(sub.timeout/2, minSubscriptionRenewal)
+		l.lock.Unlock()
+
+		select {
+		case <-l.done:
+			return
+		case <-time.After(wait):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := l.renew(ctx, sub)
+		if err != nil {
+			// the subscription may have expired on the renderer; start a new one
+			err = l.subscribe(ctx, sub)
+		}
+		cancel()
+		if err != nil {
+			log.Printf("failed to renew DLNA event subscription: %v", err)
+		}
+		if (err == nil) != active {
+			active = err == nil
+			select {
+			case <-l.done:
+				return
+			default:
+			}
+			if l.onSubscriptionChange != nil {
+				l.onSubscriptionChange(sub.service, active)
+			}
+		}
+	}
+}
+
+// Close cancels all subscriptions and shuts down the callback server.
+func (l *eventListener) Close() {
+	select {
+	case <-l.done:
+		return
+	default:
+		close(l.done)
+	}
+
+	l.lock.Lock()
+	subs := l.subs
+	l.subs = nil
+	l.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, sub := range subs {
+		req, err := http.NewRequestWithContext(ctx, "UNSUBSCRIBE", sub.eventURL, nil)
+		if err != nil {
+			continue
+		}
+		l.lock.Lock()
+		req.Header["SID"] = []string{sub.sid}
+		l.lock.Unlock()
+		if resp, err := l.client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+	l.server.Shutdown(ctx)
+}
+
+func (l *eventListener) handleNotify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "NOTIFY" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	service := strings.TrimPrefix(r.URL.Path, "/")
+	if service != eventAVTransport && service != eventRenderingControl {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !l.isSubscribed(service, r.Header.Get("SID")) {
+		// e.g. a late event of an expired subscription
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	props, err := parsePropertySet(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	lastChange, ok := props["LastChange"]
+	if !ok {
+		return
+	}
+	vars, err := parseLastChange(lastChange)
+	if err != nil {
+		log.Printf("failed to parse DLNA LastChange event: %v", err)
+		return
+	}
+	if len(vars) == 0 {
+		return
+	}
+	l.lock.Lock()
+	l.pending = append(l.pending, genaEvent{service: service, vars: vars})
+	l.lock.Unlock()
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// isSubscribed returns whether sid is the ID of a current subscription to
+// the service. Any SID is accepted while a subscription awaits its SID.
+func (l *eventListener) isSubscribed(service, sid string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return sid != "" && slices.ContainsFunc(l.subs, func(s *subscription) bool {
+		return s.service == service && (s.sid == sid || s.sid == "")
+	})
+}
+
+func (l *eventListener) eventLoop() {
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-l.wake:
+		}
+		l.lock.Lock()
+		events := l.pending
+		l.pending = nil
+		l.lock.Unlock()
+		for _, e := range events {
+			l.onEvent(e.service, e.vars)
+		}
+	}
+}
+
+// parsePropertySet returns the evented variables of a GENA NOTIFY body:
+// <e:propertyset><e:property><Variable>value</Variable></e:property>...
+func parsePropertySet(r io.Reader) (map[string]string, error) {
+	return parseElementValues(r, 3)
+}
+
+// parseElementValues returns the text content of all elements
+// at the given nesting depth of an XML document, by element name.
+func parseElementValues(r io.Reader, depth int) (map[string]string, error) {
+	dec := xml.NewDecoder(r)
+	values := make(map[string]string)
+	var curElem string
+	curDepth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			curDepth++
+			if curDepth == depth {
+				curElem = t.Name.Local
+				values[curElem] = ""
+			}
+		case xml.CharData:
+			if curDepth == depth {
+				values[curElem] += string(t)
+			}
+		case xml.EndElement:
+			curDepth--
+		}
+	}
+	return values, nil
+}
+
+// parseLastChange returns the state variables of instance 0 from a
+// LastChange document:
+// <Event><InstanceID val="0"><TransportState val="PLAYING"/>...</InstanceID></Event>
+// Only the Master channel is reported for per-channel variables such as Volume.
+func parseLastChange(lastChange string) (map[string]string, error) {
+	dec := xml.NewDecoder(strings.NewReader(lastChange))
+	vars := make(map[string]string)
+	inInstance := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "InstanceID" {
+				inInstance = attr(t, "val") == "0"
+				continue
+			}
+			if !inInstance {
+				continue
+			}
+			if ch := attr(t, "channel"); ch != "" && ch != "Master" {
+				continue
+			}
+			vars[t.Name.Local] = attr(t, "val")
+		case xml.EndElement:
+			if t.Name.Local == "InstanceID" {
+				inInstance = false
+			}
+		}
+	}
+	return vars, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func formatTimeout(d time.Duration) string {
+	return fmt.Sprintf("Second-%d", int(d.Seconds()))
+}
+
+func parseTimeout(s string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(s), "second-"))
+	if err != nil || secs <= 0 {
+		// "infinite" or missing; renew on our own schedule anyway
+		return subscriptionTimeout
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,57 @@
+package dlna
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// soapArg is an input argument of a SOAP action, sent in order.
+type soapArg struct {
+	Name  string
+	Value string
+}
+
+// soapCall invokes an action of the service at controlURL and returns
+// the output arguments of the response. Used for the actions that
+// the go-upnpcast clients do not implement or implement insufficiently.
+func soapCall(ctx context.Context, client *http.Client, controlURL, serviceType, action string, args []soapArg) (map[string]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	body.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, serviceType)
+	for _, a := range args {
+		fmt.Fprintf(&body, "<%s>", a.Name)
+		xml.EscapeText(&body, []byte(a.Value))
+		fmt.Fprintf(&body, "</%s>", a.Name)
+	}
+	fmt.Fprintf(&body, `</u:%s>`, action)
+	body.WriteString(`</s:Body></s:Envelope>`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header["SOAPACTION"] = []string{fmt.Sprintf(`"%s#%s"`, serviceType, action)}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Envelope > Body > Fault > detail > UPnPError > errorCode
+		fault, _ := parseElementValues(resp.Body, 6)
+		if code := strings.TrimSpace(fault["errorCode"]); code != "" {
+			return nil, fmt.Errorf("%s: UPnP error %s: %s", action, code, fault["errorDescription"])
+		}
+		return nil, fmt.Errorf("%s: %s", action, resp.Status)
+	}
+	// Envelope > Body > actionResponse > arguments
+	return parseElementValues(resp.Body, 4)
+}
@@ -0,0 +1,296 @@
+// Package group implements a player that plays the same
+// media in sync on several output devices.
+package group
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+const (
+	// how often followers are checked for drift from the leader
+	resyncInterval = 10 * time.Second
+	// followers further than this from the leader are re-seeked
+	maxDrift = 1500 * time.Millisecond
+)
+
+// Member is one output device of a group.
+type Member struct {
+	// ID identifies the member, e.g. the URL of a remote device.
+	ID     string
+	Player player.URLPlayer
+	// LatencyOffset is how much later than it is started this device's
+	// output is heard, e.g. due to network buffering on the device.
+	LatencyOffset time.Duration
+	// Owned is true if the group created the player and should
+	// destroy it when the group is destroyed.
+	Owned bool
+}
+
+// follower is a non-leader member with its own
+// queue so that commands run in order without
+// blocking on the slowest device.
+type follower struct {
+	*Member
+	cmds chan func()
+}
+
+// GroupPlayer fans out playback commands to all of its members.
+// The first member is the leader, which reports status and invokes
+// callbacks; the others follow it, with command timing compensated
+// for each device's latency offset.
+type GroupPlayer struct {
+	leader    *Member
+	followers []*follower
+
+	offsetLock sync.Mutex
+	destroyed  bool
+	done       chan struct{}
+}
+
+// NewGroupPlayer returns a group player for the given members,
+// of which the first is the leader.
+func NewGroupPlayer(members []Member) (*GroupPlayer, error) {
+	if len(members) == 0 {
+		return nil, errors.New("player group has no members")
+	}
+	g := &GroupPlayer{
+		leader: &members[0],
+		done:   make(chan struct{}),
+	}
+	for i := 1; i < len(members); i++ {
+		f := &follower{Member: &members[i], cmds: make(chan func(), 16)}
+		g.followers = append(g.followers, f)
+		go g.runFollower(f)
+	}
+	go g.resyncLoop()
+	return g, nil
+}
+
+// SetLatencyOffset updates the latency offset of the member with the given ID.
+func (g *GroupPlayer) SetLatencyOffset(id string, offset time.Duration) {
+	g.offsetLock.Lock()
+	defer g.offsetLock.Unlock()
+	if g.leader.ID == id {
+		g.leader.LatencyOffset = offset
+	}
+	for _, f := range g.followers {
+		if f.ID == id {
+			f.LatencyOffset = offset
+		}
+	}
+}
+
+func (g *GroupPlayer) runFollower(f *follower) {
+	for {
+		select {
+		case <-g.done:
+			return
+		case cmd := <-f.cmds:
+			cmd()
+		}
+	}
+}
+
+// fanOut runs cmd on every member. If compensate is true, each member's
+// command is delayed so that devices with less latency start later,
+// and the output of all devices is heard at the same time.
+// The leader runs on the calling goroutine, so its callbacks are
+// invoked the same way as for a single player.
+func (g *GroupPlayer) fanOut(compensate bool, cmd func(p player.URLPlayer) error) error {
+	start := time.Now()
+	g.offsetLock.Lock()
+	maxOffset := g.leader.LatencyOffset
+	for _, f := range g.followers {
+		maxOffset = max(maxOffset, f.LatencyOffset)
+	}
+	startAt := func(m *Member) time.Time {
+		if !compensate {
+			return start
+		}
+		return start.Add(maxOffset - m.LatencyOffset)
+	}
+	leaderStart := startAt(g.leader)
+	for _, f := range g.followers {
+		at := startAt(f.Member)
+		p, id := f.Player, f.ID
+		select {
+		case f.cmds <- func() {
+			time.Sleep(time.Until(at))
+			if err := cmd(p); err != nil {
+				log.Printf("player group member %s: %v", id, err)
+			}
+		}:
+		default:
+			log.Printf("player group member %s is not responding; dropping command", id)
+		}
+	}
+	g.offsetLock.Unlock()
+
+	time.Sleep(time.Until(leaderStart))
+	return cmd(g.leader.Player)
+}
+
+func (g *GroupPlayer) PlayFile(url string, meta mediaprovider.MediaItemMetadata, startTime float64) error {
+	return g.fanOut(true, func(p player.URLPlayer) error {
+		return p.PlayFile(url, meta, startTime)
+	})
+}
+
+func (g *GroupPlayer) SetNextFile(url string, meta mediaprovider.MediaItemMetadata) error {
+	return g.fanOut(false, func(p player.URLPlayer) error {
+		return p.SetNextFile(url, meta)
+	})
+}
+
+func (g *GroupPlayer) Continue() error {
+	return g.fanOut(true, func(p player.URLPlayer) error {
+		return p.Continue()
+	})
+}
+
+func (g *GroupPlayer) Pause() error {
+	return g.fanOut(true, func(p player.URLPlayer) error {
+		return p.Pause()
+	})
+}
+
+func (g *GroupPlayer) Stop(force bool) error {
+	return g.fanOut(false, func(p player.URLPlayer) error {
+		return p.Stop(force)
+	})
+}
+
+func (g *GroupPlayer) SeekSeconds(secs float64) error {
+	return g.fanOut(true, func(p player.URLPlayer) error {
+		return p.SeekSeconds(secs)
+	})
+}
+
+func (g *GroupPlayer) IsSeeking() bool {
+	return g.leader.Player.IsSeeking()
+}
+
+func (g *GroupPlayer) SetVolume(vol int) error {
+	return g.fanOut(false, func(p player.URLPlayer) error {
+		return p.SetVolume(vol)
+	})
+}
+
+func (g *GroupPlayer) GetVolume() int {
+	return g.leader.Player.GetVolume()
+}
+
+func (g *GroupPlayer) GetStatus() player.Status {
+	return g.leader.Player.GetStatus()
+}
+
+// TranscodeFor returns the first transcode requested by any member.
+// Members that still can't play the result transcode it themselves.
+func (g *GroupPlayer) TranscodeFor(mimeType string) *mediaprovider.TranscodeSettings {
+	for _, m := range g.members() {
+		if tp, ok := m.Player.(player.TranscodingPlayer); ok {
+			if ts := tp.TranscodeFor(mimeType); ts != nil {
+				return ts
+			}
+		}
+	}
+	return nil
+}
+
+// Callbacks are registered directly on the leader.
+
+func (g *GroupPlayer) OnPaused(cb func()) {
+	g.leader.Player.OnPaused(cb)
+}
+
+func (g *GroupPlayer) OnStopped(cb func()) {
+	g.leader.Player.OnStopped(cb)
+}
+
+func (g *GroupPlayer) OnPlaying(cb func()) {
+	g.leader.Player.OnPlaying(cb)
+}
+
+func (g *GroupPlayer) OnSeek(cb func()) {
+	g.leader.Player.OnSeek(cb)
+}
+
+func (g *GroupPlayer) OnTrackChange(cb func()) {
+	g.leader.Player.OnTrackChange(cb)
+}
+
+func (g *GroupPlayer) Destroy() {
+	g.offsetLock.Lock()
+	if g.destroyed {
+		g.offsetLock.Unlock()
+		return
+	}
+	g.destroyed = true
+	close(g.done)
+	g.offsetLock.Unlock()
+
+	for _, m := range g.members() {
+		if m.Owned {
+			m.Player.Destroy()
+		}
+	}
+}
+
+func (g *GroupPlayer) members() []*Member {
+	members := []*Member{g.leader}
+	for _, f := range g.followers {
+		members = append(members, f.Member)
+	}
+	return members
+}
+
+// resyncLoop periodically re-seeks followers that have
+// drifted from the leader, e.g. after a gapless transition
+// that happened at a slightly different time on each device.
+func (g *GroupPlayer) resyncLoop() {
+	t := time.NewTicker(resyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-t.C:
+			g.resync()
+		}
+	}
+}
+
+func (g *GroupPlayer) resync() {
+	lead := g.leader.Player.GetStatus()
+	if lead.State != player.Playing || g.leader.Player.IsSeeking() {
+		return
+	}
+	g.offsetLock.Lock()
+	defer g.offsetLock.Unlock()
+	for _, f := range g.followers {
+		// positions where the output heard from both devices is the same
+		target := lead.TimePos - g.leader.LatencyOffset.Seconds() + f.LatencyOffset.Seconds()
+		p := f.Player
+		select {
+		case f.cmds <- func() {
+			stat := p.GetStatus()
+			// only correct followers playing the same track
+			if stat.State != player.Playing || p.IsSeeking() ||
+				math.Abs(stat.Duration-lead.Duration) > 1 {
+				return
+			}
+			if math.Abs(stat.TimePos-target) > maxDrift.Seconds() {
+				p.SeekSeconds(target)
+			}
+		}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package group
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+// fakePlayer records when each command was received.
+type fakePlayer struct {
+	player.BasePlayerCallbackImpl
+
+	lock      sync.Mutex
+	calls     map[string]time.Time
+	status    player.Status
+	destroyed bool
+}
+
+func newFakePlayer() *fakePlayer {
+	return &fakePlayer{calls: make(map[string]time.Time)}
+}
+
+func (f *fakePlayer) record(cmd string) {
+	f.lock.Lock()
+	f.calls[cmd] = time.Now()
+	f.lock.Unlock()
+}
+
+func (f *fakePlayer) called(cmd string) (time.Time, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	t, ok := f.calls[cmd]
+	return t, ok
+}
+
+func (f *fakePlayer) PlayFile(string, mediaprovider.MediaItemMetadata, float64) error {
+	f.record("play")
+	f.InvokeOnPlaying()
+	return nil
+}
+
+func (f *fakePlayer) SetNextFile(string, mediaprovider.MediaItemMetadata) error {
+	f.record("next")
+	return nil
+}
+
+func (f *fakePlayer) Continue() error { f.record("continue"); return nil }
+func (f *fakePlayer) Pause() error    { f.record("pause"); return nil }
+func (f *fakePlayer) Stop(bool) error { f.record("stop"); return nil }
+func (f *fakePlayer) SeekSeconds(secs float64) error {
+	f.record("seek")
+	f.lock.Lock()
+	f.status.TimePos = secs
+	f.lock.Unlock()
+	return nil
+}
+func (f *fakePlayer) IsSeeking() bool     { return false }
+func (f *fakePlayer) SetVolume(int) error { f.record("volume"); return nil }
+func (f *fakePlayer) GetVolume() int      { return 50 }
+func (f *fakePlayer) Destroy()            { f.destroyed = true }
+func (f *fakePlayer) GetStatus() player.Status {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.status
+}
+
+func waitForCall(t *testing.T, f *fakePlayer, cmd string) time.Time {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if at, ok := f.called(cmd); ok {
+			return at
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%s was not called", cmd)
+	return time.Time{}
+}
+
+func TestGroupPlayerLatencyCompensation(t *testing.T) {
+	leader, slow, fast := newFakePlayer(), newFakePlayer(), newFakePlayer()
+	g, err := NewGroupPlayer([]Member{
+		{ID: "local", Player: leader, LatencyOffset: 100 * time.Millisecond},
+		{ID: "slow", Player: slow, LatencyOffset: 300 * time.Millisecond, Owned: true},
+		{ID: "fast", Player: fast, Owned: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var playing bool
+	g.OnPlaying(func() { playing = true })
+	start := time.Now()
+	if err := g.PlayFile("http://example/1.mp3", mediaprovider.MediaItemMetadata{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !playing {
+		t.Error("leader callback was not invoked")
+	}
+
+	// the device with the most latency starts first
+	slowAt := waitForCall(t, slow, "play")
+	leaderAt := waitForCall(t, leader, "play")
+	fastAt := waitForCall(t, fast, "play")
+	if !(slowAt.Before(leaderAt) && leaderAt.Before(fastAt)) {
+		t.Errorf("unexpected start order: slow %v, leader %v, fast %v",
+			slowAt.Sub(start), leaderAt.Sub(start), fastAt.Sub(start))
+	}
+	if d := fastAt.Sub(slowAt); d < 250*time.Millisecond {
+		t.Errorf("expected ~300ms between slowest and fastest device, got %v", d)
+	}
+
+	// offsets can be retuned while playing
+	g.SetLatencyOffset("fast", 300*time.Millisecond)
+	g.SetLatencyOffset("local", 300*time.Millisecond)
+	g.SeekSeconds(10)
+	slowAt, fastAt = waitForCall(t, slow, "seek"), waitForCall(t, fast, "seek")
+	if d := fastAt.Sub(slowAt).Abs(); d > 100*time.Millisecond {
+		t.Errorf("expected simultaneous seek after retuning, got %v apart", d)
+	}
+
+	g.Destroy()
+	if leader.destroyed || !slow.destroyed || !fast.destroyed {
+		t.Error("only owned members should be destroyed")
+	}
+}
+
+func TestGroupPlayerResync(t *testing.T) {
+	leader, follower := newFakePlayer(), newFakePlayer()
+	leader.status = player.Status{State: player.Playing, TimePos: 60, Duration: 200}
+	follower.status = player.Status{State: player.Playing, TimePos: 55, Duration: 200}
+	g, _ := NewGroupPlayer([]Member{
+		{ID: "local", Player: leader},
+		{ID: "remote", Player: follower, LatencyOffset: 500 * time.Millisecond},
+	})
+	defer g.Destroy()
+
+	g.resync()
+	waitForCall(t, follower, "seek")
+	if pos := follower.GetStatus().TimePos; pos != 60.5 {
+		t.Errorf("expected follower re-seeked to 60.5, got %v", pos)
+	}
+}
@@ -1,10 +1,18 @@
 package jukebox
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/sharedutil"
 )
 
 const (
@@ -13,23 +21,66 @@ const (
 	paused  = 2
 )
 
+const (
+	// how often the jukebox is polled for changes made by other clients
+	pollInterval = 2 * time.Second
+	// above this many removed tracks, the whole queue is set instead
+	maxIncrementalRemoves = 10
+)
+
+var _ player.QueuePlayer = (*JukeboxPlayer)(nil)
+
+// JukeboxPlayer plays on the server's own audio output via the Subsonic
+// jukebox API. It mirrors the entire play queue to the server-side jukebox
+// playlist, and polls the jukebox to pick up changes made by other clients.
 type JukeboxPlayer struct {
 	player.BasePlayerCallbackImpl
 
 	provider mediaprovider.JukeboxProvider
 
-	state   int // stopped, playing, paused
+	lock    sync.Mutex
+	cmdSeq  int  // incremented by every command, to discard stale poll results
+	synced  bool // whether the queue has been set or adopted from the jukebox
+	state   int  // stopped, playing, paused
 	volume  int
-	seeking bool
+	seeking atomic.Bool
 
+	queue              []*mediaprovider.Track // mirror of the jukebox playlist
 	curTrack           int
-	queueLength        int
+	nextTrack          int // queue index to play after curTrack, or -1 to stop
 	curTrackDuration   float64
 	startTrackTime     float64
 	startedAtUnixMilli int64
+
+	onQueueChange  func([]mediaprovider.MediaItem, int)
+	onVolumeChange func(int)
+	cancelPoll     context.CancelFunc
+}
+
+// NewJukeboxPlayer connects to the server's jukebox. It fails if
+// the user is not allowed to control the jukebox.
+func NewJukeboxPlayer(provider mediaprovider.JukeboxProvider) (*JukeboxPlayer, error) {
+	tracks, stat, err := provider.JukeboxGetQueue()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &JukeboxPlayer{
+		provider:   provider,
+		queue:      tracks,
+		volume:     stat.Volume,
+		curTrack:   -1,
+		nextTrack:  -1,
+		cancelPoll: cancel,
+	}
+	go j.pollLoop(ctx)
+	return j, nil
 }
 
 func (j *JukeboxPlayer) SetVolume(vol int) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.cmdSeq++
 	if err := j.provider.JukeboxSetVolume(vol); err != nil {
 		return err
 	}
@@ -38,92 +89,215 @@ func (j *JukeboxPlayer) SetVolume(vol int) error {
 }
 
 func (j *JukeboxPlayer) GetVolume() int {
+	j.lock.Lock()
+	defer j.lock.Unlock()
 	return j.volume
 }
 
+// OnVolumeChange registers a callback for when the
+// jukebox volume is changed by another client.
+func (j *JukeboxPlayer) OnVolumeChange(cb func(int)) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.onVolumeChange = cb
+}
+
+// OnQueueChange registers a callback for when the jukebox queue or
+// the current track is changed by another client.
+func (j *JukeboxPlayer) OnQueueChange(cb func([]mediaprovider.MediaItem, int)) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.onQueueChange = cb
+}
+
 func (j *JukeboxPlayer) Continue() error {
+	j.lock.Lock()
 	if j.state == playing {
+		j.lock.Unlock()
 		return nil
 	}
+	j.cmdSeq++
 	if err := j.startAndUpdateTime(); err != nil {
+		j.lock.Unlock()
 		return err
 	}
-
 	j.state = playing
+	j.lock.Unlock()
+
 	j.InvokeOnPlaying()
 	return nil
 }
 
 func (j *JukeboxPlayer) Pause() error {
+	j.lock.Lock()
 	if j.state != playing {
+		j.lock.Unlock()
 		return nil
 	}
+	j.cmdSeq++
 	if err := j.provider.JukeboxStop(); err != nil {
+		j.lock.Unlock()
 		return err
 	}
-	// TODO: calculate paused at time
+	j.startTrackTime = j.timePos()
 	j.state = paused
+	j.lock.Unlock()
+
 	j.InvokeOnPaused()
 	return nil
 }
 
 func (j *JukeboxPlayer) Stop(_ bool) error {
+	j.lock.Lock()
 	if j.state == stopped {
+		j.lock.Unlock()
 		return nil
 	}
+	j.cmdSeq++
 	if err := j.provider.JukeboxStop(); err != nil {
+		j.lock.Unlock()
 		return err
 	}
 	j.state = stopped
+	j.setCurTrack(-1)
+	j.lock.Unlock()
+
 	j.InvokeOnStopped()
 	return nil
 }
 
-func (j *JukeboxPlayer) PlayTrack(track *mediaprovider.Track, _ float64) error {
+// PlayTrack replaces the jukebox queue with the single track and plays it.
+func (j *JukeboxPlayer) PlayTrack(track *mediaprovider.Track, startTime float64) error {
+	j.lock.Lock()
+	j.cmdSeq++
 	if err := j.provider.JukeboxSet(track.ID); err != nil {
+		j.lock.Unlock()
 		return err
 	}
-	j.startTrackTime = 0
-	if err := j.startAndUpdateTime(); err != nil {
+	j.queue = []*mediaprovider.Track{track}
+	j.synced = true
+	j.lock.Unlock()
+
+	return j.PlayTrackAt(0, startTime)
+}
+
+// SetNextTrack appends the track to the jukebox queue to play next.
+func (j *JukeboxPlayer) SetNextTrack(track *mediaprovider.Track) error {
+	if track == nil {
+		return j.SetNextTrackAt(-1)
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if n := j.curTrack + 1; n < len(j.queue) && j.queue[n].ID == track.ID {
+		j.nextTrack = n
+		return nil
+	}
+	j.cmdSeq++
+	if err := j.provider.JukeboxAdd(track.ID); err != nil {
+		return err
+	}
+	j.queue = append(j.queue, track)
+	j.nextTrack = len(j.queue) - 1
+	return nil
+}
+
+func (j *JukeboxPlayer) PlayTrackAt(idx int, startTime float64) error {
+	j.lock.Lock()
+	wasPlaying := j.state == playing
+	j.cmdSeq++
+	if err := j.playAt(idx, startTime); err != nil {
+		j.lock.Unlock()
 		return err
 	}
+	j.lock.Unlock()
 
-	j.curTrack = 0
-	j.queueLength = 1
-	j.curTrackDuration = track.Duration.Seconds()
+	j.InvokeOnTrackChange()
+	if !wasPlaying {
+		j.InvokeOnPlaying()
+	}
+	return nil
+}
 
+func (j *JukeboxPlayer) SetNextTrackAt(idx int) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if idx >= len(j.queue) {
+		idx = -1
+	}
+	j.nextTrack = idx
 	return nil
 }
 
-func (j *JukeboxPlayer) SetNextTrack(track *mediaprovider.Track) error {
-	// we need to replace the last track in the queue, remove it first
-	if j.curTrack < j.queueLength-1 {
-		if err := j.provider.JukeboxRemove(j.curTrack + 1); err != nil {
-			return err
+// SetQueue updates the jukebox playlist to match the given queue,
+// with incremental adds or removes where possible.
+func (j *JukeboxPlayer) SetQueue(items []mediaprovider.MediaItem, nowPlaying int) error {
+	tracks := make([]*mediaprovider.Track, 0, len(items))
+	for _, item := range items {
+		tr, ok := item.(*mediaprovider.Track)
+		if !ok {
+			return errors.New("jukebox can only play tracks")
 		}
-		j.queueLength -= 1
+		tracks = append(tracks, tr)
 	}
-	// append the new track to the queue
-	if err := j.provider.JukeboxAdd(track.ID); err != nil {
-		return err
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.cmdSeq++
+	j.synced = true
+	oldIDs, newIDs := trackIDs(j.queue), trackIDs(tracks)
+
+	var err error
+	if slices.Equal(oldIDs, newIDs) {
+		// nothing to do
+	} else if len(newIDs) > len(oldIDs) && slices.Equal(oldIDs, newIDs[:len(oldIDs)]) {
+		err = j.provider.JukeboxAdd(newIDs[len(oldIDs):]...)
+	} else if removed, ok := removedIndexes(oldIDs, newIDs); ok && len(removed) <= maxIncrementalRemoves {
+		for i := len(removed) - 1; i >= 0 && err == nil; i-- {
+			err = j.provider.JukeboxRemove(removed[i])
+		}
+	} else if err = j.provider.JukeboxSet(newIDs...); err == nil {
+		// setting the playlist interrupts playback on the jukebox
+		j.queue = tracks
+		err = j.restorePlayback(nowPlaying)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update jukebox queue: %w", err)
+	}
+
+	j.queue = tracks
+	if nowPlaying >= len(tracks) {
+		nowPlaying = -1
+	}
+	if j.state != stopped {
+		j.setCurTrack(nowPlaying)
 	}
-	j.queueLength += 1
 	return nil
 }
 
 func (j *JukeboxPlayer) SeekSeconds(secs float64) error {
-	j.seeking = true
+	j.seeking.Store(true)
+	j.lock.Lock()
+	j.cmdSeq++
 	err := j.provider.JukeboxSeek(j.curTrack, int(secs))
-	j.seeking = false
+	if err == nil {
+		j.startTrackTime = secs
+		j.startedAtUnixMilli = time.Now().UnixMilli()
+	}
+	j.lock.Unlock()
+	j.seeking.Store(false)
+
 	j.InvokeOnSeek()
 	return err
 }
 
 func (j *JukeboxPlayer) IsSeeking() bool {
-	return j.seeking
+	return j.seeking.Load()
 }
 
 func (j *JukeboxPlayer) GetStatus() player.Status {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
 	state := player.Stopped
 	if j.state == playing {
 		state = player.Playing
@@ -131,14 +305,77 @@ func (j *JukeboxPlayer) GetStatus() player.Status {
 		state = player.Paused
 	}
 
-	// TODO - the rest
-
 	return player.Status{
-		State: state,
+		State:    state,
+		TimePos:  j.timePos(),
+		Duration: j.curTrackDuration,
+	}
+}
+
+func (j *JukeboxPlayer) Destroy() {
+	j.cancelPoll()
+}
+
+// playAt skips the jukebox to the given track and starts it.
+// Must be called with the lock held.
+func (j *JukeboxPlayer) playAt(idx int, startTime float64) error {
+	if idx < 0 || idx >= len(j.queue) {
+		return fmt.Errorf("track index (%d) out of range (0-%d)", idx, len(j.queue))
+	}
+	if err := j.provider.JukeboxSeek(idx, int(startTime)); err != nil {
+		return err
+	}
+	if err := j.startAndUpdateTime(); err != nil {
+		return err
+	}
+	j.startTrackTime = startTime
+	j.setCurTrack(idx)
+	j.state = playing
+	return nil
+}
+
+// restorePlayback returns the jukebox to the current playback state
+// after its playlist was replaced. Must be called with the lock held.
+func (j *JukeboxPlayer) restorePlayback(nowPlaying int) error {
+	if j.state == stopped || nowPlaying < 0 {
+		return j.provider.JukeboxStop()
+	}
+	pos := j.timePos()
+	if j.state == paused {
+		if err := j.provider.JukeboxSeek(nowPlaying, int(pos)); err != nil {
+			return err
+		}
+		return j.provider.JukeboxStop()
+	}
+	return j.playAt(nowPlaying, pos)
+}
+
+// setCurTrack sets the current track index, and by default plays
+// the following track next. Must be called with the lock held.
+func (j *JukeboxPlayer) setCurTrack(idx int) {
+	j.curTrack = idx
+	j.curTrackDuration = 0
+	j.nextTrack = -1
+	if idx >= 0 {
+		j.curTrackDuration = j.queue[idx].Duration.Seconds()
+		if idx+1 < len(j.queue) {
+			j.nextTrack = idx + 1
+		}
 	}
 }
 
-func (j *JukeboxPlayer) Destroy() {}
+// timePos estimates the playback position from when the
+// jukebox was last started. Must be called with the lock held.
+func (j *JukeboxPlayer) timePos() float64 {
+	if j.state != playing {
+		return j.startTrackTime
+	}
+	pos := j.startTrackTime + float64(time.Now().UnixMilli()-j.startedAtUnixMilli)/1000
+	if j.curTrackDuration > 0 {
+		pos = min(pos, j.curTrackDuration)
+	}
+	return pos
+}
 
 func (j *JukeboxPlayer) startAndUpdateTime() error {
 	beforeStart := time.Now()
@@ -149,6 +386,183 @@ func (j *JukeboxPlayer) startAndUpdateTime() error {
 
 	// assume track started playing at (ie has been playing for)
 	// half the round-trip latency
-	j.startedAtUnixMilli = time.Now().Add(-afterStart.Sub(beforeStart)).UnixMilli()
+	j.startedAtUnixMilli = time.Now().Add(-afterStart.Sub(beforeStart) / 2).UnixMilli()
 	return nil
 }
+
+func (j *JukeboxPlayer) pollLoop(ctx context.Context) {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			j.poll()
+		}
+	}
+}
+
+// poll fetches the jukebox state and reconciles it with ours,
+// invoking callbacks for any changes made by other clients.
+func (j *JukeboxPlayer) poll() {
+	j.lock.Lock()
+	seq := j.cmdSeq
+	j.lock.Unlock()
+
+	tracks, stat, err := j.provider.JukeboxGetQueue()
+	if err != nil {
+		log.Printf("failed to get jukebox status: %v", err)
+		return
+	}
+
+	j.lock.Lock()
+	if seq != j.cmdSeq {
+		// a command was sent meanwhile, so the status may be stale
+		j.lock.Unlock()
+		return
+	}
+	callbacks := j.reconcile(tracks, stat)
+	j.lock.Unlock()
+
+	for _, cb := range callbacks {
+		cb()
+	}
+}
+
+// reconcile updates our state from the polled jukebox state and returns
+// the callbacks to invoke once the lock is released.
+// Must be called with the lock held.
+func (j *JukeboxPlayer) reconcile(tracks []*mediaprovider.Track, stat *mediaprovider.JukeboxStatus) []func() {
+	var callbacks []func()
+	if stat.Volume != j.volume {
+		j.volume = stat.Volume
+		if cb, vol := j.onVolumeChange, stat.Volume; cb != nil {
+			callbacks = append(callbacks, func() { cb(vol) })
+		}
+	}
+
+	idx := stat.CurrentTrack
+	if idx < 0 || idx >= len(tracks) {
+		idx = -1
+	}
+	// the jukebox keeps its index when idle, which is meaningless to us
+	active := stat.Playing || j.state != stopped
+
+	queueChanged := !slices.Equal(trackIDs(tracks), trackIDs(j.queue))
+	if !j.synced {
+		// adopt the jukebox's existing queue if we haven't set one
+		queueChanged = len(tracks) > 0
+		j.synced = true
+	}
+	switch {
+	case queueChanged:
+		j.queue = tracks
+		if !active {
+			idx = -1
+		}
+		j.setCurTrack(idx)
+		callbacks = append(callbacks, j.queueChangeCallback(idx))
+	case active && idx >= 0 && idx == j.curTrack+1 && j.curTrack >= 0 && j.state == playing:
+		// the jukebox advanced to the next track
+		return append(callbacks, j.handleTrackEnd(idx, stat)...)
+	case active && idx >= 0 && idx != j.curTrack:
+		// another client skipped to a different track
+		j.setCurTrack(idx)
+		callbacks = append(callbacks, j.queueChangeCallback(idx))
+	}
+
+	switch {
+	case stat.Playing && j.state != playing:
+		// started by another client
+		j.state = playing
+		j.updatePosition(stat)
+		callbacks = append(callbacks, j.InvokeOnPlaying)
+	case !stat.Playing && j.state == playing:
+		if idx < 0 || j.nearTrackEnd() {
+			// finished the last track in the jukebox queue
+			return append(callbacks, j.handleTrackEnd(-1, stat)...)
+		}
+		// paused by another client
+		j.startTrackTime = stat.PositionSeconds
+		j.state = paused
+		callbacks = append(callbacks, j.InvokeOnPaused)
+	case stat.Playing:
+		j.updatePosition(stat)
+	}
+	return callbacks
+}
+
+// handleTrackEnd handles the current track finishing, with the jukebox
+// now playing the track at idx, or stopped if idx < 0. If we want to play
+// a different track next, e.g. when looping, the jukebox is skipped to it.
+// Must be called with the lock held.
+func (j *JukeboxPlayer) handleTrackEnd(idx int, stat *mediaprovider.JukeboxStatus) []func() {
+	if idx >= 0 && j.nextTrack == idx {
+		j.setCurTrack(idx)
+		j.updatePosition(stat)
+		return []func(){j.InvokeOnTrackChange}
+	}
+	if j.nextTrack >= 0 {
+		err := j.playAt(j.nextTrack, 0)
+		if err == nil {
+			return []func(){j.InvokeOnTrackChange}
+		}
+		log.Printf("failed to skip jukebox to next track: %v", err)
+	}
+	if idx >= 0 {
+		if err := j.provider.JukeboxStop(); err != nil {
+			log.Printf("failed to stop jukebox: %v", err)
+		}
+	}
+	j.state = stopped
+	j.setCurTrack(-1)
+	return []func(){j.InvokeOnStopped}
+}
+
+func (j *JukeboxPlayer) queueChangeCallback(idx int) func() {
+	items := sharedutil.CopyTrackSliceToMediaItemSlice(j.queue)
+	cb := j.onQueueChange
+	return func() {
+		if cb != nil {
+			cb(items, idx)
+		}
+	}
+}
+
+// updatePosition resyncs our position estimate to the jukebox.
+// Must be called with the lock held.
+func (j *JukeboxPlayer) updatePosition(stat *mediaprovider.JukeboxStatus) {
+	j.startTrackTime = stat.PositionSeconds
+	j.startedAtUnixMilli = time.Now().UnixMilli()
+}
+
+// nearTrackEnd returns whether the current track could have finished
+// since the last poll. Must be called with the lock held.
+func (j *JukeboxPlayer) nearTrackEnd() bool {
+	return j.curTrackDuration > 0 &&
+		j.timePos() >= j.curTrackDuration-pollInterval.Seconds()-1
+}
+
+func trackIDs(tracks []*mediaprovider.Track) []string {
+	ids := make([]string, len(tracks))
+	for i, tr := range tracks {
+		ids[i] = tr.ID
+	}
+	return ids
+}
+
+// removedIndexes returns the indexes of the items in old that were
+// removed to get new, or false if new can't be made by removing items.
+func removedIndexes(old, new []string) ([]int, bool) {
+	var removed []int
+	n := 0
+	for i, id := range old {
+		if n < len(new) && new[n] == id {
+			n++
+		} else {
+			removed = append(removed, i)
+		}
+	}
+	return removed, n == len(new)
+}
@@ -0,0 +1,161 @@
+package jukebox
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// fakeJukebox is an in-memory jukebox that records the actions it receives.
+type fakeJukebox struct {
+	queue   []string
+	current int
+	playing bool
+	gain    int
+	actions []string
+}
+
+func (f *fakeJukebox) record(action string, args ...any) {
+	f.actions = append(f.actions, strings.TrimSpace(action+" "+fmt.Sprint(args...)))
+}
+
+func (f *fakeJukebox) JukeboxStart() error { f.record("start"); f.playing = true; return nil }
+func (f *fakeJukebox) JukeboxStop() error  { f.record("stop"); f.playing = false; return nil }
+func (f *fakeJukebox) JukeboxClear() error { f.record("clear"); f.queue = nil; return nil }
+func (f *fakeJukebox) JukeboxSeek(idx, seconds int) error {
+	f.record("skip", idx)
+	f.current = idx
+	return nil
+}
+func (f *fakeJukebox) JukeboxAdd(ids ...string) error {
+	f.record("add", ids)
+	f.queue = append(f.queue, ids...)
+	return nil
+}
+func (f *fakeJukebox) JukeboxRemove(idx int) error {
+	f.record("remove", idx)
+	f.queue = slices.Delete(f.queue, idx, idx+1)
+	return nil
+}
+func (f *fakeJukebox) JukeboxSet(ids ...string) error {
+	f.record("set", ids)
+	f.queue = slices.Clone(ids)
+	return nil
+}
+func (f *fakeJukebox) JukeboxSetVolume(vol int) error { f.gain = vol; return nil }
+func (f *fakeJukebox) JukeboxGetStatus() (*mediaprovider.JukeboxStatus, error) {
+	_, stat, err := f.JukeboxGetQueue()
+	return stat, err
+}
+func (f *fakeJukebox) JukeboxGetQueue() ([]*mediaprovider.Track, *mediaprovider.JukeboxStatus, error) {
+	return tracks(f.queue...), &mediaprovider.JukeboxStatus{
+		Volume:       f.gain,
+		CurrentTrack: f.current,
+		Playing:      f.playing,
+	}, nil
+}
+
+func tracks(ids ...string) []*mediaprovider.Track {
+	tr := make([]*mediaprovider.Track, len(ids))
+	for i, id := range ids {
+		tr[i] = &mediaprovider.Track{ID: id, Duration: 3 * time.Minute}
+	}
+	return tr
+}
+
+func items(ids ...string) []mediaprovider.MediaItem {
+	var it []mediaprovider.MediaItem
+	for _, tr := range tracks(ids...) {
+		it = append(it, tr)
+	}
+	return it
+}
+
+func newTestPlayer(f *fakeJukebox) *JukeboxPlayer {
+	// not started with NewJukeboxPlayer, so the test can poll manually
+	tr, stat, _ := f.JukeboxGetQueue()
+	return &JukeboxPlayer{provider: f, queue: tr, volume: stat.Volume, curTrack: -1, nextTrack: -1}
+}
+
+func TestJukeboxQueueSync(t *testing.T) {
+	f := &fakeJukebox{}
+	j := newTestPlayer(f)
+
+	steps := []struct {
+		queue  []string
+		action string
+	}{
+		{[]string{"a", "b", "c"}, "add [a b c]"},
+		{[]string{"a", "b", "c", "d"}, "add [d]"},
+		{[]string{"a", "c", "d"}, "remove 1"},
+		{[]string{"d", "a", "c"}, "set [d a c]"},
+	}
+	for _, s := range steps {
+		f.actions = nil
+		if err := j.SetQueue(items(s.queue...), -1); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(f.queue, s.queue) {
+			t.Errorf("jukebox queue %v, expected %v", f.queue, s.queue)
+		}
+		if len(f.actions) == 0 || f.actions[0] != s.action {
+			t.Errorf("expected %q to sync %v, got %v", s.action, s.queue, f.actions)
+		}
+	}
+
+	f.actions = nil
+	j.SetQueue(items("d", "a", "c"), -1)
+	if len(f.actions) != 0 {
+		t.Errorf("unchanged queue should not be sent, got %v", f.actions)
+	}
+}
+
+func TestJukeboxExternalChanges(t *testing.T) {
+	f := &fakeJukebox{}
+	j := newTestPlayer(f)
+	var gotQueue []string
+	gotIdx := -2
+	j.OnQueueChange(func(it []mediaprovider.MediaItem, idx int) {
+		gotQueue = nil
+		for _, i := range it {
+			gotQueue = append(gotQueue, i.Metadata().ID)
+		}
+		gotIdx = idx
+	})
+	var trackChanges, stops int
+	j.OnTrackChange(func() { trackChanges++ })
+	j.OnStopped(func() { stops++ })
+
+	j.SetQueue(items("a", "b", "c"), -1)
+	if err := j.PlayTrackAt(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	trackChanges = 0
+
+	// the jukebox advances to the next track on its own
+	f.current = 1
+	j.poll()
+	if trackChanges != 1 || gotIdx != -2 {
+		t.Errorf("expected a track change only, got %d track changes and queue change to %d", trackChanges, gotIdx)
+	}
+
+	// another client skips and appends a track
+	f.current = 0
+	f.queue = append(f.queue, "e")
+	j.poll()
+	if !slices.Equal(gotQueue, []string{"a", "b", "c", "e"}) || gotIdx != 0 {
+		t.Errorf("unexpected queue change %v at %d", gotQueue, gotIdx)
+	}
+
+	// we want to stop after the current track, but the jukebox advanced
+	j.SetNextTrackAt(-1)
+	f.current = 1
+	j.poll()
+	if stops != 1 || f.playing {
+		t.Errorf("expected jukebox to be stopped, playing: %v", f.playing)
+	}
+}
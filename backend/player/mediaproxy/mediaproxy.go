@@ -0,0 +1,205 @@
+// Package mediaproxy implements a local HTTP server that proxies media
+// streams to remote players (e.g. DLNA renderers or Cast devices),
+// which may not be able to reach the music server directly, or need
+// to play files from the local audio cache.
+package mediaproxy
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/util"
+)
+
+type proxyMapEntry struct {
+	key string
+	url string
+	// if set, the media is transcoded to this MIME type
+	// when the source is in a different format
+	mimeType string
+}
+
+type Server struct {
+	server  *http.Server
+	active  atomic.Bool
+	localIP string
+	port    int
+
+	// keep in order of most recently accessed at the end
+	// that way the item in urls[0] can be kicked out
+	// when adding a new URL to the proxy, since
+	// only two will need to be active at any given time
+	urls    [3]proxyMapEntry
+	urlLock sync.Mutex
+}
+
+// EnsureStarted starts the proxy server if it is not already running.
+func (s *Server) EnsureStarted() error {
+	if s.active.Swap(true) {
+		return nil // already active
+	}
+
+	var err error
+	s.localIP, err = util.GetLocalIP()
+	if err != nil {
+		s.active.Store(false)
+		return err
+	}
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		s.active.Store(false)
+		return err
+	}
+	s.port = listener.Addr().(*net.TCPAddr).Port
+
+	s.server = &http.Server{
+		Handler: http.HandlerFunc(s.handleRequest),
+	}
+
+	go s.server.Serve(listener)
+	return nil
+}
+
+// Shutdown stops the proxy server, if running.
+func (s *Server) Shutdown() {
+	if srv := s.server; srv != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		go func() {
+			defer cancel()
+			srv.Shutdown(ctx)
+		}()
+		s.server = nil
+		s.active.Store(false)
+	}
+}
+
+// AddURL adds the URL or local file path to the proxy,
+// and returns the URL it can be fetched from through the proxy.
+func (s *Server) AddURL(url string) string {
+	return s.addEntry(proxyMapEntry{url: url})
+}
+
+// AddTranscodedURL is like AddURL, but the media is served in the given
+// MIME type. If the source (e.g. a music server that was asked to transcode)
+// is not already in that format, it is transcoded locally with mpv.
+func (s *Server) AddTranscodedURL(url, mimeType string) string {
+	return s.addEntry(proxyMapEntry{url: url, mimeType: mimeType})
+}
+
+func (s *Server) addEntry(e proxyMapEntry) string {
+	hash := md5.Sum([]byte(e.mimeType + e.url))
+	e.key = base64.URLEncoding.EncodeToString(hash[:])
+	s.urlLock.Lock()
+	defer s.urlLock.Unlock()
+	s._updateURL(e)
+	return fmt.Sprintf("http://%s:%d/%s", s.localIP, s.port, e.key)
+}
+
+func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	entry, ok := s.lookupURL(key)
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("404"))
+		return
+	}
+	if entry.mimeType != "" {
+		ServeTranscoded(w, r, entry.url, entry.mimeType)
+		return
+	}
+	ServeURL(w, r, entry.url)
+}
+
+// ServeURL serves the local file at the given path if it exists,
+// and otherwise proxies the request to the given URL.
+func ServeURL(w http.ResponseWriter, r *http.Request, url string) {
+	// if the url is a filepath for a local cached file, serve it
+	if info, err := os.Stat(url); err == nil && info.Size() > 0 {
+		http.ServeFile(w, r, url)
+		return
+	}
+
+	// Otherwise, proxy request to the music server
+	proxyReq, err := http.NewRequest(r.Method, url, r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Copy headers from the original request to the new request
+	proxyReq.Header = r.Header
+
+	// Create an HTTP client and send the request
+	client := &http.Client{}
+	resp, err := client.Do(proxyReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	// Copy headers from the response to the writer
+	for name, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	// Set the status code
+	w.WriteHeader(resp.StatusCode)
+
+	// Copy the response body to the writer
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error copying response body:", err)
+	}
+}
+
+// lookupURL finds an entry by key and updates its position to most recently used
+func (s *Server) lookupURL(key string) (proxyMapEntry, bool) {
+	s.urlLock.Lock()
+	defer s.urlLock.Unlock()
+
+	for i := range len(s.urls) {
+		if s.urls[i].key == key {
+			entry := s.urls[i]
+			// Move accessed entry to the most recent position
+			s._updateURL(entry)
+			return entry, true
+		}
+	}
+
+	return proxyMapEntry{}, false
+}
+
+func (s *Server) _updateURL(entry proxyMapEntry) {
+	// Check if the key already exists, and if so, move it to the most recently used position
+	for i := range len(s.urls) {
+		if s.urls[i].key == entry.key {
+			if i < len(s.urls)-1 {
+				// Shift elements to the left from found position to the end
+				copy(s.urls[i:], s.urls[i+1:])
+			}
+			// Place updated entry at the last position
+			s.urls[len(s.urls)-1] = entry
+			return
+		}
+	}
+
+	// Shift all elements left to make room for the new entry at the end
+	copy(s.urls[:], s.urls[1:])
+	// Insert new element at the most recent position
+	s.urls[len(s.urls)-1] = entry
+}
@@ -0,0 +1,119 @@
+package mediaproxy
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// mimeAliases maps non-standard MIME types commonly reported
+// by servers and devices to a canonical equivalent.
+var mimeAliases = map[string]string{
+	"audio/mp3":             "audio/mpeg",
+	"audio/mpeg3":           "audio/mpeg",
+	"audio/x-mpeg":          "audio/mpeg",
+	"audio/x-mp3":           "audio/mpeg",
+	"audio/x-flac":          "audio/flac",
+	"application/flac":      "audio/flac",
+	"audio/x-wav":           "audio/wav",
+	"audio/wave":            "audio/wav",
+	"audio/vnd.wave":        "audio/wav",
+	"audio/x-m4a":           "audio/mp4",
+	"audio/m4a":             "audio/mp4",
+	"audio/aac":             "audio/vnd.dlna.adts",
+	"audio/x-aac":           "audio/vnd.dlna.adts",
+	"audio/aacp":            "audio/vnd.dlna.adts",
+	"application/ogg":       "audio/ogg",
+	"audio/x-ogg":           "audio/ogg",
+	"audio/x-ape":           "audio/ape",
+	"audio/x-monkeys-audio": "audio/ape",
+}
+
+// NormalizeMIMEType returns the canonical, parameter-free, lower-case
+// form of a MIME type so that aliases of the same format compare equal.
+func NormalizeMIMEType(mimeType string) string {
+	if mt, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mt
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if alias, ok := mimeAliases[mimeType]; ok {
+		return alias
+	}
+	return mimeType
+}
+
+// localTranscodeArgs returns the mpv encoding options
+// to transcode to the given MIME type.
+func localTranscodeArgs(mimeType string) ([]string, bool) {
+	switch NormalizeMIMEType(mimeType) {
+	case "audio/mpeg":
+		return []string{"--of=mp3", "--oac=libmp3lame", "--oacopts=b=320k"}, true
+	case "audio/vnd.dlna.adts":
+		return []string{"--of=adts", "--oac=aac", "--oacopts=b=256k"}, true
+	case "audio/flac":
+		return []string{"--of=flac", "--oac=flac"}, true
+	case "audio/wav":
+		return []string{"--of=wav", "--oac=pcm_s16le"}, true
+	}
+	return nil, false
+}
+
+// ServeTranscoded serves the media at the given URL or local file path
+// in the given MIME type. Sources already in that format (e.g. when the
+// music server was asked to transcode) are proxied unchanged; otherwise
+// the media is transcoded locally by an mpv subprocess.
+func ServeTranscoded(w http.ResponseWriter, r *http.Request, url, mimeType string) {
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	if _, err := os.Stat(url); err != nil {
+		resp, err := http.Get(url)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if resp.StatusCode == http.StatusOK &&
+			NormalizeMIMEType(resp.Header.Get("Content-Type")) == NormalizeMIMEType(mimeType) {
+			w.Header().Set("Content-Type", mimeType)
+			if r.Method == http.MethodHead {
+				resp.Body.Close()
+				return
+			}
+			io.Copy(w, resp.Body)
+			resp.Body.Close()
+			return
+		}
+		resp.Body.Close()
+	}
+
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", mimeType)
+		return
+	}
+
+	args, ok := localTranscodeArgs(mimeType)
+	mpvPath, err := exec.LookPath("mpv")
+	if !ok || err != nil {
+		log.Printf("cannot transcode to %s locally: mpv not found or format unsupported", mimeType)
+		http.Error(w, "transcoding unavailable", http.StatusBadGateway)
+		return
+	}
+	args = append([]string{"--no-config", "--really-quiet", "--no-video", "--o=-"}, args...)
+	args = append(args, "--", url)
+	cmd := exec.CommandContext(r.Context(), mpvPath, args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mimeType)
+	io.Copy(w, out)
+	// killed via the request context if the client disconnected
+	cmd.Wait()
+}
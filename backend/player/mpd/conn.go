@@ -0,0 +1,167 @@
+package mpd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// timeout for commands other than idle
+const commandTimeout = 10 * time.Second
+
+// conn is a connection to an MPD server speaking the MPD text protocol.
+type conn struct {
+	c net.Conn
+	r *bufio.Reader
+}
+
+// field is a "key: value" line of a command response.
+type field struct {
+	key, value string
+}
+
+type response []field
+
+// Get returns the value of the first field with the given key.
+func (r response) Get(key string) string {
+	for _, f := range r {
+		if f.key == key {
+			return f.value
+		}
+	}
+	return ""
+}
+
+// All returns the values of all fields with the given key.
+func (r response) All(key string) []string {
+	var vals []string
+	for _, f := range r {
+		if f.key == key {
+			vals = append(vals, f.value)
+		}
+	}
+	return vals
+}
+
+// ackError is an error reported by the server in response to a command.
+type ackError struct {
+	Code    int
+	Command string
+	Message string
+}
+
+func (e *ackError) Error() string {
+	return fmt.Sprintf("mpd: %s: %s", e.Command, e.Message)
+}
+
+// dial connects to the MPD server at addr (host:port),
+// authenticating with the password if it is not empty.
+func dial(ctx context.Context, addr, password string) (*conn, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{c: nc, r: bufio.NewReader(nc)}
+
+	nc.SetDeadline(time.Now().Add(commandTimeout))
+	greeting, err := c.r.ReadString('\n')
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "OK MPD ") {
+		nc.Close()
+		return nil, fmt.Errorf("%s is not an MPD server", addr)
+	}
+	if password != "" {
+		if _, err := c.command("password", password); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// command sends a command and reads its response.
+func (c *conn) command(name string, args ...string) (response, error) {
+	c.c.SetDeadline(time.Now().Add(commandTimeout))
+	return c.do(name, args)
+}
+
+// idle waits for changes to the given subsystems, without a timeout,
+// and returns the names of the subsystems that changed.
+func (c *conn) idle(subsystems ...string) ([]string, error) {
+	c.c.SetDeadline(time.Time{})
+	resp, err := c.do("idle", subsystems)
+	if err != nil {
+		return nil, err
+	}
+	return resp.All("changed"), nil
+}
+
+func (c *conn) do(name string, args []string) (response, error) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, a := range args {
+		sb.WriteByte(' ')
+		sb.WriteString(quote(a))
+	}
+	sb.WriteByte('\n')
+	if _, err := c.c.Write([]byte(sb.String())); err != nil {
+		return nil, err
+	}
+
+	var resp response
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "OK":
+			return resp, nil
+		case strings.HasPrefix(line, "ACK "):
+			return nil, parseAck(line)
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("mpd: malformed response line %q", line)
+		}
+		resp = append(resp, field{key: key, value: value})
+	}
+}
+
+func (c *conn) Close() error {
+	return c.c.Close()
+}
+
+// parseAck parses an error line of the form
+// "ACK [code@listIndex] {command} message".
+func parseAck(line string) error {
+	e := &ackError{Message: strings.TrimPrefix(line, "ACK ")}
+	if open, rest, ok := strings.Cut(e.Message, "] {"); ok {
+		fmt.Sscanf(open, "[%d@", &e.Code)
+		if cmd, msg, ok := strings.Cut(rest, "} "); ok {
+			e.Command, e.Message = cmd, msg
+		}
+	}
+	return e
+}
+
+// quote quotes a command argument, escaping backslashes and double quotes.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// isAck returns true if err was reported by the server,
+// i.e. the connection itself is still usable.
+func isAck(err error) bool {
+	var ack *ackError
+	return errors.As(err, &ack)
+}
@@ -0,0 +1,450 @@
+// Package mpd implements a remote player that plays
+// through a Music Player Daemon (MPD) server.
+package mpd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/mediaproxy"
+)
+
+const (
+	connectTimeout = 5 * time.Second
+	// delay before reconnecting the event watcher after a lost connection
+	reconnectDelay = 5 * time.Second
+)
+
+var (
+	_ player.URLPlayer          = (*MPDPlayer)(nil)
+	_ player.VolumeChangePlayer = (*MPDPlayer)(nil)
+)
+
+// MPDPlayer plays media on an MPD server. MPD fetches the media through
+// a local proxy, since it may not be able to reach the music server,
+// and needs to play files from the local audio cache.
+//
+// The MPD playlist holds only the current and next tracks; the play queue
+// stays in Supersonic. Track changes and playback state changes are
+// watched for with the idle command on a second connection.
+type MPDPlayer struct {
+	player.BasePlayerCallbackImpl
+
+	addr     string
+	password string
+	proxy    mediaproxy.Server
+
+	// connection for commands, reconnected on demand
+	connLock sync.Mutex
+	conn     *conn
+
+	// held while running a sequence of commands that changes playback,
+	// so that the event watcher sees only the resulting state
+	lock           sync.Mutex
+	state          player.State
+	curID          string // MPD song ID of the current track
+	nextID         string // MPD song ID of the next track, if set
+	curDuration    float64
+	nextDuration   float64
+	volume         int
+	onVolumeChange func(int)
+
+	seeking   atomic.Bool
+	destroyed atomic.Bool
+	cancel    context.CancelFunc
+	idleLock  sync.Mutex
+	idleConn  *conn
+}
+
+// NewMPDPlayer connects to the MPD server at addr (host:port).
+func NewMPDPlayer(addr, password string) (*MPDPlayer, error) {
+	m := &MPDPlayer{addr: addr, password: password, volume: 100}
+	stat, err := m.command("status")
+	if err != nil {
+		return nil, err
+	}
+	if vol, err := strconv.Atoi(stat.Get("volume")); err == nil && vol >= 0 {
+		m.volume = vol
+	}
+	// the playlist must play through in order for gapless playback
+	for _, cmd := range []string{"random", "repeat", "single", "consume"} {
+		if _, err := m.command(cmd, "0"); err != nil {
+			m.closeConn()
+			return nil, err
+		}
+	}
+	if err := m.proxy.EnsureStarted(); err != nil {
+		m.closeConn()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	go m.watchEvents(ctx)
+	return m, nil
+}
+
+func (m *MPDPlayer) PlayFile(url string, meta mediaprovider.MediaItemMetadata, startTime float64) error {
+	if m.destroyed.Load() {
+		return nil
+	}
+	m.lock.Lock()
+	if _, err := m.command("clear"); err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	id, err := m.addID(url)
+	if err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	if startTime > 0 {
+		_, err = m.command("seekid", id, formatSeconds(startTime))
+	} else {
+		_, err = m.command("playid", id)
+	}
+	if err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	m.curID, m.nextID = id, ""
+	m.curDuration = meta.Duration.Seconds()
+	m.state = player.Playing
+	m.lock.Unlock()
+
+	m.InvokeOnPlaying()
+	m.InvokeOnTrackChange()
+	if startTime > 0 {
+		m.InvokeOnSeek()
+	}
+	return nil
+}
+
+func (m *MPDPlayer) SetNextFile(url string, meta mediaprovider.MediaItemMetadata) error {
+	if m.destroyed.Load() {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.nextID != "" {
+		// the song may already be gone if the playlist was changed externally
+		if _, err := m.command("deleteid", m.nextID); err != nil && !isAck(err) {
+			return err
+		}
+		m.nextID = ""
+	}
+	if url == "" {
+		return nil
+	}
+	id, err := m.addID(url)
+	if err != nil {
+		return err
+	}
+	m.nextID = id
+	m.nextDuration = meta.Duration.Seconds()
+	return nil
+}
+
+func (m *MPDPlayer) Continue() error {
+	return m.setState(player.Playing, m.InvokeOnPlaying, "pause", "0")
+}
+
+func (m *MPDPlayer) Pause() error {
+	return m.setState(player.Paused, m.InvokeOnPaused, "pause", "1")
+}
+
+func (m *MPDPlayer) Stop(_ bool) error {
+	if m.destroyed.Load() {
+		return nil
+	}
+	m.lock.Lock()
+	if m.state == player.Stopped {
+		m.lock.Unlock()
+		return nil
+	}
+	// clear the playlist too, so the next track can't play
+	_, err := m.command("stop")
+	if err == nil {
+		_, err = m.command("clear")
+	}
+	if err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	m.state = player.Stopped
+	m.curID, m.nextID = "", ""
+	m.lock.Unlock()
+
+	m.InvokeOnStopped()
+	return nil
+}
+
+func (m *MPDPlayer) setState(state player.State, callback func(), cmd string, args ...string) error {
+	if m.destroyed.Load() {
+		return nil
+	}
+	m.lock.Lock()
+	if m.state == state || m.state == player.Stopped {
+		m.lock.Unlock()
+		return nil
+	}
+	if _, err := m.command(cmd, args...); err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	m.state = state
+	m.lock.Unlock()
+
+	callback()
+	return nil
+}
+
+func (m *MPDPlayer) SeekSeconds(secs float64) error {
+	if m.destroyed.Load() {
+		return nil
+	}
+	m.seeking.Store(true)
+	_, err := m.command("seekcur", formatSeconds(secs))
+	m.seeking.Store(false)
+	if err != nil {
+		return err
+	}
+	m.InvokeOnSeek()
+	return nil
+}
+
+func (m *MPDPlayer) IsSeeking() bool {
+	return m.seeking.Load()
+}
+
+func (m *MPDPlayer) SetVolume(vol int) error {
+	if m.destroyed.Load() {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, err := m.command("setvol", strconv.Itoa(vol)); err != nil {
+		return err
+	}
+	m.volume = vol
+	return nil
+}
+
+func (m *MPDPlayer) GetVolume() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.volume
+}
+
+// OnVolumeChange registers a callback for when the
+// volume is changed on the MPD server by another client.
+func (m *MPDPlayer) OnVolumeChange(cb func(int)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.onVolumeChange = cb
+}
+
+func (m *MPDPlayer) GetStatus() player.Status {
+	m.lock.Lock()
+	stat := player.Status{State: m.state, Duration: m.curDuration}
+	m.lock.Unlock()
+	if stat.State == player.Stopped || m.destroyed.Load() {
+		return stat
+	}
+
+	resp, err := m.command("status")
+	if err != nil {
+		return stat
+	}
+	stat.TimePos, _ = strconv.ParseFloat(resp.Get("elapsed"), 64)
+	if dur, err := strconv.ParseFloat(resp.Get("duration"), 64); err == nil && dur > 0 {
+		stat.Duration = dur
+	}
+	return stat
+}
+
+func (m *MPDPlayer) Destroy() {
+	if m.destroyed.Swap(true) {
+		return
+	}
+	m.cancel()
+	m.idleLock.Lock()
+	if m.idleConn != nil {
+		m.idleConn.Close()
+	}
+	m.idleLock.Unlock()
+	m.closeConn()
+	m.proxy.Shutdown()
+}
+
+// addID adds the URL to the playlist through the proxy and returns its song ID.
+func (m *MPDPlayer) addID(url string) (string, error) {
+	resp, err := m.command("addid", m.proxy.AddURL(url))
+	if err != nil {
+		return "", err
+	}
+	id := resp.Get("Id")
+	if id == "" {
+		return "", errors.New("mpd: addid returned no song ID")
+	}
+	return id, nil
+}
+
+// command runs a command on the command connection,
+// reconnecting and retrying once if the connection was lost.
+func (m *MPDPlayer) command(name string, args ...string) (response, error) {
+	m.connLock.Lock()
+	defer m.connLock.Unlock()
+	for attempt := 0; ; attempt++ {
+		if m.conn == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+			c, err := dial(ctx, m.addr, m.password)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+			m.conn = c
+		}
+		resp, err := m.conn.command(name, args...)
+		if err == nil || isAck(err) || attempt > 0 {
+			return resp, err
+		}
+		m.conn.Close()
+		m.conn = nil
+	}
+}
+
+func (m *MPDPlayer) closeConn() {
+	m.connLock.Lock()
+	defer m.connLock.Unlock()
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+}
+
+// watchEvents waits for player and volume changes on a second
+// connection, reconnecting if the connection is lost.
+func (m *MPDPlayer) watchEvents(ctx context.Context) {
+	for ctx.Err() == nil {
+		c, err := dial(ctx, m.addr, m.password)
+		if err != nil {
+			log.Printf("failed to connect to MPD for events: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(reconnectDelay):
+			}
+			continue
+		}
+		m.idleLock.Lock()
+		m.idleConn = c
+		m.idleLock.Unlock()
+
+		// catch up on anything that changed while disconnected
+		changed := []string{"player", "mixer"}
+		for err == nil && ctx.Err() == nil {
+			m.handleChanges(changed)
+			changed, err = c.idle("player", "mixer")
+		}
+		c.Close()
+	}
+}
+
+// handleChanges reconciles our state with the server's after the
+// given subsystems changed, invoking callbacks for any differences.
+func (m *MPDPlayer) handleChanges(subsystems []string) {
+	var callbacks []func()
+	m.lock.Lock()
+	for _, s := range subsystems {
+		switch s {
+		case "player":
+			callbacks = append(callbacks, m.handlePlayerChange()...)
+		case "mixer":
+			callbacks = append(callbacks, m.handleMixerChange()...)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, cb := range callbacks {
+		cb()
+	}
+}
+
+// Must be called with the lock held.
+func (m *MPDPlayer) handlePlayerChange() []func() {
+	stat, err := m.command("status")
+	if err != nil {
+		log.Printf("failed to get MPD status: %v", err)
+		return nil
+	}
+
+	if m.curID == "" {
+		// not playing anything of ours
+		return nil
+	}
+	var callbacks []func()
+	state := player.Stopped
+	switch stat.Get("state") {
+	case "play":
+		state = player.Playing
+	case "pause":
+		state = player.Paused
+	}
+	switch songID := stat.Get("songid"); songID {
+	case m.curID:
+	case m.nextID:
+		// advanced to the next track; remove the previous one so
+		// that the playlist holds only the current and next tracks
+		if _, err := m.command("deleteid", m.curID); err != nil {
+			log.Printf("failed to remove previous track from MPD playlist: %v", err)
+		}
+		m.curID, m.nextID = songID, ""
+		m.curDuration = m.nextDuration
+		callbacks = append(callbacks, m.InvokeOnTrackChange)
+	default:
+		// finished the last track, or another client replaced the playlist
+		state = player.Stopped
+	}
+
+	if state != m.state {
+		m.state = state
+		switch state {
+		case player.Playing:
+			callbacks = append(callbacks, m.InvokeOnPlaying)
+		case player.Paused:
+			callbacks = append(callbacks, m.InvokeOnPaused)
+		case player.Stopped:
+			m.curID, m.nextID = "", ""
+			callbacks = append(callbacks, m.InvokeOnStopped)
+		}
+	}
+	return callbacks
+}
+
+// Must be called with the lock held.
+func (m *MPDPlayer) handleMixerChange() []func() {
+	stat, err := m.command("status")
+	if err != nil {
+		return nil
+	}
+	vol, err := strconv.Atoi(stat.Get("volume"))
+	if err != nil || vol < 0 || vol == m.volume {
+		return nil
+	}
+	m.volume = vol
+	if cb := m.onVolumeChange; cb != nil {
+		return []func(){func() { cb(vol) }}
+	}
+	return nil
+}
+
+func formatSeconds(secs float64) string {
+	return strconv.FormatFloat(secs, 'f', 3, 64)
+}
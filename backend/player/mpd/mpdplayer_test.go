@@ -0,0 +1,257 @@
+package mpd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+type fakeSong struct {
+	id  int
+	url string
+}
+
+// fakeMPD is a minimal stand-in for an MPD server, with a playlist
+// and playback state that tests can change to simulate events.
+type fakeMPD struct {
+	ln net.Listener
+
+	lock     sync.Mutex
+	playlist []fakeSong
+	nextID   int
+	songID   int
+	state    string
+	volume   int
+	commands []string
+	idlers   []chan string
+	pending  []string // changes that happened while no client was idling
+}
+
+func newFakeMPD(t *testing.T) *fakeMPD {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeMPD{ln: ln, nextID: 1, state: "stop", volume: 50}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeMPD) serve(c net.Conn) {
+	defer c.Close()
+	fmt.Fprint(c, "OK MPD 0.23.5\n")
+	r := bufio.NewReader(c)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		name, args := parseCommand(strings.TrimSpace(line))
+		if name == "idle" {
+			ch := make(chan string, 4)
+			f.lock.Lock()
+			if len(f.pending) > 0 {
+				ch <- f.pending[0]
+				f.pending = f.pending[1:]
+			} else {
+				f.idlers = append(f.idlers, ch)
+			}
+			f.lock.Unlock()
+			fmt.Fprintf(c, "changed: %s\nOK\n", <-ch)
+			continue
+		}
+		f.lock.Lock()
+		f.commands = append(f.commands, name)
+		resp, changed := f.handle(name, args)
+		f.lock.Unlock()
+		io.WriteString(c, resp)
+		if changed != "" {
+			f.notify(changed)
+		}
+	}
+}
+
+func (f *fakeMPD) handle(name string, args []string) (resp, changed string) {
+	switch name {
+	case "status":
+		resp = fmt.Sprintf("volume: %d\nstate: %s\n", f.volume, f.state)
+		if f.state != "stop" {
+			resp += fmt.Sprintf("songid: %d\nelapsed: 12.500\nduration: 180.000\n", f.songID)
+		}
+	case "clear":
+		f.playlist, f.state = nil, "stop"
+		changed = "player"
+	case "addid":
+		f.playlist = append(f.playlist, fakeSong{id: f.nextID, url: args[0]})
+		resp = fmt.Sprintf("Id: %d\n", f.nextID)
+		f.nextID++
+	case "deleteid":
+		idx := slices.IndexFunc(f.playlist, func(s fakeSong) bool { return fmt.Sprint(s.id) == args[0] })
+		if idx < 0 {
+			return "ACK [50@0] {deleteid} No such song\n", ""
+		}
+		f.playlist = slices.Delete(f.playlist, idx, idx+1)
+	case "playid", "seekid":
+		fmt.Sscan(args[0], &f.songID)
+		f.state = "play"
+		changed = "player"
+	case "pause":
+		f.state = map[string]string{"0": "play", "1": "pause"}[args[0]]
+		changed = "player"
+	case "stop":
+		f.state = "stop"
+		changed = "player"
+	case "setvol":
+		fmt.Sscan(args[0], &f.volume)
+		changed = "mixer"
+	}
+	return resp + "OK\n", changed
+}
+
+// notify wakes up idling clients, or queues the change
+// for the next idle command if no client is idling.
+func (f *fakeMPD) notify(subsystem string) {
+	f.lock.Lock()
+	idlers := f.idlers
+	f.idlers = nil
+	if len(idlers) == 0 && !slices.Contains(f.pending, subsystem) {
+		f.pending = append(f.pending, subsystem)
+	}
+	f.lock.Unlock()
+	for _, ch := range idlers {
+		ch <- subsystem
+	}
+}
+
+func parseCommand(line string) (string, []string) {
+	name, rest, _ := strings.Cut(line, " ")
+	var args []string
+	for _, a := range strings.Split(rest, `" "`) {
+		if a = strings.Trim(a, `"`); a != "" {
+			args = append(args, strings.ReplaceAll(a, `\"`, `"`))
+		}
+	}
+	return name, args
+}
+
+func waitFor(t *testing.T, ch <-chan int, what string) int {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		return 0
+	}
+}
+
+func TestMPDPlayer(t *testing.T) {
+	f := newFakeMPD(t)
+	m, err := NewMPDPlayer(f.ln.Addr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Destroy()
+	if v := m.GetVolume(); v != 50 {
+		t.Errorf("expected initial volume 50, got %d", v)
+	}
+
+	trackChanged := make(chan int, 4)
+	stopped := make(chan int, 4)
+	volume := make(chan int, 4)
+	m.OnTrackChange(func() { trackChanged <- 1 })
+	m.OnStopped(func() { stopped <- 1 })
+	m.OnVolumeChange(func(v int) { volume <- v })
+
+	music := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("audio " + r.URL.Path))
+	})}
+	musicLn, _ := net.Listen("tcp", "127.0.0.1:0")
+	go music.Serve(musicLn)
+	defer music.Close()
+	base := "http://" + musicLn.Addr().String()
+
+	if err := m.PlayFile(base+"/1", mediaprovider.MediaItemMetadata{Duration: time.Minute}, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, trackChanged, "initial track change")
+	if err := m.SetNextFile(base+"/2", mediaprovider.MediaItemMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// MPD fetches the media through the proxy
+	f.lock.Lock()
+	playlist := slices.Clone(f.playlist)
+	f.lock.Unlock()
+	if len(playlist) != 2 {
+		t.Fatalf("expected current and next track in playlist, got %v", playlist)
+	}
+	resp, err := http.Get(playlist[1].url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "audio /2" {
+		t.Errorf("unexpected proxied media %q", body)
+	}
+
+	if s := m.GetStatus(); s.TimePos != 12.5 || s.Duration != 180 {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	// MPD advances to the next track
+	f.lock.Lock()
+	f.songID = playlist[1].id
+	f.lock.Unlock()
+	f.notify("player")
+	waitFor(t, trackChanged, "gapless track change")
+	time.Sleep(50 * time.Millisecond)
+	f.lock.Lock()
+	if len(f.playlist) != 1 || f.playlist[0].id != playlist[1].id {
+		t.Errorf("expected previous track removed, got %v", f.playlist)
+	}
+	f.lock.Unlock()
+
+	// another client changes the volume, then stops playback
+	f.lock.Lock()
+	f.volume = 30
+	f.lock.Unlock()
+	f.notify("mixer")
+	if v := waitFor(t, volume, "volume change"); v != 30 {
+		t.Errorf("expected volume 30, got %d", v)
+	}
+	f.lock.Lock()
+	f.state = "stop"
+	f.lock.Unlock()
+	f.notify("player")
+	waitFor(t, stopped, "stop")
+}
+
+func TestParseAck(t *testing.T) {
+	err := parseAck("ACK [50@0] {play} No such song").(*ackError)
+	if err.Code != 50 || err.Command != "play" || err.Message != "No such song" {
+		t.Errorf("unexpected ack %+v", err)
+	}
+	if q := quote(`a "b" \c`); q != `"a \"b\" \\c"` {
+		t.Errorf("unexpected quoting %s", q)
+	}
+}
@@ -0,0 +1,119 @@
+package mpv
+
+import (
+	"encoding/binary"
+	"os"
+	"sync"
+
+	"github.com/supersonic-app/go-mpv"
+)
+
+// Sample rate of the mono PCM data returned by Player.GetSamples.
+const AnalysisSampleRate = 22050
+
+// analysisTap decodes the currently playing file with a secondary,
+// non-outputting mpv instance to a raw PCM file on disk,
+// which can be sampled at the main player's playback position
+// to feed FFT-based visualizations.
+type analysisTap struct {
+	lock   sync.Mutex
+	mpv    *mpv.Mpv
+	file   *os.File
+	url    string
+	raw    []byte // reusable read buffer
+	closed bool
+}
+
+func newAnalysisTap() *analysisTap {
+	return &analysisTap{}
+}
+
+// Load begins decoding the given URL, discarding data from the previous one.
+// Does nothing once the tap has been closed.
+func (a *analysisTap) Load(url string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.closed || url == a.url {
+		return nil
+	}
+	a.closeCurrent()
+
+	f, err := os.CreateTemp("", "supersonic-analysis-*.pcm")
+	if err != nil {
+		return err
+	}
+	m := mpv.Create()
+	m.SetOptionString("video", "no")
+	m.SetOptionString("audio-display", "no")
+	m.SetOptionString("terminal", "no")
+	m.SetOptionString("idle", "yes")
+	m.SetOptionString("ao", "pcm")
+	m.SetOptionString("ao-pcm-file", f.Name())
+	m.SetOptionString("ao-pcm-waveheader", "no")
+	m.SetOption("volume", mpv.FORMAT_INT64, 100)
+	m.SetOption("audio-samplerate", mpv.FORMAT_INT64, AnalysisSampleRate)
+	m.SetOptionString("audio-channels", "mono")
+	m.SetOptionString("audio-format", "s16")
+	if err := m.Initialize(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := m.Command([]string{"loadfile", url, "replace"}); err != nil {
+		m.TerminateDestroy()
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	a.mpv = m
+	a.file = f
+	a.url = url
+	return nil
+}
+
+// Samples fills buf with the samples (normalized to [-1, 1]) ending at
+// the given position in seconds, and returns the number of samples read.
+// Returns 0 if decoding has not yet reached the requested position.
+func (a *analysisTap) Samples(pos float64, buf []float64) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.file == nil {
+		return 0
+	}
+	start := int64(pos*AnalysisSampleRate) - int64(len(buf))
+	if start < 0 {
+		start = 0
+	}
+	if cap(a.raw) < len(buf)*2 {
+		a.raw = make([]byte, len(buf)*2)
+	}
+	raw := a.raw[:len(buf)*2]
+	n, _ := a.file.ReadAt(raw, start*2)
+	n /= 2
+	for i := range n {
+		buf[i] = float64(int16(binary.LittleEndian.Uint16(raw[i*2:]))) / (1 << 15)
+	}
+	return n
+}
+
+// Close stops decoding and removes the temporary PCM file.
+// The tap cannot be reused after closing.
+func (a *analysisTap) Close() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.closed = true
+	a.closeCurrent()
+}
+
+func (a *analysisTap) closeCurrent() {
+	if a.mpv != nil {
+		a.mpv.TerminateDestroy()
+		a.mpv = nil
+	}
+	if a.file != nil {
+		a.file.Close()
+		os.Remove(a.file.Name())
+		a.file = nil
+	}
+	a.url = ""
+}
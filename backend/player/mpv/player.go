@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
@@ -71,6 +72,10 @@ type Player struct {
 	equalizer      Equalizer
 	peaksEnabled   bool
 	pauseFade      bool
+	analysisTap    atomic.Pointer[analysisTap]
+
+	curPathLock sync.Mutex
+	curPath     string // path of the loaded file, for the analysis tap
 
 	icyTitleCb func(string)
 
@@ -446,6 +451,35 @@ func (p *Player) GetMediaInfo() (MediaInfo, error) {
 	return info, nil
 }
 
+// GetChapters returns the chapters embedded in the currently playing file, if any.
+func (p *Player) GetChapters() []mediaprovider.Chapter {
+	n, err := p.mpv.GetProperty("chapter-list", mpv.FORMAT_NODE)
+	if err != nil {
+		return nil
+	}
+	nodeArr, ok := n.(*mpv.Node).Data.([]*mpv.Node)
+	if !ok {
+		return nil
+	}
+
+	chapters := make([]mediaprovider.Chapter, 0, len(nodeArr))
+	for _, node := range nodeArr {
+		ch, ok := node.Data.(map[string]*mpv.Node)
+		if !ok {
+			continue
+		}
+		var chapter mediaprovider.Chapter
+		if t, ok := ch["title"]; ok {
+			chapter.Title, _ = t.Data.(string)
+		}
+		if t, ok := ch["time"]; ok {
+			chapter.Start, _ = t.Data.(float64)
+		}
+		chapters = append(chapters, chapter)
+	}
+	return chapters
+}
+
 func (p *Player) ObserveIcyRadioTitle(cb func(string)) {
 	p.icyTitleCb = cb
 	p.mpv.ObserveProperty(1, "metadata/icy-title", mpv.FORMAT_STRING)
@@ -477,6 +511,9 @@ func (p *Player) Destroy() {
 	if p.bgCancel != nil {
 		p.bgCancel()
 	}
+	if tap := p.analysisTap.Swap(nil); tap != nil {
+		tap.Close()
+	}
 	if p.initialized {
 		p.mpv.Command([]string{"stop"})
 		p.mpv.TerminateDestroy()
@@ -504,6 +541,44 @@ func (p *Player) GetPeaks() (float64, float64, float64, float64) {
 	return lPeak, rPeak, lRMS, rRMS
 }
 
+// Enables or disables the analysis tap, which decodes the playing
+// file a second time to provide PCM samples for GetSamples.
+func (p *Player) SetAnalysisTapEnabled(enabled bool) error {
+	if !enabled {
+		if tap := p.analysisTap.Swap(nil); tap != nil {
+			tap.Close()
+		}
+		return nil
+	}
+	if p.analysisTap.Load() != nil {
+		return nil
+	}
+	tap := newAnalysisTap()
+	p.analysisTap.Store(tap)
+	p.curPathLock.Lock()
+	path := p.curPath
+	p.curPathLock.Unlock()
+	if path != "" && p.status.State != player.Stopped {
+		return tap.Load(path)
+	}
+	return nil
+}
+
+// Fills buf with mono PCM samples at AnalysisSampleRate ending at the
+// current playback position and returns the number of samples read.
+// Returns 0 if the analysis tap is disabled or playback is not active.
+func (p *Player) GetSamples(buf []float64) int {
+	tap := p.analysisTap.Load()
+	if tap == nil || p.status.State != player.Playing {
+		return 0
+	}
+	pos, err := p.mpv.GetProperty("playback-time", mpv.FORMAT_DOUBLE)
+	if err != nil || pos == nil {
+		return 0
+	}
+	return tap.Samples(pos.(float64), buf)
+}
+
 // sets the state and invokes callbacks, if triggered
 func (p *Player) setState(s player.State) {
 	switch {
@@ -551,6 +626,13 @@ func (p *Player) eventHandler(ctx context.Context) {
 				p.InvokeOnSeek()
 			case mpv.EVENT_FILE_LOADED:
 				p.curPlaylistPos, _ = p.getInt64Property("playlist-pos")
+				path := p.mpv.GetPropertyString("path")
+				p.curPathLock.Lock()
+				p.curPath = path
+				p.curPathLock.Unlock()
+				if tap := p.analysisTap.Load(); tap != nil {
+					go tap.Load(path)
+				}
 				if p.status.State == player.Paused {
 					// seek while paused switches to a new file
 					// mpv does not fire seek event in this case
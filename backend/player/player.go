@@ -16,6 +16,24 @@ type TrackPlayer interface {
 	SetNextTrack(track *mediaprovider.Track) error
 }
 
+// QueuePlayer is a TrackPlayer that holds the entire play queue,
+// such as a server-side jukebox, rather than only the current and next tracks.
+type QueuePlayer interface {
+	TrackPlayer
+
+	// SetQueue replaces the player's queue. nowPlaying is the index
+	// of the current item in the new queue, or -1 if none.
+	SetQueue(items []mediaprovider.MediaItem, nowPlaying int) error
+	PlayTrackAt(idx int, startTime float64) error
+	// SetNextTrackAt sets the queue index to play after
+	// the current track, or -1 to stop after it.
+	SetNextTrackAt(idx int) error
+
+	// OnQueueChange registers a callback for when the queue or the
+	// now playing index is changed externally, e.g. by another client.
+	OnQueueChange(func(items []mediaprovider.MediaItem, nowPlaying int))
+}
+
 type BasePlayer interface {
 	Continue() error
 	Pause() error
@@ -43,6 +61,26 @@ type ReplayGainPlayer interface {
 	SetReplayGainOptions(ReplayGainOptions) error
 }
 
+// ChapterPlayer is implemented by players that can report
+// the chapters embedded in the currently playing file.
+type ChapterPlayer interface {
+	GetChapters() []mediaprovider.Chapter
+}
+
+// TranscodingPlayer is implemented by remote players that can
+// only play some formats, and may need the server to transcode.
+type TranscodingPlayer interface {
+	// TranscodeFor returns the transcode to request for media of the
+	// given MIME type, or nil if the player can play it as is.
+	TranscodeFor(mimeType string) *mediaprovider.TranscodeSettings
+}
+
+// VolumeChangePlayer is implemented by remote players whose volume
+// can also be changed on the device itself.
+type VolumeChangePlayer interface {
+	OnVolumeChange(func(int))
+}
+
 // The playback state (Stopped, Paused, or Playing).
 type State int
 
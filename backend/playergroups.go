@@ -0,0 +1,170 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/group"
+)
+
+// LocalPlayerURL is the device URL of this computer's audio output
+// as a member of a player group.
+const LocalPlayerURL = "local"
+
+const playerGroupURLPrefix = "group://"
+
+// PlayerGroups returns the configured groups of synchronized players.
+func (p *PlaybackManager) PlayerGroups() []PlayerGroupConfig {
+	return p.cfg.PlayerGroups
+}
+
+// IsPlayerGroup returns true if the remote device is a player group.
+func (rp *RemotePlaybackDevice) IsPlayerGroup() bool {
+	return rp.Protocol == "Group"
+}
+
+// SavePlayerGroup adds a player group, or replaces the one named oldName.
+// Like other config edits, it must be called on the UI thread.
+// If the group is currently playing, changed latency offsets take effect
+// immediately, and changed members cause the group to be reconnected
+// in the background.
+func (p *PlaybackManager) SavePlayerGroup(oldName string, g PlayerGroupConfig) error {
+	if g.Name == "" {
+		return errors.New("player group name is empty")
+	}
+	idx := slices.IndexFunc(p.cfg.PlayerGroups, func(c PlayerGroupConfig) bool { return c.Name == oldName })
+	if g.Name != oldName && slices.ContainsFunc(p.cfg.PlayerGroups, func(c PlayerGroupConfig) bool { return c.Name == g.Name }) {
+		return fmt.Errorf("a player group named %s already exists", g.Name)
+	}
+	var old PlayerGroupConfig
+	if idx >= 0 {
+		old = p.cfg.PlayerGroups[idx]
+		p.cfg.PlayerGroups[idx] = g
+	} else {
+		p.cfg.PlayerGroups = append(p.cfg.PlayerGroups, g)
+	}
+
+	rp := p.currentRemotePlayer
+	if rp == nil || rp.URL != playerGroupURLPrefix+oldName {
+		return nil
+	}
+	dev := p.playerGroupDevice(g)
+	gp, ok := p.engine.CurrentPlayer().(*group.GroupPlayer)
+	if !ok || !sameMembers(old, g) {
+		go p.setRemotePlayerAsync(&dev)
+		return nil
+	}
+	for _, m := range g.Members {
+		gp.SetLatencyOffset(m.DeviceURL, time.Duration(m.LatencyOffsetMS)*time.Millisecond)
+	}
+	p.currentRemotePlayer = &dev
+	return nil
+}
+
+// DeletePlayerGroup removes the named player group, switching back
+// to local playback in the background if it is currently playing.
+// Like other config edits, it must be called on the UI thread.
+func (p *PlaybackManager) DeletePlayerGroup(name string) {
+	p.cfg.PlayerGroups = slices.DeleteFunc(p.cfg.PlayerGroups, func(c PlayerGroupConfig) bool { return c.Name == name })
+	if rp := p.currentRemotePlayer; rp != nil && rp.URL == playerGroupURLPrefix+name {
+		go p.setRemotePlayerAsync(nil)
+	}
+}
+
+func (p *PlaybackManager) setRemotePlayerAsync(rp *RemotePlaybackDevice) {
+	if err := p.SetRemotePlayer(rp); err != nil {
+		log.Printf("error switching remote player: %v", err)
+	}
+}
+
+func (p *PlaybackManager) playerGroupDevice(g PlayerGroupConfig) RemotePlaybackDevice {
+	return RemotePlaybackDevice{
+		Name:     g.Name,
+		URL:      playerGroupURLPrefix + g.Name,
+		Protocol: "Group",
+		new: func() (player.BasePlayer, error) {
+			return p.newGroupPlayer(g)
+		},
+	}
+}
+
+func (p *PlaybackManager) newGroupPlayer(g PlayerGroupConfig) (player.BasePlayer, error) {
+	members := make([]*group.Member, len(g.Members))
+	var wg sync.WaitGroup
+	for i, m := range g.Members {
+		offset := time.Duration(m.LatencyOffsetMS) * time.Millisecond
+		if m.DeviceURL == LocalPlayerURL {
+			if local, ok := p.localPlayer.(player.URLPlayer); ok {
+				members[i] = &group.Member{ID: LocalPlayerURL, Player: local, LatencyOffset: offset}
+			}
+			continue
+		}
+		dev := p.findRemotePlayer(m.DeviceURL)
+		if dev == nil {
+			log.Printf("player group %s: device %s not found", g.Name, m.DeviceURL)
+			continue
+		}
+		// connect to remote devices concurrently
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pl, err := dev.new()
+			if err != nil {
+				log.Printf("player group %s: failed to connect to %s: %v", g.Name, dev.Name, err)
+				return
+			}
+			up, ok := pl.(player.URLPlayer)
+			if !ok {
+				pl.Destroy()
+				return
+			}
+			members[i] = &group.Member{ID: m.DeviceURL, Player: up, LatencyOffset: offset, Owned: true}
+		}()
+	}
+	wg.Wait()
+
+	var connected []group.Member
+	for _, m := range members {
+		if m == nil {
+			continue
+		}
+		if m.ID == LocalPlayerURL {
+			// the local player leads, since its status is the most accurate
+			connected = slices.Insert(connected, 0, *m)
+		} else {
+			connected = append(connected, *m)
+		}
+	}
+	if len(connected) == 0 {
+		return nil, fmt.Errorf("no devices of player group %s are available", g.Name)
+	}
+	return group.NewGroupPlayer(connected)
+}
+
+func (p *PlaybackManager) findRemotePlayer(url string) *RemotePlaybackDevice {
+	for _, s := range p.cfg.MPDServers {
+		if d := p.mpdServerDevice(s); d.URL == url {
+			return &d
+		}
+	}
+	p.remotePlayersLock.Lock()
+	defer p.remotePlayersLock.Unlock()
+	for i := range p.remotePlayers {
+		if p.remotePlayers[i].URL == url {
+			d := p.remotePlayers[i]
+			return &d
+		}
+	}
+	return nil
+}
+
+func sameMembers(a, b PlayerGroupConfig) bool {
+	return slices.EqualFunc(a.Members, b.Members, func(x, y PlayerGroupMemberConfig) bool {
+		return x.DeviceURL == y.DeviceURL
+	})
+}
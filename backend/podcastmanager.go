@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+const podcastsPlayedFile = "podcasts_played.json"
+
+// PodcastManager fetches podcasts from the current server and keeps
+// track of which episodes have been played. Subsonic servers don't
+// store played state, so it is saved locally for each server.
+type PodcastManager struct {
+	sm       *ServerManager
+	filePath string
+
+	mutex  sync.Mutex
+	played map[string]map[string]bool // server ID -> episode ID -> played
+}
+
+func NewPodcastManager(sm *ServerManager, configDir string) *PodcastManager {
+	p := &PodcastManager{
+		sm:       sm,
+		filePath: filepath.Join(configDir, podcastsPlayedFile),
+		played:   make(map[string]map[string]bool),
+	}
+	if b, err := os.ReadFile(p.filePath); err == nil {
+		if err := json.Unmarshal(b, &p.played); err != nil {
+			log.Printf("failed to read podcast played state: %v", err)
+		}
+	}
+	return p
+}
+
+// IsSupported returns true if the current server supports podcasts.
+func (p *PodcastManager) IsSupported() bool {
+	_, ok := p.sm.Server.(mediaprovider.PodcastProvider)
+	return ok
+}
+
+// Provider returns the podcast provider of the current server,
+// or nil if it doesn't support podcasts.
+func (p *PodcastManager) Provider() mediaprovider.PodcastProvider {
+	pp, _ := p.sm.Server.(mediaprovider.PodcastProvider)
+	return pp
+}
+
+func (p *PodcastManager) GetChannels() ([]*mediaprovider.PodcastChannel, error) {
+	pp := p.Provider()
+	if pp == nil {
+		return nil, nil
+	}
+	return pp.GetPodcastChannels()
+}
+
+// GetChannel fetches the channel with its episodes, with their played state set.
+func (p *PodcastManager) GetChannel(id string) (*mediaprovider.PodcastChannel, error) {
+	pp := p.Provider()
+	if pp == nil {
+		return nil, nil
+	}
+	ch, err := pp.GetPodcastChannel(id)
+	if err != nil {
+		return nil, err
+	}
+	p.mutex.Lock()
+	played := p.played[p.sm.ServerID.String()]
+	for _, ep := range ch.Episodes {
+		ep.Played = played[ep.ID]
+	}
+	p.mutex.Unlock()
+	return ch, nil
+}
+
+func (p *PodcastManager) IsPlayed(episodeID string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.played[p.sm.ServerID.String()][episodeID]
+}
+
+// SetPlayed marks the episode as played or unplayed and saves the played state.
+func (p *PodcastManager) SetPlayed(episodeID string, played bool) {
+	serverID := p.sm.ServerID.String()
+	p.mutex.Lock()
+	if p.played[serverID][episodeID] == played {
+		p.mutex.Unlock()
+		return
+	}
+	if played {
+		if p.played[serverID] == nil {
+			p.played[serverID] = make(map[string]bool)
+		}
+		p.played[serverID][episodeID] = true
+	} else {
+		delete(p.played[serverID], episodeID)
+	}
+	b, err := json.Marshal(p.played)
+	p.mutex.Unlock()
+
+	if err == nil {
+		err = os.WriteFile(p.filePath, b, 0644)
+	}
+	if err != nil {
+		log.Printf("failed to save podcast played state: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package rebroadcast
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const oggHeaderLen = 27
+
+// oggPage is a complete Ogg page, including its header.
+type oggPage []byte
+
+func (p oggPage) granulePos() uint64 {
+	return binary.LittleEndian.Uint64(p[6:14])
+}
+
+// readOggPage reads the next page from an Ogg stream.
+func readOggPage(r io.Reader) (oggPage, error) {
+	hdr := make([]byte, oggHeaderLen, oggHeaderLen+255)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	if string(hdr[:4]) != "OggS" {
+		return nil, errors.New("ogg: lost page sync")
+	}
+	segs := make([]byte, hdr[26])
+	if _, err := io.ReadFull(r, segs); err != nil {
+		return nil, err
+	}
+	bodyLen := 0
+	for _, s := range segs {
+		bodyLen += int(s)
+	}
+	page := append(hdr, segs...)
+	page = append(page, make([]byte, bodyLen)...)
+	if _, err := io.ReadFull(r, page[len(page)-bodyLen:]); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
@@ -0,0 +1,246 @@
+package rebroadcast
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PCM format passed from the decoder to the encoder
+const (
+	sampleRate     = 44100
+	bytesPerFrame  = 4 // s16le stereo
+	bytesPerSecond = sampleRate * bytesPerFrame
+
+	pumpInterval = 50 * time.Millisecond
+	// decoded audio buffered ahead of the pacer
+	maxBuffered = 2 * bytesPerSecond
+)
+
+var rawAudioArgs = []string{
+	"--demuxer=rawaudio",
+	"--demuxer-rawaudio-format=s16le",
+	"--demuxer-rawaudio-channels=stereo",
+	"--demuxer-rawaudio-rate=" + strconv.Itoa(sampleRate),
+}
+
+// encoderArgs returns the mpv options to encode raw PCM from stdin
+// to the given format, or false if the format is unsupported.
+func encoderArgs(format string, bitRateKBPS int) ([]string, bool) {
+	br := "b=" + strconv.Itoa(bitRateKBPS) + "k"
+	var args []string
+	switch format {
+	case FormatMP3:
+		args = []string{"--of=mp3", "--oac=libmp3lame", "--oacopts=" + br}
+	case FormatOpus:
+		args = []string{"--of=ogg", "--oac=libopus", "--oacopts=" + br}
+	default:
+		return nil, false
+	}
+	args = append([]string{"--no-config", "--really-quiet", "--no-video", "--cache=no", "--o=-"}, args...)
+	args = append(args, rawAudioArgs...)
+	return append(args, "--", "-"), true
+}
+
+// decoderArgs returns the mpv options to decode the media at url,
+// starting at startTime, to raw PCM on stdout.
+func decoderArgs(url string, startTime float64) []string {
+	return []string{
+		"--no-config", "--really-quiet", "--no-video", "--o=-",
+		"--of=s16le", "--oac=pcm_s16le",
+		"--audio-samplerate=" + strconv.Itoa(sampleRate),
+		"--audio-channels=stereo",
+		"--start=" + strconv.FormatFloat(startTime, 'f', 3, 64),
+		"--", url,
+	}
+}
+
+// decoder decodes a track to PCM in the background,
+// buffering a limited amount ahead of the pacer.
+type decoder struct {
+	cancel context.CancelFunc
+
+	lock sync.Mutex
+	cond *sync.Cond
+	buf  []byte
+	done bool
+}
+
+func startDecoder(mpvPath, url string, startTime float64) (*decoder, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, mpvPath, decoderArgs(url, startTime)...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	d := &decoder{cancel: cancel}
+	d.cond = sync.NewCond(&d.lock)
+	go func() {
+		d.fill(out)
+		cmd.Wait()
+	}()
+	return d, nil
+}
+
+func (d *decoder) fill(r io.Reader) {
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(chunk)
+		d.lock.Lock()
+		d.buf = append(d.buf, chunk[:n]...)
+		for len(d.buf) >= maxBuffered && !d.done {
+			d.cond.Wait()
+		}
+		if err != nil {
+			d.done = true
+		}
+		done := d.done
+		d.lock.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// read copies up to len(p) bytes of decoded audio into p without
+// blocking, returning a whole number of PCM frames.
+func (d *decoder) read(p []byte) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	n := copy(p, d.buf[:len(d.buf)&^(bytesPerFrame-1)])
+	d.buf = d.buf[n:]
+	d.cond.Signal()
+	return n
+}
+
+func (d *decoder) stop() {
+	d.lock.Lock()
+	d.done = true
+	d.cond.Signal()
+	d.lock.Unlock()
+	d.cancel()
+}
+
+// pump runs the encoder while there are listeners, feeding it
+// decoded audio in real time, or silence while nothing is playing.
+func (s *Server) pump(ctx context.Context, gen int) {
+	defer s.pumpStopped(gen)
+
+	args, _ := encoderArgs(s.format, s.bitRateKBPS)
+	cmd := exec.CommandContext(ctx, s.mpvPath, args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		log.Printf("rebroadcast: %v", err)
+		return
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		log.Printf("rebroadcast: %v", err)
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("rebroadcast: failed to start encoder: %v", err)
+		return
+	}
+	go func() {
+		if err := s.readEncoded(out); err != nil && ctx.Err() == nil {
+			log.Printf("rebroadcast: encoder output: %v", err)
+		}
+	}()
+	defer cmd.Wait()
+	defer in.Close()
+
+	buf := make([]byte, bytesPerSecond)
+	start := time.Now()
+	var written int64
+	ticker := time.NewTicker(pumpInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		want := int64(time.Since(start).Seconds()*bytesPerSecond) &^ (bytesPerFrame - 1)
+		n := want - written
+		if n > bytesPerSecond {
+			// fell behind, e.g. the system was suspended; don't burst
+			written, n = want-bytesPerSecond, bytesPerSecond
+		}
+		p := buf[:n]
+		read := s.readPCM(p)
+		clear(p[read:])
+		if _, err := in.Write(p); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("rebroadcast: encoder stopped: %v", err)
+			}
+			return
+		}
+		written += n
+	}
+}
+
+// readPCM reads decoded audio of the current track into p,
+// returning 0 if paused or nothing is playing.
+func (s *Server) readPCM(p []byte) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.paused || s.decoder == nil {
+		return 0
+	}
+	return s.decoder.read(p)
+}
+
+// readEncoded reads the encoder's output and broadcasts it to listeners.
+func (s *Server) readEncoded(r io.Reader) error {
+	if s.format == FormatOpus {
+		return s.readOggPages(r)
+	}
+	for {
+		chunk := make([]byte, 4096)
+		n, err := r.Read(chunk)
+		if n > 0 {
+			s.broadcast(chunk[:n])
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// readOggPages broadcasts the encoder's output page by page, keeping the
+// header pages at the start of the stream to send to new listeners.
+func (s *Server) readOggPages(r io.Reader) error {
+	headersDone := false
+	for {
+		page, err := readOggPage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if !headersDone && page.granulePos() != 0 {
+			headersDone = true
+		}
+		s.lock.Lock()
+		if !headersDone {
+			s.headers = append(s.headers, page...)
+		}
+		s.broadcastLocked(page)
+		s.lock.Unlock()
+	}
+}
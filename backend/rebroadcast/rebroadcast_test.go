@@ -0,0 +1,63 @@
+package rebroadcast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestICYWriter(t *testing.T) {
+	var buf bytes.Buffer
+	title := "Artist - Title"
+	iw := &icyWriter{w: &buf, metaInt: 4, untilMeta: 4, title: func() string { return title }}
+	iw.Write([]byte("abcdef"))
+	iw.Write([]byte("gh"))
+	title = "Next"
+	iw.Write([]byte("ijkl"))
+
+	meta1 := icyMetadata("Artist - Title")
+	meta2 := icyMetadata("Next")
+	want := []byte("abcd")
+	want = append(want, meta1...)
+	want = append(want, "efgh"...)
+	want = append(want, 0) // title unchanged
+	want = append(want, "ijkl"...)
+	want = append(want, meta2...)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("got %q, want %q", buf.Bytes(), want)
+	}
+	if meta1[0] != 2 || len(meta1) != 33 || !bytes.HasPrefix(meta1[1:], []byte("StreamTitle='Artist - Title';")) {
+		t.Errorf("malformed metadata block %q", meta1)
+	}
+}
+
+func oggTestPage(granule uint64, body []byte) []byte {
+	page := make([]byte, oggHeaderLen)
+	copy(page, "OggS")
+	binary.LittleEndian.PutUint64(page[6:14], granule)
+	page[26] = 1
+	page = append(page, byte(len(body)))
+	return append(page, body...)
+}
+
+func TestReadOggPage(t *testing.T) {
+	head := oggTestPage(0, []byte("OpusHead"))
+	audio := oggTestPage(960, []byte("audio"))
+	r := bytes.NewReader(append(append([]byte{}, head...), audio...))
+
+	for _, want := range [][]byte{head, audio} {
+		page, err := readOggPage(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(page, want) {
+			t.Errorf("got page %q, want %q", page, want)
+		}
+	}
+	if p, _ := readOggPage(bytes.NewReader(audio)); p.granulePos() != 960 {
+		t.Errorf("unexpected granule position %d", p.granulePos())
+	}
+	if _, err := readOggPage(bytes.NewReader([]byte("garbage that is not an ogg page"))); err == nil {
+		t.Error("expected error for missing page sync")
+	}
+}
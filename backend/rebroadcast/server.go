@@ -0,0 +1,377 @@
+// Package rebroadcast implements an Icecast-compatible HTTP stream
+// of whatever Supersonic is playing, so that other devices can tune in
+// with any internet radio app. The current track is decoded to PCM by
+// an mpv subprocess, paced in real time (with silence while paused or
+// stopped), and re-encoded by a second, long-running mpv process into
+// one continuous MP3 or Opus stream with ICY metadata.
+package rebroadcast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/util"
+)
+
+const (
+	FormatMP3  = "mp3"
+	FormatOpus = "opus"
+
+	// bytes of audio between ICY metadata blocks
+	icyMetaInt = 16000
+	// chunks buffered for each listener before it is dropped as too slow
+	listenerBuffer = 256
+)
+
+type listener struct {
+	ch chan []byte
+}
+
+type Server struct {
+	name        string
+	port        int
+	format      string
+	bitRateKBPS int
+	mpvPath     string
+	httpServer  *http.Server
+	streamURL   string
+
+	lock      sync.Mutex
+	listeners map[*listener]struct{}
+	headers   []byte // Ogg header pages of the current encoder output
+	pumpGen   int
+	stopPump  context.CancelFunc
+
+	// what is playing, and its position as of posTime
+	url     string
+	title   string
+	pos     float64
+	posTime time.Time
+	paused  bool
+	decoder *decoder
+}
+
+// NewServer creates a stream server in the given format (FormatMP3
+// or FormatOpus). name is sent to listeners as the station name.
+func NewServer(name string, port int, format string, bitRateKBPS int) *Server {
+	return &Server{
+		name:        name,
+		port:        port,
+		format:      format,
+		bitRateKBPS: bitRateKBPS,
+		listeners:   make(map[*listener]struct{}),
+		paused:      true,
+	}
+}
+
+// Start starts listening for HTTP connections.
+func (s *Server) Start() error {
+	if _, ok := encoderArgs(s.format, s.bitRateKBPS); !ok {
+		return fmt.Errorf("unsupported stream format %q", s.format)
+	}
+	mpvPath, err := exec.LookPath("mpv")
+	if err != nil {
+		return errors.New("mpv is required to encode the stream")
+	}
+	s.mpvPath = mpvPath
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return err
+	}
+	host := "localhost"
+	if ip, err := util.GetLocalIP(); err == nil {
+		host = ip
+	}
+	s.streamURL = fmt.Sprintf("http://%s:%d%s", host, l.Addr().(*net.TCPAddr).Port, s.mountPath())
+	s.httpServer = &http.Server{Handler: s.Handler()}
+	go s.httpServer.Serve(l)
+	log.Printf("rebroadcast stream available at %s", s.streamURL)
+	return nil
+}
+
+// StreamURL returns the URL listeners can tune in to.
+func (s *Server) StreamURL() string {
+	return s.streamURL
+}
+
+// Shutdown disconnects all listeners and stops encoding.
+func (s *Server) Shutdown(ctx context.Context) {
+	s.lock.Lock()
+	if s.stopPump != nil {
+		s.stopPump()
+		s.stopPump = nil
+	}
+	if s.decoder != nil {
+		s.decoder.stop()
+		s.decoder = nil
+	}
+	s.lock.Unlock()
+	if s.httpServer != nil {
+		s.httpServer.Shutdown(ctx)
+	}
+}
+
+// SetTrack sets the media to stream, starting at startTime.
+// It should also be called after seeking. An empty url streams silence.
+// title is sent to listeners as the ICY StreamTitle.
+func (s *Server) SetTrack(url, title string, startTime float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.url, s.title = url, title
+	s.pos, s.posTime = startTime, time.Now()
+	if url == "" {
+		s.paused = true
+	}
+	s.restartDecoder()
+}
+
+// SetTitle updates the ICY StreamTitle, e.g. for radio stations.
+func (s *Server) SetTitle(title string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.title = title
+}
+
+// SetPaused pauses or resumes the stream, sending silence while paused.
+func (s *Server) SetPaused(paused bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if paused == s.paused {
+		return
+	}
+	if paused {
+		s.pos = s.position()
+	}
+	s.posTime = time.Now()
+	s.paused = paused
+}
+
+// position returns the current playback position. Must be called with the lock held.
+func (s *Server) position() float64 {
+	if s.paused {
+		return s.pos
+	}
+	return s.pos + time.Since(s.posTime).Seconds()
+}
+
+// restartDecoder decodes the current track from the current position,
+// if anyone is listening. Must be called with the lock held.
+func (s *Server) restartDecoder() {
+	if s.decoder != nil {
+		s.decoder.stop()
+		s.decoder = nil
+	}
+	if s.url == "" || s.stopPump == nil {
+		return
+	}
+	d, err := startDecoder(s.mpvPath, s.url, s.position())
+	if err != nil {
+		log.Printf("rebroadcast: failed to start decoder: %v", err)
+		return
+	}
+	s.decoder = d
+}
+
+// Handler returns the HTTP handler serving the stream.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleStream)
+	return mux
+}
+
+func (s *Server) mountPath() string {
+	return "/stream." + s.format
+}
+
+func (s *Server) contentType() string {
+	if s.format == FormatOpus {
+		return "audio/ogg"
+	}
+	return "audio/mpeg"
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != s.mountPath() {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", s.contentType())
+	h.Set("Cache-Control", "no-cache, no-store")
+	h.Set("icy-name", s.name)
+	h.Set("icy-br", strconv.Itoa(s.bitRateKBPS))
+	h.Set("icy-pub", "0")
+	var out io.Writer = w
+	if r.Header.Get("Icy-MetaData") == "1" {
+		h.Set("icy-metaint", strconv.Itoa(icyMetaInt))
+		out = &icyWriter{w: w, metaInt: icyMetaInt, untilMeta: icyMetaInt, title: s.currentTitle}
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	l, headers := s.subscribe()
+	defer s.unsubscribe(l)
+	rc := http.NewResponseController(w)
+	if len(headers) > 0 {
+		if _, err := out.Write(headers); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case chunk, ok := <-l.ch:
+			if !ok {
+				return
+			}
+			if _, err := out.Write(chunk); err != nil {
+				return
+			}
+			rc.Flush()
+		}
+	}
+}
+
+func (s *Server) currentTitle() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.title
+}
+
+// subscribe adds a listener, starting the encoder if it is the first one,
+// and returns the stream headers that must be sent before any chunks.
+func (s *Server) subscribe() (*listener, []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	l := &listener{ch: make(chan []byte, listenerBuffer)}
+	s.listeners[l] = struct{}{}
+	if s.stopPump == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopPump = cancel
+		s.pumpGen++
+		s.headers = nil
+		go s.pump(ctx, s.pumpGen)
+		s.restartDecoder()
+	}
+	return l, s.headers
+}
+
+func (s *Server) unsubscribe(l *listener) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.listeners, l)
+	if len(s.listeners) == 0 && s.stopPump != nil {
+		// nobody is listening; stop encoding until someone tunes in
+		s.stopPump()
+		s.stopPump = nil
+		s.restartDecoder()
+	}
+}
+
+// pumpStopped disconnects all listeners if the encoder stopped unexpectedly.
+func (s *Server) pumpStopped(gen int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if gen != s.pumpGen || s.stopPump == nil {
+		return
+	}
+	s.stopPump()
+	s.stopPump = nil
+	s.restartDecoder()
+	for l := range s.listeners {
+		close(l.ch)
+		delete(s.listeners, l)
+	}
+}
+
+func (s *Server) broadcast(chunk []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.broadcastLocked(chunk)
+}
+
+// broadcastLocked sends a chunk to all listeners, dropping any that
+// have fallen too far behind. Must be called with the lock held.
+func (s *Server) broadcastLocked(chunk []byte) {
+	for l := range s.listeners {
+		select {
+		case l.ch <- chunk:
+		default:
+			log.Print("rebroadcast: dropping slow listener")
+			close(l.ch)
+			delete(s.listeners, l)
+		}
+	}
+}
+
+// icyWriter interleaves ICY metadata blocks into a stream
+// every metaInt bytes, as requested by clients with "Icy-MetaData: 1".
+type icyWriter struct {
+	w       io.Writer
+	metaInt int
+	title   func() string
+
+	untilMeta int
+	lastTitle string
+}
+
+func (iw *icyWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), iw.untilMeta)
+		if _, err := iw.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+		iw.untilMeta -= n
+		if iw.untilMeta == 0 {
+			if _, err := iw.w.Write(iw.metadataBlock()); err != nil {
+				return written, err
+			}
+			iw.untilMeta = iw.metaInt
+		}
+	}
+	return written, nil
+}
+
+// metadataBlock returns the next metadata block, which is
+// empty unless the title changed since the last one.
+func (iw *icyWriter) metadataBlock() []byte {
+	title := iw.title()
+	if title == iw.lastTitle {
+		return []byte{0}
+	}
+	iw.lastTitle = title
+	return icyMetadata(title)
+}
+
+// icyMetadata encodes a metadata block: a length byte counting
+// 16-byte units, followed by the zero-padded metadata.
+func icyMetadata(title string) []byte {
+	meta := "StreamTitle='" + title + "';"
+	if len(meta) > 255*16 {
+		meta = meta[:255*16]
+	}
+	n := (len(meta) + 15) / 16
+	block := make([]byte, 1+n*16)
+	block[0] = byte(n)
+	copy(block[1:], meta)
+	return block
+}
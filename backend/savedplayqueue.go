@@ -26,13 +26,23 @@ type serializedSavedPlayQueue struct {
 // If the provided CanSavePlayQueue server is non-nil, it will also save to the server.
 func SavePlayQueue(serverID string, queue []mediaprovider.MediaItem, pm *PlaybackManager, filepath string, server mediaprovider.CanSavePlayQueue) error {
 	stats := pm.PlaybackStatus()
-	trackIdx := pm.NowPlayingIndex()
+	nowPlayingIdx := pm.NowPlayingIndex()
+	trackIdx := nowPlayingIdx
+	timePos := stats.TimePos
 
 	trackIDs := make([]string, 0, len(queue))
-	for _, item := range queue {
+	for i, item := range queue {
 		if _, ok := item.(*mediaprovider.Track); ok {
-			// don't save radio stations in play queue
 			trackIDs = append(trackIDs, item.Metadata().ID)
+			continue
+		}
+		// don't save radio stations or podcast episodes in play queue,
+		// and index the now playing track among the saved tracks
+		if i < nowPlayingIdx {
+			trackIdx--
+		} else if i == nowPlayingIdx {
+			// resume from the start of the track after it
+			timePos = 0
 		}
 	}
 	if l := len(trackIDs); trackIdx >= l {
@@ -47,14 +57,14 @@ func SavePlayQueue(serverID string, queue []mediaprovider.MediaItem, pm *Playbac
 		ServerID:   serverID,
 		TrackIDs:   trackIDs,
 		TrackIndex: trackIdx,
-		TimePos:    stats.TimePos,
+		TimePos:    timePos,
 	}
 	b, _ := json.Marshal(saved)
 	err := os.WriteFile(filepath, b, 0o644)
 
 	if server != nil {
 		// save to server
-		err = server.SavePlayQueue(trackIDs, trackIdx, int(stats.TimePos))
+		err = server.SavePlayQueue(trackIDs, trackIdx, int(timePos))
 	}
 	return err
 }
@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/hooks"
+	"github.com/dweymouth/supersonic/backend/ipc"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// playback events that hooks are run for
+const (
+	hookEventSongChange = "song_change"
+	hookEventPlay       = "play"
+	hookEventPause      = "pause"
+	hookEventStop       = "stop"
+	hookEventScrobble   = "scrobble"
+)
+
+// hookPayload is the JSON sent to hooks on standard input.
+type hookPayload struct {
+	Event    string         `json:"event"`
+	Track    *ipc.TrackInfo `json:"track"`
+	Position float64        `json:"position"`
+}
+
+func newHookPayload(event string, item mediaprovider.MediaItem, position float64) hookPayload {
+	return hookPayload{Event: event, Track: ipc.NewTrackInfo(item), Position: position}
+}
+
+// env returns the payload as SUPERSONIC_* environment variables.
+func (h hookPayload) env() []string {
+	env := []string{
+		"SUPERSONIC_EVENT=" + h.Event,
+		"SUPERSONIC_POSITION=" + strconv.FormatFloat(h.Position, 'f', 2, 64),
+	}
+	if t := h.Track; t != nil {
+		env = append(env,
+			"SUPERSONIC_TRACK_ID="+t.ID,
+			"SUPERSONIC_TRACK_TYPE="+t.Type,
+			"SUPERSONIC_TITLE="+t.Title,
+			"SUPERSONIC_ARTIST="+strings.Join(t.Artists, ", "),
+			"SUPERSONIC_ALBUM="+t.Album,
+			"SUPERSONIC_ALBUM_ID="+t.AlbumID,
+			"SUPERSONIC_DURATION="+strconv.FormatFloat(t.Duration, 'f', 2, 64),
+		)
+	}
+	return env
+}
+
+// startScriptHooks runs the configured commands on playback events.
+func (a *App) startScriptHooks() {
+	cfg := a.Config.ScriptHooks
+	runner := hooks.NewRunner(time.Duration(cfg.TimeoutSecs)*time.Second, cfg.MaxConcurrent)
+	pm := a.PlaybackManager
+	run := func(command, event string, item mediaprovider.MediaItem) {
+		if command == "" {
+			return
+		}
+		h := newHookPayload(event, item, pm.PlaybackStatus().TimePos)
+		stdin, _ := json.Marshal(h)
+		runner.Run(a.bgrndCtx, command, h.env(), stdin)
+	}
+
+	pm.OnSongChange(func(item mediaprovider.MediaItem, justScrobbled *mediaprovider.Track) {
+		if justScrobbled != nil {
+			run(cfg.OnScrobble, hookEventScrobble, justScrobbled)
+		}
+		if item != nil {
+			run(cfg.OnSongChange, hookEventSongChange, item)
+		}
+	})
+	pm.OnPlaying(func() { run(cfg.OnPlay, hookEventPlay, pm.NowPlaying()) })
+	pm.OnPaused(func() { run(cfg.OnPause, hookEventPause, pm.NowPlaying()) })
+	pm.OnStopped(func() { run(cfg.OnStop, hookEventStop, nil) })
+}
+
+func (c ScriptHooksConfig) hasHooks() bool {
+	return c.OnSongChange != "" || c.OnPlay != "" || c.OnPause != "" ||
+		c.OnStop != "" || c.OnScrobble != ""
+}
@@ -170,8 +170,14 @@ func (s *ServerManager) GetServerPassword(serverID uuid.UUID) (string, error) {
 }
 
 func (s *ServerManager) SetServerPassword(server *ServerConfig, password string) error {
+	return s.setPassword(server.ID, password)
+}
+
+// setPassword stores a password in the keyring under the given ID,
+// which may be the ID of a server or of another configured service.
+func (s *ServerManager) setPassword(id uuid.UUID, password string) error {
 	if s.useKeyring {
-		return keyring.Set(s.appName, server.ID.String(), password)
+		return keyring.Set(s.appName, id.String(), password)
 	}
 	return errors.New("keyring not available")
 }
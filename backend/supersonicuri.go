@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// URIScheme is the scheme of URIs linking to items in the server's library,
+// of the form supersonic://<kind>/<id>[?play=1&shuffle=1],
+// or to a search, of the form supersonic://search?q=<query>.
+const URIScheme = "supersonic"
+
+const (
+	URIKindAlbum    = "album"
+	URIKindArtist   = "artist"
+	URIKindPlaylist = "playlist"
+	URIKindTrack    = "track"
+	URIKindSearch   = "search"
+)
+
+var ErrInvalidURI = errors.New("invalid " + URIScheme + ":// URI")
+
+// SupersonicURI is a parsed supersonic:// URI.
+type SupersonicURI struct {
+	Kind    string // one of the URIKind* constants
+	ID      string // empty for URIKindSearch
+	Query   string // search query for URIKindSearch
+	Play    bool   // start playing the item
+	Shuffle bool   // shuffle the item's tracks, if played
+}
+
+// ParseSupersonicURI parses a supersonic:// URI.
+func ParseSupersonicURI(uri string) (SupersonicURI, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != URIScheme {
+		return SupersonicURI{}, ErrInvalidURI
+	}
+	q := u.Query()
+	s := SupersonicURI{
+		Kind:    u.Host,
+		ID:      strings.TrimPrefix(u.Path, "/"),
+		Play:    parseURIBool(q.Get("play")),
+		Shuffle: parseURIBool(q.Get("shuffle")),
+	}
+	switch s.Kind {
+	case URIKindAlbum, URIKindArtist, URIKindPlaylist, URIKindTrack:
+		if s.ID == "" {
+			return SupersonicURI{}, ErrInvalidURI
+		}
+	case URIKindSearch:
+		s.Query = q.Get("q")
+		if s.ID != "" || s.Query == "" {
+			return SupersonicURI{}, ErrInvalidURI
+		}
+	default:
+		return SupersonicURI{}, ErrInvalidURI
+	}
+	return s, nil
+}
+
+func (s SupersonicURI) String() string {
+	uri := URIScheme + "://" + s.Kind
+	if s.ID != "" {
+		uri += "/" + url.PathEscape(s.ID)
+	}
+	q := url.Values{}
+	if s.Query != "" {
+		q.Set("q", s.Query)
+	}
+	if s.Play {
+		q.Set("play", "1")
+	}
+	if s.Shuffle {
+		q.Set("shuffle", "1")
+	}
+	if len(q) > 0 {
+		uri += "?" + q.Encode()
+	}
+	return uri
+}
+
+func parseURIBool(s string) bool {
+	return s == "1" || s == "true"
+}
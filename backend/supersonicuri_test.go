@@ -0,0 +1,42 @@
+package backend
+
+import "testing"
+
+func TestParseSupersonicURI(t *testing.T) {
+	for _, uri := range []string{
+		"supersonic://album/al-123",
+		"supersonic://artist/ar%2F1",
+		"supersonic://playlist/42",
+		"supersonic://track/tr-1",
+		"supersonic://playlist/42?play=1&shuffle=1",
+		"supersonic://search?q=daft+punk",
+	} {
+		s, err := ParseSupersonicURI(uri)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", uri, err)
+		}
+		if got := s.String(); got != uri {
+			t.Errorf("round trip of %s: got %s", uri, got)
+		}
+	}
+	if s, _ := ParseSupersonicURI("supersonic://artist/ar%2F1"); s.ID != "ar/1" {
+		t.Errorf("expected unescaped ID, got %q", s.ID)
+	}
+	s, _ := ParseSupersonicURI("supersonic://album/1?play=true")
+	if !s.Play || s.Shuffle {
+		t.Errorf("expected play without shuffle, got %+v", s)
+	}
+
+	for _, uri := range []string{
+		"https://example.com/album/1",
+		"supersonic://genre/rock",
+		"supersonic://album/",
+		"supersonic:album",
+		"supersonic://search",
+		"supersonic://search/1?q=x",
+	} {
+		if _, err := ParseSupersonicURI(uri); err != ErrInvalidURI {
+			t.Errorf("%s: expected ErrInvalidURI, got %v", uri, err)
+		}
+	}
+}
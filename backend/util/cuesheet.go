@@ -0,0 +1,129 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CUE sheet time positions are in mm:ss:ff with 75 frames per second
+const cueFramesPerSecond = 75
+
+// CueSheet is a parsed CUE sheet describing the tracks in one or more audio files.
+type CueSheet struct {
+	Title     string
+	Performer string
+	Files     []CueFile
+}
+
+type CueFile struct {
+	Name   string
+	Tracks []CueTrack
+}
+
+type CueTrack struct {
+	Number    int
+	Title     string
+	Performer string
+	Start     float64 // seconds, from INDEX 01
+}
+
+// ParseCueSheet parses a CUE sheet. Unknown commands are ignored.
+func ParseCueSheet(r io.Reader) (*CueSheet, error) {
+	sheet := &CueSheet{}
+	var file *CueFile
+	var track *CueTrack
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF") // UTF-8 BOM
+		}
+		cmd, args := splitCueCommand(line)
+		switch strings.ToUpper(cmd) {
+		case "TITLE":
+			if track != nil {
+				track.Title = unquoteCueArg(args)
+			} else {
+				sheet.Title = unquoteCueArg(args)
+			}
+		case "PERFORMER":
+			if track != nil {
+				track.Performer = unquoteCueArg(args)
+			} else {
+				sheet.Performer = unquoteCueArg(args)
+			}
+		case "FILE":
+			// FILE "name" TYPE
+			name := args
+			if i := strings.LastIndexByte(args, ' '); i > 0 {
+				name = args[:i]
+			}
+			sheet.Files = append(sheet.Files, CueFile{Name: unquoteCueArg(name)})
+			file = &sheet.Files[len(sheet.Files)-1]
+			track = nil
+		case "TRACK":
+			if file == nil {
+				return nil, fmt.Errorf("line %d: TRACK before FILE", lineNum)
+			}
+			num, _, _ := strings.Cut(args, " ")
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid track number %q", lineNum, num)
+			}
+			file.Tracks = append(file.Tracks, CueTrack{Number: n})
+			track = &file.Tracks[len(file.Tracks)-1]
+		case "INDEX":
+			if track == nil {
+				continue
+			}
+			num, pos, _ := strings.Cut(args, " ")
+			if num != "01" && num != "1" {
+				continue
+			}
+			start, err := parseCueTime(strings.TrimSpace(pos))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+			track.Start = start
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+func splitCueCommand(line string) (string, string) {
+	cmd, args, _ := strings.Cut(line, " ")
+	return cmd, strings.TrimSpace(args)
+}
+
+func unquoteCueArg(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func parseCueTime(s string) (float64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	var vals [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		vals[i] = v
+	}
+	return float64(vals[0]*60+vals[1]) + float64(vals[2])/cueFramesPerSecond, nil
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+const testCueSheet = `REM GENRE Rock
+PERFORMER "The Band"
+TITLE "The Album"
+FILE "The Band - The Album.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "First Song"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Second Song"
+    PERFORMER "The Band feat. Guest"
+    INDEX 00 03:58:50
+    INDEX 01 04:00:37
+`
+
+func TestParseCueSheet(t *testing.T) {
+	sheet, err := ParseCueSheet(strings.NewReader(testCueSheet))
+	if err != nil {
+		t.Fatalf("ParseCueSheet failed: %v", err)
+	}
+	if sheet.Title != "The Album" || sheet.Performer != "The Band" {
+		t.Errorf("Unexpected album title/performer: %q/%q", sheet.Title, sheet.Performer)
+	}
+	if len(sheet.Files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(sheet.Files))
+	}
+	f := sheet.Files[0]
+	if f.Name != "The Band - The Album.flac" {
+		t.Errorf("Unexpected file name %q", f.Name)
+	}
+	if len(f.Tracks) != 2 {
+		t.Fatalf("Expected 2 tracks, got %d", len(f.Tracks))
+	}
+	if f.Tracks[0].Title != "First Song" || f.Tracks[0].Start != 0 {
+		t.Errorf("Unexpected first track %+v", f.Tracks[0])
+	}
+	tr := f.Tracks[1]
+	if tr.Number != 2 || tr.Title != "Second Song" || tr.Performer != "The Band feat. Guest" {
+		t.Errorf("Unexpected second track %+v", tr)
+	}
+	if want := 240 + 37.0/75; tr.Start != want {
+		t.Errorf("Expected second track start %v, got %v", want, tr.Start)
+	}
+}
+
+func TestParseCueSheet_Invalid(t *testing.T) {
+	if _, err := ParseCueSheet(strings.NewReader("TRACK 01 AUDIO\n")); err == nil {
+		t.Error("Expected error for TRACK before FILE")
+	}
+	bad := "FILE \"a.flac\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:xx:00\n"
+	if _, err := ParseCueSheet(strings.NewReader(bad)); err == nil {
+		t.Error("Expected error for invalid INDEX time")
+	}
+}
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"math"
+	"math/bits"
+)
+
+// SpectrumAnalyzer computes the levels of logarithmically-spaced
+// frequency bands from blocks of mono PCM samples using an FFT.
+// It is not thread-safe; each consumer should use its own instance.
+type SpectrumAnalyzer struct {
+	fftSize   int
+	window    []float64
+	windowSum float64
+	re        []float64
+	im        []float64
+	bandBins  [][2]int // [lo, hi) FFT bin range for each band
+}
+
+// NewSpectrumAnalyzer creates a SpectrumAnalyzer operating on blocks of fftSize
+// samples (must be a power of 2) recorded at sampleRate, which reports the
+// levels of numBands bands logarithmically spaced between minFreq and maxFreq.
+func NewSpectrumAnalyzer(fftSize, sampleRate, numBands int, minFreq, maxFreq float64) *SpectrumAnalyzer {
+	if fftSize < 2 || bits.OnesCount(uint(fftSize)) != 1 {
+		panic("SpectrumAnalyzer: fftSize must be a power of 2")
+	}
+	s := &SpectrumAnalyzer{
+		fftSize: fftSize,
+		window:  make([]float64, fftSize),
+		re:      make([]float64, fftSize),
+		im:      make([]float64, fftSize),
+	}
+	// Hann window
+	for i := range s.window {
+		s.window[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(fftSize-1)))
+		s.windowSum += s.window[i]
+	}
+
+	maxFreq = math.Min(maxFreq, float64(sampleRate)/2)
+	binWidth := float64(sampleRate) / float64(fftSize)
+	ratio := math.Pow(maxFreq/minFreq, 1/float64(numBands))
+	s.bandBins = make([][2]int, numBands)
+	lo := minFreq
+	for i := range s.bandBins {
+		hi := lo * ratio
+		loBin := int(math.Round(lo / binWidth))
+		hiBin := int(math.Round(hi / binWidth))
+		// narrow low-frequency bands may span less than one bin
+		hiBin = max(hiBin, loBin+1)
+		s.bandBins[i] = [2]int{min(loBin, fftSize/2-1), min(hiBin, fftSize/2)}
+		lo = hi
+	}
+	return s
+}
+
+// FFTSize returns the number of samples consumed by each call to Analyze.
+func (s *SpectrumAnalyzer) FFTSize() int {
+	return s.fftSize
+}
+
+// NumBands returns the number of frequency bands reported by Analyze.
+func (s *SpectrumAnalyzer) NumBands() int {
+	return len(s.bandBins)
+}
+
+// Analyze computes the level of each frequency band, in dB relative to
+// a full-scale sine wave, from the given samples (normalized to [-1, 1]).
+// samples must have length FFTSize and out must have length NumBands.
+func (s *SpectrumAnalyzer) Analyze(samples []float64, out []float64) {
+	for i, v := range samples[:s.fftSize] {
+		s.re[i] = v * s.window[i]
+		s.im[i] = 0
+	}
+	FFT(s.re, s.im)
+
+	// scale so that a full-scale sine wave reads as 0 dB
+	scale := 2 / s.windowSum
+	for b, bins := range s.bandBins {
+		var peak float64
+		for i := bins[0]; i < bins[1]; i++ {
+			peak = math.Max(peak, math.Hypot(s.re[i], s.im[i]))
+		}
+		out[b] = 20 * math.Log10(math.Max(peak*scale, 1e-10))
+	}
+}
+
+// FFT computes the discrete Fourier transform of the complex sequence
+// given by re and im in place. The length must be a power of 2.
+func FFT(re, im []float64) {
+	n := len(re)
+	if n < 2 {
+		return
+	}
+	// bit reversal permutation
+	shift := 64 - uint(bits.TrailingZeros(uint(n)))
+	for i := range n {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if j > i {
+			re[i], re[j] = re[j], re[i]
+			im[i], im[j] = im[j], im[i]
+		}
+	}
+	// iterative radix-2 butterflies
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := range half {
+				wr, wi := math.Cos(step*float64(k)), math.Sin(step*float64(k))
+				a, b := start+k, start+k+half
+				tr := wr*re[b] - wi*im[b]
+				ti := wr*im[b] + wi*re[b]
+				re[b], im[b] = re[a]-tr, im[a]-ti
+				re[a], im[a] = re[a]+tr, im[a]+ti
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpectrumAnalyzer_Sine(t *testing.T) {
+	const sampleRate = 22050
+	s := NewSpectrumAnalyzer(2048, sampleRate, 32, 40, 11000)
+
+	samples := make([]float64, s.FFTSize())
+	for i := range samples {
+		samples[i] = math.Sin(2 * math.Pi * 1000 * float64(i) / sampleRate)
+	}
+	out := make([]float64, s.NumBands())
+	s.Analyze(samples, out)
+
+	peakBand := 0
+	for i, v := range out {
+		if v > out[peakBand] {
+			peakBand = i
+		}
+	}
+	lo, hi := s.bandBins[peakBand][0], s.bandBins[peakBand][1]
+	binWidth := float64(sampleRate) / float64(s.FFTSize())
+	if f := 1000 / binWidth; f < float64(lo)-1 || f > float64(hi)+1 {
+		t.Errorf("Expected 1 kHz in peak band, got bins [%d, %d)", lo, hi)
+	}
+	if math.Abs(out[peakBand]) > 1.5 {
+		t.Errorf("Expected full-scale sine near 0 dB, got %v", out[peakBand])
+	}
+}
+
+func TestSpectrumAnalyzer_Silence(t *testing.T) {
+	s := NewSpectrumAnalyzer(1024, 22050, 16, 40, 11000)
+	out := make([]float64, s.NumBands())
+	s.Analyze(make([]float64, s.FFTSize()), out)
+	for i, v := range out {
+		if v > -150 {
+			t.Errorf("Expected silence in band %d, got %v dB", i, v)
+		}
+	}
+}
+
+func TestFFT_Impulse(t *testing.T) {
+	re := make([]float64, 64)
+	im := make([]float64, 64)
+	re[0] = 1
+	FFT(re, im)
+	for i := range re {
+		if math.Abs(re[i]-1) > 1e-9 || math.Abs(im[i]) > 1e-9 {
+			t.Errorf("Expected flat spectrum at bin %d, got (%v, %v)", i, re[i], im[i])
+		}
+	}
+}
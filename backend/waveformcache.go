@@ -0,0 +1,183 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/20after4/configdir"
+	"github.com/google/uuid"
+)
+
+const (
+	waveformFileExt        = ".wfm"
+	waveformFileMagic      = "SSWF1"
+	waveformPruneThreshold = 50 // prune after this many files written
+)
+
+// WaveformPeaks is the analyzed peak and RMS amplitude data
+// (one value per pixel column) from which a WaveformImage is drawn.
+type WaveformPeaks struct {
+	Peak [1024]byte
+	RMS  [1024]byte
+}
+
+// WaveformCache is a size-bounded on-disk cache of analyzed waveform data,
+// stored per server and track ID, so that a track's waveform need only be
+// computed once rather than every time it is played.
+type WaveformCache struct {
+	mutex sync.Mutex
+
+	s            *ServerManager
+	baseCacheDir string
+	maxSizeBytes int64
+	numWritten   int
+}
+
+// NewWaveformCache returns a new WaveformCache storing files under baseCacheDir,
+// which will delete least recently used entries to stay within maxSizeBytes.
+func NewWaveformCache(s *ServerManager, baseCacheDir string, maxSizeBytes int64) *WaveformCache {
+	w := &WaveformCache{
+		s:            s,
+		baseCacheDir: baseCacheDir,
+		maxSizeBytes: maxSizeBytes,
+	}
+	go w.prune()
+	return w
+}
+
+// Get returns the cached waveform data for the given track ID
+// on the current server, if it exists.
+func (w *WaveformCache) Get(trackID string) (*WaveformPeaks, bool) {
+	path := w.pathForID(w.s.ServerID, trackID)
+	if path == "" {
+		return nil, false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	peaks, err := decodeWaveformPeaks(b)
+	if err != nil {
+		log.Printf("invalid waveform cache file %s: %v", path, err)
+		_ = os.Remove(path)
+		return nil, false
+	}
+	// modTime is used as last access time for pruning
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return peaks, true
+}
+
+// Has returns true if waveform data for the given track ID
+// on the current server is in the cache.
+func (w *WaveformCache) Has(trackID string) bool {
+	path := w.pathForID(w.s.ServerID, trackID)
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Put stores the waveform data for the given track ID on the current server.
+func (w *WaveformCache) Put(trackID string, peaks *WaveformPeaks) error {
+	path := w.pathForID(w.s.ServerID, trackID)
+	if path == "" {
+		return errors.New("not connected to a server")
+	}
+	if err := configdir.MakePath(filepath.Dir(path)); err != nil {
+		return err
+	}
+	// write to temp file and rename so that readers never see a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, encodeWaveformPeaks(peaks), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	w.mutex.Lock()
+	w.numWritten++
+	needsPrune := w.numWritten >= waveformPruneThreshold
+	if needsPrune {
+		w.numWritten = 0
+	}
+	w.mutex.Unlock()
+	if needsPrune {
+		go w.prune()
+	}
+	return nil
+}
+
+func (w *WaveformCache) pathForID(serverID uuid.UUID, trackID string) string {
+	if serverID == uuid.Nil || w.baseCacheDir == "" {
+		return ""
+	}
+	return filepath.Join(w.baseCacheDir, serverID.String(), sanitizeFileName(trackID)+waveformFileExt)
+}
+
+// deletes least recently used files until the cache is within its size limit
+func (w *WaveformCache) prune() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	type fileInfo struct {
+		path    string
+		size    int64
+		modTime int64
+	}
+	var files []fileInfo
+	var totalSize int64
+	filepath.WalkDir(w.baseCacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, waveformFileExt) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files = append(files, fileInfo{path: path, size: info.Size(), modTime: info.ModTime().UnixMilli()})
+			totalSize += info.Size()
+		}
+		return nil
+	})
+
+	if totalSize > w.maxSizeBytes {
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].modTime < files[j].modTime
+		})
+		for i := 0; i < len(files) && totalSize > w.maxSizeBytes; i++ {
+			if err := os.Remove(files[i].path); err == nil {
+				totalSize -= files[i].size
+			}
+		}
+	}
+}
+
+func encodeWaveformPeaks(peaks *WaveformPeaks) []byte {
+	b := make([]byte, 0, len(waveformFileMagic)+len(peaks.Peak)+len(peaks.RMS))
+	b = append(b, waveformFileMagic...)
+	b = append(b, peaks.Peak[:]...)
+	return append(b, peaks.RMS[:]...)
+}
+
+func decodeWaveformPeaks(b []byte) (*WaveformPeaks, error) {
+	var peaks WaveformPeaks
+	if len(b) != len(waveformFileMagic)+len(peaks.Peak)+len(peaks.RMS) {
+		return nil, fmt.Errorf("unexpected size %d", len(b))
+	}
+	if string(b[:len(waveformFileMagic)]) != waveformFileMagic {
+		return nil, errors.New("bad header")
+	}
+	b = b[len(waveformFileMagic):]
+	copy(peaks.Peak[:], b)
+	copy(peaks.RMS[:], b[len(peaks.Peak):])
+	return &peaks, nil
+}
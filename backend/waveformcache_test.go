@@ -0,0 +1,64 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestWaveformCache_PutGet(t *testing.T) {
+	s := &ServerManager{ServerID: uuid.New()}
+	w := NewWaveformCache(s, t.TempDir(), 1_048_576)
+
+	if _, ok := w.Get("track1"); ok {
+		t.Error("Expected cache miss for uncached track")
+	}
+
+	var peaks WaveformPeaks
+	for i := range peaks.Peak {
+		peaks.Peak[i] = byte(i)
+		peaks.RMS[i] = byte(i / 2)
+	}
+	if err := w.Put("track1", &peaks); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if !w.Has("track1") {
+		t.Error("Expected Has to return true after Put")
+	}
+	got, ok := w.Get("track1")
+	if !ok {
+		t.Fatal("Expected cache hit after Put")
+	}
+	if *got != peaks {
+		t.Error("Cached waveform data does not match stored data")
+	}
+
+	// entries are per server
+	s.ServerID = uuid.New()
+	if w.Has("track1") {
+		t.Error("Expected cache miss for track on a different server")
+	}
+}
+
+func TestWaveformCache_Prune(t *testing.T) {
+	s := &ServerManager{ServerID: uuid.New()}
+	entrySize := int64(len(encodeWaveformPeaks(&WaveformPeaks{})))
+	w := NewWaveformCache(s, t.TempDir(), 2*entrySize)
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := w.Put(id, &WaveformPeaks{}); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	w.prune()
+
+	n := 0
+	for _, id := range []string{"a", "b", "c"} {
+		if w.Has(id) {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 entries remaining after prune, got %d", n)
+	}
+}
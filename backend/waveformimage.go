@@ -11,6 +11,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -23,6 +24,18 @@ import (
 
 type WaveformImageGenerator struct {
 	audioCache *AudioCache
+	cache      *WaveformCache // may be nil
+
+	precomputeLock    sync.Mutex
+	precomputeQueue   []WaveformPrecomputeRequest
+	precomputeRunning bool
+}
+
+// WaveformPrecomputeRequest is a request to compute and cache
+// the waveform data for a track ahead of when it is played.
+type WaveformPrecomputeRequest struct {
+	Track     *mediaprovider.Track
+	StreamURL string
 }
 
 // Buffer pool for waveform analysis to reduce allocations
@@ -102,8 +115,8 @@ func (w *WaveformImageJob) Get() *WaveformImage {
 	return result
 }
 
-func NewWaveformImageGenerator(cache *AudioCache) *WaveformImageGenerator {
-	return &WaveformImageGenerator{audioCache: cache}
+func NewWaveformImageGenerator(audioCache *AudioCache, waveformCache *WaveformCache) *WaveformImageGenerator {
+	return &WaveformImageGenerator{audioCache: audioCache, cache: waveformCache}
 }
 
 func (w *WaveformImageGenerator) StartWaveformGeneration(item *mediaprovider.Track) *WaveformImageJob {
@@ -114,6 +127,16 @@ func (w *WaveformImageGenerator) StartWaveformGeneration(item *mediaprovider.Tra
 		cancel: cancel,
 	}
 
+	if w.cache != nil {
+		if peaks, ok := w.cache.Get(item.ID); ok {
+			// draw the image immediately from the cached data
+			data := &waveformData{WaveformPeaks: *peaks, progress: 1024, done: true}
+			generateWaveformImage(ctx, data, job)
+			job.done = true
+			return job
+		}
+	}
+
 	// Set up a pipeline of concurrent tasks that need to complete to generate
 	// a waveform image:
 	// 1. Begin downloading the file from the server
@@ -179,7 +202,8 @@ func (w *WaveformImageGenerator) StartWaveformGeneration(item *mediaprovider.Tra
 		// Start converting the file to WAV for analysis
 		var wavConvertDone bool
 		go func() {
-			err := w.convertToWav(ctx, job.ItemID, path, transcodeFile)
+			err := w.convertToWav(ctx, path, transcodeFile)
+			w.audioCache.ReleaseReferenceToFile(job.ItemID)
 			wavConvertDone = true
 			if err != nil {
 				job.setError(err)
@@ -204,6 +228,10 @@ func (w *WaveformImageGenerator) StartWaveformGeneration(item *mediaprovider.Tra
 			err := analyzeWavFile(ctx, transcodeFile, data, item.Duration.Milliseconds(), func() bool { return wavConvertDone })
 			if err != nil {
 				job.setError(err)
+			} else if w.cache != nil && wavConvertDone && data.coversTrack(item.Duration) {
+				if err := w.cache.Put(item.ID, &data.WaveformPeaks); err != nil {
+					log.Printf("failed to cache waveform: %v", err)
+				}
 			}
 			data.done = true
 			// Final notification that processing is complete
@@ -224,14 +252,21 @@ func (w *WaveformImageGenerator) StartWaveformGeneration(item *mediaprovider.Tra
 }
 
 type waveformData struct {
-	Peak [1024]byte
-	RMS  [1024]byte
+	WaveformPeaks
 
 	progress int // first invalid index for Peak/RMS data
 	done     bool
 	notify   chan struct{} // signals when new data is available
 }
 
+// coversTrack reports whether the analysis reached the end of the track,
+// rather than stopping early on a stream that was cut off. Durations are
+// only accurate to the second, so up to a second may be missing at the end.
+func (d *waveformData) coversTrack(duration time.Duration) bool {
+	missing := 1024 - d.progress
+	return missing <= 0 || time.Duration(missing)*duration/1024 <= time.Second
+}
+
 func generateWaveformImage(ctx context.Context, data *waveformData, job *WaveformImageJob) {
 	centerY := job.img.Rect.Dy() / 2 // 24
 	top := centerY - 1               // 23
@@ -435,7 +470,71 @@ func float64ToByte(val float64) byte {
 	return byte(val * 255)
 }
 
-func (w *WaveformImageGenerator) convertToWav(ctx context.Context, id, inPath, outPath string) error {
+// PrecomputeWaveforms replaces the queue of tracks whose waveform data should be
+// computed and cached in the background. Tracks already in the cache are skipped.
+// Tracks are decoded directly from their stream URL, one at a time.
+func (w *WaveformImageGenerator) PrecomputeWaveforms(reqs []WaveformPrecomputeRequest) {
+	if w.cache == nil {
+		return
+	}
+	w.precomputeLock.Lock()
+	defer w.precomputeLock.Unlock()
+	w.precomputeQueue = slices.DeleteFunc(slices.Clone(reqs), func(r WaveformPrecomputeRequest) bool {
+		return r.Track == nil || w.cache.Has(r.Track.ID)
+	})
+	if !w.precomputeRunning && len(w.precomputeQueue) > 0 {
+		w.precomputeRunning = true
+		go w.runPrecompute()
+	}
+}
+
+func (w *WaveformImageGenerator) runPrecompute() {
+	ctx := w.audioCache.rootCtx
+	for {
+		w.precomputeLock.Lock()
+		if len(w.precomputeQueue) == 0 || ctx.Err() != nil {
+			w.precomputeRunning = false
+			w.precomputeLock.Unlock()
+			return
+		}
+		req := w.precomputeQueue[0]
+		w.precomputeQueue = w.precomputeQueue[1:]
+		w.precomputeLock.Unlock()
+
+		if w.cache.Has(req.Track.ID) {
+			continue
+		}
+		if err := w.precompute(ctx, req); err != nil {
+			log.Printf("failed to precompute waveform for %s: %v", req.Track.ID, err)
+		}
+	}
+}
+
+func (w *WaveformImageGenerator) precompute(ctx context.Context, req WaveformPrecomputeRequest) error {
+	f, err := os.CreateTemp("", "supersonic-waveform-*.wav")
+	if err != nil {
+		return err
+	}
+	wavFile := f.Name()
+	f.Close()
+
+	if err := w.convertToWav(ctx, req.StreamURL, wavFile); err != nil {
+		os.Remove(wavFile)
+		return err
+	}
+	data := &waveformData{notify: make(chan struct{}, 1)}
+	// analyzeWavFile removes the WAV file when done
+	err = analyzeWavFile(ctx, wavFile, data, req.Track.Duration.Milliseconds(), func() bool { return true })
+	if err != nil {
+		return err
+	}
+	if !data.coversTrack(req.Track.Duration) {
+		return fmt.Errorf("stream ended early, analyzed %d of 1024 samples", data.progress)
+	}
+	return w.cache.Put(req.Track.ID, &data.WaveformPeaks)
+}
+
+func (w *WaveformImageGenerator) convertToWav(ctx context.Context, inPath, outPath string) error {
 	m := mpv.Create()
 	m.SetOptionString("video", "no")
 	m.SetOptionString("audio-display", "no")
@@ -456,7 +555,6 @@ func (w *WaveformImageGenerator) convertToWav(ctx context.Context, id, inPath, o
 	defer m.TerminateDestroy()
 
 	m.Command([]string{"loadfile", inPath, "replace"})
-	defer w.audioCache.ReleaseReferenceToFile(id)
 
 	// Wait for MPV idle or ctx expiry
 	for {
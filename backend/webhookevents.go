@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"path/filepath"
+
+	"github.com/dweymouth/supersonic/backend/ipc"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/webhooks"
+)
+
+// actions of playlist webhook events
+const (
+	PlaylistActionCreate       = "create"
+	PlaylistActionEdit         = "edit"
+	PlaylistActionDelete       = "delete"
+	PlaylistActionAddTracks    = "add_tracks"
+	PlaylistActionRemoveTracks = "remove_tracks"
+	PlaylistActionReorder      = "reorder"
+)
+
+type webhookTrackData struct {
+	Track *ipc.TrackInfo `json:"track"`
+}
+
+type webhookFavoriteData struct {
+	TrackIDs []string `json:"trackIds"`
+	Favorite bool     `json:"favorite"`
+}
+
+type webhookRatingData struct {
+	TrackIDs []string `json:"trackIds"`
+	Rating   int      `json:"rating"`
+}
+
+type webhookPlaylistData struct {
+	Action     string   `json:"action"`
+	PlaylistID string   `json:"playlistId,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	TrackIDs   []string `json:"trackIds,omitempty"`
+}
+
+func (a *App) startWebhooks() {
+	hooks := make([]webhooks.Webhook, 0, len(a.Config.Webhooks.Hooks))
+	for _, h := range a.Config.Webhooks.Hooks {
+		hooks = append(hooks, webhooks.Webhook{
+			URL:          h.URL,
+			Method:       h.Method,
+			Headers:      h.Headers,
+			BodyTemplate: h.BodyTemplate,
+			Events:       h.Events,
+		})
+	}
+	a.webhooks = webhooks.NewDispatcher(hooks, filepath.Join(a.configDir, webhookQueueFile))
+	go a.webhooks.Run(a.bgrndCtx)
+
+	a.PlaybackManager.OnSongChange(func(item mediaprovider.MediaItem, justScrobbled *mediaprovider.Track) {
+		if justScrobbled != nil {
+			a.fireWebhooks(webhooks.EventScrobble, webhookTrackData{Track: ipc.NewTrackInfo(justScrobbled)})
+		}
+		if item != nil {
+			a.fireWebhooks(webhooks.EventSongChange, webhookTrackData{Track: ipc.NewTrackInfo(item)})
+		}
+	})
+}
+
+func (a *App) fireWebhooks(event string, data any) {
+	if a.webhooks != nil {
+		a.webhooks.Fire(webhooks.Event{Type: event, Data: data})
+	}
+}
+
+// OnTrackFavoritesChanged notifies webhooks that the tracks were favorited or unfavorited.
+func (a *App) OnTrackFavoritesChanged(trackIDs []string, favorite bool) {
+	a.fireWebhooks(webhooks.EventFavorite, webhookFavoriteData{TrackIDs: trackIDs, Favorite: favorite})
+}
+
+// OnTrackRatingsChanged notifies webhooks that the tracks were rated.
+func (a *App) OnTrackRatingsChanged(trackIDs []string, rating int) {
+	a.fireWebhooks(webhooks.EventRating, webhookRatingData{TrackIDs: trackIDs, Rating: rating})
+}
+
+// OnPlaylistChanged notifies webhooks of a playlist edit, with the
+// PlaylistAction* action and the track IDs added, if any.
+// The ID of newly created playlists is not known.
+func (a *App) OnPlaylistChanged(action, playlistID, name string, trackIDs []string) {
+	a.fireWebhooks(webhooks.EventPlaylist, webhookPlaylistData{
+		Action:     action,
+		PlaylistID: playlistID,
+		Name:       name,
+		TrackIDs:   trackIDs,
+	})
+}
+
+// WebhookDeliveries returns the most recent webhook deliveries, newest first.
+func (a *App) WebhookDeliveries() []webhooks.Delivery {
+	if a.webhooks == nil {
+		return nil
+	}
+	return a.webhooks.Log()
+}
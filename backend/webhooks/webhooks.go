@@ -0,0 +1,324 @@
+// Package webhooks delivers app events to user-configured HTTP endpoints,
+// retrying failed deliveries from a queue persisted to disk.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// events sent to webhooks
+const (
+	EventSongChange = "song_change"
+	EventScrobble   = "scrobble"
+	EventFavorite   = "favorite"
+	EventRating     = "rating"
+	EventPlaylist   = "playlist"
+)
+
+const (
+	maxAttempts       = 8
+	initialRetryDelay = 5 * time.Second
+	maxRetryDelay     = 10 * time.Minute
+	requestTimeout    = 15 * time.Second
+	// oldest undelivered requests are dropped beyond this
+	maxQueueLen = 500
+	maxLogLen   = 100
+)
+
+// Webhook is an HTTP endpoint that events are sent to.
+type Webhook struct {
+	URL string
+	// POST if empty
+	Method  string
+	Headers map[string]string
+	// Go text/template executed with the JSON event decoded into a map,
+	// e.g. {{.event}} and {{.data.track.title}}. The JSON event is sent if empty.
+	BodyTemplate string
+	// events sent to the webhook, or all events if empty
+	Events []string
+}
+
+// Event is sent to webhooks as the JSON request body by default.
+type Event struct {
+	Type string    `json:"event"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Delivery is an attempt to send an event to a webhook.
+type Delivery struct {
+	Time       time.Time
+	Event      string
+	URL        string
+	Attempt    int
+	StatusCode int // 0 if no response was received
+	Err        string
+	// whether the request will be attempted again
+	Retrying bool
+}
+
+// Succeeded returns whether the webhook accepted the event.
+func (d Delivery) Succeeded() bool {
+	return d.Err == ""
+}
+
+// request is an event rendered for a webhook, queued until delivered.
+type request struct {
+	Event       string            `json:"event"`
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body"`
+	Attempts    int               `json:"attempts"`
+	NextAttempt time.Time         `json:"nextAttempt"`
+}
+
+type hook struct {
+	Webhook
+	body *template.Template
+}
+
+// Dispatcher sends events to webhooks from a background goroutine.
+type Dispatcher struct {
+	hooks      []hook
+	queueFile  string
+	client     *http.Client
+	retryDelay time.Duration
+
+	lock  sync.Mutex
+	queue []*request
+	log   []Delivery
+	wake  chan struct{}
+}
+
+var templateFuncs = template.FuncMap{
+	// json quotes a value for use in a JSON body template
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// NewDispatcher returns a dispatcher for the webhooks, which resumes
+// delivering the requests left in queueFile by a previous run.
+func NewDispatcher(webhooks []Webhook, queueFile string) *Dispatcher {
+	d := &Dispatcher{
+		queueFile:  queueFile,
+		client:     &http.Client{Timeout: requestTimeout},
+		retryDelay: initialRetryDelay,
+		wake:       make(chan struct{}, 1),
+	}
+	for _, w := range webhooks {
+		h := hook{Webhook: w}
+		if w.BodyTemplate != "" {
+			t, err := template.New("body").Funcs(templateFuncs).Parse(w.BodyTemplate)
+			if err != nil {
+				log.Printf("invalid body template for webhook %s, sending JSON event: %s", w.URL, err.Error())
+			}
+			h.body = t
+		}
+		d.hooks = append(d.hooks, h)
+	}
+	if b, err := os.ReadFile(queueFile); err == nil {
+		if err := json.Unmarshal(b, &d.queue); err != nil {
+			log.Printf("error reading webhook queue: %s", err.Error())
+		}
+		// drop requests for webhooks that have since been removed
+		d.queue = slices.DeleteFunc(d.queue, func(r *request) bool {
+			return !slices.ContainsFunc(webhooks, func(w Webhook) bool { return w.URL == r.URL })
+		})
+	}
+	return d
+}
+
+// Fire queues the event for delivery to the webhooks subscribed to it.
+func (d *Dispatcher) Fire(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("error encoding webhook event: %s", err.Error())
+		return
+	}
+	var data map[string]any
+	json.Unmarshal(b, &data)
+
+	var reqs []*request
+	for _, h := range d.hooks {
+		if len(h.Events) > 0 && !slices.Contains(h.Events, e.Type) {
+			continue
+		}
+		body := string(b)
+		if h.body != nil {
+			var sb strings.Builder
+			if err := h.body.Execute(&sb, data); err != nil {
+				log.Printf("error executing body template for webhook %s: %s", h.URL, err.Error())
+				continue
+			}
+			body = sb.String()
+		}
+		method := h.Method
+		if method == "" {
+			method = http.MethodPost
+		}
+		reqs = append(reqs, &request{
+			Event:   e.Type,
+			URL:     h.URL,
+			Method:  strings.ToUpper(method),
+			Headers: h.Headers,
+			Body:    body,
+		})
+	}
+	if len(reqs) == 0 {
+		return
+	}
+
+	d.lock.Lock()
+	d.queue = append(d.queue, reqs...)
+	if drop := len(d.queue) - maxQueueLen; drop > 0 {
+		log.Printf("webhook queue full, dropping %d undelivered requests", drop)
+		d.queue = slices.Delete(d.queue, 0, drop)
+	}
+	d.saveQueue()
+	d.lock.Unlock()
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers queued requests until the context is canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		req, wait := d.next()
+		if req != nil {
+			d.deliver(ctx, req)
+			continue
+		}
+		var timer <-chan time.Time
+		if wait > 0 {
+			timer = time.After(wait)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-timer:
+		}
+	}
+}
+
+// Log returns the most recent deliveries, newest first.
+func (d *Dispatcher) Log() []Delivery {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	deliveries := slices.Clone(d.log)
+	slices.Reverse(deliveries)
+	return deliveries
+}
+
+// next returns the next request due for delivery, or else
+// how long until the next one is due (0 if the queue is empty).
+func (d *Dispatcher) next() (*request, time.Duration) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var next *request
+	for _, r := range d.queue {
+		if next == nil || r.NextAttempt.Before(next.NextAttempt) {
+			next = r
+		}
+	}
+	if next == nil {
+		return nil, 0
+	}
+	if wait := time.Until(next.NextAttempt); wait > 0 {
+		return nil, wait
+	}
+	return next, 0
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, r *request) {
+	status, err := d.send(ctx, r)
+	if ctx.Err() != nil {
+		return // shutting down; the request remains queued
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	r.Attempts++
+	retry := err != nil && r.Attempts < maxAttempts && retryable(status)
+	if retry {
+		delay := min(d.retryDelay<<(r.Attempts-1), maxRetryDelay)
+		r.NextAttempt = time.Now().Add(delay)
+	} else {
+		d.queue = slices.DeleteFunc(d.queue, func(q *request) bool { return q == r })
+	}
+	delivery := Delivery{
+		Time:       time.Now(),
+		Event:      r.Event,
+		URL:        r.URL,
+		Attempt:    r.Attempts,
+		StatusCode: status,
+		Retrying:   retry,
+	}
+	if err != nil {
+		delivery.Err = err.Error()
+		log.Printf("error delivering %s event to webhook %s: %s", r.Event, r.URL, err.Error())
+	}
+	d.log = append(d.log, delivery)
+	if len(d.log) > maxLogLen {
+		d.log = slices.Delete(d.log, 0, len(d.log)-maxLogLen)
+	}
+	d.saveQueue()
+}
+
+func (d *Dispatcher) send(ctx context.Context, r *request) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, bytes.NewBufferString(r.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New(resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable returns whether a request failing with the
+// HTTP status (0 if no response) may succeed if retried.
+func retryable(status int) bool {
+	return status == 0 || status >= 500 ||
+		status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// saveQueue writes the queue to disk. Must be called with the lock held.
+func (d *Dispatcher) saveQueue() {
+	b, err := json.Marshal(d.queue)
+	if err == nil {
+		// queued requests may include auth headers
+		err = os.WriteFile(d.queueFile, b, 0o600)
+	}
+	if err != nil {
+		log.Printf("error saving webhook queue: %s", err.Error())
+	}
+}
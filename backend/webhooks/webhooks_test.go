@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedRequest struct {
+	method, auth, body string
+}
+
+func TestDispatcher(t *testing.T) {
+	var lock sync.Mutex
+	var received []recordedRequest
+	failures := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		received = append(received, recordedRequest{r.Method, r.Header.Get("Authorization"), string(b)})
+	}))
+	defer srv.Close()
+
+	queueFile := filepath.Join(t.TempDir(), "queue.json")
+	d := NewDispatcher([]Webhook{{
+		URL:          srv.URL,
+		Method:       "put",
+		Headers:      map[string]string{"Authorization": "Bearer abc"},
+		BodyTemplate: `{"text": {{json .data.title}}}`,
+		Events:       []string{EventSongChange},
+	}}, queueFile)
+	d.retryDelay = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.Fire(Event{Type: EventRating, Data: map[string]any{"rating": 5}}) // not subscribed
+	d.Fire(Event{Type: EventSongChange, Data: map[string]string{"title": `Say "Hello"`}})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.Log()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// newest first: the retry succeeded after the first attempt failed
+	log := d.Log()
+	if len(log) != 2 || !log[0].Succeeded() || log[0].Attempt != 2 ||
+		log[1].Succeeded() || log[1].StatusCode != http.StatusServiceUnavailable || !log[1].Retrying {
+		t.Fatalf("unexpected delivery log %+v", log)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	want := recordedRequest{"PUT", "Bearer abc", `{"text": "Say \"Hello\""}`}
+	if len(received) != 1 || received[0] != want {
+		t.Errorf("expected request %+v, got %+v", want, received)
+	}
+}
+
+func TestDispatcherPersistsQueue(t *testing.T) {
+	queueFile := filepath.Join(t.TempDir(), "queue.json")
+	hooks := []Webhook{{URL: "http://127.0.0.1:1/unreachable"}}
+	d := NewDispatcher(hooks, queueFile)
+	d.Fire(Event{Type: EventScrobble, Data: nil})
+	if fi, err := os.Stat(queueFile); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+		t.Errorf("expected queue file to be private, got mode %v", fi.Mode())
+	}
+
+	// the undelivered request is resumed by the next run
+	d = NewDispatcher(hooks, queueFile)
+	if len(d.queue) != 1 || d.queue[0].Event != EventScrobble || d.queue[0].Method != http.MethodPost {
+		t.Errorf("expected queued scrobble event, got %+v", d.queue)
+	}
+	// unless the webhook was removed
+	d = NewDispatcher(nil, queueFile)
+	if len(d.queue) != 0 {
+		t.Errorf("expected request for removed webhook to be dropped, got %+v", d.queue)
+	}
+}
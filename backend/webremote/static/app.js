@@ -0,0 +1,172 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+const pollInterval = 1000;
+
+let status = null;
+let seeking = false;
+let queueIndex = -1;
+
+async function api(method, path) {
+  const resp = await fetch("/api/" + path, { method, credentials: "same-origin" });
+  if (resp.status === 401) {
+    document.body.textContent = "Not authorized. Open the web remote link shown in Supersonic's settings.";
+    throw new Error("unauthorized");
+  }
+  if (!resp.ok) throw new Error(await resp.text());
+  return resp.status === 204 ? null : resp.json();
+}
+
+const post = (path) => api("POST", path).catch(console.error);
+
+function fmtTime(secs) {
+  secs = Math.max(0, Math.floor(secs || 0));
+  const m = Math.floor(secs / 60), s = secs % 60;
+  return m + ":" + String(s).padStart(2, "0");
+}
+
+function artURL(id) {
+  return id ? "/api/art/" + encodeURIComponent(id) : "";
+}
+
+function row(it, sub) {
+  const li = document.createElement("li");
+  const img = document.createElement("img");
+  img.loading = "lazy";
+  if (it.coverArt) img.src = artURL(it.coverArt);
+  const text = document.createElement("div");
+  text.className = "text";
+  const name = document.createElement("div");
+  name.className = "name";
+  name.textContent = it.title;
+  const subtitle = document.createElement("div");
+  subtitle.className = "sub";
+  subtitle.textContent = sub;
+  text.append(name, subtitle);
+  li.append(img, text);
+  return li;
+}
+
+// Now playing
+
+async function refreshStatus() {
+  try {
+    status = await api("GET", "status");
+  } catch (e) {
+    return;
+  }
+  const np = status.nowPlaying;
+  $("title").textContent = np ? np.title : "Not playing";
+  $("artist").textContent = np ? np.artist || "" : "";
+  $("album").textContent = np ? np.album || "" : "";
+  const art = np ? artURL(np.coverArt) : "";
+  if ($("cover").getAttribute("src") !== art) $("cover").setAttribute("src", art);
+  $("playpause").innerHTML = status.state === "playing" ? "&#x23F8;" : "&#x25B6;";
+  $("dur").textContent = fmtTime(status.duration);
+  if (!seeking) {
+    $("seekbar").max = Math.floor(status.duration);
+    $("seekbar").value = Math.floor(status.position);
+    $("pos").textContent = fmtTime(status.position);
+  }
+  if (document.activeElement !== $("volume")) $("volume").value = status.volume;
+  if (status.queueIndex !== queueIndex) {
+    queueIndex = status.queueIndex;
+    if ($("queue").classList.contains("active")) refreshQueue();
+  }
+}
+
+$("playpause").onclick = () => post("playpause").then(refreshStatus);
+$("prev").onclick = () => post("previous").then(refreshStatus);
+$("next").onclick = () => post("next").then(refreshStatus);
+
+$("seekbar").oninput = () => {
+  seeking = true;
+  $("pos").textContent = fmtTime($("seekbar").value);
+};
+$("seekbar").onchange = () => {
+  post("seek?s=" + $("seekbar").value).then(() => {
+    seeking = false;
+    refreshStatus();
+  });
+};
+$("volume").onchange = () => post("volume?v=" + $("volume").value);
+
+// Queue
+
+async function refreshQueue() {
+  const items = await api("GET", "queue").catch(() => []);
+  const list = $("queuelist");
+  list.replaceChildren();
+  items.forEach((it, i) => {
+    const li = row(it, [it.artist, fmtTime(it.duration)].filter(Boolean).join(" · "));
+    li.dataset.index = i;
+    if (i === queueIndex) li.classList.add("current");
+    li.querySelector(".text").onclick = () => post("queue/play?i=" + i).then(refreshStatus);
+    const handle = document.createElement("span");
+    handle.className = "handle";
+    handle.textContent = "☰";
+    handle.onpointerdown = (e) => startDrag(e, li);
+    li.append(handle);
+    list.append(li);
+  });
+}
+
+// drag a queue row by its handle to reorder
+function startDrag(e, li) {
+  e.preventDefault();
+  const list = $("queuelist");
+  const from = Number(li.dataset.index);
+  li.classList.add("dragging");
+  const move = (ev) => {
+    const over = document.elementFromPoint(ev.clientX, ev.clientY)?.closest("li");
+    if (!over || over === li || over.parentNode !== list) return;
+    const rect = over.getBoundingClientRect();
+    list.insertBefore(li, ev.clientY < rect.top + rect.height / 2 ? over : over.nextSibling);
+  };
+  const end = () => {
+    document.removeEventListener("pointermove", move);
+    document.removeEventListener("pointerup", end);
+    li.classList.remove("dragging");
+    const rows = [...list.children];
+    const to = rows.indexOf(li);
+    if (to === from) return;
+    post("queue/move?from=" + from + "&to=" + to).then(refreshQueue);
+  };
+  document.addEventListener("pointermove", move);
+  document.addEventListener("pointerup", end);
+}
+
+// Search
+
+$("searchform").onsubmit = async (e) => {
+  e.preventDefault();
+  const q = $("query").value.trim();
+  const list = $("results");
+  list.replaceChildren();
+  if (!q) return;
+  const results = await api("GET", "search?q=" + encodeURIComponent(q)).catch(() => []);
+  for (const it of results) {
+    const kind = it.type[0].toUpperCase() + it.type.slice(1);
+    const li = row(it, [kind, it.artist].filter(Boolean).join(" · "));
+    li.onclick = () => {
+      post("play?type=" + it.type + "&id=" + encodeURIComponent(it.id)).then(refreshStatus);
+      showTab("now");
+    };
+    list.append(li);
+  }
+};
+
+// Tabs
+
+function showTab(name) {
+  document.querySelectorAll("nav button").forEach((b) => b.classList.toggle("active", b.dataset.tab === name));
+  document.querySelectorAll(".tab").forEach((t) => t.classList.toggle("active", t.id === name));
+  if (name === "queue") refreshQueue();
+}
+
+document.querySelectorAll("nav button").forEach((b) => (b.onclick = () => showTab(b.dataset.tab)));
+
+refreshStatus();
+setInterval(() => {
+  if (!document.hidden) refreshStatus();
+}, pollInterval);
@@ -0,0 +1,383 @@
+// Package webremote serves a small web app on the LAN for controlling
+// playback from a phone: now playing, transport controls, volume,
+// the play queue, and library search.
+package webremote
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/ipc"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/util"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+const (
+	tokenCookie       = "supersonic_remote"
+	maxSearchResults  = 30
+	coverArtCacheSecs = 86400
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// Player is the playback control the web remote is backed by.
+type Player interface {
+	ipc.PlaybackHandler
+	NowPlayingIndex() int
+	GetActivePlayQueue() []mediaprovider.MediaItem
+	PlayTrackAt(idx int)
+	UpdatePlayQueue(items []mediaprovider.MediaItem)
+	Status() (state player.State, timePos, duration float64)
+}
+
+type Server struct {
+	port       int
+	token      string
+	player     Player
+	library    func() mediaprovider.MediaProvider
+	coverArt   func(id string) (image.Image, error)
+	httpServer *http.Server
+	url        string
+}
+
+// NewServer creates a web remote server. Clients must authenticate with
+// the token, which is exchanged for a cookie when opening the URL returned
+// by URL. library returns the connected server (nil when not connected).
+// If port is 0, a random port is used.
+func NewServer(port int, token string, pl Player, library func() mediaprovider.MediaProvider, coverArt func(id string) (image.Image, error)) *Server {
+	return &Server{port: port, token: token, player: pl, library: library, coverArt: coverArt}
+}
+
+// NewToken returns a new random authentication token.
+func NewToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RemoteURL returns the URL, including the token, to open the web remote
+// served on the given port of this computer.
+func RemoteURL(port int, token string) string {
+	host := "localhost"
+	if ip, err := util.GetLocalIP(); err == nil {
+		host = ip
+	}
+	return fmt.Sprintf("http://%s:%d/?token=%s", host, port, token)
+}
+
+// Start starts listening for HTTP connections on the LAN.
+func (s *Server) Start() error {
+	if s.token == "" {
+		return errors.New("web remote token is empty")
+	}
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return err
+	}
+	s.url = RemoteURL(l.Addr().(*net.TCPAddr).Port, s.token)
+	s.httpServer = &http.Server{Handler: s.Handler()}
+	go s.httpServer.Serve(l)
+	log.Printf("web remote listening on port %d", l.Addr().(*net.TCPAddr).Port)
+	return nil
+}
+
+// URL returns the URL, including the token, to open the web remote.
+func (s *Server) URL() string {
+	return s.url
+}
+
+func (s *Server) Shutdown(ctx context.Context) {
+	if s.httpServer != nil {
+		s.httpServer.Shutdown(ctx)
+	}
+}
+
+// Handler returns the HTTP handler serving the web app and its API.
+func (s *Server) Handler() http.Handler {
+	static, _ := fs.Sub(staticFiles, "static")
+	fileServer := http.FileServerFS(static)
+
+	m := http.NewServeMux()
+	m.HandleFunc("GET /{$}", s.handleIndex)
+	m.Handle("GET /assets/", s.authenticated(http.StripPrefix("/assets", fileServer).ServeHTTP))
+	m.HandleFunc("GET /api/status", s.authenticated(s.handleStatus))
+	m.HandleFunc("GET /api/queue", s.authenticated(s.handleQueue))
+	m.HandleFunc("GET /api/search", s.authenticated(s.handleSearch))
+	m.HandleFunc("GET /api/art/{id}", s.authenticated(s.handleCoverArt))
+	m.HandleFunc("POST /api/playpause", s.authenticated(s.simple(s.player.PlayPause)))
+	m.HandleFunc("POST /api/previous", s.authenticated(s.simple(s.player.SeekBackOrPrevious)))
+	m.HandleFunc("POST /api/next", s.authenticated(s.simple(s.player.SeekNext)))
+	m.HandleFunc("POST /api/stop", s.authenticated(s.simple(s.player.Stop)))
+	m.HandleFunc("POST /api/seek", s.authenticated(s.handleSeek))
+	m.HandleFunc("POST /api/volume", s.authenticated(s.handleVolume))
+	m.HandleFunc("POST /api/queue/play", s.authenticated(s.handleQueuePlay))
+	m.HandleFunc("POST /api/queue/move", s.authenticated(s.handleQueueMove))
+	m.HandleFunc("POST /api/play", s.authenticated(s.handlePlay))
+	return m
+}
+
+func (s *Server) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// isAuthorized checks the token from the cookie or the Authorization header.
+func (s *Server) isAuthorized(r *http.Request) bool {
+	if c, err := r.Cookie(tokenCookie); err == nil && s.validToken(c.Value) {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.validToken(token)
+}
+
+func (s *Server) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAuthorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// handleIndex serves the web app, exchanging a token
+// in the query for a cookie so that it isn't kept in the URL.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		if !s.validToken(token) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     tokenCookie,
+			Value:    token,
+			Path:     "/",
+			MaxAge:   365 * 24 * 60 * 60,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if !s.isAuthorized(r) {
+		http.Error(w, "Open the web remote link shown in Supersonic's settings.", http.StatusUnauthorized)
+		return
+	}
+	http.ServeFileFS(w, r, staticFiles, "static/index.html")
+}
+
+type item struct {
+	Type     string  `json:"type"`
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Artist   string  `json:"artist,omitempty"`
+	Album    string  `json:"album,omitempty"`
+	CoverArt string  `json:"coverArt,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+func toItem(it mediaprovider.MediaItem) item {
+	meta := it.Metadata()
+	typ := "track"
+	switch meta.Type {
+	case mediaprovider.MediaItemTypeRadioStation:
+		typ = "radio"
+	case mediaprovider.MediaItemTypePodcastEpisode:
+		typ = "episode"
+	}
+	return item{
+		Type:     typ,
+		ID:       meta.ID,
+		Title:    meta.Name,
+		Artist:   strings.Join(meta.Artists, ", "),
+		Album:    meta.Album,
+		CoverArt: meta.CoverArtID,
+		Duration: meta.Duration.Seconds(),
+	}
+}
+
+type status struct {
+	State      string  `json:"state"`
+	Position   float64 `json:"position"`
+	Duration   float64 `json:"duration"`
+	Volume     int     `json:"volume"`
+	QueueIndex int     `json:"queueIndex"`
+	NowPlaying *item   `json:"nowPlaying"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	state, pos, dur := s.player.Status()
+	stat := status{
+		State:      stateName(state),
+		Position:   pos,
+		Duration:   dur,
+		Volume:     s.player.Volume(),
+		QueueIndex: s.player.NowPlayingIndex(),
+	}
+	if queue := s.player.GetActivePlayQueue(); stat.QueueIndex >= 0 && stat.QueueIndex < len(queue) {
+		it := toItem(queue[stat.QueueIndex])
+		stat.NowPlaying = &it
+	}
+	writeJSON(w, stat)
+}
+
+func stateName(state player.State) string {
+	switch state {
+	case player.Playing:
+		return "playing"
+	case player.Paused:
+		return "paused"
+	}
+	return "stopped"
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	queue := s.player.GetActivePlayQueue()
+	items := make([]item, len(queue))
+	for i, it := range queue {
+		items[i] = toItem(it)
+	}
+	writeJSON(w, items)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	mp := s.library()
+	if mp == nil {
+		http.Error(w, ipc.ErrNoServerConnection.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	results, err := mp.SearchAll(r.URL.Query().Get("q"), maxSearchResults)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	items := make([]item, 0, len(results))
+	for _, res := range results {
+		var typ string
+		switch res.Type {
+		case mediaprovider.ContentTypeAlbum:
+			typ = "album"
+		case mediaprovider.ContentTypePlaylist:
+			typ = "playlist"
+		case mediaprovider.ContentTypeTrack:
+			typ = "track"
+		default:
+			continue // can't be played from the remote
+		}
+		it := item{Type: typ, ID: res.ID, Title: res.Name, Artist: res.ArtistName, CoverArt: res.CoverID}
+		if typ == "track" {
+			it.Duration = float64(res.Size)
+		}
+		items = append(items, it)
+	}
+	writeJSON(w, items)
+}
+
+func (s *Server) handleCoverArt(w http.ResponseWriter, r *http.Request) {
+	img, err := s.coverArt(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", coverArtCacheSecs))
+	jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
+
+func (s *Server) handleSeek(w http.ResponseWriter, r *http.Request) {
+	secs, err := strconv.ParseFloat(r.URL.Query().Get("s"), 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.player.SeekSeconds(secs)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleVolume(w http.ResponseWriter, r *http.Request) {
+	vol, err := strconv.Atoi(r.URL.Query().Get("v"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.player.SetVolume(vol)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleQueuePlay(w http.ResponseWriter, r *http.Request) {
+	idx, err := strconv.Atoi(r.URL.Query().Get("i"))
+	if err != nil || idx < 0 || idx >= len(s.player.GetActivePlayQueue()) {
+		http.Error(w, "invalid queue index", http.StatusBadRequest)
+		return
+	}
+	s.player.PlayTrackAt(idx)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleQueueMove moves the queue item at index "from" to index "to",
+// with the same meaning as the IPC API's queue move.
+func (s *Server) handleQueueMove(w http.ResponseWriter, r *http.Request) {
+	queue := s.player.GetActivePlayQueue()
+	from, err1 := strconv.Atoi(r.URL.Query().Get("from"))
+	to, err2 := strconv.Atoi(r.URL.Query().Get("to"))
+	if err1 != nil || err2 != nil || from < 0 || from >= len(queue) || to < 0 || to >= len(queue) {
+		http.Error(w, "invalid queue index", http.StatusBadRequest)
+		return
+	}
+	s.player.UpdatePlayQueue(sharedutil.MoveItem(queue, from, to))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	id := q.Get("id")
+	shuffle := q.Get("shuffle") == "1"
+	var err error
+	switch q.Get("type") {
+	case "album":
+		err = s.player.PlayAlbum(id, 0, shuffle)
+	case "playlist":
+		err = s.player.PlayPlaylist(id, 0, shuffle)
+	case "track":
+		err = s.player.PlayTrack(id)
+	default:
+		http.Error(w, "invalid type", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) simple(f func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("web remote: error writing response: %v", err)
+	}
+}
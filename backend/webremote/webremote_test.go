@@ -0,0 +1,150 @@
+package webremote
+
+import (
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+type fakePlayer struct {
+	queue  []mediaprovider.MediaItem
+	idx    int
+	volume int
+	calls  []string
+}
+
+func (f *fakePlayer) PlayPause()                { f.calls = append(f.calls, "playpause") }
+func (f *fakePlayer) Stop()                     {}
+func (f *fakePlayer) Pause()                    {}
+func (f *fakePlayer) Continue()                 {}
+func (f *fakePlayer) SeekBackOrPrevious()       {}
+func (f *fakePlayer) SeekNext()                 { f.calls = append(f.calls, "next") }
+func (f *fakePlayer) SetPauseAfterCurrent(bool) {}
+func (f *fakePlayer) SeekSeconds(float64)       {}
+func (f *fakePlayer) SeekBySeconds(float64)     {}
+func (f *fakePlayer) Volume() int               { return f.volume }
+func (f *fakePlayer) SetVolume(v int)           { f.volume = v }
+func (f *fakePlayer) PlayAlbum(id string, _ int, _ bool) error {
+	f.calls = append(f.calls, "album "+id)
+	return nil
+}
+func (f *fakePlayer) PlayPlaylist(string, int, bool) error { return nil }
+func (f *fakePlayer) PlayTrack(string) error               { return nil }
+func (f *fakePlayer) NowPlayingIndex() int                 { return f.idx }
+func (f *fakePlayer) GetActivePlayQueue() []mediaprovider.MediaItem {
+	return f.queue
+}
+func (f *fakePlayer) PlayTrackAt(idx int) { f.idx = idx }
+func (f *fakePlayer) UpdatePlayQueue(items []mediaprovider.MediaItem) {
+	f.queue = items
+}
+func (f *fakePlayer) Status() (player.State, float64, float64) {
+	return player.Playing, 12, 180
+}
+
+func newTestServer() (*fakePlayer, http.Handler) {
+	pl := &fakePlayer{volume: 80, idx: 1}
+	for _, id := range []string{"a", "b", "c"} {
+		pl.queue = append(pl.queue, &mediaprovider.Track{ID: id, Title: "Song " + id, ArtistNames: []string{"Artist"}, Duration: 3 * time.Minute})
+	}
+	s := NewServer(0, "secret", pl, func() mediaprovider.MediaProvider { return nil },
+		func(string) (image.Image, error) { return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil })
+	return pl, s.Handler()
+}
+
+func do(h http.Handler, method, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebRemoteAuth(t *testing.T) {
+	_, h := newTestServer()
+
+	for _, path := range []string{"/", "/api/status", "/assets/app.js", "/api/art/x"} {
+		if rec := do(h, "GET", path, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s without token: got status %d", path, rec.Code)
+		}
+	}
+	if rec := do(h, "GET", "/?token=wrong", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: got status %d", rec.Code)
+	}
+
+	rec := do(h, "GET", "/?token=secret", nil)
+	if rec.Code != http.StatusSeeOther || len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("expected redirect setting a cookie, got %d", rec.Code)
+	}
+	cookie := rec.Result().Cookies()[0]
+	if rec := do(h, "GET", "/", cookie); rec.Code != http.StatusOK {
+		t.Errorf("index with cookie: got status %d", rec.Code)
+	}
+	if rec := do(h, "GET", "/assets/app.js", cookie); rec.Code != http.StatusOK {
+		t.Errorf("asset with cookie: got status %d", rec.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status with bearer token: got status %d", rec.Code)
+	}
+}
+
+func TestWebRemoteAPI(t *testing.T) {
+	pl, h := newTestServer()
+	cookie := &http.Cookie{Name: tokenCookie, Value: "secret"}
+
+	var stat status
+	json.NewDecoder(do(h, "GET", "/api/status", cookie).Body).Decode(&stat)
+	if stat.State != "playing" || stat.Volume != 80 || stat.NowPlaying == nil || stat.NowPlaying.Title != "Song b" {
+		t.Errorf("unexpected status %+v", stat)
+	}
+
+	do(h, "POST", "/api/next", cookie)
+	do(h, "POST", "/api/play?type=album&id=al1", cookie)
+	do(h, "POST", "/api/volume?v=35", cookie)
+	if !slices.Equal(pl.calls, []string{"next", "album al1"}) || pl.volume != 35 {
+		t.Errorf("unexpected calls %v, volume %d", pl.calls, pl.volume)
+	}
+	if rec := do(h, "GET", "/api/next", cookie); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET of transport command: got status %d", rec.Code)
+	}
+
+	// move "c" before "a"
+	do(h, "POST", "/api/queue/move?from=2&to=0", cookie)
+	var queue []item
+	json.NewDecoder(do(h, "GET", "/api/queue", cookie).Body).Decode(&queue)
+	var ids []string
+	for _, it := range queue {
+		ids = append(ids, it.ID)
+	}
+	if !slices.Equal(ids, []string{"c", "a", "b"}) {
+		t.Errorf("unexpected queue order %v", ids)
+	}
+	// "to" is the index after the move
+	do(h, "POST", "/api/queue/move?from=0&to=2", cookie)
+	queue = nil
+	json.NewDecoder(do(h, "GET", "/api/queue", cookie).Body).Decode(&queue)
+	ids = nil
+	for _, it := range queue {
+		ids = append(ids, it.ID)
+	}
+	if !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected queue order after moving down %v", ids)
+	}
+	if rec := do(h, "POST", "/api/queue/play?i=5", cookie); rec.Code != http.StatusBadRequest {
+		t.Errorf("out of range queue index: got status %d", rec.Code)
+	}
+}
@@ -55,6 +55,16 @@ func main() {
 		return
 	}
 
+	if *backend.FlagHeadless {
+		err := myApp.RunHeadless(*backend.FlagPasswordFile)
+		log.Println("Running shutdown tasks...")
+		myApp.Shutdown()
+		if err != nil {
+			log.Fatalf("failed to connect to server: %v", err.Error())
+		}
+		return
+	}
+
 	if myApp.Config.Application.UIScaleSize == "Smaller" {
 		os.Setenv("FYNE_SCALE", "0.85")
 	} else if myApp.Config.Application.UIScaleSize == "Larger" {
@@ -111,6 +121,9 @@ func main() {
 	myApp.OnReactivate = util.FyneDoFunc(mainWindow.Show)
 	myApp.OnExit = util.FyneDoFunc(mainWindow.Quit)
 	myApp.OnReloadTheme = util.FyneDoFunc(mainWindow.ReloadTheme)
+	myApp.OnOpenURI = func(u backend.SupersonicURI) {
+		fyne.Do(func() { mainWindow.Controller.OpenURI(u) })
+	}
 
 	if runtime.GOOS == "windows" {
 		windowStartupTasks := sync.OnceFunc(func() {
@@ -176,6 +176,20 @@ var ResSaveasSvg = &fyne.StaticResource{
 	StaticContent: ResSaveasSvgData,
 }
 
+//go:embed icons/publicdomain/bookmark.svg
+var ResBookmarkSvgData []byte
+var ResBookmarkSvg = &fyne.StaticResource{
+	StaticName:    "icons/publicdomain/bookmark.svg",
+	StaticContent: ResBookmarkSvgData,
+}
+
+//go:embed icons/publicdomain/podcast.svg
+var ResPodcastSvgData []byte
+var ResPodcastSvg = &fyne.StaticResource{
+	StaticName:    "icons/publicdomain/podcast.svg",
+	StaticContent: ResPodcastSvgData,
+}
+
 //go:embed icons/remix_design/broadcast.svg
 var ResBroadcastSvgData []byte
 var ResBroadcastSvg = &fyne.StaticResource{
@@ -24,6 +24,8 @@ fyne bundle -append -prefix Res icons/publicdomain/list.svg >> bundled.go
 fyne bundle -append -prefix Res icons/publicdomain/filter.svg >> bundled.go
 fyne bundle -append -prefix Res icons/publicdomain/save.svg >> bundled.go
 fyne bundle -append -prefix Res icons/publicdomain/saveas.svg >> bundled.go
+fyne bundle -append -prefix Res icons/publicdomain/bookmark.svg >> bundled.go
+fyne bundle -append -prefix Res icons/publicdomain/podcast.svg >> bundled.go
 fyne bundle -append -prefix Res icons/remix_design/broadcast.svg >> bundled.go
 fyne bundle -append -prefix Res icons/remix_design/repeat.svg >> bundled.go
 fyne bundle -append -prefix Res icons/remix_design/repeatone.svg >> bundled.go
@@ -145,6 +145,17 @@ func ReorderItems[T any](items []T, idxToMove []int, insertIdx int) []T {
 	return newItems
 }
 
+// MoveItem moves the item at index from so that it ends up at index to,
+// and returns a new slice. Both indexes must be valid indexes into items.
+func MoveItem[T any](items []T, from, to int) []T {
+	insertIdx := to
+	if to > from {
+		// ReorderItems inserts before the item at the given original index
+		insertIdx = to + 1
+	}
+	return ReorderItems(items, []int{from}, insertIdx)
+}
+
 // DownloadFileWithContext downloads a file from the specified URL and saves it to destPath.
 // It respects the provided context and will cancel the request and cleanup if context is done.
 // Returns an error if an error other than cancellation occurs, and returns true IFF the file was completely downloaded.
@@ -7,6 +7,23 @@ import (
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 )
 
+func Test_MoveItem(t *testing.T) {
+	items := []string{"a", "b", "c", "d"}
+	for _, tt := range []struct {
+		from, to int
+		want     []string
+	}{
+		{0, 2, []string{"b", "c", "a", "d"}},
+		{3, 0, []string{"d", "a", "b", "c"}},
+		{1, 3, []string{"a", "c", "d", "b"}},
+		{2, 2, []string{"a", "b", "c", "d"}},
+	} {
+		if got := MoveItem(items, tt.from, tt.to); !slices.Equal(got, tt.want) {
+			t.Errorf("MoveItem(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
 func Test_ReorderItems(t *testing.T) {
 	tracks := []*mediaprovider.Track{
 		{ID: "a"}, // 0
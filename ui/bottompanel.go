@@ -110,6 +110,15 @@ func NewBottomPanel(pm *backend.PlaybackManager, im *backend.ImageManager, contr
 	bp.Controls.OnChangeShuffle = func(shuffle bool) {
 		pm.SetShuffle(shuffle)
 	}
+	pm.OnChaptersChange(func(chapters []mediaprovider.Chapter) {
+		var marks []float64
+		if dur := pm.PlaybackStatus().Duration; dur > 0 {
+			for _, ch := range chapters {
+				marks = append(marks, ch.Start/dur)
+			}
+		}
+		fyne.Do(func() { bp.Controls.SetChapterMarks(marks) })
+	})
 	pm.OnLoopModeChange(func(lm backend.LoopMode) {
 		fyne.Do(func() { bp.Controls.SetLoopMode(lm) })
 	})
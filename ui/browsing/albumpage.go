@@ -143,10 +143,17 @@ var _ CanShowNowPlaying = (*AlbumPage)(nil)
 
 func (a *AlbumPage) OnSongChange(track mediaprovider.MediaItem, lastScrobbledIfAny *mediaprovider.Track) {
 	a.nowPlayingID = sharedutil.MediaItemIDOrEmptyStr(track)
+	a.tracklist.SetNowPlayingChapters(a.pm.NowPlayingChapters())
 	a.tracklist.SetNowPlaying(a.nowPlayingID)
 	a.tracklist.IncrementPlayCount(sharedutil.MediaItemIDOrEmptyStr(lastScrobbledIfAny))
 }
 
+var _ CanShowPlayTime = (*AlbumPage)(nil)
+
+func (a *AlbumPage) OnPlayTimeUpdate(curTime, _ float64, _ bool) {
+	a.tracklist.UpdatePlayPos(curTime)
+}
+
 func (a *AlbumPage) Reload() {
 	a.tracklist.SetLoading(true)
 	go a.load()
@@ -292,7 +299,11 @@ func NewAlbumPageHeader(page *AlbumPage) *AlbumPageHeader {
 				a.page.contr.ShowShareDialog(a.albumID)
 			})
 			a.shareMenuItem.Icon = myTheme.ShareIcon
-			menu := fyne.NewMenu("", playNext, queue, playlist, download, info, a.shareMenuItem)
+			copyLink := fyne.NewMenuItem(lang.L("Copy link"), func() {
+				a.page.contr.CopyLink(backend.URIKindAlbum, a.albumID)
+			})
+			copyLink.Icon = theme.ContentCopyIcon()
+			menu := fyne.NewMenu("", playNext, queue, playlist, download, info, a.shareMenuItem, copyLink)
 			pop = widget.NewPopUpMenu(menu, fyne.CurrentApp().Driver().CanvasForObject(a))
 		}
 		_, canShare := page.mp.(mediaprovider.SupportsSharing)
@@ -635,7 +635,11 @@ func NewArtistPageHeader(page *ArtistPage) *ArtistPageHeader {
 				go a.artistPage.pm.ShuffleArtistAlbums(a.artistID)
 			})
 			shuffleAlbums.Icon = myTheme.AlbumIcon
-			menu := fyne.NewMenu("", shuffleTracks, shuffleAlbums)
+			copyLink := fyne.NewMenuItem(lang.L("Copy link"), func() {
+				a.artistPage.contr.CopyLink(backend.URIKindArtist, a.artistID)
+			})
+			copyLink.Icon = theme.ContentCopyIcon()
+			menu := fyne.NewMenu("", shuffleTracks, shuffleAlbums, copyLink)
 			pop = widget.NewPopUpMenu(menu, fyne.CurrentApp().Driver().CanvasForObject(a))
 		}
 		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(a.menuBtn)
@@ -0,0 +1,367 @@
+package browsing
+
+import (
+	"log"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+	"github.com/dweymouth/supersonic/ui/controller"
+	"github.com/dweymouth/supersonic/ui/layouts"
+	"github.com/dweymouth/supersonic/ui/util"
+	"github.com/dweymouth/supersonic/ui/widgets"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+type BookmarksPage struct {
+	widget.BaseWidget
+
+	contr     *controller.Controller
+	bm        *backend.BookmarkManager
+	pm        *backend.PlaybackManager
+	bookmarks []*mediaprovider.Bookmark
+	list      *BookmarkList
+
+	nowPlayingID string
+
+	titleDisp      *widget.RichText
+	noBookmarksMsg fyne.CanvasObject
+	container      *fyne.Container
+	searcher       *widgets.SearchEntry
+}
+
+func NewBookmarksPage(contr *controller.Controller, bm *backend.BookmarkManager, pm *backend.PlaybackManager) *BookmarksPage {
+	return newBookmarksPage(contr, bm, pm, "", 0)
+}
+
+func newBookmarksPage(contr *controller.Controller, bm *backend.BookmarkManager, pm *backend.PlaybackManager, searchText string, scrollPos float32) *BookmarksPage {
+	a := &BookmarksPage{
+		contr:     contr,
+		bm:        bm,
+		pm:        pm,
+		titleDisp: widget.NewRichTextWithText(lang.L("Bookmarks")),
+	}
+	a.ExtendBaseWidget(a)
+	a.titleDisp.Segments[0].(*widget.TextSegment).Style.SizeName = theme.SizeNameHeadingText
+	a.list = NewBookmarkList(&a.nowPlayingID)
+	a.list.OnPlay = a.onPlay
+	a.list.OnDelete = a.onDelete
+	a.searcher = widgets.NewSearchEntry()
+	a.searcher.PlaceHolder = lang.L("Search page")
+	a.searcher.OnSearched = a.onSearched
+	a.searcher.Entry.Text = searchText
+
+	a.noBookmarksMsg = container.NewCenter(widgets.NewInfoMessage(
+		lang.L("No bookmarks"),
+		lang.L("The playback position of long tracks is bookmarked automatically"),
+	))
+	a.noBookmarksMsg.Hide()
+
+	a.buildContainer()
+	go a.load(searchText != "", scrollPos)
+	return a
+}
+
+// should be called asynchronously
+func (a *BookmarksPage) load(searchOnLoad bool, scrollPos float32) {
+	bookmarks, err := a.bm.GetBookmarks()
+	if err != nil {
+		log.Printf("error loading bookmarks: %v", err.Error())
+	}
+
+	fyne.Do(func() {
+		a.bookmarks = bookmarks
+		a.updateNoBookmarksMsg()
+		if searchOnLoad {
+			a.onSearched(a.searcher.Entry.Text)
+		} else {
+			a.list.SetBookmarks(a.bookmarks)
+		}
+		if scrollPos != 0 {
+			a.list.list.ScrollToOffset(scrollPos)
+		}
+	})
+}
+
+func (a *BookmarksPage) updateNoBookmarksMsg() {
+	if len(a.bookmarks) == 0 {
+		a.noBookmarksMsg.Show()
+	} else {
+		a.noBookmarksMsg.Hide()
+	}
+}
+
+func (a *BookmarksPage) onPlay(bookmark *mediaprovider.Bookmark) {
+	// playback resumes from the bookmarked position automatically
+	a.pm.LoadTracks([]*mediaprovider.Track{bookmark.Track}, backend.Replace, false)
+	a.pm.PlayTrackAt(0)
+}
+
+func (a *BookmarksPage) onDelete(bookmark *mediaprovider.Bookmark) {
+	a.bm.DeleteAsync(bookmark.Track.ID)
+	a.bookmarks = sharedutil.FilterSlice(a.bookmarks, func(b *mediaprovider.Bookmark) bool {
+		return b != bookmark
+	})
+	a.updateNoBookmarksMsg()
+	a.onSearched(a.searcher.Entry.Text)
+}
+
+func (a *BookmarksPage) onSearched(query string) {
+	// bookmarks are returned in full non-paginated, so do our own
+	// simple search based on the track title and artist
+	if query == "" {
+		a.list.SetBookmarks(a.bookmarks)
+		return
+	}
+	query = strings.ToLower(query)
+	result := sharedutil.FilterSlice(a.bookmarks, func(x *mediaprovider.Bookmark) bool {
+		return strings.Contains(strings.ToLower(x.Track.Title), query) ||
+			strings.Contains(strings.ToLower(strings.Join(x.Track.ArtistNames, ", ")), query)
+	})
+	a.list.SetBookmarks(result)
+	a.list.list.ScrollTo(0)
+}
+
+var _ Searchable = (*BookmarksPage)(nil)
+
+func (a *BookmarksPage) SearchWidget() fyne.Focusable {
+	return a.searcher
+}
+
+var _ Scrollable = (*BookmarksPage)(nil)
+
+func (a *BookmarksPage) Scroll(amount float32) {
+	a.list.list.ScrollToOffset(a.list.list.GetScrollOffset() + amount)
+}
+
+var _ CanShowNowPlaying = (*BookmarksPage)(nil)
+
+func (a *BookmarksPage) OnSongChange(playing mediaprovider.MediaItem, _ *mediaprovider.Track) {
+	if playing != nil {
+		a.nowPlayingID = playing.Metadata().ID
+	} else {
+		a.nowPlayingID = ""
+	}
+	a.list.Refresh()
+}
+
+func (a *BookmarksPage) Route() controller.Route {
+	return controller.BookmarksRoute()
+}
+
+func (a *BookmarksPage) Reload() {
+	go a.load(a.searcher.Entry.Text != "", 0)
+}
+
+func (a *BookmarksPage) Save() SavedPage {
+	return &savedBookmarksPage{
+		contr:      a.contr,
+		bm:         a.bm,
+		pm:         a.pm,
+		searchText: a.searcher.Entry.Text,
+		scrollPos:  a.list.list.GetScrollOffset(),
+	}
+}
+
+type savedBookmarksPage struct {
+	contr      *controller.Controller
+	bm         *backend.BookmarkManager
+	pm         *backend.PlaybackManager
+	searchText string
+	scrollPos  float32
+}
+
+func (s *savedBookmarksPage) Restore() Page {
+	return newBookmarksPage(s.contr, s.bm, s.pm, s.searchText, s.scrollPos)
+}
+
+func (a *BookmarksPage) buildContainer() {
+	searchVbox := container.NewVBox(layout.NewSpacer(), a.searcher, layout.NewSpacer())
+	a.container = container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, TopPadding: 5, BottomPadding: 15},
+		container.NewBorder(
+			container.New(&layout.CustomPaddedLayout{LeftPadding: -5},
+				container.NewHBox(a.titleDisp, layout.NewSpacer(), searchVbox)),
+			nil, nil, nil,
+			container.NewStack(a.noBookmarksMsg, a.list)),
+	)
+}
+
+func (a *BookmarksPage) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.container)
+}
+
+type BookmarkList struct {
+	widget.BaseWidget
+
+	OnPlay   func(*mediaprovider.Bookmark)
+	OnDelete func(*mediaprovider.Bookmark)
+
+	bookmarks []*mediaprovider.Bookmark
+	selected  *BookmarkListRow
+
+	columnsLayout *layouts.ColumnsLayout
+	hdr           *widgets.ListHeader
+	list          *widgets.FocusList
+	container     *fyne.Container
+	playingIcon   fyne.CanvasObject
+	menu          *widget.PopUpMenu
+}
+
+type BookmarkListRow struct {
+	widgets.FocusListRowBase
+
+	Item              *mediaprovider.Bookmark
+	IsPlaying         bool
+	OnTappedSecondary func(*fyne.PointEvent)
+
+	titleLabel    *widget.RichText
+	artistLabel   *widget.Label
+	positionLabel *widget.Label
+	changedLabel  *widget.Label
+}
+
+func NewBookmarkListRow(layout *layouts.ColumnsLayout) *BookmarkListRow {
+	a := &BookmarkListRow{
+		titleLabel:    widget.NewRichTextWithText(""),
+		artistLabel:   widget.NewLabel(""),
+		positionLabel: widget.NewLabel(""),
+		changedLabel:  widget.NewLabel(""),
+	}
+	a.ExtendBaseWidget(a)
+	a.titleLabel.Truncation = fyne.TextTruncateEllipsis
+	a.artistLabel.Truncation = fyne.TextTruncateEllipsis
+	a.positionLabel.Alignment = fyne.TextAlignTrailing
+	a.changedLabel.Alignment = fyne.TextAlignTrailing
+	a.Content = container.New(layout, a.titleLabel, a.artistLabel, a.positionLabel, a.changedLabel)
+	return a
+}
+
+func (a *BookmarkListRow) TappedSecondary(e *fyne.PointEvent) {
+	if a.OnTappedSecondary != nil {
+		a.OnTappedSecondary(e)
+	}
+}
+
+func NewBookmarkList(nowPlayingIDPtr *string) *BookmarkList {
+	a := &BookmarkList{
+		columnsLayout: layouts.NewColumnsLayout([]float32{-1, -1, 150, 160}),
+	}
+	playIcon := theme.NewThemedResource(theme.MediaPlayIcon())
+	playIcon.ColorName = theme.ColorNamePrimary
+	a.playingIcon = container.NewCenter(widget.NewIcon(playIcon))
+	a.ExtendBaseWidget(a)
+	a.hdr = widgets.NewListHeader([]widgets.ListColumn{
+		{Text: lang.L("Title"), Alignment: fyne.TextAlignLeading, CanToggleVisible: false},
+		{Text: lang.L("Artist"), Alignment: fyne.TextAlignLeading, CanToggleVisible: false},
+		{Text: lang.L("Position"), Alignment: fyne.TextAlignTrailing, CanToggleVisible: false},
+		{Text: lang.L("Saved"), Alignment: fyne.TextAlignTrailing, CanToggleVisible: false},
+	},
+		a.columnsLayout)
+	a.hdr.DisableSorting = true
+	a.list = widgets.NewFocusList(
+		func() int { return len(a.bookmarks) },
+		func() fyne.CanvasObject {
+			r := NewBookmarkListRow(a.columnsLayout)
+			r.OnTapped = func() {
+				r.Selected = true
+				if a.selected != nil {
+					// unselect old row
+					a.selected.Selected = false
+					a.selected.Refresh()
+				}
+				a.selected = r
+				r.Refresh()
+			}
+			r.OnDoubleTapped = func() { a.onPlayBookmark(r.Item) }
+			r.OnTappedSecondary = func(e *fyne.PointEvent) {
+				r.OnTapped() // handle selection
+				a.showMenu(e.AbsolutePosition)
+			}
+			r.OnFocusNeighbor = func(up bool) {
+				a.list.FocusNeighbor(r.ItemID(), up)
+			}
+			return r
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			row := item.(*BookmarkListRow)
+			changed := false
+			if row.Item != a.bookmarks[id] {
+				row.EnsureUnfocused()
+				row.ListItemID = id
+				row.Item = a.bookmarks[id]
+				tr := row.Item.Track
+				row.titleLabel.Segments[0].(*widget.TextSegment).Text = tr.Title
+				row.artistLabel.SetText(strings.Join(tr.ArtistNames, ", "))
+				row.positionLabel.SetText(util.SecondsToMMSS(row.Item.Position) +
+					" / " + util.SecondsToMMSS(tr.Duration.Seconds()))
+				row.changedLabel.SetText(row.Item.Changed.Local().Format("2006-01-02 15:04"))
+				changed = true
+			}
+			isPlaying := *nowPlayingIDPtr == row.Item.Track.ID
+			if row.IsPlaying != isPlaying {
+				row.IsPlaying = isPlaying
+				row.titleLabel.Segments[0].(*widget.TextSegment).Style.TextStyle.Bold = isPlaying
+				if isPlaying {
+					row.Content.(*fyne.Container).Objects[0] = container.NewBorder(nil, nil, a.playingIcon, nil,
+						container.New(layout.NewCustomPaddedLayout(0, 0, -5, 0), row.titleLabel))
+				} else {
+					row.Content.(*fyne.Container).Objects[0] = row.titleLabel
+				}
+				changed = true
+			}
+			if changed {
+				row.Refresh()
+			}
+		},
+	)
+	a.container = container.NewBorder(a.hdr, nil, nil, nil, a.list)
+	return a
+}
+
+func (a *BookmarkList) showMenu(pos fyne.Position) {
+	if a.menu == nil {
+		play := fyne.NewMenuItem(lang.L("Play"), func() {
+			if a.OnPlay != nil {
+				a.OnPlay(a.selected.Item)
+			}
+		})
+		play.Icon = theme.MediaPlayIcon()
+
+		del := fyne.NewMenuItem(lang.L("Delete bookmark"), func() {
+			if a.OnDelete != nil {
+				a.OnDelete(a.selected.Item)
+			}
+		})
+		del.Icon = theme.DeleteIcon()
+
+		a.menu = widget.NewPopUpMenu(fyne.NewMenu("",
+			play,
+			del,
+		),
+			fyne.CurrentApp().Driver().CanvasForObject(a),
+		)
+	}
+	a.menu.ShowAtPosition(pos)
+}
+
+func (a *BookmarkList) SetBookmarks(bookmarks []*mediaprovider.Bookmark) {
+	a.bookmarks = bookmarks
+	a.Refresh()
+}
+
+func (a *BookmarkList) onPlayBookmark(item *mediaprovider.Bookmark) {
+	if a.OnPlay != nil {
+		a.OnPlay(item)
+	}
+}
+
+func (a *BookmarkList) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.container)
+}
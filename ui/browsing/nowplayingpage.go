@@ -21,6 +21,7 @@ import (
 	"github.com/dweymouth/supersonic/ui/layouts"
 	myTheme "github.com/dweymouth/supersonic/ui/theme"
 	"github.com/dweymouth/supersonic/ui/util"
+	"github.com/dweymouth/supersonic/ui/visualizations"
 	"github.com/dweymouth/supersonic/ui/widgets"
 
 	"fyne.io/fyne/v2"
@@ -54,6 +55,9 @@ type NowPlayingPage struct {
 	backgroundImgA     *canvas.Image
 	backgroundImgB     *canvas.Image
 	backgroundGradient *canvas.LinearGradient
+	backgroundVis      visualizations.SpectrumDisplay
+	backgroundVisKind  string
+	backgroundVisLayer *fyne.Container
 	queueList          *widgets.PlayQueueList
 	relatedList        *widgets.PlayQueueList
 	lyricsViewer       *widgets.LyricsViewer
@@ -154,6 +158,7 @@ func NewNowPlayingPage(
 
 	a.relatedList.OnDownload = a.queueList.OnDownload
 	a.relatedList.OnShare = a.queueList.OnShare
+	a.relatedList.OnCopyLink = a.queueList.OnCopyLink
 	a.relatedList.OnAddToPlaylist = a.queueList.OnAddToPlaylist
 	a.relatedList.OnShowArtistPage = a.queueList.OnShowArtistPage
 	a.relatedList.OnSetRating = a.queueList.OnSetRating
@@ -242,6 +247,8 @@ func (a *NowPlayingPage) CreateRenderer() fyne.WidgetRenderer {
 		a.backgroundGradient = canvas.NewLinearGradient(c, c, 0)
 		a.backgroundImgA = canvas.NewImageFromImage(nil)
 		a.backgroundImgB = canvas.NewImageFromImage(nil)
+		a.backgroundVisLayer = container.NewStack()
+		a.updateBackgroundVisualization()
 
 		mainContent := container.NewGridWithColumns(2,
 			container.New(paddedLayout, a.card),
@@ -252,6 +259,7 @@ func (a *NowPlayingPage) CreateRenderer() fyne.WidgetRenderer {
 			a.backgroundImgA,
 			a.backgroundImgB,
 			a.backgroundGradient,
+			a.backgroundVisLayer,
 			mainContent,
 			container.NewVBox(
 				layout.NewSpacer(),
@@ -269,6 +277,7 @@ func (a *NowPlayingPage) Save() SavedPage {
 		a.imageLoadCancel()
 	}
 	a.alreadyLoaded = false
+	a.contr.SetBackgroundVisualization(nil)
 	nps := a.nowPlayingPageState
 	a.pool.Release(util.WidgetTypeNowPlayingPage, a)
 	return &nps
@@ -298,6 +307,7 @@ func (a *NowPlayingPage) OnSongChange(song mediaprovider.MediaItem, lastScrobble
 		a.imageLoadCancel()
 	}
 	a.nowPlayingID = sharedutil.MediaItemIDOrEmptyStr(song)
+	a.queueList.SetNowPlayingChapters(a.pm.NowPlayingChapters())
 	a.queueList.SetNowPlaying(a.nowPlayingID)
 	if !a.alreadyLoaded {
 		a.queueList.ScrollToNowPlaying()
@@ -383,7 +393,7 @@ func (a *NowPlayingPage) updateLyrics() {
 			return
 		}
 	}
-	if a.nowPlaying == nil || a.nowPlaying.Metadata().Type == mediaprovider.MediaItemTypeRadioStation {
+	if a.nowPlaying == nil || a.nowPlaying.Metadata().Type != mediaprovider.MediaItemTypeTrack {
 		a.lyricsViewer.SetLyrics(nil)
 		a.curLyrics = nil
 		a.curLyricsID = ""
@@ -472,6 +482,7 @@ func (a *NowPlayingPage) Reload() {
 	if a.tabs == nil {
 		return
 	}
+	a.updateBackgroundVisualization()
 	switch a.tabs.SelectedIndex() {
 	case 1: /*lyrics*/
 		a.lastPlayPos = a.pm.PlaybackStatus().TimePos
@@ -490,6 +501,7 @@ var _ CanShowPlayTime = (*NowPlayingPage)(nil)
 func (a *NowPlayingPage) OnPlayTimeUpdate(curTime, _ float64, seeked bool) {
 	a.lastPlayPos = curTime
 	a.formatStatusLine()
+	a.queueList.UpdatePlayPos(curTime)
 	if a.tabs == nil || a.tabs.SelectedIndex() != 1 /*lyrics*/ {
 		return
 	}
@@ -534,12 +546,29 @@ func (a *NowPlayingPage) Refresh() {
 			a.backgroundGradient.Refresh()
 		}
 	}
+	if a.backgroundVisLayer != nil {
+		a.updateBackgroundVisualization()
+	}
 	a.BaseWidget.Refresh()
 
 	a.card.ShowAlbumYear = a.cfg.AlbumsPage.ShowYears
 	a.card.Update(a.nowPlaying)
 }
 
+// creates the background visualization if the configured kind has changed,
+// and registers it with the controller to receive spectrum updates
+func (a *NowPlayingPage) updateBackgroundVisualization() {
+	if a.conf.BackgroundVisualization != a.backgroundVisKind {
+		a.backgroundVisKind = a.conf.BackgroundVisualization
+		a.backgroundVis = controller.NewBackgroundVisualization(a.backgroundVisKind)
+		a.backgroundVisLayer.RemoveAll()
+		if a.backgroundVis != nil {
+			a.backgroundVisLayer.Add(a.backgroundVis)
+		}
+	}
+	a.contr.SetBackgroundVisualization(a.backgroundVis)
+}
+
 func (a *NowPlayingPage) saveSelectedTab(tabNum int) {
 	var tabName string
 	switch tabNum {
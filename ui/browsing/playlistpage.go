@@ -142,10 +142,17 @@ var _ CanShowNowPlaying = (*PlaylistPage)(nil)
 
 func (a *PlaylistPage) OnSongChange(item mediaprovider.MediaItem, lastScrobbledIfAny *mediaprovider.Track) {
 	a.nowPlayingID = sharedutil.MediaItemIDOrEmptyStr(item)
+	a.tracklist.SetNowPlayingChapters(a.pm.NowPlayingChapters())
 	a.tracklist.SetNowPlaying(a.nowPlayingID)
 	a.tracklist.IncrementPlayCount(sharedutil.MediaItemIDOrEmptyStr(lastScrobbledIfAny))
 }
 
+var _ CanShowPlayTime = (*PlaylistPage)(nil)
+
+func (a *PlaylistPage) OnPlayTimeUpdate(curTime, _ float64, _ bool) {
+	a.tracklist.UpdatePlayPos(curTime)
+}
+
 func (a *PlaylistPage) Reload() {
 	a.tracklist.SetLoading(true)
 	go a.load()
@@ -232,6 +239,7 @@ func (a *PlaylistPage) doSetNewTrackOrder(ids []string, newPos int) {
 				)
 			})
 		} else {
+			a.contr.App.OnPlaylistChanged(backend.PlaylistActionReorder, a.playlistID, "", nil)
 			renumberTracks(newTracks)
 			fyne.Do(func() {
 				// force-switch back to unsorted view to show new track order
@@ -257,6 +265,7 @@ func (a *PlaylistPage) onRemoveSelectedFromPlaylist() {
 				)
 			})
 		} else {
+			a.contr.App.OnPlaylistChanged(backend.PlaylistActionRemoveTracks, a.playlistID, "", nil)
 			fyne.Do(func() {
 				a.tracklist.UnselectAll()
 				a.Reload()
@@ -430,7 +439,11 @@ func NewPlaylistPageHeader(page *PlaylistPage) *PlaylistPageHeader {
 				a.page.contr.ShowDownloadDialog(a.page.tracks, a.titleLabel.String())
 			})
 			download.Icon = theme.DownloadIcon()
-			menu := fyne.NewMenu("", playNext, queue, playlist, download)
+			copyLink := fyne.NewMenuItem(lang.L("Copy link"), func() {
+				a.page.contr.CopyLink(backend.URIKindPlaylist, a.page.playlistID)
+			})
+			copyLink.Icon = theme.ContentCopyIcon()
+			menu := fyne.NewMenu("", playNext, queue, playlist, download, copyLink)
 			pop = widget.NewPopUpMenu(menu, fyne.CurrentApp().Driver().CanvasForObject(a))
 		}
 		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(menuBtn)
@@ -166,6 +166,9 @@ func (a *PlaylistsPage) createGridView(playlists []*mediaprovider.Playlist) {
 	}
 	a.gridView.OnShowItemPage = a.showPlaylistPage
 	a.gridView.OnShowSecondaryPage = nil
+	a.gridView.OnCopyLink = func(id string) {
+		a.contr.CopyLink(backend.URIKindPlaylist, id)
+	}
 	a.gridView.OnAddToPlaylist = func(id string) {
 		go func() {
 			pl, err := a.contr.App.ServerManager.Server.GetPlaylist(id)
@@ -0,0 +1,465 @@
+package browsing
+
+import (
+	"log"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+	"github.com/dweymouth/supersonic/ui/controller"
+	"github.com/dweymouth/supersonic/ui/layouts"
+	myTheme "github.com/dweymouth/supersonic/ui/theme"
+	"github.com/dweymouth/supersonic/ui/util"
+	"github.com/dweymouth/supersonic/ui/widgets"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+type PodcastChannelPage struct {
+	widget.BaseWidget
+
+	channelID string
+	contr     *controller.Controller
+	podcasts  *backend.PodcastManager
+	pm        *backend.PlaybackManager
+	episodes  []*mediaprovider.PodcastEpisode
+	list      *PodcastEpisodeList
+
+	nowPlayingID string
+
+	titleDisp       *widget.RichText
+	descriptionDisp *widget.Label
+	noEpisodesMsg   fyne.CanvasObject
+	container       *fyne.Container
+	searcher        *widgets.SearchEntry
+}
+
+func NewPodcastChannelPage(channelID string, contr *controller.Controller, podcasts *backend.PodcastManager, pm *backend.PlaybackManager) *PodcastChannelPage {
+	return newPodcastChannelPage(channelID, contr, podcasts, pm, "", 0)
+}
+
+func newPodcastChannelPage(channelID string, contr *controller.Controller, podcasts *backend.PodcastManager, pm *backend.PlaybackManager, searchText string, scrollPos float32) *PodcastChannelPage {
+	a := &PodcastChannelPage{
+		channelID:       channelID,
+		contr:           contr,
+		podcasts:        podcasts,
+		pm:              pm,
+		titleDisp:       widget.NewRichTextWithText(""),
+		descriptionDisp: widget.NewLabel(""),
+	}
+	a.ExtendBaseWidget(a)
+	a.titleDisp.Segments[0].(*widget.TextSegment).Style.SizeName = theme.SizeNameHeadingText
+	a.titleDisp.Truncation = fyne.TextTruncateEllipsis
+	a.descriptionDisp.Truncation = fyne.TextTruncateEllipsis
+	if np := pm.NowPlaying(); np != nil {
+		a.nowPlayingID = np.Metadata().ID
+	}
+	a.list = NewPodcastEpisodeList(&a.nowPlayingID)
+	a.list.OnPlay = a.onPlay
+	a.list.OnQueue = a.onQueue
+	a.list.OnDownload = contr.DoDownloadPodcastEpisode
+	a.list.OnSetPlayed = a.onSetPlayed
+	a.searcher = widgets.NewSearchEntry()
+	a.searcher.PlaceHolder = lang.L("Search page")
+	a.searcher.OnSearched = a.onSearched
+	a.searcher.Entry.Text = searchText
+
+	a.noEpisodesMsg = container.NewCenter(widgets.NewInfoMessage(
+		lang.L("No episodes available"),
+		lang.L("The server may still be fetching the podcast feed"),
+	))
+	a.noEpisodesMsg.Hide()
+
+	a.buildContainer()
+	go a.load(searchText != "", scrollPos)
+	return a
+}
+
+// should be called asynchronously
+func (a *PodcastChannelPage) load(searchOnLoad bool, scrollPos float32) {
+	channel, err := a.podcasts.GetChannel(a.channelID)
+	if err != nil {
+		log.Printf("error loading podcast: %v", err.Error())
+		fyne.Do(func() { a.contr.ToastProvider.ShowErrorToast(lang.L("An error occurred")) })
+		return
+	}
+	if channel == nil {
+		return
+	}
+
+	fyne.Do(func() {
+		a.titleDisp.Segments[0].(*widget.TextSegment).Text = channel.Title
+		a.titleDisp.Refresh()
+		a.descriptionDisp.SetText(strings.Join(strings.Fields(channel.Description), " "))
+		a.episodes = channel.Episodes
+		if len(a.episodes) == 0 {
+			a.noEpisodesMsg.Show()
+		} else {
+			a.noEpisodesMsg.Hide()
+		}
+		if searchOnLoad {
+			a.onSearched(a.searcher.Entry.Text)
+		} else {
+			a.list.SetEpisodes(a.episodes)
+		}
+		if scrollPos != 0 {
+			a.list.list.ScrollToOffset(scrollPos)
+		}
+	})
+}
+
+func (a *PodcastChannelPage) onPlay(episode *mediaprovider.PodcastEpisode) {
+	if !episode.IsDownloaded() {
+		a.contr.ToastProvider.ShowErrorToast(lang.L("Episode has not been downloaded by the server"))
+		return
+	}
+	a.pm.LoadItems([]mediaprovider.MediaItem{episode}, backend.Replace, false)
+	a.pm.PlayFromBeginning()
+}
+
+func (a *PodcastChannelPage) onQueue(episode *mediaprovider.PodcastEpisode, next bool) {
+	if !episode.IsDownloaded() {
+		a.contr.ToastProvider.ShowErrorToast(lang.L("Episode has not been downloaded by the server"))
+		return
+	}
+	queueMode := backend.Append
+	if next {
+		queueMode = backend.InsertNext
+	}
+	a.pm.LoadItems([]mediaprovider.MediaItem{episode}, queueMode, false)
+}
+
+func (a *PodcastChannelPage) onSetPlayed(episode *mediaprovider.PodcastEpisode, played bool) {
+	episode.Played = played
+	a.list.Refresh()
+	go a.podcasts.SetPlayed(episode.ID, played)
+}
+
+func (a *PodcastChannelPage) onSearched(query string) {
+	// since the episodes list is returned in full non-paginated, we will do our own
+	// simple search based on the episode title, rather than calling a server API
+	if query == "" {
+		a.list.SetEpisodes(a.episodes)
+		return
+	}
+	query = strings.ToLower(query)
+	result := sharedutil.FilterSlice(a.episodes, func(x *mediaprovider.PodcastEpisode) bool {
+		return strings.Contains(strings.ToLower(x.Title), query)
+	})
+	a.list.SetEpisodes(result)
+	a.list.list.ScrollTo(0)
+}
+
+var _ Searchable = (*PodcastChannelPage)(nil)
+
+func (a *PodcastChannelPage) SearchWidget() fyne.Focusable {
+	return a.searcher
+}
+
+var _ Scrollable = (*PodcastChannelPage)(nil)
+
+func (a *PodcastChannelPage) Scroll(amount float32) {
+	a.list.list.ScrollToOffset(a.list.list.GetScrollOffset() + amount)
+}
+
+var _ CanShowNowPlaying = (*PodcastChannelPage)(nil)
+
+func (a *PodcastChannelPage) OnSongChange(playing mediaprovider.MediaItem, _ *mediaprovider.Track) {
+	if playing != nil {
+		a.nowPlayingID = playing.Metadata().ID
+	} else {
+		a.nowPlayingID = ""
+	}
+	// the previous episode may have been marked played
+	for _, ep := range a.episodes {
+		ep.Played = a.podcasts.IsPlayed(ep.ID)
+	}
+	a.list.Refresh()
+}
+
+func (a *PodcastChannelPage) Route() controller.Route {
+	return controller.PodcastChannelRoute(a.channelID)
+}
+
+func (a *PodcastChannelPage) Reload() {
+	go a.load(a.searcher.Entry.Text != "", 0)
+}
+
+func (a *PodcastChannelPage) Save() SavedPage {
+	return &savedPodcastChannelPage{
+		channelID:  a.channelID,
+		contr:      a.contr,
+		podcasts:   a.podcasts,
+		pm:         a.pm,
+		searchText: a.searcher.Entry.Text,
+		scrollPos:  a.list.list.GetScrollOffset(),
+	}
+}
+
+type savedPodcastChannelPage struct {
+	channelID  string
+	contr      *controller.Controller
+	podcasts   *backend.PodcastManager
+	pm         *backend.PlaybackManager
+	searchText string
+	scrollPos  float32
+}
+
+func (s *savedPodcastChannelPage) Restore() Page {
+	return newPodcastChannelPage(s.channelID, s.contr, s.podcasts, s.pm, s.searchText, s.scrollPos)
+}
+
+func (a *PodcastChannelPage) buildContainer() {
+	searchVbox := container.NewVBox(layout.NewSpacer(), a.searcher, layout.NewSpacer())
+	a.container = container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, TopPadding: 5, BottomPadding: 15},
+		container.NewBorder(
+			container.NewVBox(
+				container.New(&layout.CustomPaddedLayout{LeftPadding: -5},
+					container.NewBorder(nil, nil, nil, searchVbox, a.titleDisp)),
+				a.descriptionDisp),
+			nil, nil, nil,
+			container.NewStack(a.noEpisodesMsg, a.list)),
+	)
+}
+
+func (a *PodcastChannelPage) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.container)
+}
+
+type PodcastEpisodeList struct {
+	widget.BaseWidget
+
+	OnPlay      func(*mediaprovider.PodcastEpisode)
+	OnQueue     func(e *mediaprovider.PodcastEpisode, next bool)
+	OnDownload  func(*mediaprovider.PodcastEpisode)
+	OnSetPlayed func(e *mediaprovider.PodcastEpisode, played bool)
+
+	episodes []*mediaprovider.PodcastEpisode
+	selected *PodcastEpisodeListRow
+
+	columnsLayout *layouts.ColumnsLayout
+	hdr           *widgets.ListHeader
+	list          *widgets.FocusList
+	container     *fyne.Container
+	playingIcon   fyne.CanvasObject
+	menu          *fyne.Menu
+	menuPopUp     *widget.PopUpMenu
+	downloadItem  *fyne.MenuItem
+	playedItem    *fyne.MenuItem
+}
+
+type PodcastEpisodeListRow struct {
+	widgets.FocusListRowBase
+
+	Item              *mediaprovider.PodcastEpisode
+	IsPlaying         bool
+	Played            bool
+	OnTappedSecondary func(*fyne.PointEvent)
+
+	playedIcon    *widget.Icon
+	titleLabel    *widget.RichText
+	dateLabel     *widget.Label
+	durationLabel *widget.Label
+	statusLabel   *widget.Label
+}
+
+func NewPodcastEpisodeListRow(layout *layouts.ColumnsLayout) *PodcastEpisodeListRow {
+	a := &PodcastEpisodeListRow{
+		playedIcon:    widget.NewIcon(nil),
+		titleLabel:    widget.NewRichTextWithText(""),
+		dateLabel:     widget.NewLabel(""),
+		durationLabel: widget.NewLabel(""),
+		statusLabel:   widget.NewLabel(""),
+	}
+	a.ExtendBaseWidget(a)
+	a.titleLabel.Truncation = fyne.TextTruncateEllipsis
+	a.durationLabel.Alignment = fyne.TextAlignTrailing
+	a.statusLabel.Truncation = fyne.TextTruncateEllipsis
+	a.Content = container.New(layout, a.titleLabel, a.dateLabel, a.durationLabel, a.statusLabel, container.NewCenter(a.playedIcon))
+	return a
+}
+
+func (a *PodcastEpisodeListRow) TappedSecondary(e *fyne.PointEvent) {
+	if a.OnTappedSecondary != nil {
+		a.OnTappedSecondary(e)
+	}
+}
+
+func NewPodcastEpisodeList(nowPlayingIDPtr *string) *PodcastEpisodeList {
+	a := &PodcastEpisodeList{
+		columnsLayout: layouts.NewColumnsLayout([]float32{-1, 120, 80, 140, 60}),
+	}
+	playIcon := theme.NewThemedResource(theme.MediaPlayIcon())
+	playIcon.ColorName = theme.ColorNamePrimary
+	a.playingIcon = container.NewCenter(widget.NewIcon(playIcon))
+	a.ExtendBaseWidget(a)
+	a.hdr = widgets.NewListHeader([]widgets.ListColumn{
+		{Text: lang.L("Title"), Alignment: fyne.TextAlignLeading, CanToggleVisible: false},
+		{Text: lang.L("Published"), Alignment: fyne.TextAlignLeading, CanToggleVisible: false},
+		{Text: lang.L("Time"), Alignment: fyne.TextAlignTrailing, CanToggleVisible: false},
+		{Text: lang.L("Status"), Alignment: fyne.TextAlignLeading, CanToggleVisible: false},
+		{Text: lang.L("Played"), Alignment: fyne.TextAlignCenter, CanToggleVisible: false},
+	},
+		a.columnsLayout)
+	a.hdr.DisableSorting = true
+	a.list = widgets.NewFocusList(
+		func() int { return len(a.episodes) },
+		func() fyne.CanvasObject {
+			r := NewPodcastEpisodeListRow(a.columnsLayout)
+			r.OnTapped = func() {
+				r.Selected = true
+				if a.selected != nil {
+					// unselect old row
+					a.selected.Selected = false
+					a.selected.Refresh()
+				}
+				a.selected = r
+				r.Refresh()
+			}
+			r.OnDoubleTapped = func() { a.onPlayEpisode(r.Item) }
+			r.OnTappedSecondary = func(e *fyne.PointEvent) {
+				r.OnTapped() // handle selection
+				a.showMenu(e.AbsolutePosition)
+			}
+			r.OnFocusNeighbor = func(up bool) {
+				a.list.FocusNeighbor(r.ItemID(), up)
+			}
+			return r
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			row := item.(*PodcastEpisodeListRow)
+			changed := false
+			if row.Item != a.episodes[id] {
+				row.EnsureUnfocused()
+				row.ListItemID = id
+				row.Item = a.episodes[id]
+				row.titleLabel.Segments[0].(*widget.TextSegment).Text = row.Item.Title
+				date := ""
+				if !row.Item.PublishDate.IsZero() {
+					date = row.Item.PublishDate.Local().Format("2006-01-02")
+				}
+				row.dateLabel.SetText(date)
+				row.durationLabel.SetText(util.SecondsToMMSS(row.Item.Duration.Seconds()))
+				row.statusLabel.SetText(episodeStatusText(row.Item))
+				row.Played = !row.Item.Played // force update below
+				changed = true
+			}
+			if row.Played != row.Item.Played {
+				row.Played = row.Item.Played
+				if row.Played {
+					row.playedIcon.SetResource(theme.ConfirmIcon())
+				} else {
+					row.playedIcon.SetResource(nil)
+				}
+				changed = true
+			}
+			isPlaying := *nowPlayingIDPtr == row.Item.ID
+			if row.IsPlaying != isPlaying {
+				row.IsPlaying = isPlaying
+				row.titleLabel.Segments[0].(*widget.TextSegment).Style.TextStyle.Bold = isPlaying
+				if isPlaying {
+					row.Content.(*fyne.Container).Objects[0] = container.NewBorder(nil, nil, a.playingIcon, nil,
+						container.New(layout.NewCustomPaddedLayout(0, 0, -5, 0), row.titleLabel))
+				} else {
+					row.Content.(*fyne.Container).Objects[0] = row.titleLabel
+				}
+				changed = true
+			}
+			if changed {
+				row.Refresh()
+			}
+		},
+	)
+	a.container = container.NewBorder(a.hdr, nil, nil, nil, a.list)
+	return a
+}
+
+func episodeStatusText(e *mediaprovider.PodcastEpisode) string {
+	switch e.Status {
+	case mediaprovider.PodcastEpisodeStatusCompleted:
+		return lang.L("Downloaded")
+	case mediaprovider.PodcastEpisodeStatusDownloading:
+		return lang.L("Downloading")
+	case mediaprovider.PodcastEpisodeStatusError:
+		return lang.L("Download failed")
+	default:
+		return lang.L("Not downloaded")
+	}
+}
+
+func (a *PodcastEpisodeList) showMenu(pos fyne.Position) {
+	if a.menu == nil {
+		play := fyne.NewMenuItem(lang.L("Play"), func() {
+			a.onPlayEpisode(a.selected.Item)
+		})
+		play.Icon = theme.MediaPlayIcon()
+
+		playNext := fyne.NewMenuItem(lang.L("Play next"), func() {
+			if a.OnQueue != nil {
+				a.OnQueue(a.selected.Item, true)
+			}
+		})
+		playNext.Icon = myTheme.PlayNextIcon
+
+		append := fyne.NewMenuItem(lang.L("Add to queue"), func() {
+			if a.OnQueue != nil {
+				a.OnQueue(a.selected.Item, false)
+			}
+		})
+		append.Icon = theme.ContentAddIcon()
+
+		a.downloadItem = fyne.NewMenuItem(lang.L("Download on server"), func() {
+			if a.OnDownload != nil {
+				a.OnDownload(a.selected.Item)
+			}
+		})
+		a.downloadItem.Icon = theme.DownloadIcon()
+
+		a.playedItem = fyne.NewMenuItem("", func() {
+			if a.OnSetPlayed != nil {
+				a.OnSetPlayed(a.selected.Item, !a.selected.Item.Played)
+			}
+		})
+		a.playedItem.Icon = theme.ConfirmIcon()
+
+		a.menu = fyne.NewMenu("",
+			play,
+			playNext,
+			append,
+			fyne.NewMenuItemSeparator(),
+			a.downloadItem,
+			a.playedItem,
+		)
+		a.menuPopUp = widget.NewPopUpMenu(a.menu, fyne.CurrentApp().Driver().CanvasForObject(a))
+	}
+	ep := a.selected.Item
+	a.downloadItem.Disabled = ep.IsDownloaded() || ep.Status == mediaprovider.PodcastEpisodeStatusDownloading
+	if ep.Played {
+		a.playedItem.Label = lang.L("Mark as unplayed")
+	} else {
+		a.playedItem.Label = lang.L("Mark as played")
+	}
+	a.menu.Refresh()
+	a.menuPopUp.ShowAtPosition(pos)
+}
+
+func (a *PodcastEpisodeList) SetEpisodes(episodes []*mediaprovider.PodcastEpisode) {
+	a.episodes = episodes
+	a.Refresh()
+}
+
+func (a *PodcastEpisodeList) onPlayEpisode(item *mediaprovider.PodcastEpisode) {
+	if a.OnPlay != nil {
+		a.OnPlay(item)
+	}
+}
+
+func (a *PodcastEpisodeList) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.container)
+}
@@ -0,0 +1,353 @@
+package browsing
+
+import (
+	"log"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+	"github.com/dweymouth/supersonic/ui/controller"
+	"github.com/dweymouth/supersonic/ui/layouts"
+	"github.com/dweymouth/supersonic/ui/widgets"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+)
+
+type PodcastsPage struct {
+	widget.BaseWidget
+
+	contr    *controller.Controller
+	podcasts *backend.PodcastManager
+	channels []*mediaprovider.PodcastChannel
+	list     *PodcastChannelList
+
+	titleDisp     *widget.RichText
+	addBtn        *ttwidget.Button
+	refreshBtn    *ttwidget.Button
+	noPodcastsMsg fyne.CanvasObject
+	container     *fyne.Container
+	searcher      *widgets.SearchEntry
+}
+
+func NewPodcastsPage(contr *controller.Controller, podcasts *backend.PodcastManager) *PodcastsPage {
+	return newPodcastsPage(contr, podcasts, "", 0)
+}
+
+func newPodcastsPage(contr *controller.Controller, podcasts *backend.PodcastManager, searchText string, scrollPos float32) *PodcastsPage {
+	a := &PodcastsPage{
+		contr:     contr,
+		podcasts:  podcasts,
+		titleDisp: widget.NewRichTextWithText(lang.L("Podcasts")),
+	}
+	a.ExtendBaseWidget(a)
+	a.titleDisp.Segments[0].(*widget.TextSegment).Style.SizeName = theme.SizeNameHeadingText
+	a.list = NewPodcastChannelList()
+	a.list.OnOpen = a.onOpen
+	a.list.OnDelete = a.onDelete
+	a.searcher = widgets.NewSearchEntry()
+	a.searcher.PlaceHolder = lang.L("Search page")
+	a.searcher.OnSearched = a.onSearched
+	a.searcher.Entry.Text = searchText
+
+	a.addBtn = ttwidget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+		a.contr.DoAddPodcastWorkflow(a.Reload)
+	})
+	a.addBtn.SetToolTip(lang.L("Add podcast"))
+	a.refreshBtn = ttwidget.NewButtonWithIcon("", theme.DownloadIcon(), a.onCheckForNewEpisodes)
+	a.refreshBtn.SetToolTip(lang.L("Check for new episodes"))
+
+	a.noPodcastsMsg = container.NewCenter(widgets.NewInfoMessage(
+		lang.L("No podcasts available"),
+		lang.L("Add a podcast by its RSS feed URL"),
+	))
+	a.noPodcastsMsg.Hide()
+
+	a.buildContainer()
+	go a.load(searchText != "", scrollPos)
+	return a
+}
+
+// should be called asynchronously
+func (a *PodcastsPage) load(searchOnLoad bool, scrollPos float32) {
+	channels, err := a.podcasts.GetChannels()
+	if err != nil {
+		log.Printf("error loading podcasts: %v", err.Error())
+	}
+
+	fyne.Do(func() {
+		a.channels = channels
+		a.updateNoPodcastsMsg()
+		if searchOnLoad {
+			a.onSearched(a.searcher.Entry.Text)
+		} else {
+			a.list.SetChannels(a.channels)
+		}
+		if scrollPos != 0 {
+			a.list.list.ScrollToOffset(scrollPos)
+		}
+	})
+}
+
+func (a *PodcastsPage) updateNoPodcastsMsg() {
+	if len(a.channels) == 0 {
+		a.noPodcastsMsg.Show()
+	} else {
+		a.noPodcastsMsg.Hide()
+	}
+}
+
+func (a *PodcastsPage) onOpen(channel *mediaprovider.PodcastChannel) {
+	a.contr.NavigateTo(controller.PodcastChannelRoute(channel.ID))
+}
+
+func (a *PodcastsPage) onDelete(channel *mediaprovider.PodcastChannel) {
+	a.contr.DoDeletePodcastChannelWorkflow(channel, func() {
+		a.channels = sharedutil.FilterSlice(a.channels, func(c *mediaprovider.PodcastChannel) bool {
+			return c != channel
+		})
+		a.updateNoPodcastsMsg()
+		a.onSearched(a.searcher.Entry.Text)
+	})
+}
+
+func (a *PodcastsPage) onCheckForNewEpisodes() {
+	pp := a.podcasts.Provider()
+	if pp == nil {
+		return
+	}
+	a.refreshBtn.Disable()
+	go func() {
+		err := pp.RefreshPodcasts()
+		if err != nil {
+			log.Printf("error refreshing podcasts: %v", err.Error())
+		}
+		fyne.Do(func() {
+			a.refreshBtn.Enable()
+			if err != nil {
+				a.contr.ToastProvider.ShowErrorToast(lang.L("An error occurred"))
+			} else {
+				a.contr.ToastProvider.ShowSuccessToast(lang.L("Checking for new episodes on server"))
+			}
+		})
+	}()
+}
+
+func (a *PodcastsPage) onSearched(query string) {
+	// since the channels list is returned in full non-paginated, we will do our own
+	// simple search based on the channel title, rather than calling a server API
+	if query == "" {
+		a.list.SetChannels(a.channels)
+		return
+	}
+	query = strings.ToLower(query)
+	result := sharedutil.FilterSlice(a.channels, func(x *mediaprovider.PodcastChannel) bool {
+		return strings.Contains(strings.ToLower(x.Title), query)
+	})
+	a.list.SetChannels(result)
+	a.list.list.ScrollTo(0)
+}
+
+var _ Searchable = (*PodcastsPage)(nil)
+
+func (a *PodcastsPage) SearchWidget() fyne.Focusable {
+	return a.searcher
+}
+
+var _ Scrollable = (*PodcastsPage)(nil)
+
+func (a *PodcastsPage) Scroll(amount float32) {
+	a.list.list.ScrollToOffset(a.list.list.GetScrollOffset() + amount)
+}
+
+func (a *PodcastsPage) Route() controller.Route {
+	return controller.PodcastsRoute()
+}
+
+func (a *PodcastsPage) Reload() {
+	go a.load(a.searcher.Entry.Text != "", 0)
+}
+
+func (a *PodcastsPage) Save() SavedPage {
+	return &savedPodcastsPage{
+		contr:      a.contr,
+		podcasts:   a.podcasts,
+		searchText: a.searcher.Entry.Text,
+		scrollPos:  a.list.list.GetScrollOffset(),
+	}
+}
+
+type savedPodcastsPage struct {
+	contr      *controller.Controller
+	podcasts   *backend.PodcastManager
+	searchText string
+	scrollPos  float32
+}
+
+func (s *savedPodcastsPage) Restore() Page {
+	return newPodcastsPage(s.contr, s.podcasts, s.searchText, s.scrollPos)
+}
+
+func (a *PodcastsPage) buildContainer() {
+	searchVbox := container.NewVBox(layout.NewSpacer(), a.searcher, layout.NewSpacer())
+	btnVbox := container.NewVBox(layout.NewSpacer(), container.NewHBox(a.addBtn, a.refreshBtn), layout.NewSpacer())
+	a.container = container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, TopPadding: 5, BottomPadding: 15},
+		container.NewBorder(
+			container.New(&layout.CustomPaddedLayout{LeftPadding: -5},
+				container.NewHBox(a.titleDisp, btnVbox, layout.NewSpacer(), searchVbox)),
+			nil, nil, nil,
+			container.NewStack(a.noPodcastsMsg, a.list)),
+	)
+}
+
+func (a *PodcastsPage) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.container)
+}
+
+type PodcastChannelList struct {
+	widget.BaseWidget
+
+	OnOpen   func(*mediaprovider.PodcastChannel)
+	OnDelete func(*mediaprovider.PodcastChannel)
+
+	channels []*mediaprovider.PodcastChannel
+	selected *PodcastChannelListRow
+
+	columnsLayout *layouts.ColumnsLayout
+	hdr           *widgets.ListHeader
+	list          *widgets.FocusList
+	container     *fyne.Container
+	menu          *widget.PopUpMenu
+}
+
+type PodcastChannelListRow struct {
+	widgets.FocusListRowBase
+
+	Item              *mediaprovider.PodcastChannel
+	OnTappedSecondary func(*fyne.PointEvent)
+
+	titleLabel       *widget.Label
+	descriptionLabel *widget.Label
+}
+
+func NewPodcastChannelListRow(layout *layouts.ColumnsLayout) *PodcastChannelListRow {
+	a := &PodcastChannelListRow{
+		titleLabel:       widget.NewLabel(""),
+		descriptionLabel: widget.NewLabel(""),
+	}
+	a.ExtendBaseWidget(a)
+	a.titleLabel.Truncation = fyne.TextTruncateEllipsis
+	a.descriptionLabel.Truncation = fyne.TextTruncateEllipsis
+	a.Content = container.New(layout, a.titleLabel, a.descriptionLabel)
+	return a
+}
+
+func (a *PodcastChannelListRow) TappedSecondary(e *fyne.PointEvent) {
+	if a.OnTappedSecondary != nil {
+		a.OnTappedSecondary(e)
+	}
+}
+
+func NewPodcastChannelList() *PodcastChannelList {
+	a := &PodcastChannelList{
+		columnsLayout: layouts.NewColumnsLayout([]float32{-1, -1}),
+	}
+	a.ExtendBaseWidget(a)
+	a.hdr = widgets.NewListHeader([]widgets.ListColumn{
+		{Text: lang.L("Title"), Alignment: fyne.TextAlignLeading, CanToggleVisible: false},
+		{Text: lang.L("Description"), Alignment: fyne.TextAlignLeading, CanToggleVisible: false},
+	},
+		a.columnsLayout)
+	a.hdr.DisableSorting = true
+	a.list = widgets.NewFocusList(
+		func() int { return len(a.channels) },
+		func() fyne.CanvasObject {
+			r := NewPodcastChannelListRow(a.columnsLayout)
+			r.OnTapped = func() {
+				r.Selected = true
+				if a.selected != nil {
+					// unselect old row
+					a.selected.Selected = false
+					a.selected.Refresh()
+				}
+				a.selected = r
+				r.Refresh()
+			}
+			r.OnDoubleTapped = func() { a.onOpenChannel(r.Item) }
+			r.OnTappedSecondary = func(e *fyne.PointEvent) {
+				r.OnTapped() // handle selection
+				a.showMenu(e.AbsolutePosition)
+			}
+			r.OnFocusNeighbor = func(up bool) {
+				a.list.FocusNeighbor(r.ItemID(), up)
+			}
+			return r
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			row := item.(*PodcastChannelListRow)
+			if row.Item != a.channels[id] {
+				row.EnsureUnfocused()
+				row.ListItemID = id
+				row.Item = a.channels[id]
+				title := row.Item.Title
+				if title == "" {
+					title = row.Item.URL
+				}
+				row.titleLabel.SetText(title)
+				desc := row.Item.Description
+				if row.Item.ErrorMessage != "" {
+					desc = row.Item.ErrorMessage
+				}
+				row.descriptionLabel.SetText(strings.Join(strings.Fields(desc), " "))
+				row.Refresh()
+			}
+		},
+	)
+	a.container = container.NewBorder(a.hdr, nil, nil, nil, a.list)
+	return a
+}
+
+func (a *PodcastChannelList) showMenu(pos fyne.Position) {
+	if a.menu == nil {
+		open := fyne.NewMenuItem(lang.L("Show episodes"), func() {
+			a.onOpenChannel(a.selected.Item)
+		})
+		open.Icon = theme.ListIcon()
+
+		del := fyne.NewMenuItem(lang.L("Delete"), func() {
+			if a.OnDelete != nil {
+				a.OnDelete(a.selected.Item)
+			}
+		})
+		del.Icon = theme.DeleteIcon()
+
+		a.menu = widget.NewPopUpMenu(fyne.NewMenu("",
+			open,
+			del,
+		),
+			fyne.CurrentApp().Driver().CanvasForObject(a),
+		)
+	}
+	a.menu.ShowAtPosition(pos)
+}
+
+func (a *PodcastChannelList) SetChannels(channels []*mediaprovider.PodcastChannel) {
+	a.channels = channels
+	a.Refresh()
+}
+
+func (a *PodcastChannelList) onOpenChannel(item *mediaprovider.PodcastChannel) {
+	if a.OnOpen != nil {
+		a.OnOpen(item)
+	}
+}
+
+func (a *PodcastChannelList) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(a.container)
+}
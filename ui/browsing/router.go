@@ -61,6 +61,12 @@ func (r Router) CreatePage(rte controller.Route) Page {
 		var rp mediaprovider.RadioProvider
 		rp, _ = r.App.ServerManager.Server.(mediaprovider.RadioProvider)
 		return NewRadiosPage(r.Controller, rp, r.App.PlaybackManager)
+	case controller.Bookmarks:
+		return NewBookmarksPage(r.Controller, r.App.BookmarkManager, r.App.PlaybackManager)
+	case controller.Podcasts:
+		return NewPodcastsPage(r.Controller, r.App.PodcastManager)
+	case controller.PodcastChannel:
+		return NewPodcastChannelPage(rte.Arg, r.Controller, r.App.PodcastManager, r.App.PlaybackManager)
 	}
 	return nil
 }
@@ -36,6 +36,7 @@ func (m *Controller) connectTracklistActionsWithReplayGainMode(tracklist *widget
 			m.App.PlaybackManager.SetReplayGainMode(mode)
 		}
 	}
+	tracklist.OnSeekToChapter = m.App.PlaybackManager.SeekToChapter
 	tracklist.OnPlaySelection = func(tracks []*mediaprovider.Track, shuffle bool) {
 		m.App.PlaybackManager.LoadTracks(tracks, backend.Replace, shuffle)
 		if m.App.Config.ReplayGain.Mode == backend.ReplayGainAuto {
@@ -61,6 +62,9 @@ func (m *Controller) connectTracklistActionsWithReplayGainMode(tracklist *widget
 	tracklist.OnShare = func(trackID string) {
 		m.ShowShareDialog(trackID)
 	}
+	tracklist.OnCopyLink = func(trackID string) {
+		m.CopyLink(backend.URIKindTrack, trackID)
+	}
 	tracklist.OnShowTrackInfo = m.ShowTrackInfoDialog
 	tracklist.OnPlaySongRadio = func(track *mediaprovider.Track) {
 		go func() {
@@ -104,6 +108,9 @@ func (m *Controller) ConnectAlbumGridActions(grid *widgets.GridView) {
 	grid.OnShare = func(albumID string) {
 		m.ShowShareDialog(albumID)
 	}
+	grid.OnCopyLink = func(albumID string) {
+		m.CopyLink(backend.URIKindAlbum, albumID)
+	}
 }
 
 func (m *Controller) ConnectGroupedReleasesActions(grid *widgets.GroupedReleases) {
@@ -132,6 +139,9 @@ func (m *Controller) ConnectGroupedReleasesActions(grid *widgets.GroupedReleases
 	grid.OnShare = func(albumID string) {
 		m.ShowShareDialog(albumID)
 	}
+	grid.OnCopyLink = func(albumID string) {
+		m.CopyLink(backend.URIKindAlbum, albumID)
+	}
 }
 
 func (m *Controller) onAddAlbumToPlaylist(albumID string) {
@@ -194,6 +204,9 @@ func (m *Controller) ConnectArtistGridActions(grid *widgets.GridView) {
 	grid.OnShare = func(artistID string) {
 		m.ShowShareDialog(artistID)
 	}
+	grid.OnCopyLink = func(artistID string) {
+		m.CopyLink(backend.URIKindArtist, artistID)
+	}
 }
 
 func (c *Controller) ConnectPlayQueuelistActions(list *widgets.PlayQueueList) {
@@ -208,10 +221,14 @@ func (c *Controller) ConnectPlayQueuelistActions(list *widgets.PlayQueueList) {
 			c.ShowShareDialog(tracks[0].ID)
 		}
 	}
+	list.OnCopyLink = func(trackID string) {
+		c.CopyLink(backend.URIKindTrack, trackID)
+	}
 	list.OnAddToPlaylist = c.DoAddTracksToPlaylistWorkflow
 	list.OnPlayItemAt = func(tracknum int) {
 		c.App.PlaybackManager.PlayTrackAt(tracknum)
 	}
+	list.OnSeekToChapter = c.App.PlaybackManager.SeekToChapter
 	list.OnShowArtistPage = func(artistID string) {
 		c.NavigateTo(ArtistRoute(artistID))
 	}
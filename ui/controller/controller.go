@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	fynetooltip "github.com/dweymouth/fyne-tooltip"
@@ -83,6 +84,7 @@ func New(app *backend.App, appVersion string, mainWindow fyne.Window) *Controlle
 			if c.popUpQueue == nil {
 				return
 			}
+			c.popUpQueueList.SetNowPlayingChapters(c.App.PlaybackManager.NowPlayingChapters())
 			if track == nil {
 				c.popUpQueueList.SetNowPlaying("")
 			} else {
@@ -90,6 +92,13 @@ func New(app *backend.App, appVersion string, mainWindow fyne.Window) *Controlle
 			}
 		})
 	})
+	c.App.PlaybackManager.OnPlayTimeUpdate(func(curTime, _ float64, _ bool) {
+		fyne.Do(func() {
+			if c.popUpQueue != nil && c.popUpQueue.Visible() {
+				c.popUpQueueList.UpdatePlayPos(curTime)
+			}
+		})
+	})
 	return c
 }
 
@@ -165,7 +174,11 @@ func (m *Controller) ShowCastMenu(onPendingPlayerChange func()) {
 	for _, d := range devices {
 		_d := d
 		isCurrent := rp != nil && _d.URL == rp.URL
-		item := fyne.NewMenuItem(d.Name, func() {
+		name := d.Name
+		if d.IsJukebox() {
+			name = lang.L("Server jukebox")
+		}
+		item := fyne.NewMenuItem(name, func() {
 			if isCurrent {
 				return // no-op.
 			}
@@ -180,6 +193,22 @@ func (m *Controller) ShowCastMenu(onPendingPlayerChange func()) {
 		item.Checked = isCurrent
 		menu.Items = append(menu.Items, item)
 	}
+
+	groups := fyne.NewMenu("", fyne.NewMenuItem(lang.L("New group")+"...", func() {
+		m.DoEditPlayerGroupWorkflow(nil)
+	}))
+	for _, g := range m.App.PlaybackManager.PlayerGroups() {
+		_g := g
+		groups.Items = append(groups.Items, fyne.NewMenuItem(lang.L("Edit")+" "+g.Name+"...", func() {
+			m.DoEditPlayerGroupWorkflow(&_g)
+		}))
+	}
+	groupsItem := fyne.NewMenuItem(lang.L("Player groups"), nil)
+	groupsItem.ChildMenu = groups
+	mpdItem := fyne.NewMenuItem(lang.L("MPD servers"), nil)
+	mpdItem.ChildMenu = m.mpdServersMenu()
+	menu.Items = append(menu.Items, fyne.NewMenuItemSeparator(), groupsItem, mpdItem)
+
 	pop := widget.NewPopUpMenu(menu, m.MainWindow.Canvas())
 	canvasSize := m.MainWindow.Canvas().Size()
 	pop.ShowAtPosition(fyne.NewPos(
@@ -188,6 +217,55 @@ func (m *Controller) ShowCastMenu(onPendingPlayerChange func()) {
 	))
 }
 
+// DoEditPlayerGroupWorkflow shows a dialog to edit the given
+// player group, or to create a new one if group is nil.
+func (m *Controller) DoEditPlayerGroupWorkflow(group *backend.PlayerGroupConfig) {
+	devices := []dialogs.PlayerGroupDevice{{Name: lang.L("This computer"), URL: backend.LocalPlayerURL}}
+	for _, d := range m.App.PlaybackManager.RemotePlayers() {
+		if !d.IsPlayerGroup() && !d.IsJukebox() {
+			devices = append(devices, dialogs.PlayerGroupDevice{Name: d.Name, URL: d.URL})
+		}
+	}
+	oldName := ""
+	if group != nil {
+		oldName = group.Name
+		// keep members that are not currently discovered
+		for _, mem := range group.Members {
+			if !slices.ContainsFunc(devices, func(d dialogs.PlayerGroupDevice) bool { return d.URL == mem.DeviceURL }) {
+				devices = append(devices, dialogs.PlayerGroupDevice{Name: mem.DeviceURL, URL: mem.DeviceURL})
+			}
+		}
+	}
+
+	dlg := dialogs.NewEditPlayerGroupDialog(group, devices)
+	pop := widget.NewModalPopUp(dlg, m.MainWindow.Canvas())
+	m.ClosePopUpOnEscape(pop)
+	dlg.OnCanceled = func() {
+		pop.Hide()
+		m.doModalClosed()
+	}
+	dlg.OnDelete = func() {
+		pop.Hide()
+		m.doModalClosed()
+		m.App.PlaybackManager.DeletePlayerGroup(oldName)
+	}
+	dlg.OnSubmit = func() {
+		g := backend.PlayerGroupConfig{Name: dlg.Name, Members: dlg.Members()}
+		if g.Name == "" || len(g.Members) == 0 {
+			m.ToastProvider.ShowErrorToast(lang.L("A player group needs a name and at least one device"))
+			return
+		}
+		pop.Hide()
+		m.doModalClosed()
+		if err := m.App.PlaybackManager.SavePlayerGroup(oldName, g); err != nil {
+			log.Printf("error saving player group: %v", err)
+			m.ToastProvider.ShowErrorToast(lang.L("Failed to save player group"))
+		}
+	}
+	m.haveModal = true
+	pop.Show()
+}
+
 func (m *Controller) ShowPopUpPlayQueue() {
 	if m.popUpQueue == nil {
 		m.popUpQueueList = widgets.NewPlayQueueList(m.App.ImageManager, false)
@@ -248,7 +326,9 @@ func (m *Controller) ShowPopUpPlayQueue() {
 	if np := m.App.PlaybackManager.NowPlaying(); np != nil {
 		npID = np.Metadata().ID
 	}
+	popUpQueueList.SetNowPlayingChapters(m.App.PlaybackManager.NowPlayingChapters())
 	popUpQueueList.SetNowPlaying(npID)
+	popUpQueueList.UpdatePlayPos(m.App.PlaybackManager.PlaybackStatus().TimePos)
 	popUpQueueList.UnselectAll()
 
 	m.ClosePopUpOnEscape(pop)
@@ -327,6 +407,7 @@ func (c *Controller) ShowSettingsDialog(themeUpdateCallbk func(), themeFiles map
 		devs, themeFiles, bands,
 		c.App.ServerManager.Server.ClientDecidesScrobble(),
 		isLocalPlayer, isReplayGainPlayer, isEqualizerPlayer, canSavePlayQueue,
+		c.App.BookmarkManager.IsSupported(),
 		c.App.EQPresetManager,
 		c.MainWindow,
 		c.App.AutoEQManager,
@@ -375,6 +456,7 @@ func (c *Controller) ShowSettingsDialog(themeUpdateCallbk func(), themeFiles map
 	}
 	dlg.OnPageNeedsRefresh = c.RefreshPageFunc
 	dlg.OnClearCaches = func() { go c.App.ClearCaches() }
+	dlg.OnShowWebhookDeliveries = c.ShowWebhookDeliveriesDialog
 	pop := widget.NewModalPopUp(dlg, c.MainWindow.Canvas())
 	fynetooltip.AddPopUpToolTipLayer(pop)
 	dlg.OnDismiss = func() {
@@ -397,9 +479,17 @@ func (c *Controller) doModalClosed() {
 }
 
 func (c *Controller) SetTrackFavorites(trackIDs []string, favorite bool) {
-	go c.App.ServerManager.Server.SetFavorite(mediaprovider.RatingFavoriteParameters{
-		TrackIDs: trackIDs,
-	}, favorite)
+	server := c.App.ServerManager.Server
+	go func() {
+		err := server.SetFavorite(mediaprovider.RatingFavoriteParameters{
+			TrackIDs: trackIDs,
+		}, favorite)
+		if err != nil {
+			log.Printf("error setting favorite: %s", err.Error())
+			return
+		}
+		c.App.OnTrackFavoritesChanged(trackIDs, favorite)
+	}()
 
 	for _, id := range trackIDs {
 		c.App.PlaybackManager.OnTrackFavoriteStatusChanged(id, favorite)
@@ -411,9 +501,16 @@ func (c *Controller) SetTrackRatings(trackIDs []string, rating int) {
 	if !ok {
 		return
 	}
-	go r.SetRating(mediaprovider.RatingFavoriteParameters{
-		TrackIDs: trackIDs,
-	}, rating)
+	go func() {
+		err := r.SetRating(mediaprovider.RatingFavoriteParameters{
+			TrackIDs: trackIDs,
+		}, rating)
+		if err != nil {
+			log.Printf("error setting rating: %s", err.Error())
+			return
+		}
+		c.App.OnTrackRatingsChanged(trackIDs, rating)
+	}()
 
 	// Notify PlaybackManager of rating change to update
 	// the in-memory track models
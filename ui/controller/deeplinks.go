@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/lang"
+	"github.com/dweymouth/supersonic/backend"
+)
+
+// OpenURI navigates to the item linked by a supersonic:// URI,
+// and starts playing it if the link requests it.
+func (m *Controller) OpenURI(u backend.SupersonicURI) {
+	pm := m.App.PlaybackManager
+	switch u.Kind {
+	case backend.URIKindAlbum:
+		m.NavigateTo(AlbumRoute(u.ID))
+		if u.Play {
+			go pm.PlayAlbum(u.ID, 0, u.Shuffle)
+		}
+	case backend.URIKindArtist:
+		m.NavigateTo(ArtistRoute(u.ID))
+		if u.Play {
+			go pm.PlayArtistDiscography(u.ID, u.Shuffle)
+		}
+	case backend.URIKindPlaylist:
+		m.NavigateTo(PlaylistRoute(u.ID))
+		if u.Play {
+			go pm.PlayPlaylist(u.ID, 0, u.Shuffle)
+		}
+	case backend.URIKindTrack:
+		go func() {
+			tr, err := m.App.ServerManager.Server.GetTrack(u.ID)
+			if err != nil {
+				log.Printf("error loading track: %s", err.Error())
+				return
+			}
+			fyne.Do(func() { m.NavigateTo(AlbumRoute(tr.AlbumID)) })
+			if u.Play {
+				pm.PlayTrack(u.ID)
+			}
+		}()
+	case backend.URIKindSearch:
+		m.showQuickSearch(u.Query)
+	}
+}
+
+// CopyLink copies a supersonic:// link to the item to the clipboard.
+func (m *Controller) CopyLink(kind, id string) {
+	uri := backend.SupersonicURI{Kind: kind, ID: id}
+	m.MainWindow.Clipboard().SetContent(uri.String())
+	m.ToastProvider.ShowSuccessToast(lang.L("Link copied to clipboard"))
+}
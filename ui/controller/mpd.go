@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend"
+)
+
+// DoAddMPDServerWorkflow prompts for the name and address of
+// an MPD server to add as a remote player in the cast menu.
+func (m *Controller) DoAddMPDServerWorkflow() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder(lang.L("Living room"))
+	addrEntry := widget.NewEntry()
+	addrEntry.SetPlaceHolder("localhost:6600")
+	passEntry := widget.NewPasswordEntry()
+	dlg := dialog.NewForm(lang.L("Add MPD server"), lang.L("OK"), lang.L("Cancel"),
+		[]*widget.FormItem{
+			widget.NewFormItem(lang.L("Name"), nameEntry),
+			widget.NewFormItem(lang.L("Address"), addrEntry),
+			widget.NewFormItem(lang.L("Password"), passEntry),
+		},
+		func(ok bool) {
+			m.doModalClosed()
+			if !ok || addrEntry.Text == "" {
+				return
+			}
+			s := backend.MPDServerConfig{Name: nameEntry.Text, Address: addrEntry.Text}
+			if s.Name == "" {
+				s.Name = s.Address
+			}
+			if err := m.App.PlaybackManager.AddMPDServer(s, passEntry.Text); err != nil {
+				log.Printf("error adding MPD server: %v", err)
+				m.ToastProvider.ShowErrorToast(lang.L("Failed to add MPD server"))
+			}
+		}, m.MainWindow)
+	dlg.Resize(fyne.NewSize(400, dlg.MinSize().Height))
+	m.haveModal = true
+	dlg.Show()
+	m.MainWindow.Canvas().Focus(nameEntry)
+}
+
+// mpdServersMenu returns the cast menu submenu for adding and removing MPD servers.
+func (m *Controller) mpdServersMenu() *fyne.Menu {
+	menu := fyne.NewMenu("", fyne.NewMenuItem(lang.L("Add MPD server")+"...", m.DoAddMPDServerWorkflow))
+	for _, s := range m.App.PlaybackManager.MPDServers() {
+		name := s.Name
+		menu.Items = append(menu.Items, fyne.NewMenuItem(lang.L("Remove")+" "+name, func() {
+			m.App.PlaybackManager.RemoveMPDServer(name)
+		}))
+	}
+	return menu
+}
@@ -9,6 +9,7 @@ import (
 	"fyne.io/fyne/v2/lang"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/sharedutil"
 	"github.com/dweymouth/supersonic/ui/dialogs"
@@ -43,8 +44,10 @@ func (m *Controller) DoAddTracksToPlaylistWorkflow(trackIDs []string) {
 		m.App.Config.Application.AddToPlaylistSkipDuplicates = sp.SkipDuplicates
 		if id == "" /* creating new playlist */ {
 			go func() {
-				err := m.App.ServerManager.Server.CreatePlaylistWithTracks(sp.SearchDialog.SearchQuery(), trackIDs)
+				name := sp.SearchDialog.SearchQuery()
+				err := m.App.ServerManager.Server.CreatePlaylistWithTracks(name, trackIDs)
 				if err == nil {
+					m.App.OnPlaylistChanged(backend.PlaylistActionCreate, "", name, trackIDs)
 					notifySuccess(len(trackIDs))
 				} else {
 					log.Printf("error adding tracks to playlist: %s", err.Error())
@@ -69,6 +72,7 @@ func (m *Controller) DoAddTracksToPlaylistWorkflow(trackIDs []string) {
 						})
 						err := m.App.ServerManager.Server.AddPlaylistTracks(id, filterTrackIDs)
 						if err == nil {
+							m.App.OnPlaylistChanged(backend.PlaylistActionAddTracks, id, selectedPlaylist.Name, filterTrackIDs)
 							notifySuccess(len(filterTrackIDs))
 						} else {
 							log.Printf("error adding tracks to playlist: %s", err.Error())
@@ -80,6 +84,7 @@ func (m *Controller) DoAddTracksToPlaylistWorkflow(trackIDs []string) {
 				go func() {
 					err := m.App.ServerManager.Server.AddPlaylistTracks(id, trackIDs)
 					if err == nil {
+						m.App.OnPlaylistChanged(backend.PlaylistActionAddTracks, id, "", trackIDs)
 						notifySuccess(len(trackIDs))
 					} else {
 						log.Printf("error adding tracks to playlist: %s", err.Error())
@@ -119,9 +124,12 @@ func (m *Controller) DoEditPlaylistWorkflow(playlist *mediaprovider.Playlist) {
 					go func() {
 						if err := m.App.ServerManager.Server.DeletePlaylist(playlist.ID); err != nil {
 							log.Printf("error deleting playlist: %s", err.Error())
-						} else if rte := m.CurPageFunc(); rte.Page == Playlist && rte.Arg == playlist.ID {
-							// navigate to playlists page if user is still on the page of the deleted playlist
-							fyne.Do(func() { m.NavigateTo(PlaylistsRoute()) })
+						} else {
+							m.App.OnPlaylistChanged(backend.PlaylistActionDelete, playlist.ID, playlist.Name, nil)
+							if rte := m.CurPageFunc(); rte.Page == Playlist && rte.Arg == playlist.ID {
+								// navigate to playlists page if user is still on the page of the deleted playlist
+								fyne.Do(func() { m.NavigateTo(PlaylistsRoute()) })
+							}
 						}
 					}()
 				}
@@ -139,7 +147,10 @@ func (m *Controller) DoEditPlaylistWorkflow(playlist *mediaprovider.Playlist) {
 			if err != nil {
 				fyne.Do(func() { m.ToastProvider.ShowErrorToast(lang.L("Error updating playlist")) })
 				log.Printf("error updating playlist: %s", err.Error())
-			} else if rte := m.CurPageFunc(); rte.Page == Playlist && rte.Arg == playlist.ID {
+				return
+			}
+			m.App.OnPlaylistChanged(backend.PlaylistActionEdit, playlist.ID, dlg.Name, nil)
+			if rte := m.CurPageFunc(); rte.Page == Playlist && rte.Arg == playlist.ID {
 				// if user is on playlist page, reload to get the updates
 				fyne.Do(m.ReloadFunc)
 			}
@@ -171,6 +182,7 @@ func (m *Controller) DoCreatePlaylistWorkflow() {
 				fyne.Do(func() { m.ToastProvider.ShowErrorToast(lang.L("Error creating playlist")) })
 				log.Printf("error creating playlist: %s", err.Error())
 			} else {
+				m.App.OnPlaylistChanged(backend.PlaylistActionCreate, "", dlg.Name, nil)
 				fyne.Do(func() {
 					// Right now, this workflow is only initiated by the "New Playlist" button
 					// on the playlists page. Reload it so the new playlist shows up.
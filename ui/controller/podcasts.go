@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// DoAddPodcastWorkflow prompts for the RSS feed URL of a podcast and
+// subscribes to it on the server. onAdded is invoked on success.
+func (m *Controller) DoAddPodcastWorkflow(onAdded func()) {
+	pp := m.App.PodcastManager.Provider()
+	if pp == nil {
+		return
+	}
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://")
+	dlg := dialog.NewForm(lang.L("Add podcast"), lang.L("OK"), lang.L("Cancel"),
+		[]*widget.FormItem{widget.NewFormItem(lang.L("Feed URL"), urlEntry)},
+		func(ok bool) {
+			m.doModalClosed()
+			if !ok || urlEntry.Text == "" {
+				return
+			}
+			go func() {
+				if err := pp.CreatePodcastChannel(urlEntry.Text); err != nil {
+					log.Printf("error adding podcast: %s", err.Error())
+					fyne.Do(func() { m.ToastProvider.ShowErrorToast(lang.L("Unable to add podcast")) })
+					return
+				}
+				fyne.Do(func() {
+					m.ToastProvider.ShowSuccessToast(lang.L("Podcast added"))
+					if onAdded != nil {
+						onAdded()
+					}
+				})
+			}()
+		}, m.MainWindow)
+	dlg.Resize(fyne.NewSize(450, dlg.MinSize().Height))
+	m.haveModal = true
+	dlg.Show()
+	m.MainWindow.Canvas().Focus(urlEntry)
+}
+
+// DoDeletePodcastChannelWorkflow asks for confirmation and unsubscribes
+// from the podcast. onDeleted is invoked on success.
+func (m *Controller) DoDeletePodcastChannelWorkflow(channel *mediaprovider.PodcastChannel, onDeleted func()) {
+	pp := m.App.PodcastManager.Provider()
+	if pp == nil {
+		return
+	}
+	dialog.ShowCustomConfirm(lang.L("Confirm Delete Podcast"), lang.L("OK"), lang.L("Cancel"), layout.NewSpacer(), /*custom content*/
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				if err := pp.DeletePodcastChannel(channel.ID); err != nil {
+					log.Printf("error deleting podcast: %s", err.Error())
+					fyne.Do(func() { m.ToastProvider.ShowErrorToast(lang.L("An error occurred")) })
+					return
+				}
+				if onDeleted != nil {
+					fyne.Do(onDeleted)
+				}
+			}()
+		}, m.MainWindow)
+}
+
+// DoDownloadPodcastEpisode requests the server to download the episode.
+func (m *Controller) DoDownloadPodcastEpisode(episode *mediaprovider.PodcastEpisode) {
+	pp := m.App.PodcastManager.Provider()
+	if pp == nil {
+		return
+	}
+	go func() {
+		if err := pp.DownloadPodcastEpisode(episode.ID); err != nil {
+			log.Printf("error downloading podcast episode: %s", err.Error())
+			fyne.Do(func() { m.ToastProvider.ShowErrorToast(lang.L("Unable to download episode")) })
+			return
+		}
+		fyne.Do(func() { m.ToastProvider.ShowSuccessToast(lang.L("Episode download started on server")) })
+	}()
+}
@@ -16,6 +16,9 @@ const (
 	Playlists
 	Tracks
 	Radios
+	Bookmarks
+	Podcasts
+	PodcastChannel
 )
 
 func (p PageName) String() string {
@@ -44,6 +47,12 @@ func (p PageName) String() string {
 		return "All Tracks"
 	case Radios:
 		return "Internet Radio Stations"
+	case Bookmarks:
+		return "Bookmarks"
+	case Podcasts:
+		return "Podcasts"
+	case PodcastChannel:
+		return "Podcast"
 	default:
 		return ""
 	}
@@ -98,6 +107,18 @@ func RadiosRoute() Route {
 	return Route{Page: Radios}
 }
 
+func BookmarksRoute() Route {
+	return Route{Page: Bookmarks}
+}
+
+func PodcastsRoute() Route {
+	return Route{Page: Podcasts}
+}
+
+func PodcastChannelRoute(channelID string) Route {
+	return Route{Page: PodcastChannel, Arg: channelID}
+}
+
 func NowPlayingRoute() Route {
 	return Route{Page: NowPlaying}
 }
@@ -12,6 +12,10 @@ import (
 )
 
 func (c *Controller) ShowQuickSearch() {
+	c.showQuickSearch("")
+}
+
+func (c *Controller) showQuickSearch(query string) {
 	qs := dialogs.NewQuickSearch(c.App.ServerManager.Server, c.App.ImageManager)
 	pop := widget.NewModalPopUp(qs.SearchDialog, c.MainWindow.Canvas())
 	qs.SetOnDismiss(func() {
@@ -83,6 +87,9 @@ func (c *Controller) ShowQuickSearch() {
 	qs.OnShare = func(trackID string) {
 		c.ShowShareDialog(trackID)
 	}
+	qs.OnCopyLink = func(trackID string) {
+		c.CopyLink(backend.URIKindTrack, trackID)
+	}
 	qs.OnShowTrackInfo = func(track *mediaprovider.Track) {
 		c.ShowTrackInfoDialog(track)
 	}
@@ -95,6 +102,9 @@ func (c *Controller) ShowQuickSearch() {
 	pop.Resize(fyne.NewSize(min.Width, height))
 	pop.Show()
 	c.MainWindow.Canvas().Focus(qs.GetSearchEntry())
+	if query != "" {
+		qs.SearchDialog.SetSearchQuery(query)
+	}
 }
 
 func (c *Controller) handleSearchDialogOnAddToQueue(t mediaprovider.ContentType, id string, item any, next bool) {
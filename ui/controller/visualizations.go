@@ -2,21 +2,54 @@ package controller
 
 import (
 	"math"
+	"slices"
 	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
 	"github.com/dweymouth/supersonic/backend/player"
 	"github.com/dweymouth/supersonic/backend/player/mpv"
+	backendutil "github.com/dweymouth/supersonic/backend/util"
 	"github.com/dweymouth/supersonic/ui/shortcuts"
 	"github.com/dweymouth/supersonic/ui/util"
 	"github.com/dweymouth/supersonic/ui/visualizations"
 )
 
+// Visualizations that can be shown in the visualizations window.
+// These strings are saved in the config and may be translated for display.
+const (
+	VisualizationPeakMeter    = "Peak Meter"
+	VisualizationSpectrumBars = "Spectrum (bars)"
+	VisualizationSpectrumLine = "Spectrum (line)"
+	VisualizationSpectrogram  = "Spectrogram"
+)
+
+const (
+	spectrumFFTSize  = 2048
+	spectrumNumBands = 64
+	spectrumMinFreq  = 40
+	spectrumMaxFreq  = 11000
+)
+
+var visualizationNames = []string{
+	VisualizationPeakMeter,
+	VisualizationSpectrumBars,
+	VisualizationSpectrumLine,
+	VisualizationSpectrogram,
+}
+
 // embedded in parent controller struct
 type visualizationData struct {
-	peakMeter    *visualizations.PeakMeter
-	peakMeterWin fyne.Window
+	visualizationWin fyne.Window
+	peakMeter        *visualizations.PeakMeter
+	spectrumDisplay  visualizations.SpectrumDisplay // shown in visualizationWin
+	backgroundVis    visualizations.SpectrumDisplay // shown in Now Playing page
+
+	analyzer *backendutil.SpectrumAnalyzer
+	samples  []float64
+	bands    []float64
 
 	visualizationAnim *fyne.Animation
 }
@@ -24,50 +57,135 @@ type visualizationData struct {
 func (c *Controller) initVisualizations() {
 	c.App.PlaybackManager.OnStopped(c.stopVisualizationAnim)
 	c.App.PlaybackManager.OnPaused(c.stopVisualizationAnim)
-	c.App.PlaybackManager.OnPlaying(func() {
-		if _, ok := c.App.PlaybackManager.CurrentPlayer().(*mpv.Player); ok {
-			if c.peakMeter != nil {
-				c.startVisualizationAnim()
-			}
+	c.App.PlaybackManager.OnPlaying(c.startVisualizationAnimIfNeeded)
+}
+
+// NewBackgroundVisualization creates a translucent spectrum visualization
+// for the given NowPlayingPageConfig.BackgroundVisualization setting,
+// or returns nil if the setting is "None".
+func NewBackgroundVisualization(kind string) visualizations.SpectrumDisplay {
+	switch kind {
+	case "Spectrum":
+		s := visualizations.NewSpectrumAnalyzer(spectrumNumBands, visualizations.SpectrumModeBars)
+		s.Translucent = true
+		return s
+	case "Spectrogram":
+		s := visualizations.NewSpectrogram(spectrumNumBands)
+		s.Translucent = true
+		return s
+	}
+	return nil
+}
+
+// SetBackgroundVisualization sets the visualization shown as the Now Playing page
+// background, which will be fed from the playback analysis while it is visible.
+// Pass nil when the page is hidden.
+func (c *Controller) SetBackgroundVisualization(v visualizations.SpectrumDisplay) {
+	c.backgroundVis = v
+	if v == nil {
+		if c.peakMeter == nil && c.spectrumDisplay == nil {
+			c.stopVisualizationAnim()
+		} else {
+			c.updateAnalysisEnabled()
 		}
-	})
+		return
+	}
+	c.startVisualizationAnimIfNeeded()
 }
 
 func (c *Controller) ShowPeakMeter() {
-	if c.peakMeterWin != nil {
-		c.peakMeterWin.Show()
+	c.ShowVisualization(VisualizationPeakMeter)
+}
+
+// ShowVisualization shows the visualizations window displaying the given visualization.
+func (c *Controller) ShowVisualization(name string) {
+	c.App.Config.PeakMeter.Visualization = name
+	if c.visualizationWin != nil {
+		c.setWindowVisualization(name)
+		c.visualizationWin.Show()
 		return
 	}
-	c.peakMeterWin = fyne.CurrentApp().NewWindow(lang.L("Peak Meter"))
+	c.visualizationWin = fyne.CurrentApp().NewWindow(lang.L("Visualizations"))
 
 	onClose := func() {
-		c.stopVisualizationAnim()
 		c.peakMeter = nil
-		util.SaveWindowSize(c.peakMeterWin,
+		c.spectrumDisplay = nil
+		if c.backgroundVis == nil {
+			c.stopVisualizationAnim()
+		} else {
+			c.updateAnalysisEnabled()
+		}
+		util.SaveWindowSize(c.visualizationWin,
 			&c.App.Config.PeakMeter.WindowWidth,
 			&c.App.Config.PeakMeter.WindowHeight)
-		c.peakMeterWin.Close()
-		c.peakMeterWin = nil
+		c.visualizationWin.Close()
+		c.visualizationWin = nil
 	}
 
-	c.peakMeterWin.SetCloseIntercept(onClose)
-	c.peakMeterWin.Canvas().AddShortcut(&shortcuts.ShortcutCloseWindow, func(_ fyne.Shortcut) {
+	c.visualizationWin.SetCloseIntercept(onClose)
+	c.visualizationWin.Canvas().AddShortcut(&shortcuts.ShortcutCloseWindow, func(_ fyne.Shortcut) {
 		onClose()
 	})
 	if c.App.Config.PeakMeter.WindowHeight > 0 {
-		c.peakMeterWin.Resize(fyne.NewSize(
+		c.visualizationWin.Resize(fyne.NewSize(
 			float32(c.App.Config.PeakMeter.WindowWidth),
 			float32(c.App.Config.PeakMeter.WindowHeight)))
 	}
-	c.peakMeter = visualizations.NewPeakMeter()
-	c.peakMeterWin.SetContent(c.peakMeter)
+	c.setWindowVisualization(name)
+	c.visualizationWin.Show()
+}
+
+func (c *Controller) setWindowVisualization(name string) {
+	var content fyne.CanvasObject
+	c.peakMeter = nil
+	c.spectrumDisplay = nil
+	switch name {
+	case VisualizationSpectrumBars:
+		c.spectrumDisplay = visualizations.NewSpectrumAnalyzer(spectrumNumBands, visualizations.SpectrumModeBars)
+		content = c.spectrumDisplay
+	case VisualizationSpectrumLine:
+		c.spectrumDisplay = visualizations.NewSpectrumAnalyzer(spectrumNumBands, visualizations.SpectrumModeLine)
+		content = c.spectrumDisplay
+	case VisualizationSpectrogram:
+		c.spectrumDisplay = visualizations.NewSpectrogram(spectrumNumBands)
+		content = c.spectrumDisplay
+	default:
+		c.peakMeter = visualizations.NewPeakMeter()
+		content = c.peakMeter
+	}
+
+	options := make([]string, len(visualizationNames))
+	for i, n := range visualizationNames {
+		options[i] = lang.L(n)
+	}
+	sel := widget.NewSelect(options, nil)
+	sel.SetSelectedIndex(max(0, slices.Index(visualizationNames, name)))
+	sel.OnChanged = func(_ string) {
+		n := visualizationNames[sel.SelectedIndex()]
+		c.App.Config.PeakMeter.Visualization = n
+		c.setWindowVisualization(n)
+	}
+	c.visualizationWin.SetContent(container.NewBorder(
+		container.NewHBox(sel), nil, nil, nil, content))
+
 	if c.App.LocalPlayer.GetStatus().State == player.Playing {
-		c.startVisualizationAnim()
+		c.startVisualizationAnimIfNeeded()
 	} else {
 		// TODO: why is this needed?
-		c.peakMeter.Refresh()
+		content.Refresh()
+	}
+}
+
+func (c *Controller) startVisualizationAnimIfNeeded() {
+	if _, ok := c.App.PlaybackManager.CurrentPlayer().(*mpv.Player); !ok {
+		return
+	}
+	if c.App.LocalPlayer.GetStatus().State != player.Playing {
+		return
+	}
+	if c.peakMeter != nil || c.spectrumDisplay != nil || c.backgroundVis != nil {
+		c.startVisualizationAnim()
 	}
-	c.peakMeterWin.Show()
 }
 
 func (c *Controller) stopVisualizationAnim() {
@@ -75,12 +193,13 @@ func (c *Controller) stopVisualizationAnim() {
 		c.visualizationAnim.Stop()
 		c.visualizationAnim = nil
 		c.App.LocalPlayer.SetPeaksEnabled(false)
+		c.App.LocalPlayer.SetAnalysisTapEnabled(false)
 	}
 }
 
 func (c *Controller) startVisualizationAnim() {
+	c.updateAnalysisEnabled()
 	if c.visualizationAnim == nil {
-		c.App.LocalPlayer.SetPeaksEnabled(true)
 		c.visualizationAnim = fyne.NewAnimation(
 			time.Duration(math.MaxInt64), /*until stopped*/
 			c.tickVisualizations)
@@ -88,9 +207,35 @@ func (c *Controller) startVisualizationAnim() {
 	}
 }
 
+// enables only the analysis sources needed by the visible visualizations
+func (c *Controller) updateAnalysisEnabled() {
+	c.App.LocalPlayer.SetPeaksEnabled(c.peakMeter != nil)
+	c.App.LocalPlayer.SetAnalysisTapEnabled(c.spectrumDisplay != nil || c.backgroundVis != nil)
+}
+
 func (c *Controller) tickVisualizations(_ float32) {
-	lP, rP, lRMS, rRMS := c.App.LocalPlayer.GetPeaks()
-	if c.visualizationData.peakMeter != nil {
-		c.visualizationData.peakMeter.UpdatePeaks(lP, rP, lRMS, rRMS)
+	if c.peakMeter != nil {
+		lP, rP, lRMS, rRMS := c.App.LocalPlayer.GetPeaks()
+		c.peakMeter.UpdatePeaks(lP, rP, lRMS, rRMS)
+	}
+	if c.spectrumDisplay == nil && c.backgroundVis == nil {
+		return
+	}
+	if c.analyzer == nil {
+		c.analyzer = backendutil.NewSpectrumAnalyzer(spectrumFFTSize, mpv.AnalysisSampleRate,
+			spectrumNumBands, spectrumMinFreq, spectrumMaxFreq)
+		c.samples = make([]float64, spectrumFFTSize)
+		c.bands = make([]float64, spectrumNumBands)
+	}
+	if c.App.LocalPlayer.GetSamples(c.samples) < len(c.samples) {
+		// analysis has not caught up to the playback position yet
+		return
+	}
+	c.analyzer.Analyze(c.samples, c.bands)
+	if c.spectrumDisplay != nil {
+		c.spectrumDisplay.UpdateBands(c.bands)
+	}
+	if c.backgroundVis != nil {
+		c.backgroundVis.UpdateBands(c.bands)
 	}
 }
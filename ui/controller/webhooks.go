@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend/webhooks"
+)
+
+// ShowWebhookDeliveriesDialog shows the most recent webhook deliveries.
+func (c *Controller) ShowWebhookDeliveriesDialog() {
+	deliveries := c.App.WebhookDeliveries()
+	var content fyne.CanvasObject
+	if len(deliveries) == 0 {
+		content = widget.NewLabel(lang.L("No webhook deliveries yet"))
+	} else {
+		list := widget.NewList(
+			func() int { return len(deliveries) },
+			func() fyne.CanvasObject { return widget.NewLabel("") },
+			func(id widget.ListItemID, obj fyne.CanvasObject) {
+				obj.(*widget.Label).SetText(webhookDeliveryText(deliveries[id]))
+			},
+		)
+		content = list
+	}
+	dlg := dialog.NewCustom(lang.L("Webhook delivery log"), lang.L("Close"), content, c.MainWindow)
+	if len(deliveries) > 0 {
+		dlg.Resize(fyne.NewSize(700, 400))
+	}
+	dlg.Show()
+}
+
+func webhookDeliveryText(d webhooks.Delivery) string {
+	status := lang.L("Delivered")
+	if !d.Succeeded() {
+		status = fmt.Sprintf("%s: %s", lang.L("Failed"), d.Err)
+		if d.Retrying {
+			status += fmt.Sprintf(" (%s)", lang.L("will retry"))
+		}
+	}
+	return fmt.Sprintf("%s  %s  %s  %s", d.Time.Format("15:04:05"), d.Event, d.URL, status)
+}
@@ -25,6 +25,7 @@ type AddEditServerDialog struct {
 	Password      string
 	LegacyAuth    bool
 	SkipSSLVerify bool
+	HideDiscord   bool
 	OnSubmit      func()
 	OnCancel      func()
 
@@ -47,6 +48,7 @@ func NewAddEditServerDialog(title string, cancelable bool, prefillServer *backen
 		a.Username = prefillServer.Username
 		a.LegacyAuth = prefillServer.LegacyAuth
 		a.SkipSSLVerify = prefillServer.SkipSSLVerify
+		a.HideDiscord = prefillServer.HideDiscordPresence
 	}
 
 	titleLabel := widget.NewLabel(title)
@@ -61,6 +63,7 @@ func NewAddEditServerDialog(title string, cancelable bool, prefillServer *backen
 		}
 	})
 	skipSSLCheck := widget.NewCheckWithData(lang.L("Skip SSL certificate verification"), binding.BindBool(&a.SkipSSLVerify))
+	hideDiscordCheck := widget.NewCheckWithData(lang.L("Hide from Discord"), binding.BindBool(&a.HideDiscord))
 	serverTypeChoice.Required = true
 	serverTypeChoice.Horizontal = true
 	selected := backend.ServerTypeSubsonic
@@ -116,7 +119,7 @@ func NewAddEditServerDialog(title string, cancelable bool, prefillServer *backen
 			widget.NewLabel(lang.L("Password")),
 			a.passField,
 		),
-		container.NewHBox(layout.NewSpacer(), legacyAuthCheck, skipSSLCheck),
+		container.NewHBox(layout.NewSpacer(), legacyAuthCheck, skipSSLCheck, hideDiscordCheck),
 		widget.NewSeparator(),
 		bottomRow,
 	)
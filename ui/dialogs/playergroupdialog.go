@@ -0,0 +1,145 @@
+package dialogs
+
+import (
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend"
+)
+
+// PlayerGroupDevice is a device that can be added to a player group.
+type PlayerGroupDevice struct {
+	Name string
+	URL  string
+}
+
+type EditPlayerGroupDialog struct {
+	widget.BaseWidget
+
+	OnCanceled func()
+	OnDelete   func()
+	OnSubmit   func()
+
+	Name string
+
+	members   []playerGroupMemberRow
+	container *fyne.Container
+}
+
+type playerGroupMemberRow struct {
+	url    string
+	check  *widget.Check
+	offset *widget.Entry
+}
+
+// NewEditPlayerGroupDialog creates a dialog to edit the given group,
+// or to create a new one if group is nil.
+func NewEditPlayerGroupDialog(group *backend.PlayerGroupConfig, devices []PlayerGroupDevice) *EditPlayerGroupDialog {
+	e := &EditPlayerGroupDialog{}
+	e.ExtendBaseWidget(e)
+
+	offsets := make(map[string]int)
+	if group != nil {
+		e.Name = group.Name
+		for _, m := range group.Members {
+			offsets[m.DeviceURL] = m.LatencyOffsetMS
+		}
+	}
+
+	memberGrid := container.New(layout.NewFormLayout(),
+		widget.NewLabelWithStyle(lang.L("Devices"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle(lang.L("Latency offset (ms)"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	)
+	for _, d := range devices {
+		offset, isMember := offsets[d.URL]
+		row := playerGroupMemberRow{
+			url:    d.URL,
+			check:  widget.NewCheck(d.Name, nil),
+			offset: widget.NewEntry(),
+		}
+		row.check.Checked = isMember
+		row.offset.SetText(strconv.Itoa(offset))
+		e.members = append(e.members, row)
+		memberGrid.Add(row.check)
+		memberGrid.Add(row.offset)
+	}
+
+	nameEntry := widget.NewEntryWithData(binding.BindString(&e.Name))
+	deleteBtn := widget.NewButtonWithIcon(lang.L("Delete Group"), theme.DeleteIcon(), func() {
+		if e.OnDelete != nil {
+			e.OnDelete()
+		}
+	})
+	deleteBtn.Hidden = group == nil
+	submitBtn := widget.NewButtonWithIcon(lang.L("OK"), theme.ConfirmIcon(), func() {
+		if e.OnSubmit != nil {
+			e.OnSubmit()
+		}
+	})
+	submitBtn.Importance = widget.HighImportance
+	cancelBtn := widget.NewButtonWithIcon(lang.L("Cancel"), theme.CancelIcon(), func() {
+		if e.OnCanceled != nil {
+			e.OnCanceled()
+		}
+	})
+
+	var titleStr string
+	if group == nil {
+		titleStr = lang.L("New Player Group")
+	} else {
+		titleStr = lang.L("Edit Player Group")
+	}
+	title := widget.NewLabel(titleStr)
+	title.Alignment = fyne.TextAlignCenter
+	title.TextStyle.Bold = true
+
+	hint := widget.NewLabel(lang.L("Increase a device's latency offset if its audio is heard later than the others."))
+	hint.Wrapping = fyne.TextWrapWord
+	hint.Importance = widget.LowImportance
+
+	e.container = container.NewVBox(
+		title,
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel(lang.L("Name")),
+			nameEntry,
+		),
+		memberGrid,
+		hint,
+		container.NewHBox(layout.NewSpacer(), deleteBtn),
+		widget.NewSeparator(),
+		container.NewHBox(
+			layout.NewSpacer(),
+			cancelBtn, submitBtn),
+	)
+	return e
+}
+
+// Members returns the selected devices and their latency offsets.
+func (e *EditPlayerGroupDialog) Members() []backend.PlayerGroupMemberConfig {
+	var members []backend.PlayerGroupMemberConfig
+	for _, row := range e.members {
+		if !row.check.Checked {
+			continue
+		}
+		offset, _ := strconv.Atoi(row.offset.Text)
+		members = append(members, backend.PlayerGroupMemberConfig{
+			DeviceURL:       row.url,
+			LatencyOffsetMS: offset,
+		})
+	}
+	return members
+}
+
+func (e *EditPlayerGroupDialog) MinSize() fyne.Size {
+	return fyne.NewSize(450, e.BaseWidget.MinSize().Height)
+}
+
+func (e *EditPlayerGroupDialog) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(e.container)
+}
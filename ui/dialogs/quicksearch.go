@@ -24,6 +24,7 @@ type QuickSearch struct {
 	OnSetRating     func(trackID string, rating int)
 	OnDownload      func(track *mediaprovider.Track)
 	OnShare         func(trackID string)
+	OnCopyLink      func(trackID string)
 	OnPlaySongRadio func(track *mediaprovider.Track)
 	OnShowTrackInfo func(track *mediaprovider.Track)
 }
@@ -84,6 +85,9 @@ func (q *QuickSearch) showMenu(idx int, pos fyne.Position) {
 		menu.OnShare = func() {
 			q.OnShare(id)
 		}
+		menu.OnCopyLink = func() {
+			q.OnCopyLink(id)
+		}
 		menu.ShowAtPosition(pos, canvas)
 	default:
 		play := fyne.NewMenuItem(lang.L("Play"), func() {
@@ -100,6 +100,11 @@ func (sd *SearchDialog) SearchQuery() string {
 	return sd.searchEntry.Text
 }
 
+// SetSearchQuery enters a search query, which is searched for as if typed by the user
+func (sd *SearchDialog) SetSearchQuery(query string) {
+	sd.searchEntry.SetText(query)
+}
+
 func (sd *SearchDialog) Show() {
 	sd.BaseWidget.Show()
 	sd.onSearched("")
@@ -12,7 +12,9 @@ import (
 	"unicode"
 
 	"github.com/dweymouth/supersonic/backend"
+	"github.com/dweymouth/supersonic/backend/ipc"
 	"github.com/dweymouth/supersonic/backend/player/mpv"
+	"github.com/dweymouth/supersonic/backend/webremote"
 	"github.com/dweymouth/supersonic/res"
 	myTheme "github.com/dweymouth/supersonic/ui/theme"
 	"github.com/dweymouth/supersonic/ui/util"
@@ -41,6 +43,7 @@ type SettingsDialog struct {
 	OnEqualizerSettingsChanged     func()
 	OnPageNeedsRefresh             func()
 	OnClearCaches                  func()
+	OnShowWebhookDeliveries        func()
 
 	config          *backend.Config
 	audioDevices    []mpv.AudioDevice
@@ -73,6 +76,7 @@ func NewSettingsDialog(
 	isReplayGainPlayer bool,
 	isEqualizerPlayer bool,
 	canSavePlayQueue bool,
+	canBookmark bool,
 	eqPresetMgr *backend.EQPresetManager,
 	window fyne.Window,
 	autoEQManager *backend.AutoEQManager,
@@ -99,7 +103,7 @@ func NewSettingsDialog(
 		tabs = container.NewAppTabs(
 			s.createGeneralTab(canSavePlayQueue),
 			s.createAppearanceTab(window),
-			s.createPlaybackTab(isLocalPlayer, isReplayGainPlayer),
+			s.createPlaybackTab(isLocalPlayer, isReplayGainPlayer, canBookmark),
 			s.createEqualizerTab(equalizerBands),
 			s.createAdvancedTab(),
 		)
@@ -107,7 +111,7 @@ func NewSettingsDialog(
 		tabs = container.NewAppTabs(
 			s.createGeneralTab(canSavePlayQueue),
 			s.createAppearanceTab(window),
-			s.createPlaybackTab(isLocalPlayer, isReplayGainPlayer),
+			s.createPlaybackTab(isLocalPlayer, isReplayGainPlayer, canBookmark),
 			s.createAdvancedTab(),
 		)
 	}
@@ -325,7 +329,7 @@ func (s *SettingsDialog) createGeneralTab(canSaveQueueToServer bool) *container.
 	))
 }
 
-func (s *SettingsDialog) createPlaybackTab(isLocalPlayer, isReplayGainPlayer bool) *container.TabItem {
+func (s *SettingsDialog) createPlaybackTab(isLocalPlayer, isReplayGainPlayer, canBookmark bool) *container.TabItem {
 	transcodeCodec := widget.NewSelectWithData([]string{"opus", "mp3"}, binding.BindString(&s.config.Transcoding.Codec))
 	transcodeBitRate := widget.NewSelectWithData([]string{"96", "128", "160", "192", "256", "320"},
 		binding.IntToString(binding.BindInt(&s.config.Transcoding.MaxBitRateKBPS)))
@@ -452,6 +456,25 @@ func (s *SettingsDialog) createPlaybackTab(isLocalPlayer, isReplayGainPlayer boo
 		preampGain.Disable()
 	}
 
+	bookmarkEntry := widgets.NewTextRestrictedEntry(func(text, selText string, r rune) bool {
+		return unicode.IsDigit(r) && len(text)-len(selText) < 3
+	})
+	bookmarkEntry.SetMinCharWidth(3)
+	bookmarkEntry.OnChanged = func(str string) {
+		if i, err := strconv.Atoi(str); err == nil {
+			s.config.Playback.AutoBookmarkMinTrackMinutes = i
+		}
+	}
+	bookmarkEntry.Text = strconv.Itoa(s.config.Playback.AutoBookmarkMinTrackMinutes)
+	bookmarkHBox := container.NewHBox(
+		widget.NewLabel(lang.L("Bookmark playback position of tracks longer than")),
+		bookmarkEntry,
+		widget.NewLabel(lang.L("minutes")),
+	)
+	if !canBookmark {
+		bookmarkHBox.Hide()
+	}
+
 	return container.NewTabItem(lang.L("Playback"), container.NewVBox(
 
 		container.New(&layout.CustomPaddedLayout{TopPadding: 5},
@@ -460,6 +483,7 @@ func (s *SettingsDialog) createPlaybackTab(isLocalPlayer, isReplayGainPlayer boo
 				layout.NewSpacer(), audioExclusive,
 			)),
 		pauseFade,
+		bookmarkHBox,
 		s.newSectionSeparator(),
 		disableTranscode,
 		container.NewHBox(transcode, transcodeCodec, transcodeBitRate),
@@ -760,6 +784,16 @@ func (s *SettingsDialog) createAppearanceTab(window fyne.Window) *container.TabI
 
 	nowPlayingBackground := widget.NewCheckWithData(lang.L("Use blurred album cover for Now Playing page background"), binding.BindBool(&s.config.NowPlayingConfig.UseBackgroundImage))
 
+	bgVisualizations := []string{"None", "Spectrum", "Spectrogram"}
+	bgVisualizationSelect := widget.NewSelect([]string{lang.L("None"), lang.L("Spectrum"), lang.L("Spectrogram")}, nil)
+	bgVisualizationSelect.SetSelectedIndex(max(0, slices.Index(bgVisualizations, s.config.NowPlayingConfig.BackgroundVisualization)))
+	bgVisualizationSelect.OnChanged = func(_ string) {
+		s.config.NowPlayingConfig.BackgroundVisualization = bgVisualizations[bgVisualizationSelect.SelectedIndex()]
+		if s.OnPageNeedsRefresh != nil {
+			s.OnPageNeedsRefresh()
+		}
+	}
+
 	useRoundedImageCorners := widget.NewCheck(lang.L("Use rounded image corners"), func(b bool) {
 		s.config.Theme.UseRoundedImageCorners = b
 		if s.OnPageNeedsRefresh != nil {
@@ -781,6 +815,7 @@ func (s *SettingsDialog) createAppearanceTab(window fyne.Window) *container.TabI
 		s.newSectionSeparator(),
 		useWaveformSeekbar,
 		nowPlayingBackground,
+		container.NewHBox(widget.NewLabel(lang.L("Now Playing background visualization")), bgVisualizationSelect),
 		useRoundedImageCorners,
 		s.newSectionSeparator(),
 		widget.NewRichText(&widget.TextSegment{Text: lang.L("Application font"), Style: util.BoldRichTextStyle}),
@@ -809,6 +844,16 @@ func (s *SettingsDialog) createAdvancedTab() *container.TabItem {
 	}
 	percentEntry.Text = strconv.Itoa(s.config.Application.MaxImageCacheSizeMB)
 
+	waveformCacheEntry := widgets.NewTextRestrictedEntry(threeDigitValidator)
+	waveformCacheEntry.SetMinCharWidth(3)
+	waveformCacheEntry.OnChanged = func(str string) {
+		if i, err := strconv.Atoi(str); err == nil {
+			s.config.Playback.MaxWaveformCacheSizeMB = i
+			s.setRestartRequired()
+		}
+	}
+	waveformCacheEntry.Text = strconv.Itoa(s.config.Playback.MaxWaveformCacheSizeMB)
+
 	clearCaches := widget.NewButton(lang.L("Clear caches"), func() {
 		if s.OnClearCaches != nil {
 			s.OnClearCaches()
@@ -832,13 +877,120 @@ func (s *SettingsDialog) createAdvancedTab() *container.TabItem {
 	preventScreensaver := widget.NewCheckWithData(lang.L("Prevent screensaver on Now Playing page"),
 		binding.BindBool(&s.config.Application.PreventScreensaverOnNowPlayingPage))
 
+	mediaServer := widget.NewCheck(lang.L("Share library with DLNA devices on the local network"), func(b bool) {
+		s.config.MediaServer.Enabled = b
+		s.setRestartRequired()
+	})
+	mediaServer.Checked = s.config.MediaServer.Enabled
+
+	rebroadcast := widget.NewCheck(lang.L("Rebroadcast playback as an internet radio stream"), func(b bool) {
+		s.config.Rebroadcast.Enabled = b
+		s.setRestartRequired()
+	})
+	rebroadcast.Checked = s.config.Rebroadcast.Enabled
+	rebroadcastFormat := widget.NewSelect([]string{"MP3", "Opus"}, func(f string) {
+		s.config.Rebroadcast.Format = strings.ToLower(f)
+		s.setRestartRequired()
+	})
+	rebroadcastFormat.Selected = "MP3"
+	if s.config.Rebroadcast.Format == "opus" {
+		rebroadcastFormat.Selected = "Opus"
+	}
+
+	copyRemoteLink := widget.NewButton(lang.L("Copy link"), func() {
+		cfg := s.config.WebRemote
+		fyne.CurrentApp().Clipboard().SetContent(webremote.RemoteURL(cfg.Port, cfg.Token))
+	})
+	webRemote := widget.NewCheck(lang.L("Enable web remote control on the local network"), func(b bool) {
+		s.config.WebRemote.Enabled = b
+		if b && s.config.WebRemote.Token == "" {
+			s.config.WebRemote.Token = webremote.NewToken()
+		}
+		if b {
+			copyRemoteLink.Enable()
+		} else {
+			copyRemoteLink.Disable()
+		}
+		s.setRestartRequired()
+	})
+	webRemote.Checked = s.config.WebRemote.Enabled
+	if !webRemote.Checked {
+		copyRemoteLink.Disable()
+	}
+
+	copyIPCToken := widget.NewButton(lang.L("Copy token"), func() {
+		fyne.CurrentApp().Clipboard().SetContent(s.config.RemoteIPC.Token)
+	})
+	remoteIPC := widget.NewCheck(lang.L("Allow command line control from other computers"), func(b bool) {
+		s.config.RemoteIPC.Enabled = b
+		if b && s.config.RemoteIPC.Token == "" {
+			s.config.RemoteIPC.Token = ipc.NewToken()
+		}
+		if b {
+			copyIPCToken.Enable()
+		} else {
+			copyIPCToken.Disable()
+		}
+		s.setRestartRequired()
+	})
+	remoteIPC.Checked = s.config.RemoteIPC.Enabled
+	if !remoteIPC.Checked {
+		copyIPCToken.Disable()
+	}
+
+	discordAppID := widget.NewEntry()
+	discordAppID.SetPlaceHolder(lang.L("Application ID"))
+	discordAppID.Text = s.config.Discord.ApplicationID
+	discordAppID.OnChanged = func(id string) {
+		s.config.Discord.ApplicationID = strings.TrimSpace(id)
+		s.setRestartRequired()
+	}
+	discord := widget.NewCheck(lang.L("Show playing track on Discord"), func(b bool) {
+		s.config.Discord.Enabled = b
+		if b {
+			discordAppID.Enable()
+		} else {
+			discordAppID.Disable()
+		}
+		s.setRestartRequired()
+	})
+	discord.Checked = s.config.Discord.Enabled
+	if !discord.Checked {
+		discordAppID.Disable()
+	}
+
+	webhookLog := widget.NewButton(lang.L("Delivery log"), func() {
+		if s.OnShowWebhookDeliveries != nil {
+			s.OnShowWebhookDeliveries()
+		}
+	})
+	webhooks := widget.NewCheck(lang.L("Send events to webhooks in the config file"), func(b bool) {
+		s.config.Webhooks.Enabled = b
+		s.setRestartRequired()
+	})
+	webhooks.Checked = s.config.Webhooks.Enabled
+	if !webhooks.Checked {
+		webhookLog.Disable()
+	}
+
 	return container.NewTabItem(lang.L("Advanced"), container.NewVBox(
 		multi,
 		update,
 		lrclib,
 		osMediaAPIs,
 		preventScreensaver,
+		mediaServer,
+		container.NewHBox(rebroadcast, rebroadcastFormat),
+		container.NewHBox(webRemote, copyRemoteLink),
+		container.NewHBox(remoteIPC, copyIPCToken),
+		container.NewBorder(nil, nil, discord, nil, discordAppID),
+		container.NewHBox(webhooks, webhookLog),
 		imgCacheCfg,
+		container.NewHBox(
+			widget.NewLabel(lang.L("Maximum waveform cache size")),
+			waveformCacheEntry,
+			widget.NewLabel("MB"),
+		),
 	))
 }
 
@@ -139,6 +139,15 @@ func NewMainWindow(fyneApp fyne.App, appName, displayAppName, appVersion string,
 	m.Toolbar.AddSettingsSubmenu(lang.L("Visualizations"), myTheme.VisualizationIcon,
 		fyne.NewMenu("", []*fyne.MenuItem{
 			fyne.NewMenuItem(lang.L("Peak Meter"), m.Controller.ShowPeakMeter),
+			fyne.NewMenuItem(lang.L("Spectrum (bars)"), func() {
+				m.Controller.ShowVisualization(controller.VisualizationSpectrumBars)
+			}),
+			fyne.NewMenuItem(lang.L("Spectrum (line)"), func() {
+				m.Controller.ShowVisualization(controller.VisualizationSpectrumLine)
+			}),
+			fyne.NewMenuItem(lang.L("Spectrogram"), func() {
+				m.Controller.ShowVisualization(controller.VisualizationSpectrogram)
+			}),
 		}...))
 	m.Toolbar.AddSettingsMenuSeparator()
 	m.Toolbar.AddSettingsMenuItem(lang.L("Check for Updates"), theme.DownloadIcon(), func() {
@@ -365,7 +374,10 @@ func (m *MainWindow) RunOnServerConnectedTasks(serverConf *backend.ServerConfig,
 
 		_, supportsRadio := m.App.ServerManager.Server.(mediaprovider.RadioProvider)
 		m.Toolbar.SetRadioButtonVisible(supportsRadio)
+		m.Toolbar.SetPodcastsButtonVisible(m.App.PodcastManager.IsSupported())
+		m.Toolbar.SetBookmarksButtonVisible(m.App.BookmarkManager.IsSupported())
 	})
+	app.OpenPendingURI()
 
 	m.App.SaveConfigFile()
 
@@ -15,6 +15,7 @@ import (
 type Sidebar struct {
 	widget.BaseWidget
 
+	pm *backend.PlaybackManager
 	lm *backend.LyricsManager
 
 	queueList     *widgets.PlayQueueList
@@ -31,6 +32,7 @@ type Sidebar struct {
 
 func NewSidebar(contr *controller.Controller, pm *backend.PlaybackManager, im *backend.ImageManager, lm *backend.LyricsManager) *Sidebar {
 	s := &Sidebar{
+		pm:        pm,
 		lm:        lm,
 		queueList: widgets.NewPlayQueueList(im, false),
 	}
@@ -60,6 +62,7 @@ func NewSidebar(contr *controller.Controller, pm *backend.PlaybackManager, im *b
 
 	pm.OnPlayTimeUpdate(func(curTime, _ float64, seeked bool) {
 		s.lastPlayPos = curTime
+		fyne.Do(func() { s.queueList.UpdatePlayPos(curTime) })
 		if seeked {
 			fyne.Do(func() { s.lyricsViewer.OnSeeked(curTime) })
 		} else {
@@ -89,6 +92,7 @@ func (s *Sidebar) SetNowPlaying(item mediaprovider.MediaItem) {
 	if item != nil {
 		id = item.Metadata().ID
 	}
+	s.queueList.SetNowPlayingChapters(s.pm.NowPlayingChapters())
 	s.queueList.SetNowPlaying(id)
 	s.nowPlayingID = id
 	if s.tabs.SelectedIndex() == 1 /*lyrics*/ {
@@ -106,7 +110,7 @@ func (s *Sidebar) updateLyrics() {
 			return
 		}
 	}
-	if s.nowPlaying == nil || s.nowPlaying.Metadata().Type == mediaprovider.MediaItemTypeRadioStation {
+	if s.nowPlaying == nil || s.nowPlaying.Metadata().Type != mediaprovider.MediaItemTypeTrack {
 		s.lyricsViewer.SetLyrics(nil)
 		s.curLyrics = nil
 		s.curLyricsID = ""
@@ -71,6 +71,8 @@ var (
 	LibraryIcon       fyne.Resource = theme.NewThemedResource(res.ResLibrarySvg)
 	SaveIcon          fyne.Resource = theme.NewThemedResource(res.ResSaveSvg)
 	SaveAsIcon        fyne.Resource = theme.NewThemedResource(res.ResSaveasSvg)
+	BookmarkIcon      fyne.Resource = theme.NewThemedResource(res.ResBookmarkSvg)
+	PodcastIcon       fyne.Resource = theme.NewThemedResource(res.ResPodcastSvg)
 )
 
 type AppearanceMode string
@@ -28,6 +28,8 @@ type Toolbar struct {
 	navBtnsContainer *fyne.Container
 	navBtnsPageMap   map[controller.PageName]fyne.Resource
 	radioBtn         fyne.CanvasObject
+	bookmarksBtn     fyne.CanvasObject
+	podcastsBtn      fyne.CanvasObject
 
 	quickSearchBtn *ttwidget.Button
 	sidebarBtn     *ttwidget.Button
@@ -82,6 +84,24 @@ func (t *Toolbar) SetRadioButtonVisible(vis bool) {
 	}
 }
 
+// SetBookmarksButtonVisible sets whether the bookmarks button is visible
+func (t *Toolbar) SetBookmarksButtonVisible(vis bool) {
+	if vis {
+		t.bookmarksBtn.Show()
+	} else {
+		t.bookmarksBtn.Hide()
+	}
+}
+
+// SetPodcastsButtonVisible sets whether the podcasts button is visible
+func (t *Toolbar) SetPodcastsButtonVisible(vis bool) {
+	if vis {
+		t.podcastsBtn.Show()
+	} else {
+		t.podcastsBtn.Hide()
+	}
+}
+
 // AddSettingsMenuItem adds an item to the Settings menu
 func (t *Toolbar) AddSettingsMenuItem(label string, icon fyne.Resource, action func()) {
 	item := fyne.NewMenuItem(label, action)
@@ -170,6 +190,12 @@ func (t *Toolbar) setupNavigationButtons(navigateFn func(controller.Route)) {
 	t.radioBtn = t.addNavigationButton(myTheme.RadioIcon, controller.Radios, func() {
 		navigateFn(controller.RadiosRoute())
 	})
+	t.podcastsBtn = t.addNavigationButton(myTheme.PodcastIcon, controller.Podcasts, func() {
+		navigateFn(controller.PodcastsRoute())
+	})
+	t.bookmarksBtn = t.addNavigationButton(myTheme.BookmarkIcon, controller.Bookmarks, func() {
+		navigateFn(controller.BookmarksRoute())
+	})
 }
 
 func (t *Toolbar) addNavigationButton(icon fyne.Resource, pageName controller.PageName, action func()) *ttwidget.Button {
@@ -0,0 +1,135 @@
+package visualizations
+
+import (
+	"image"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	myTheme "github.com/dweymouth/supersonic/ui/theme"
+)
+
+// number of frames of history shown by the spectrogram
+const spectrogramColumns = 300
+
+var _ SpectrumDisplay = (*Spectrogram)(nil)
+
+// Spectrogram displays a scrolling history of the levels of frequency bands,
+// with time on the X axis, frequency on the Y axis, and level as intensity.
+type Spectrogram struct {
+	widget.BaseWidget
+
+	// If true, draw with reduced opacity, for use
+	// as a background behind other content.
+	Translucent bool
+
+	img     *image.NRGBA
+	palette [256]color.NRGBA
+
+	// inputs the current palette was built from
+	paletteFg          color.Color
+	palettePrimary     color.Color
+	paletteTranslucent bool
+
+	canvasImg *canvas.Image
+}
+
+func NewSpectrogram(numBands int) *Spectrogram {
+	s := &Spectrogram{
+		img: image.NewNRGBA(image.Rect(0, 0, spectrogramColumns, numBands)),
+	}
+	s.canvasImg = canvas.NewImageFromImage(s.img)
+	s.canvasImg.FillMode = canvas.ImageFillStretch
+	s.canvasImg.ScaleMode = canvas.ImageScaleSmooth
+	s.ExtendBaseWidget(s)
+	s.updatePalette()
+	return s
+}
+
+// UpdateBands scrolls the spectrogram left by one column
+// and draws the given band levels in the rightmost column.
+func (s *Spectrogram) UpdateBands(bands []float64) {
+	// the widget may not have been refreshed since it was
+	// created or made translucent, so the palette may be stale
+	s.updatePalette()
+	w, h := s.img.Rect.Dx(), s.img.Rect.Dy()
+	for y := range h {
+		row := s.img.Pix[y*s.img.Stride : y*s.img.Stride+w*4]
+		copy(row, row[4:])
+	}
+	for i := range min(len(bands), h) {
+		frac := (bands[i] + spectrumRangeDB) / spectrumRangeDB
+		idx := int(max(0, min(1, frac)) * 255)
+		// lowest frequencies at the bottom
+		setPixel(s.img, w-1, h-1-i, s.palette[idx])
+	}
+	s.canvasImg.Refresh()
+}
+
+func (s *Spectrogram) CreateRenderer() fyne.WidgetRenderer {
+	return &spectrogramRenderer{s: s, objects: []fyne.CanvasObject{s.canvasImg}}
+}
+
+// builds a palette fading from transparent through
+// the theme primary color to the foreground color
+func (s *Spectrogram) updatePalette() {
+	fg := theme.ForegroundColor()
+	primary := color.NRGBAModel.Convert(theme.PrimaryColor()).(color.NRGBA)
+	if fg == s.paletteFg && primary == s.palettePrimary && s.Translucent == s.paletteTranslucent {
+		return
+	}
+	s.paletteFg, s.palettePrimary, s.paletteTranslucent = fg, primary, s.Translucent
+	maxAlpha := 255
+	if s.Translucent {
+		maxAlpha = translucentAlpha * 2
+	}
+	for i := range s.palette {
+		f := float64(i) / 255
+		var c color.NRGBA
+		if f < 0.6 {
+			c = primary
+			c.A = uint8(f / 0.6 * float64(maxAlpha))
+		} else {
+			c = color.NRGBAModel.Convert(
+				myTheme.BlendColors(fg, primary, (f-0.6)/0.4)).(color.NRGBA)
+			c.A = uint8(maxAlpha)
+		}
+		s.palette[i] = c
+	}
+}
+
+type spectrogramRenderer struct {
+	s       *Spectrogram
+	objects []fyne.CanvasObject
+}
+
+func (r *spectrogramRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(150, 50)
+}
+
+func (r *spectrogramRenderer) Layout(size fyne.Size) {
+	r.s.canvasImg.Resize(size)
+}
+
+func (r *spectrogramRenderer) Refresh() {
+	r.s.updatePalette()
+	r.s.canvasImg.Refresh()
+}
+
+func (r *spectrogramRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *spectrogramRenderer) Destroy() {
+}
+
+func setPixel(img *image.NRGBA, x, y int, c color.NRGBA) {
+	offset := img.PixOffset(x, y)
+	img.Pix[offset+0] = c.R
+	img.Pix[offset+1] = c.G
+	img.Pix[offset+2] = c.B
+	img.Pix[offset+3] = c.A
+}
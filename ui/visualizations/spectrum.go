@@ -0,0 +1,179 @@
+package visualizations
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	spectrumRangeDB      = 70
+	spectrumFalloffDB    = 1.5 // per frame
+	spectrumBarSpacing   = 2
+	translucentAlpha     = 96
+	spectrumMinBandWidth = 2
+)
+
+// SpectrumDisplay is implemented by visualizations that consume the
+// levels of frequency bands (in dB) computed by util.SpectrumAnalyzer.
+type SpectrumDisplay interface {
+	fyne.CanvasObject
+
+	// UpdateBands updates the displayed band levels.
+	// This function is expected to be called from a fyne.Animation callback,
+	// running at 60 Hz
+	UpdateBands(bands []float64)
+}
+
+type SpectrumMode int
+
+const (
+	SpectrumModeBars SpectrumMode = iota
+	SpectrumModeLine
+)
+
+var _ SpectrumDisplay = (*SpectrumAnalyzer)(nil)
+
+// SpectrumAnalyzer displays the levels of frequency bands
+// as either a bar graph or a line graph.
+type SpectrumAnalyzer struct {
+	widget.BaseWidget
+
+	Mode SpectrumMode
+
+	// If true, draw with reduced opacity, for use
+	// as a background behind other content.
+	Translucent bool
+
+	levels []float64
+
+	// true iff only a layout is needed, rathern than a full refresh.
+	// cleared by the renderer
+	refreshLayoutOnly bool
+}
+
+func NewSpectrumAnalyzer(numBands int, mode SpectrumMode) *SpectrumAnalyzer {
+	s := &SpectrumAnalyzer{Mode: mode, levels: make([]float64, numBands)}
+	for i := range s.levels {
+		s.levels[i] = -spectrumRangeDB
+	}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+func (s *SpectrumAnalyzer) UpdateBands(bands []float64) {
+	for i := range min(len(bands), len(s.levels)) {
+		// bars jump up instantly and fall off gradually
+		s.levels[i] = max(bands[i], s.levels[i]-spectrumFalloffDB, -spectrumRangeDB)
+	}
+	s.refreshLayoutOnly = true
+	s.Refresh()
+}
+
+func (s *SpectrumAnalyzer) CreateRenderer() fyne.WidgetRenderer {
+	return newSpectrumRenderer(s)
+}
+
+func (s *SpectrumAnalyzer) Refresh() {
+	s.refreshLayoutOnly = false
+	s.BaseWidget.Refresh()
+}
+
+// heightFraction returns the fraction of the full height
+// at which the level of the given band should be drawn.
+func (s *SpectrumAnalyzer) heightFraction(band int) float32 {
+	return float32((s.levels[band] + spectrumRangeDB) / spectrumRangeDB)
+}
+
+type spectrumRenderer struct {
+	s *SpectrumAnalyzer
+
+	bars  []canvas.Rectangle
+	lines []canvas.Line
+
+	mode    SpectrumMode
+	objects []fyne.CanvasObject
+}
+
+func newSpectrumRenderer(s *SpectrumAnalyzer) *spectrumRenderer {
+	r := &spectrumRenderer{s: s}
+	r.bars = make([]canvas.Rectangle, len(s.levels))
+	r.lines = make([]canvas.Line, len(s.levels)-1)
+	r.updateObjects()
+	return r
+}
+
+func (r *spectrumRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(float32(len(r.s.levels)*spectrumMinBandWidth), 50)
+}
+
+func (r *spectrumRenderer) Layout(size fyne.Size) {
+	n := len(r.s.levels)
+	bandWidth := size.Width / float32(n)
+	switch r.mode {
+	case SpectrumModeBars:
+		barWidth := max(1, bandWidth-spectrumBarSpacing)
+		for i := range r.bars {
+			h := r.s.heightFraction(i) * size.Height
+			r.bars[i].Move(fyne.NewPos(float32(i)*bandWidth, size.Height-h))
+			r.bars[i].Resize(fyne.NewSize(barWidth, h))
+		}
+	case SpectrumModeLine:
+		for i := range r.lines {
+			x1 := (float32(i) + 0.5) * bandWidth
+			r.lines[i].Position1 = fyne.NewPos(x1, size.Height*(1-r.s.heightFraction(i)))
+			r.lines[i].Position2 = fyne.NewPos(x1+bandWidth, size.Height*(1-r.s.heightFraction(i+1)))
+			canvas.Refresh(&r.lines[i])
+		}
+	}
+}
+
+func (r *spectrumRenderer) Refresh() {
+	if r.s.refreshLayoutOnly {
+		r.s.refreshLayoutOnly = false
+		r.Layout(r.s.Size())
+		return
+	}
+
+	c := color.NRGBAModel.Convert(theme.PrimaryColor()).(color.NRGBA)
+	if r.s.Translucent {
+		c.A = translucentAlpha
+	}
+	for i := range r.bars {
+		r.bars[i].FillColor = c
+	}
+	for i := range r.lines {
+		r.lines[i].StrokeColor = c
+		r.lines[i].StrokeWidth = theme.SeparatorThicknessSize() * 2
+	}
+	if r.mode != r.s.Mode {
+		r.mode = r.s.Mode
+		r.updateObjects()
+	}
+	r.Layout(r.s.Size())
+}
+
+func (r *spectrumRenderer) updateObjects() {
+	r.mode = r.s.Mode
+	r.objects = make([]fyne.CanvasObject, 0, len(r.bars))
+	switch r.mode {
+	case SpectrumModeBars:
+		for i := range r.bars {
+			r.objects = append(r.objects, &r.bars[i])
+		}
+	case SpectrumModeLine:
+		for i := range r.lines {
+			r.objects = append(r.objects, &r.lines[i])
+		}
+	}
+}
+
+func (r *spectrumRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *spectrumRenderer) Destroy() {
+}
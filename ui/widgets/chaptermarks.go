@@ -0,0 +1,85 @@
+package widgets
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ChapterMarks draws tick marks at chapter positions,
+// to be stacked on top of a seek bar.
+type ChapterMarks struct {
+	widget.BaseWidget
+
+	// Horizontal inset of the seek bar's track from each edge.
+	Inset float32
+
+	marks []float64 // positions in the range [0, 1]
+	rects []*canvas.Rectangle
+}
+
+func NewChapterMarks() *ChapterMarks {
+	c := &ChapterMarks{}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetMarks sets the positions of the marks as ratios from 0 to 1
+// of the seek bar's length. Marks at the very start are not drawn.
+func (c *ChapterMarks) SetMarks(marks []float64) {
+	c.marks = c.marks[:0]
+	for _, m := range marks {
+		if m > 0 && m < 1 {
+			c.marks = append(c.marks, m)
+		}
+	}
+	c.Refresh()
+}
+
+func (c *ChapterMarks) CreateRenderer() fyne.WidgetRenderer {
+	return &chapterMarksRenderer{c: c}
+}
+
+type chapterMarksRenderer struct {
+	c       *ChapterMarks
+	objects []fyne.CanvasObject
+}
+
+func (r *chapterMarksRenderer) Layout(size fyne.Size) {
+	width := size.Width - 2*r.c.Inset
+	height := size.Height / 2
+	for i, rect := range r.c.rects {
+		x := r.c.Inset + width*float32(r.c.marks[i])
+		rect.Move(fyne.NewPos(x-1, (size.Height-height)/2))
+		rect.Resize(fyne.NewSize(2, height))
+	}
+}
+
+func (r *chapterMarksRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(0, 0)
+}
+
+func (r *chapterMarksRenderer) Refresh() {
+	fg := color.NRGBAModel.Convert(theme.ForegroundColor()).(color.NRGBA)
+	fg.A = 160
+	for len(r.c.rects) < len(r.c.marks) {
+		r.c.rects = append(r.c.rects, canvas.NewRectangle(fg))
+	}
+	r.c.rects = r.c.rects[:len(r.c.marks)]
+	r.objects = r.objects[:0]
+	for _, rect := range r.c.rects {
+		rect.FillColor = fg
+		r.objects = append(r.objects, rect)
+	}
+	r.Layout(r.c.Size())
+	canvas.Refresh(r.c)
+}
+
+func (r *chapterMarksRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *chapterMarksRenderer) Destroy() {}
@@ -133,6 +133,7 @@ type GridView struct {
 	itemWidth          float32
 	numColsCached      int
 	shareMenuItem      *fyne.MenuItem
+	copyLinkMenuItem   *fyne.MenuItem
 }
 
 type GridViewState struct {
@@ -152,6 +153,7 @@ type GridViewState struct {
 	OnFavorite          func(id string, fav bool)
 	OnDownload          func(id string)
 	OnShare             func(id string)
+	OnCopyLink          func(id string)
 	OnShowItemPage      func(id string)
 	OnShowSecondaryPage func(id string)
 
@@ -512,10 +514,15 @@ func (g *GridView) showContextMenu(card *GridViewItem, pos fyne.Position) {
 			g.OnShare(g.menuGridViewItemId)
 		})
 		g.shareMenuItem.Icon = myTheme.ShareIcon
-		g.menu = widget.NewPopUpMenu(fyne.NewMenu("", play, shuffle, queueNext, queue, playlist, download, g.shareMenuItem),
+		g.copyLinkMenuItem = fyne.NewMenuItem(lang.L("Copy link"), func() {
+			g.OnCopyLink(g.menuGridViewItemId)
+		})
+		g.copyLinkMenuItem.Icon = theme.ContentCopyIcon()
+		g.menu = widget.NewPopUpMenu(fyne.NewMenu("", play, shuffle, queueNext, queue, playlist, download, g.shareMenuItem, g.copyLinkMenuItem),
 			fyne.CurrentApp().Driver().CanvasForObject(g))
 	}
 	g.shareMenuItem.Disabled = g.DisableSharing
+	g.copyLinkMenuItem.Disabled = g.OnCopyLink == nil
 	g.menu.ShowAtPosition(pos)
 }
 
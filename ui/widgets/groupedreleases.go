@@ -43,6 +43,7 @@ type GroupedReleases struct {
 	OnFavorite          func(id string, fav bool)
 	OnDownload          func(id string)
 	OnShare             func(id string)
+	OnCopyLink          func(id string)
 	OnShowItemPage      func(id string)
 	OnShowSecondaryPage func(id string)
 
@@ -251,7 +252,13 @@ func (g *GroupedReleases) showContextMenu(card *GridViewItem, pos fyne.Position)
 			g.OnShare(g.menuGridViewItemId)
 		})
 		g.shareMenuItem.Icon = myTheme.ShareIcon
-		g.menu = widget.NewPopUpMenu(fyne.NewMenu("", play, shuffle, queueNext, queue, playlist, download, g.shareMenuItem),
+		copyLink := fyne.NewMenuItem(lang.L("Copy link"), func() {
+			if g.OnCopyLink != nil {
+				g.OnCopyLink(g.menuGridViewItemId)
+			}
+		})
+		copyLink.Icon = theme.ContentCopyIcon()
+		g.menu = widget.NewPopUpMenu(fyne.NewMenu("", play, shuffle, queueNext, queue, playlist, download, g.shareMenuItem, copyLink),
 			fyne.CurrentApp().Driver().CanvasForObject(g))
 	}
 	g.menu.ShowAtPosition(pos)
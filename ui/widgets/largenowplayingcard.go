@@ -156,6 +156,12 @@ func (n *LargeNowPlayingCard) Update(item mediaprovider.MediaItem) {
 		n.cover.PlaceholderIcon = myTheme.RadioIcon
 		n.isRadio = true
 		n.albumYear = ""
+	} else if ep, ok := item.(*mediaprovider.PodcastEpisode); ok {
+		n.artistName.BuildSegments([]string{ep.ChannelTitle}, nil)
+		n.ratingFavoriteContainer.Hidden = true
+		n.cover.PlaceholderIcon = myTheme.PodcastIcon
+		n.isRadio = false
+		n.albumYear = ""
 	}
 
 	n.Refresh()
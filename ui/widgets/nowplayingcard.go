@@ -153,6 +153,11 @@ func (n *NowPlayingCard) Update(track mediaprovider.MediaItem) {
 			n.albumName.BuildSegments([]string{tr.Album}, []string{tr.AlbumID})
 			n.albumYear = strconv.Itoa(tr.Year)
 			n.cover.PlaceholderIcon = myTheme.TracksIcon
+		} else if ep, ok := track.(*mediaprovider.PodcastEpisode); ok {
+			n.artistName.BuildSegments([]string{ep.ChannelTitle}, nil)
+			n.albumName.BuildSegments([]string{}, []string{})
+			n.albumName.Suffix = ""
+			n.cover.PlaceholderIcon = myTheme.PodcastIcon
 		} else {
 			n.artistName.BuildSegments([]string{}, []string{})
 			n.albumName.BuildSegments([]string{}, []string{})
@@ -161,7 +166,7 @@ func (n *NowPlayingCard) Update(track mediaprovider.MediaItem) {
 		}
 	}
 	n.trackName.Hidden = n.trackName.Text() == ""
-	n.trackName.SetMenuBtnEnabled(n.cover.PlaceholderIcon != myTheme.RadioIcon)
+	n.trackName.SetMenuBtnEnabled(n.cover.PlaceholderIcon == myTheme.TracksIcon)
 	n.artistName.Hidden = len(n.artistName.Segments) == 0
 	n.albumName.Hidden = len(n.albumName.Segments) == 0
 	n.Refresh()
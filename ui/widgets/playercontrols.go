@@ -91,6 +91,7 @@ type PlayerControls struct {
 
 	slider         *TrackPosSlider
 	waveform       *WaveformSeekbar
+	chapterMarks   *ChapterMarks
 	curTimeLabel   *labelMinSize
 	totalTimeLabel *labelMinSize
 	shuffle        *IconButton
@@ -134,6 +135,8 @@ func NewPlayerControls(useWaveformSeekbar bool, initialLoopMode backend.LoopMode
 	} else {
 		pc.waveform.Hidden = true
 	}
+	pc.chapterMarks = NewChapterMarks()
+	pc.updateChapterMarksInset()
 	pc.curTimeLabel = NewLabelMinSize(util.SecondsToMMSS(0), 55)
 	pc.curTimeLabel.Alignment = fyne.TextAlignTrailing
 	pc.totalTimeLabel = NewLabelMinSize(util.SecondsToMMSS(0), 55)
@@ -178,6 +181,7 @@ func NewPlayerControls(useWaveformSeekbar bool, initialLoopMode backend.LoopMode
 	seekCtrl := container.NewStack(
 		pc.slider,
 		pc.waveform,
+		pc.chapterMarks,
 	)
 	c := container.NewBorder(nil, nil, pc.curTimeLabel, pc.totalTimeLabel, seekCtrl)
 	pc.container = container.New(layout.NewCustomPaddedVBoxLayout(0), c, buttons)
@@ -282,12 +286,28 @@ func (p *PlayerControls) UpdateWaveformImg(img *backend.WaveformImage) {
 	p.waveform.UpdateImage(img)
 }
 
+// SetChapterMarks sets the positions of chapter marks on the seek bar,
+// as ratios from 0 to 1 of the track duration.
+func (p *PlayerControls) SetChapterMarks(marks []float64) {
+	p.chapterMarks.SetMarks(marks)
+}
+
 func (p *PlayerControls) Refresh() {
 	p.waveform.Hidden = !p.UseWaveformSeekbar
 	p.slider.Hidden = p.UseWaveformSeekbar
+	p.updateChapterMarksInset()
 	p.BaseWidget.Refresh()
 }
 
+func (p *PlayerControls) updateChapterMarksInset() {
+	if p.UseWaveformSeekbar {
+		p.chapterMarks.Inset = 0
+		return
+	}
+	// matches the end offset of the slider track
+	p.chapterMarks.Inset = (theme.IconInlineSize()-4)/2 + theme.InnerPadding() - 1.5
+}
+
 func (p *PlayerControls) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(p.container)
 }
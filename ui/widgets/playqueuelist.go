@@ -1,6 +1,7 @@
 package widgets
 
 import (
+	"fmt"
 	"image"
 	"slices"
 	"strconv"
@@ -51,8 +52,10 @@ type PlayQueueList struct {
 	OnDownload          func(tracks []*mediaprovider.Track, downloadName string)
 	OnShowTrackInfo     func(track *mediaprovider.Track)
 	OnShare             func(tracks []*mediaprovider.Track)
+	OnCopyLink          func(trackID string)
 	OnShowArtistPage    func(artistID string)
 	OnReorderItems      func(idxs []int, reorderTo int)
+	OnSeekToChapter     func(idx int)
 
 	useNonQueueMenu bool
 	menu            *util.TrackContextMenu // ctx menu for when only tracks are selected
@@ -60,6 +63,11 @@ type PlayQueueList struct {
 
 	nowPlayingID string
 
+	// chapters of the now playing item, shown as rows beneath it
+	chapters       []mediaprovider.Chapter
+	curChapter     int
+	chaptersRowIdx int // index of the item the chapters are shown under, or -1
+
 	list        *FocusList
 	colLayout   *layouts.ColumnsLayout
 	tracksMutex sync.RWMutex
@@ -67,7 +75,7 @@ type PlayQueueList struct {
 }
 
 func NewPlayQueueList(im *backend.ImageManager, useNonQueueMenu bool) *PlayQueueList {
-	p := &PlayQueueList{useNonQueueMenu: useNonQueueMenu}
+	p := &PlayQueueList{useNonQueueMenu: useNonQueueMenu, chaptersRowIdx: -1}
 	p.ExtendBaseWidget(p)
 
 	// #, Cover, Title/Artist, Time
@@ -89,32 +97,47 @@ func NewPlayQueueList(im *backend.ImageManager, useNonQueueMenu bool) *PlayQueue
 		},
 		func(itemID widget.ListItemID, item fyne.CanvasObject) {
 			p.tracksMutex.RLock()
+			idx, chapterIdx := p.rowToItemIdx(itemID)
 			// we could have removed tracks from the list in between
 			// Fyne calling the length callback and this update callback
 			// so the itemID may be out of bounds. if so, do nothing.
-			if itemID >= len(p.items) {
+			if idx >= len(p.items) {
 				p.tracksMutex.RUnlock()
 				return
 			}
-			model := p.items[itemID]
+			model := p.items[idx]
+			var chapter mediaprovider.Chapter
+			if chapterIdx >= 0 {
+				chapter = p.chapters[chapterIdx]
+			}
+			isCurChapter := chapterIdx == p.curChapter
 			p.tracksMutex.RUnlock()
 
 			tr := item.(*PlayQueueListRow)
-			if tr.trackID != model.Item.Metadata().ID || tr.ListItemID != itemID {
-				tr.ListItemID = itemID
+			tr.ListItemID = itemID
+			if chapterIdx >= 0 {
+				tr.UpdateChapter(chapter, chapterIdx+1, isCurChapter)
+				return
 			}
-			tr.Update(model, itemID+1)
+			tr.Update(model, idx+1)
 		},
 	)
 	p.list.OnDragBegin = func(id int) {
-		if !p.items[id].Selected {
-			p.selectTrack(id)
+		p.tracksMutex.RLock()
+		idx, _ := p.rowToItemIdx(id)
+		selected := p.items[idx].Selected
+		p.tracksMutex.RUnlock()
+		if !selected {
+			p.selectTrack(idx)
 			p.list.Refresh()
 		}
 	}
 	p.list.OnDragEnd = func(dragged, insertPos int) {
 		if p.OnReorderItems != nil {
-			p.OnReorderItems(p.selectedIdxs(), insertPos)
+			p.tracksMutex.RLock()
+			insertIdx := p.rowToInsertIdx(insertPos)
+			p.tracksMutex.RUnlock()
+			p.OnReorderItems(p.selectedIdxs(), insertIdx)
 		}
 	}
 
@@ -124,6 +147,7 @@ func NewPlayQueueList(im *backend.ImageManager, useNonQueueMenu bool) *PlayQueue
 func (p *PlayQueueList) SetTracks(trs []*mediaprovider.Track) {
 	p.tracksMutex.Lock()
 	p.items = util.ToTrackListModels(trs)
+	p.updateChaptersRowIdx()
 	p.tracksMutex.Unlock()
 	p.Refresh()
 }
@@ -133,6 +157,7 @@ func (p *PlayQueueList) SetItems(items []mediaprovider.MediaItem) {
 	p.items = sharedutil.MapSlice(items, func(item mediaprovider.MediaItem) *util.TrackListModel {
 		return &util.TrackListModel{Item: item}
 	})
+	p.updateChaptersRowIdx()
 	p.tracksMutex.Unlock()
 	p.Refresh()
 }
@@ -146,11 +171,17 @@ func (p *PlayQueueList) Items() []mediaprovider.MediaItem {
 // Sets the currently playing item ID and updates the list rendering
 func (p *PlayQueueList) SetNowPlaying(itemID string) {
 	prevNowPlaying := p.nowPlayingID
-	p.tracksMutex.RLock()
+	p.tracksMutex.Lock()
 	trPrev, idxPrev := util.FindItemByID(p.items, prevNowPlaying)
 	tr, idx := util.FindItemByID(p.items, itemID)
-	p.tracksMutex.RUnlock()
 	p.nowPlayingID = itemID
+	chaptersMoved := p.updateChaptersRowIdx()
+	idxPrev, idx = p.itemIdxToRow(idxPrev), p.itemIdxToRow(idx)
+	p.tracksMutex.Unlock()
+	if chaptersMoved {
+		p.list.Refresh()
+		return
+	}
 	if trPrev != nil {
 		p.list.RefreshItem(idxPrev)
 	}
@@ -159,6 +190,43 @@ func (p *PlayQueueList) SetNowPlaying(itemID string) {
 	}
 }
 
+// Sets the chapters of the now playing item, which are shown
+// as rows beneath it. Pass nil if the item has no chapters.
+func (p *PlayQueueList) SetNowPlayingChapters(chapters []mediaprovider.Chapter) {
+	p.tracksMutex.Lock()
+	p.chapters = chapters
+	p.curChapter = -1
+	p.updateChaptersRowIdx()
+	p.tracksMutex.Unlock()
+	p.list.Refresh()
+}
+
+// Updates the highlighted current chapter for the given playback position.
+func (p *PlayQueueList) UpdatePlayPos(curTime float64) {
+	p.tracksMutex.Lock()
+	if p.chaptersRowIdx < 0 {
+		p.tracksMutex.Unlock()
+		return
+	}
+	cur := -1
+	for i, ch := range p.chapters {
+		if ch.Start <= curTime {
+			cur = i
+		}
+	}
+	prev := p.curChapter
+	p.curChapter = cur
+	p.tracksMutex.Unlock()
+	if cur != prev {
+		if prev >= 0 {
+			p.list.RefreshItem(p.chaptersRowIdx + 1 + prev)
+		}
+		if cur >= 0 {
+			p.list.RefreshItem(p.chaptersRowIdx + 1 + cur)
+		}
+	}
+}
+
 func (p *PlayQueueList) SelectAll() {
 	p.tracksMutex.RLock()
 	util.SelectAllItems(p.items)
@@ -181,7 +249,7 @@ func (p *PlayQueueList) ScrollToNowPlaying() {
 	idx := slices.IndexFunc(p.items, func(item *util.TrackListModel) bool {
 		return item.Item.Metadata().ID == p.nowPlayingID
 	})
-	p.list.ScrollTo(idx)
+	p.list.ScrollTo(p.itemIdxToRow(idx))
 }
 
 func (p *PlayQueueList) Refresh() {
@@ -192,22 +260,82 @@ func (p *PlayQueueList) Refresh() {
 func (p *PlayQueueList) lenTracks() int {
 	p.tracksMutex.RLock()
 	defer p.tracksMutex.RUnlock()
+	if p.chaptersRowIdx >= 0 {
+		return len(p.items) + len(p.chapters)
+	}
 	return len(p.items)
 }
 
+// updates the index of the item the chapters are shown beneath.
+// Returns true if it changed. Must be called with tracksMutex locked.
+func (p *PlayQueueList) updateChaptersRowIdx() bool {
+	prev := p.chaptersRowIdx
+	p.chaptersRowIdx = -1
+	if len(p.chapters) > 0 && p.nowPlayingID != "" {
+		_, p.chaptersRowIdx = util.FindItemByID(p.items, p.nowPlayingID)
+	}
+	return p.chaptersRowIdx != prev
+}
+
+// maps a list row to the index of its item, and the index of its chapter
+// if it is a chapter row (else -1). Must be called with tracksMutex locked.
+func (p *PlayQueueList) rowToItemIdx(row int) (int, int) {
+	switch {
+	case p.chaptersRowIdx < 0 || row <= p.chaptersRowIdx:
+		return row, -1
+	case row <= p.chaptersRowIdx+len(p.chapters):
+		return p.chaptersRowIdx, row - p.chaptersRowIdx - 1
+	default:
+		return row - len(p.chapters), -1
+	}
+}
+
+// maps an item index to its list row. Must be called with tracksMutex locked.
+func (p *PlayQueueList) itemIdxToRow(idx int) int {
+	if p.chaptersRowIdx < 0 || idx <= p.chaptersRowIdx {
+		return idx
+	}
+	return idx + len(p.chapters)
+}
+
+// maps a drag-and-drop insert position in list rows to an item index.
+// Must be called with tracksMutex locked.
+func (p *PlayQueueList) rowToInsertIdx(row int) int {
+	switch {
+	case p.chaptersRowIdx < 0 || row <= p.chaptersRowIdx:
+		return row
+	case row <= p.chaptersRowIdx+len(p.chapters):
+		return p.chaptersRowIdx + 1
+	default:
+		return row - len(p.chapters)
+	}
+}
+
 func (t *PlayQueueList) onArtistTapped(artistID string) {
 	if t.OnShowArtistPage != nil {
 		t.OnShowArtistPage(artistID)
 	}
 }
 
-func (p *PlayQueueList) onPlayTrackAt(idx int) {
+func (p *PlayQueueList) onPlayTrackAt(row int) {
+	p.tracksMutex.RLock()
+	idx, chapterIdx := p.rowToItemIdx(row)
+	p.tracksMutex.RUnlock()
+	if chapterIdx >= 0 {
+		if p.OnSeekToChapter != nil {
+			p.OnSeekToChapter(chapterIdx)
+		}
+		return
+	}
 	if p.OnPlayItemAt != nil {
 		p.OnPlayItemAt(idx)
 	}
 }
 
-func (p *PlayQueueList) onSelectTrack(idx int) {
+func (p *PlayQueueList) onSelectTrack(row int) {
+	p.tracksMutex.RLock()
+	idx, _ := p.rowToItemIdx(row)
+	p.tracksMutex.RUnlock()
 	if d, ok := fyne.CurrentApp().Driver().(desktop.Driver); ok {
 		mod := d.CurrentKeyModifiers()
 		if mod&fyne.KeyModifierShortcutDefault != 0 {
@@ -241,14 +369,17 @@ func (p *PlayQueueList) selectRange(idx int) {
 	util.SelectItemRange(p.items, idx)
 }
 
-func (p *PlayQueueList) onShowContextMenu(e *fyne.PointEvent, trackIdx int) {
+func (p *PlayQueueList) onShowContextMenu(e *fyne.PointEvent, row int) {
+	p.tracksMutex.RLock()
+	trackIdx, _ := p.rowToItemIdx(row)
+	p.tracksMutex.RUnlock()
 	p.selectTrack(trackIdx)
 	p.list.Refresh()
 	selected := p.selectedItems()
 
 	allTracks := true
 	for _, item := range selected {
-		if item.Metadata().Type == mediaprovider.MediaItemTypeRadioStation {
+		if item.Metadata().Type != mediaprovider.MediaItemTypeTrack {
 			allTracks = false
 			break
 		}
@@ -306,6 +437,11 @@ func (p *PlayQueueList) ensureTracksMenu() {
 	p.menu.OnShare = func() {
 		p.OnShare(p.selectedTracks())
 	}
+	p.menu.OnCopyLink = func() {
+		if tracks := p.selectedTracks(); len(tracks) > 0 && p.OnCopyLink != nil {
+			p.OnCopyLink(tracks[0].ID)
+		}
+	}
 	p.menu.OnSetRating = func(rating int) {
 		p.OnSetRating(p.selectedItemIDs(), rating)
 	}
@@ -367,6 +503,7 @@ type PlayQueueListRow struct {
 	playQueueList *PlayQueueList
 	trackID       string
 	isPlaying     bool
+	isChapter     bool
 
 	playingIcon fyne.CanvasObject
 	num         *widget.Label
@@ -432,6 +569,14 @@ func (p *PlayQueueListRow) Update(tm *util.TrackListModel, rowNum int) {
 	if tm.Selected != p.Selected {
 		p.Selected = tm.Selected
 	}
+	if p.isChapter {
+		p.isChapter = false
+		p.trackID = "" // force re-binding below
+		p.num.Importance = widget.MediumImportance
+		p.title.Importance = widget.MediumImportance
+		p.title.TextStyle.Bold = false
+		p.cover.Show()
+	}
 
 	if num := strconv.Itoa(rowNum); p.num.Text != num {
 		p.num.Text = num
@@ -441,9 +586,12 @@ func (p *PlayQueueListRow) Update(tm *util.TrackListModel, rowNum int) {
 	// a new track (*mediaprovider.Track)
 	meta := tm.Item.Metadata()
 	if meta.ID != p.trackID {
-		if meta.Type == mediaprovider.MediaItemTypeRadioStation {
+		switch meta.Type {
+		case mediaprovider.MediaItemTypeRadioStation:
 			p.cover.PlaceholderIcon = myTheme.RadioIcon
-		} else {
+		case mediaprovider.MediaItemTypePodcastEpisode:
+			p.cover.PlaceholderIcon = myTheme.PodcastIcon
+		default:
 			p.cover.PlaceholderIcon = myTheme.TracksIcon
 		}
 		p.imageLoader.Load(meta.CoverArtID)
@@ -473,3 +621,40 @@ func (p *PlayQueueListRow) Update(tm *util.TrackListModel, rowNum int) {
 	// hidden and re-shown after a theme variant change
 	p.Refresh()
 }
+
+// UpdateChapter binds the row to a chapter of the now playing item.
+func (p *PlayQueueListRow) UpdateChapter(chapter mediaprovider.Chapter, chapterNum int, isCurrent bool) {
+	if !p.isChapter {
+		p.isChapter = true
+		p.trackID = ""
+		p.EnsureUnfocused()
+		p.imageLoader.Load("")
+		p.cover.Hide()
+		p.num.Importance = widget.LowImportance
+	}
+	p.Selected = false
+
+	p.num.Text = strconv.Itoa(chapterNum)
+	title := chapter.Title
+	if title == "" {
+		title = fmt.Sprintf("%s %d", lang.L("Chapter"), chapterNum)
+	}
+	p.title.Text = title
+	p.title.SetToolTip(title)
+	if chapter.Performer != "" {
+		p.artist.BuildSegments([]string{chapter.Performer}, nil)
+	} else {
+		p.artist.Segments = nil
+	}
+	p.time.Text = util.SecondsToMMSS(chapter.Start)
+
+	p.title.TextStyle.Bold = isCurrent
+	if isCurrent {
+		p.title.Importance = widget.HighImportance
+	} else {
+		p.title.Importance = widget.MediumImportance
+	}
+	p.isPlaying = false
+	p.Content.(*fyne.Container).Objects[0] = container.NewCenter(p.num)
+	p.Refresh()
+}
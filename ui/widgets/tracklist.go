@@ -69,9 +69,11 @@ type Tracklist struct {
 	OnSetRating         func(trackIDs []string, rating int)
 	OnDownload          func(tracks []*mediaprovider.Track, downloadName string)
 	OnShare             func(trackID string)
+	OnCopyLink          func(trackID string)
 	OnPlaySongRadio     func(track *mediaprovider.Track)
 	OnReorderTracks     func(trackIDs []string, insertPos int)
 	OnShowTrackInfo     func(track *mediaprovider.Track)
+	OnSeekToChapter     func(idx int)
 
 	OnShowArtistPage func(artistID string)
 	OnShowAlbumPage  func(albumID string)
@@ -94,6 +96,11 @@ type Tracklist struct {
 	ctxMenu      *util.TrackContextMenu
 	loadingDots  *LoadingDots
 	container    *fyne.Container
+
+	// chapters of the now playing track, shown as rows beneath it
+	chapters       []mediaprovider.Chapter
+	curChapter     int
+	chaptersRowIdx int // index of the track the chapters are shown under, or -1
 }
 
 func NewTracklist(tracks []*mediaprovider.Track, im *backend.ImageManager, useCompactRows bool) *Tracklist {
@@ -101,7 +108,7 @@ func NewTracklist(tracks []*mediaprovider.Track, im *backend.ImageManager, useCo
 	playIcon := theme.NewThemedResource(theme.MediaPlayIcon())
 	playIcon.ColorName = theme.ColorNamePrimary
 
-	t := &Tracklist{compactRows: useCompactRows}
+	t := &Tracklist{compactRows: useCompactRows, chaptersRowIdx: -1}
 	t.ExtendBaseWidget(t)
 	t.columns = ExpandedTracklistRowColumns
 	colWidths := ExpandedTracklistRowColumnWidths
@@ -137,7 +144,7 @@ func NewTracklist(tracks []*mediaprovider.Track, im *backend.ImageManager, useCo
 	}
 
 	t.list = NewFocusList(
-		func() int { return len(t.tracks) },
+		t.lenRows,
 		func() fyne.CanvasObject {
 			var tr TracklistRow
 			if t.compactRows {
@@ -158,36 +165,43 @@ func NewTracklist(tracks []*mediaprovider.Track, im *backend.ImageManager, useCo
 			return tr
 		},
 		func(itemID widget.ListItemID, item fyne.CanvasObject) {
+			idx, chapterIdx := t.rowToTrackIdx(itemID)
 			// we could have removed tracks from the list in between
 			// Fyne calling the length callback and this update callback
 			// so the itemID may be out of bounds. if so, do nothing.
-			if itemID >= len(t.tracks) {
+			if idx >= len(t.tracks) {
 				return
 			}
-			model := t.tracks[itemID]
+			model := t.tracks[idx]
 
 			tr := item.(TracklistRow)
+			if chapterIdx >= 0 {
+				tr.SetItemID(itemID)
+				tr.UpdateChapter(t.chapters[chapterIdx], chapterIdx+1, chapterIdx == t.curChapter)
+				return
+			}
 			if tr.TrackID() != model.Item.Metadata().ID || tr.ItemID() != itemID {
 				tr.SetItemID(itemID)
 			}
 			i := -1 // signal that we want to display the actual track num.
 			if t.Options.AutoNumber {
-				i = itemID + 1
+				i = idx + 1
 			}
 			tr.Update(model, i, func() {})
 			if t.OnTrackShown != nil {
-				t.OnTrackShown(itemID)
+				t.OnTrackShown(idx)
 			}
 		})
 	t.list.OnDragBegin = func(id int) {
-		if !t.tracks[id].Selected {
-			t.selectTrack(id)
+		idx, _ := t.rowToTrackIdx(id)
+		if !t.tracks[idx].Selected {
+			t.selectTrack(idx)
 			t.list.Refresh()
 		}
 	}
 	t.list.OnDragEnd = func(dragged, insertPos int) {
 		if t.OnReorderTracks != nil {
-			t.OnReorderTracks(t.SelectedTrackIDs(), insertPos)
+			t.OnReorderTracks(t.SelectedTrackIDs(), t.rowToInsertIdx(insertPos))
 		}
 	}
 	t.loadingDots = NewLoadingDots()
@@ -297,11 +311,47 @@ func (t *Tracklist) SetNowPlaying(trackID string) {
 	trPrev, idxPrev := util.FindItemByID(t.tracks, prevNowPlaying)
 	tr, idx := util.FindItemByID(t.tracks, trackID)
 	t.nowPlayingID = trackID
+	if t.updateChaptersRowIdx() {
+		t.list.Refresh()
+		return
+	}
 	if trPrev != nil {
-		t.list.RefreshItem(idxPrev)
+		t.list.RefreshItem(t.trackIdxToRow(idxPrev))
 	}
 	if tr != nil {
-		t.list.RefreshItem(idx)
+		t.list.RefreshItem(t.trackIdxToRow(idx))
+	}
+}
+
+// Sets the chapters of the now playing track, which are shown
+// as rows beneath it. Pass nil if the track has no chapters.
+func (t *Tracklist) SetNowPlayingChapters(chapters []mediaprovider.Chapter) {
+	t.chapters = chapters
+	t.curChapter = -1
+	t.updateChaptersRowIdx()
+	t.list.Refresh()
+}
+
+// Updates the highlighted current chapter for the given playback position.
+func (t *Tracklist) UpdatePlayPos(curTime float64) {
+	if t.chaptersRowIdx < 0 {
+		return
+	}
+	cur := -1
+	for i, ch := range t.chapters {
+		if ch.Start <= curTime {
+			cur = i
+		}
+	}
+	prev := t.curChapter
+	t.curChapter = cur
+	if cur != prev {
+		if prev >= 0 {
+			t.list.RefreshItem(t.chaptersRowIdx + 1 + prev)
+		}
+		if cur >= 0 {
+			t.list.RefreshItem(t.chaptersRowIdx + 1 + cur)
+		}
 	}
 }
 
@@ -310,7 +360,7 @@ func (t *Tracklist) IncrementPlayCount(trackID string) {
 	tr, idx := util.FindItemByID(t.tracks, trackID)
 	if tr != nil {
 		tr.(*mediaprovider.Track).PlayCount += 1
-		t.list.RefreshItem(idx)
+		t.list.RefreshItem(t.trackIdxToRow(idx))
 	}
 }
 
@@ -318,6 +368,8 @@ func (t *Tracklist) IncrementPlayCount(trackID string) {
 func (t *Tracklist) Clear() {
 	t.tracks = nil
 	t.tracksOrigOrder = nil
+	t.chapters = nil
+	t.chaptersRowIdx = -1
 }
 
 // Sets the tracks in the tracklist.
@@ -329,6 +381,7 @@ func (t *Tracklist) SetTracks(trs []*mediaprovider.Track) {
 func (t *Tracklist) _setTracks(trs []*mediaprovider.Track) {
 	t.tracksOrigOrder = util.ToTrackListModels(trs)
 	t.doSortTracks()
+	t.updateChaptersRowIdx()
 }
 
 // Returns the tracks in the tracklist in the current display order.
@@ -342,6 +395,7 @@ func (t *Tracklist) GetTracks() []*mediaprovider.Track {
 func (t *Tracklist) AppendTracks(trs []*mediaprovider.Track) {
 	t.tracksOrigOrder = append(t.tracksOrigOrder, util.ToTrackListModels(trs)...)
 	t.doSortTracks()
+	t.updateChaptersRowIdx()
 	t.list.Refresh()
 }
 
@@ -370,7 +424,7 @@ func (t *Tracklist) SelectAndScrollToTrack(trackID string) {
 		}
 	}
 	if idx >= 0 {
-		t.list.ScrollTo(idx)
+		t.list.ScrollTo(t.trackIdxToRow(idx))
 	}
 }
 
@@ -384,6 +438,57 @@ func (t *Tracklist) Refresh() {
 	t.BaseWidget.Refresh()
 }
 
+func (t *Tracklist) lenRows() int {
+	if t.chaptersRowIdx >= 0 {
+		return len(t.tracks) + len(t.chapters)
+	}
+	return len(t.tracks)
+}
+
+// updates the index of the track the chapters are shown beneath.
+// Returns true if it changed.
+func (t *Tracklist) updateChaptersRowIdx() bool {
+	prev := t.chaptersRowIdx
+	t.chaptersRowIdx = -1
+	if len(t.chapters) > 0 && t.nowPlayingID != "" {
+		_, t.chaptersRowIdx = util.FindItemByID(t.tracks, t.nowPlayingID)
+	}
+	return t.chaptersRowIdx != prev
+}
+
+// maps a list row to the index of its track, and the index
+// of its chapter if it is a chapter row (else -1).
+func (t *Tracklist) rowToTrackIdx(row int) (int, int) {
+	switch {
+	case t.chaptersRowIdx < 0 || row <= t.chaptersRowIdx:
+		return row, -1
+	case row <= t.chaptersRowIdx+len(t.chapters):
+		return t.chaptersRowIdx, row - t.chaptersRowIdx - 1
+	default:
+		return row - len(t.chapters), -1
+	}
+}
+
+// maps a track index to its list row.
+func (t *Tracklist) trackIdxToRow(idx int) int {
+	if t.chaptersRowIdx < 0 || idx <= t.chaptersRowIdx {
+		return idx
+	}
+	return idx + len(t.chapters)
+}
+
+// maps a drag-and-drop insert position in list rows to a track index.
+func (t *Tracklist) rowToInsertIdx(row int) int {
+	switch {
+	case t.chaptersRowIdx < 0 || row <= t.chaptersRowIdx:
+		return row
+	case row <= t.chaptersRowIdx+len(t.chapters):
+		return t.chaptersRowIdx + 1
+	default:
+		return row - len(t.chapters)
+	}
+}
+
 // do nothing Tapped handler so that tapping the separator between rows
 // doesn't fall through to the page (which calls UnselectAll on tracklist)
 func (t *Tracklist) Tapped(*fyne.PointEvent) {}
@@ -472,16 +577,25 @@ func (t *Tracklist) doSortTracks() {
 func (t *Tracklist) onSorted(sort ListHeaderSort) {
 	t.sorting = TracklistSort{ColumnName: t.colName(sort.ColNumber), SortOrder: sort.Type}
 	t.doSortTracks()
+	t.updateChaptersRowIdx()
 	t.Refresh()
 }
 
-func (t *Tracklist) onPlayTrackAt(idx int) {
+func (t *Tracklist) onPlayTrackAt(row int) {
+	idx, chapterIdx := t.rowToTrackIdx(row)
+	if chapterIdx >= 0 {
+		if t.OnSeekToChapter != nil {
+			t.OnSeekToChapter(chapterIdx)
+		}
+		return
+	}
 	if t.OnPlayTrackAt != nil {
 		t.OnPlayTrackAt(idx)
 	}
 }
 
-func (t *Tracklist) onSelectTrack(idx int) {
+func (t *Tracklist) onSelectTrack(row int) {
+	idx, _ := t.rowToTrackIdx(row)
 	if d, ok := fyne.CurrentApp().Driver().(desktop.Driver); ok {
 		mod := d.CurrentKeyModifiers()
 		if mod&fyne.KeyModifierShortcutDefault != 0 {
@@ -509,7 +623,8 @@ func (t *Tracklist) selectRange(idx int) {
 	util.SelectItemRange(t.tracks, idx)
 }
 
-func (t *Tracklist) onShowContextMenu(e *fyne.PointEvent, trackIdx int) {
+func (t *Tracklist) onShowContextMenu(e *fyne.PointEvent, row int) {
+	trackIdx, _ := t.rowToTrackIdx(row)
 	if t.selectTrack(trackIdx) {
 		t.list.Refresh()
 	}
@@ -543,12 +658,18 @@ func (t *Tracklist) onShowContextMenu(e *fyne.PointEvent, trackIdx int) {
 		t.ctxMenu.OnShare = func() {
 			t.onShare(t.SelectedTracks())
 		}
+		t.ctxMenu.OnCopyLink = func() {
+			if tracks := t.SelectedTracks(); len(tracks) > 0 && t.OnCopyLink != nil {
+				t.OnCopyLink(tracks[0].ID)
+			}
+		}
 		t.ctxMenu.OnSetRating = func(rating int) {
 			t.onSetRatings(t.SelectedTracks(), rating, true /*needRefresh*/)
 		}
 	}
 	t.ctxMenu.SetRatingDisabled(t.Options.DisableRating)
 	t.ctxMenu.SetShareDisabled(t.Options.DisableSharing || len(t.SelectedTracks()) != 1)
+	t.ctxMenu.SetCopyLinkDisabled(len(t.SelectedTracks()) != 1)
 	t.ctxMenu.SetInfoDisabled(len(t.SelectedTracks()) != 1)
 	t.ctxMenu.ShowAtPosition(e.AbsolutePosition, fyne.CurrentApp().Driver().CanvasForObject(t))
 }
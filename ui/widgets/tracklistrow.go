@@ -212,6 +212,7 @@ type tracklistRowBase struct {
 	isPlaying  bool
 	isFavorite bool
 	playCount  int
+	isChapter  bool
 
 	nextUpdateModel  *util.TrackListModel
 	nextUpdateRowNum int
@@ -237,6 +238,15 @@ type tracklistRowBase struct {
 	dateAdded   *widget.Label
 	path        *ttwidget.Label
 
+	// the track columns, and the columns shown in their place
+	// when the row is bound to a chapter of the now playing track
+	cols             *fyne.Container
+	chapterCols      *fyne.Container
+	chapterNum       *widget.Label
+	chapterTitle     *ttwidget.Label
+	chapterPerformer *widget.Label
+	chapterStart     *widget.Label
+
 	// must be injected by extending widget
 	setColVisibility func(int, bool) bool
 	layout           func(fyne.Size)
@@ -250,6 +260,7 @@ type TracklistRow interface {
 
 	TrackID() string
 	Update(model *util.TrackListModel, rowNum int, onDone func())
+	UpdateChapter(chapter mediaprovider.Chapter, chapterNum int, isCurrent bool)
 }
 
 type ExpandedTracklistRow struct {
@@ -288,10 +299,13 @@ func NewExpandedTracklistRow(tracklist *Tracklist, im *backend.ImageManager, pla
 		container.New(layout.NewCustomPaddedVBoxLayout(theme.Padding()-16),
 			t.name, t.artist))
 
+	chapterTitle := container.New(layout.NewCustomPaddedVBoxLayout(theme.Padding()-16),
+		t.chapterTitle, t.chapterPerformer)
+
 	v := makeVerticallyCentered // func alias
 	container := container.New(tracklist.colLayout,
 		v(t.num), titleArtistImg, v(t.album), v(t.albumArtist), v(t.composer), v(t.genre), v(t.dur), v(t.year), v(t.favorite), v(t.rating), v(t.plays), v(t.lastPlayed), v(t.comment), v(t.bpm), v(t.bitrate), v(t.size), v(t.fileType), v(t.dateAdded), v(t.path))
-	t.Content = container
+	t.setContent(container, t.newChapterColumns(v, chapterTitle))
 	t.setColVisibility = func(colNum int, vis bool) bool {
 		c := container.Objects[colNum].(*fyne.Container)
 		wasHidden := c.Hidden
@@ -322,7 +336,9 @@ func NewCompactTracklistRow(tracklist *Tracklist, playingIcon fyne.CanvasObject)
 
 	container := container.New(tracklist.colLayout,
 		t.num, t.name, t.artist, t.album, t.albumArtist, t.composer, t.genre, t.dur, t.year, t.favorite, t.rating, t.plays, t.lastPlayed, t.comment, t.bpm, t.bitrate, t.size, t.fileType, t.dateAdded, t.path)
-	t.Content = container
+	chapterCols := t.newChapterColumns(func(o fyne.CanvasObject) fyne.CanvasObject { return o }, t.chapterTitle)
+	chapterCols.Objects[tracklist.ColNumber(ColumnArtist)] = t.chapterPerformer
+	t.setContent(container, chapterCols)
 
 	colHiddenPtrMap := map[int]*bool{
 		2:  &t.artist.Hidden,
@@ -407,6 +423,44 @@ func (t *tracklistRowBase) create(tracklist *Tracklist) {
 	t.path = util.NewTruncatingTooltipLabel()
 	t.path.OnMouseIn = t.MouseIn
 	t.path.OnMouseOut = t.MouseOut
+	t.chapterNum = util.NewTrailingAlignLabel()
+	t.chapterNum.Importance = widget.LowImportance
+	t.chapterTitle = util.NewTruncatingTooltipLabel()
+	t.chapterTitle.OnMouseIn = t.MouseIn
+	t.chapterTitle.OnMouseOut = t.MouseOut
+	t.chapterPerformer = util.NewTruncatingLabel()
+	t.chapterStart = util.NewTrailingAlignLabel()
+}
+
+// newChapterColumns creates the columns shown for chapter rows: the chapter
+// number, title and start time, with empty cells for the other columns.
+func (t *tracklistRowBase) newChapterColumns(wrap func(fyne.CanvasObject) fyne.CanvasObject, title fyne.CanvasObject) *fyne.Container {
+	objs := make([]fyne.CanvasObject, len(t.tracklist.columns))
+	for i := range objs {
+		objs[i] = layout.NewSpacer()
+	}
+	objs[0] = wrap(t.chapterNum)
+	objs[1] = title
+	objs[t.tracklist.ColNumber(ColumnTime)] = wrap(t.chapterStart)
+	return container.New(t.tracklist.colLayout, objs...)
+}
+
+func (t *tracklistRowBase) setContent(cols, chapterCols *fyne.Container) {
+	t.cols = cols
+	t.chapterCols = chapterCols
+	t.chapterCols.Hide()
+	t.Content = container.NewStack(cols, chapterCols)
+}
+
+func (t *tracklistRowBase) updateColumnVisibility() {
+	for i := 2; i < len(t.tracklist.columns); i++ {
+		t.setColVisibility(i, !t.tracklist.visibleColumns[i])
+		if t.tracklist.visibleColumns[i] {
+			t.chapterCols.Objects[i].Show()
+		} else {
+			t.chapterCols.Objects[i].Hide()
+		}
+	}
 }
 
 func (t *tracklistRowBase) SetOnTappedSecondary(f func(*fyne.PointEvent, int)) {
@@ -426,10 +480,15 @@ func (t *tracklistRowBase) MinSize() fyne.Size {
 }
 
 func (t *tracklistRowBase) Update(tm *util.TrackListModel, rowNum int, onUpdate func()) {
-	// Show only columns configured to be visible
-	for i := 2; i < len(t.tracklist.columns); i++ {
-		t.setColVisibility(i, !t.tracklist.visibleColumns[i])
+	if t.isChapter {
+		t.isChapter = false
+		t.trackID = "" // force re-binding below
+		t.chapterCols.Hide()
+		t.cols.Show()
 	}
+
+	// Show only columns configured to be visible
+	t.updateColumnVisibility()
 	t.layout(t.Size())
 
 	// Ealy return if nothing else needs updating
@@ -557,10 +616,10 @@ func (t *tracklistRowBase) doUpdate(tm *util.TrackListModel, rowNum int) {
 		t.name.Refresh()
 
 		if isPlaying {
-			t.originalNumColContent = t.Content.(*fyne.Container).Objects[0]
-			t.Content.(*fyne.Container).Objects[0] = t.playingIcon
+			t.originalNumColContent = t.cols.Objects[0]
+			t.cols.Objects[0] = t.playingIcon
 		} else {
-			t.Content.(*fyne.Container).Objects[0] = t.originalNumColContent
+			t.cols.Objects[0] = t.originalNumColContent
 		}
 		canvas.Refresh(t)
 	}
@@ -588,6 +647,38 @@ func (t *tracklistRowBase) doUpdate(tm *util.TrackListModel, rowNum int) {
 	}
 }
 
+// UpdateChapter binds the row to a chapter of the now playing track.
+func (t *tracklistRowBase) UpdateChapter(chapter mediaprovider.Chapter, chapterNum int, isCurrent bool) {
+	if !t.isChapter {
+		t.isChapter = true
+		t.trackID = ""
+		t.nextUpdateModel = nil // cancel any throttled track update
+		t.EnsureUnfocused()
+		t.cols.Hide()
+		t.chapterCols.Show()
+	}
+	t.Selected = false
+	t.updateColumnVisibility()
+
+	t.chapterNum.Text = strconv.Itoa(chapterNum)
+	title := chapter.Title
+	if title == "" {
+		title = fmt.Sprintf("%s %d", lang.L("Chapter"), chapterNum)
+	}
+	t.chapterTitle.Text = title
+	t.chapterTitle.SetToolTip(title)
+	t.chapterPerformer.Text = chapter.Performer
+	t.chapterStart.Text = util.SecondsToMMSS(chapter.Start)
+
+	t.chapterTitle.TextStyle.Bold = isCurrent
+	if isCurrent {
+		t.chapterTitle.Importance = widget.HighImportance
+	} else {
+		t.chapterTitle.Importance = widget.MediumImportance
+	}
+	t.Refresh()
+}
+
 func (t *tracklistRowBase) toggleFavorited() {
 	t.isFavorite = !t.isFavorite
 	favIcon := t.favorite.Objects[0].(*FavoriteIcon)